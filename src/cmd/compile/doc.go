@@ -101,6 +101,13 @@ Flags:
 		and diagnose imports that would cause a circular dependency.
 	-pack
 		Write a package (archive) file rather than an object file
+	-pgoprofile file
+		Use the CPU profile in file, as written by runtime/pprof, for
+		profile-guided optimization: functions called from hot call
+		sites get a larger inlining budget, hot interface method
+		calls are devirtualized behind a type assertion, and the
+		branches of if statements are laid out by how often the
+		profile shows them taken.
 	-race
 		Compile with race detector enabled.
 	-s
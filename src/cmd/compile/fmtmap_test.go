@@ -77,6 +77,7 @@ var knownFormats = map[string]string{
 	"*cmd/compile/internal/types2.TypeName %s":        "",
 	"*cmd/compile/internal/types2.TypeName %v":        "",
 	"*cmd/compile/internal/types2.TypeParam %s":       "",
+	"*cmd/compile/internal/types2.TypeParam %v":       "",
 	"*cmd/compile/internal/types2.Var %s":             "",
 	"*cmd/compile/internal/types2.operand %s":         "",
 	"*cmd/compile/internal/types2.substMap %s":        "",
@@ -99,7 +100,6 @@ var knownFormats = map[string]string{
 	"[]cmd/compile/internal/ssa.posetUndo %v":         "",
 	"[]cmd/compile/internal/syntax.token %s":          "",
 	"[]cmd/compile/internal/types2.Type %s":           "",
-	"[]cmd/compile/internal/types2.Type %v":           "",
 	"[]int %v":                                        "",
 	"[]string %v":                                     "",
 	"[]uint32 %v":                                     "",
@@ -159,6 +159,8 @@ var knownFormats = map[string]string{
 	"cmd/compile/internal/syntax.Operator %s":         "",
 	"cmd/compile/internal/syntax.Pos %s":              "",
 	"cmd/compile/internal/syntax.Pos %v":              "",
+	"cmd/compile/internal/syntax.SimpleStmt %T":       "",
+	"cmd/compile/internal/syntax.Stmt %T":             "",
 	"cmd/compile/internal/syntax.position %s":         "",
 	"cmd/compile/internal/syntax.token %q":            "",
 	"cmd/compile/internal/syntax.token %s":            "",
@@ -374,7 +374,10 @@ func closureType(clo *Node) *types.Type {
 		if !v.Name.Byval() {
 			typ = types.NewPtr(typ)
 		}
-		fields = append(fields, symfield(v.Sym, typ))
+		// Variables of copies of the generic code of other
+		// packages are in those packages, but the fields of
+		// the struct must all be in one.
+		fields = append(fields, symfield(lookup(v.Sym.Name), typ))
 	}
 	typ := tostruct(fields)
 	typ.SetNoalg(true)
@@ -72,7 +72,7 @@ func declare(n *Node, ctxt Class) {
 	s := n.Sym
 
 	// kludgy: typecheckok means we're past parsing. Eg genwrapper may declare out of package names later.
-	if !inimport && !typecheckok && s.Pkg != localpkg {
+	if !inimport && !typecheckok && s.Pkg != localpkg && !stenciledPkgs[s.Pkg] {
 		yyerrorl(n.Pos, "cannot declare name %v", s)
 	}
 
@@ -600,6 +600,9 @@ func tostruct(l []*Node) *types.Type {
 		fields[i] = f
 	}
 	t.SetFields(fields)
+	if pkg := stenciledPkg(fields); pkg != nil {
+		t.SetPkg(pkg)
+	}
 
 	checkdupfields("field", t.FieldSlice())
 
@@ -687,6 +690,9 @@ func tointerface(l []*Node) *types.Type {
 		fields = append(fields, f)
 	}
 	t.SetInterface(fields)
+	if pkg := stenciledPkg(fields); pkg != nil {
+		t.SetPkg(pkg)
+	}
 	return t
 }
 
@@ -720,6 +726,11 @@ func functype(this *Node, in, out []*Node) *types.Type {
 	t.FuncType().Results = tofunargs(out, types.FunargResults)
 
 	checkdupfields("argument", t.Recvs().FieldSlice(), t.Params().FieldSlice(), t.Results().FieldSlice())
+	for _, fs := range [][]*types.Field{t.Recvs().FieldSlice(), t.Params().FieldSlice(), t.Results().FieldSlice()} {
+		if pkg := stenciledPkg(fs); pkg != nil {
+			t.SetPkg(pkg)
+		}
+	}
 
 	if t.Recvs().Broke() || t.Results().Broke() || t.Params().Broke() {
 		t.SetBroke(true)
@@ -879,7 +890,7 @@ func addmethod(msym *types.Sym, t *types.Type, local, nointerface bool) *types.F
 		return nil
 	}
 
-	if local && mt.Sym.Pkg != localpkg {
+	if local && mt.Sym.Pkg != localpkg && !isInstSym(mt.Sym) {
 		yyerror("cannot define new methods on non-local type %v", mt)
 		return nil
 	}
@@ -5,10 +5,12 @@
 package gc
 
 import (
+	"cmd/compile/internal/importer"
 	"cmd/compile/internal/types"
 	"cmd/internal/bio"
 	"cmd/internal/src"
 	"fmt"
+	"strconv"
 )
 
 var (
@@ -70,6 +72,16 @@ func dumpexport(bout *bio.Writer) {
 	size := bout.Offset() - off
 	exportf(bout, "\n$$\n")
 
+	// The source of generic declarations follows in a section of
+	// its own, see importer.GenericHeader.
+	if len(genericSource) > 0 {
+		exportf(bout, "%s", importer.GenericHeader)
+		for _, src := range genericSource {
+			exportf(bout, "%s\n", strconv.Quote(src))
+		}
+		exportf(bout, "$$\n")
+	}
+
 	if Debug_export != 0 {
 		fmt.Printf("BenchmarkExportSize:%s 1 %d bytes\n", myimportpath, size)
 	}
@@ -228,6 +228,11 @@ var flag_msan bool
 
 var flagDWARF bool
 
+// Whether generic code is accepted, as set by the -G flag. The package
+// is then type checked with types2, and its generic functions and
+// types are stenciled into ordinary ones before noding.
+var flagG bool
+
 // Whether we are adding any sort of code instrumentation, such as
 // when the race detector is enabled.
 var instrumenting bool
@@ -53,7 +53,7 @@
 // section where the associated declaration can be found.
 //
 //
-// There are five kinds of declarations, distinguished by their first
+// There are six kinds of declarations, distinguished by their first
 // byte:
 //
 //     type Var struct {
@@ -93,6 +93,20 @@
 //         Type typeOff
 //     }
 //
+//     type Instance struct {
+//         Tag        byte // 'I'
+//         Pos        Pos
+//         Generic    stringOff
+//         TArgs      []typeOff
+//         Underlying typeOff
+//         Methods    ... // as for Type
+//     }
+//
+// An Instance is the copy of an instantiated type that a package
+// compiled with -G declares, named after the instantiation, as in
+// Set[int]. It is declared in the package of the generic type, which
+// is named Generic, and stands for its instantiation with TArgs.
+//
 //
 // typeOff means a uvarint that either indicates a predeclared type,
 // or an offset into the Data section. If the uvarint is less than
@@ -251,6 +265,13 @@ func iexport(out *bufio.Writer) {
 			sym := n.Sym
 			p.markType(asNode(sym.Def).Type)
 		}
+
+		// Copies of exported generic code may call the
+		// unexported functions and methods it refers to.
+		decls, methods := genericRefNodes()
+		for _, n := range append(decls, methods...) {
+			p.markType(n.Type)
+		}
 	}
 
 	p := iexporter{
@@ -273,6 +294,13 @@ func iexport(out *bufio.Writer) {
 		p.pushDecl(n)
 	}
 
+	// And with the unexported declarations that exported generic
+	// code refers to.
+	decls, _ := genericRefNodes()
+	for _, n := range decls {
+		p.pushDecl(n)
+	}
+
 	// Loop until no more work. We use a queue because while
 	// writing out inline bodies, we may discover additional
 	// declarations that are needed.
@@ -463,8 +491,18 @@ func (p *iexporter) doDecl(n *Node) {
 		}
 
 		// Defined type.
-		w.tag('T')
-		w.pos(n.Pos)
+		if inst := instTypes[n.Type]; inst != nil {
+			w.tag('I')
+			w.pos(n.Pos)
+			w.string(inst.generic)
+			w.uint64(uint64(len(inst.targs)))
+			for _, targ := range inst.targs {
+				w.typ(targ)
+			}
+		} else {
+			w.tag('T')
+			w.pos(n.Pos)
+		}
 
 		underlying := n.Type.Orig
 		if underlying == types.Errortype.Orig {
@@ -969,6 +1007,7 @@ func (w *exportWriter) funcExt(n *Node) {
 	// Inline body.
 	if n.Func.Inl != nil {
 		w.uint64(1 + uint64(n.Func.Inl.Cost))
+		w.uint64(uint64(n.Func.Inl.HotCost))
 		if n.Func.ExportInline() {
 			w.p.doInline(n)
 		}
@@ -1136,7 +1175,11 @@ func (w *exportWriter) stmt(n *Node) {
 	case OBREAK, OCONTINUE:
 		w.op(op)
 		w.pos(n.Pos)
-		w.exprsOrNil(n.Left, nil)
+		label := ""
+		if n.Sym != nil {
+			label = n.Sym.Name
+		}
+		w.string(label)
 
 	case OEMPTY:
 		// nothing to emit
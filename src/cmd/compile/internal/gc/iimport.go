@@ -169,6 +169,11 @@ func iimport(pkg *types.Pkg, in *bio.Reader) (fingerprint goobj2.FingerprintType
 
 			// Create stub declaration. If used, this will
 			// be overwritten by expandDecl.
+			if s.Def != nil && isInstSym(s) {
+				// The package being compiled made its own
+				// copy of the instantiation.
+				continue
+			}
 			if s.Def != nil {
 				Fatalf("unexpected definition for %v: %v", s, asNode(s.Def))
 			}
@@ -303,7 +308,7 @@ func (r *importReader) doDecl(n *Node) {
 		importfunc(r.p.ipkg, pos, n.Sym, typ)
 		r.funcExt(n)
 
-	case 'T':
+	case 'T', 'I':
 		// Types can be recursive. We need to setup a stub
 		// declaration before recursing.
 		t := importtype(r.p.ipkg, pos, n.Sym)
@@ -311,6 +316,13 @@ func (r *importReader) doDecl(n *Node) {
 		// We also need to defer width calculations until
 		// after the underlying type has been assigned.
 		defercheckwidth()
+		if tag == 'I' {
+			inst := &instType{generic: r.string()}
+			for n := r.uint64(); n > 0; n-- {
+				inst.targs = append(inst.targs, r.typ())
+			}
+			instTypes[t] = inst
+		}
 		underlying := r.typ()
 		setUnderlying(t, underlying)
 		resumecheckwidth()
@@ -679,7 +691,8 @@ func (r *importReader) funcExt(n *Node) {
 	// Inline body.
 	if u := r.uint64(); u > 0 {
 		n.Func.Inl = &Inline{
-			Cost: int32(u - 1),
+			Cost:    int32(u - 1),
+			HotCost: int32(r.uint64()),
 		}
 		n.Func.Endlineno = r.pos()
 	}
@@ -757,6 +770,7 @@ func (r *importReader) doInline(n *Node) {
 
 func (r *importReader) stmtList() []*Node {
 	var list []*Node
+	var label *Node // last label not yet linked to its statement
 	for {
 		n := r.node()
 		if n == nil {
@@ -766,6 +780,16 @@ func (r *importReader) stmtList() []*Node {
 		if n.Op == OBLOCK {
 			list = append(list, n.List.Slice()...)
 		} else {
+			// Labels are exported separately from the statement
+			// they label; link them up again. The declarations of
+			// a range statement are exported in between.
+			if label != nil {
+				label.Name.Defn = n
+				label = nil
+			}
+			if n.Op == OLABEL {
+				label = n
+			}
 			list = append(list, n)
 		}
 
@@ -1030,12 +1054,11 @@ func (r *importReader) node() *Node {
 		return n
 
 	case OBREAK, OCONTINUE:
-		pos := r.pos()
-		left, _ := r.exprsOrNil()
-		if left != nil {
-			left = newname(left.Sym)
+		n := nodl(r.pos(), op, nil, nil)
+		if label := r.string(); label != "" {
+			n.Sym = lookup(label)
 		}
-		return nodl(pos, op, left, nil)
+		return n
 
 	// case OEMPTY:
 	// 	unreachable - not emitted by exporter
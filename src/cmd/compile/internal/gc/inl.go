@@ -200,8 +200,15 @@ func caninl(fn *Node) {
 	// locals, and we use this map to produce a pruned Inline.Dcl
 	// list. See issue 25249 for more context.
 
+	// Functions called from hot call sites get a larger budget;
+	// mkinlcall inlines them at hot call sites only.
+	budget := int32(inlineMaxBudget)
+	if pgoHotCallee(n) {
+		budget = inlineHotMaxBudget
+	}
+
 	visitor := hairyVisitor{
-		budget:        inlineMaxBudget,
+		budget:        budget,
 		extraCallCost: cc,
 		usedLocals:    make(map[*Node]bool),
 	}
@@ -210,28 +217,40 @@ func caninl(fn *Node) {
 		return
 	}
 	if visitor.budget < 0 {
-		reason = fmt.Sprintf("function too complex: cost %d exceeds budget %d", inlineMaxBudget-visitor.budget, inlineMaxBudget)
+		reason = fmt.Sprintf("function too complex: cost %d exceeds budget %d", budget-visitor.budget, budget)
 		return
 	}
 
 	n.Func.Inl = &Inline{
-		Cost: inlineMaxBudget - visitor.budget,
+		Cost: budget - visitor.budget,
 		Dcl:  inlcopylist(pruneUnusedAutos(n.Name.Defn.Func.Dcl, &visitor)),
 		Body: inlcopylist(fn.Nbody.Slice()),
 	}
+	if n.Func.Inl.Cost > inlineMaxBudget {
+		n.Func.Inl.HotCost = n.Func.Inl.Cost
+		n.Func.Inl.Cost = inlineExtraCallCost
+	}
 
 	// hack, TODO, check for better way to link method nodes back to the thing with the ->inl
 	// this is so export can find the body of a method
 	fn.Type.FuncType().Nname = asTypesNode(n)
 
 	if Debug['m'] > 1 {
-		fmt.Printf("%v: can inline %#v with cost %d as: %#v { %#v }\n", fn.Line(), n, inlineMaxBudget-visitor.budget, fn.Type, asNodes(n.Func.Inl.Body))
+		fmt.Printf("%v: can inline %#v with cost %d as: %#v { %#v }\n", fn.Line(), n, n.Func.Inl.inlCost(), fn.Type, asNodes(n.Func.Inl.Body))
 	} else if Debug['m'] != 0 {
 		fmt.Printf("%v: can inline %v\n", fn.Line(), n)
 	}
 	if logopt.Enabled() {
-		logopt.LogOpt(fn.Pos, "canInlineFunction", "inline", fn.funcname(), fmt.Sprintf("cost: %d", inlineMaxBudget-visitor.budget))
+		logopt.LogOpt(fn.Pos, "canInlineFunction", "inline", fn.funcname(), fmt.Sprintf("cost: %d", n.Func.Inl.inlCost()))
+	}
+}
+
+// inlCost returns the cost of inlining a call to inl's function.
+func (inl *Inline) inlCost() int32 {
+	if inl.HotCost != 0 {
+		return inl.HotCost
 	}
+	return inl.Cost
 }
 
 // inlFlood marks n's inline body for export and recursively ensures
@@ -384,15 +403,11 @@ func (v *hairyVisitor) visit(n *Node) bool {
 
 	case OCLOSURE,
 		OCALLPART,
-		ORANGE,
-		OFOR,
-		OFORUNTIL,
 		OSELECT,
 		OTYPESW,
 		OGO,
 		ODEFER,
 		ODCLTYPE, // can't print yet
-		OBREAK,
 		ORETJMP:
 		v.reason = "unhandled op " + n.Op.String()
 		return true
@@ -438,6 +453,7 @@ func inlcopylist(ll []*Node) []*Node {
 	for _, n := range ll {
 		s = append(s, inlcopy(n))
 	}
+	relinkLabels(ll, s)
 	return s
 }
 
@@ -455,6 +471,12 @@ func inlcopy(n *Node) *Node {
 	if m.Func != nil {
 		Fatalf("unexpected Func: %v", m)
 	}
+	if m.Op == OLABEL {
+		// Don't share the Name with the original; its Defn is
+		// updated by relinkLabels.
+		name := *n.Name
+		m.Name = &name
+	}
 	m.Left = inlcopy(n.Left)
 	m.Right = inlcopy(n.Right)
 	m.List.Set(inlcopylist(n.List.Slice()))
@@ -465,6 +487,25 @@ func inlcopy(n *Node) *Node {
 	return m
 }
 
+// relinkLabels updates each OLABEL in copies, the node-by-node copy
+// of the statement list orig, to refer to the copy of its labeled
+// statement instead of the original.
+func relinkLabels(orig, copies []*Node) {
+	for i, n := range orig {
+		if n == nil || n.Op != OLABEL {
+			continue
+		}
+		m := copies[i]
+		m.Name.Defn = nil
+		for j := i + 1; j < len(orig); j++ {
+			if orig[j] == n.Name.Defn {
+				m.Name.Defn = copies[j]
+				break
+			}
+		}
+	}
+}
+
 func countNodes(n *Node) int {
 	if n == nil {
 		return 0
@@ -658,7 +699,7 @@ func inlnode(n *Node, maxCost int32, inlMap map[*Node]bool) *Node {
 	// transmogrify this node itself unless inhibited by the
 	// switch at the top of this function.
 	switch n.Op {
-	case OCALLFUNC, OCALLMETH:
+	case OCALLFUNC, OCALLMETH, OCALLINTER:
 		if n.NoInline() {
 			return n
 		}
@@ -733,6 +774,11 @@ func inlnode(n *Node, maxCost int32, inlMap map[*Node]bool) *Node {
 		}
 
 		n = mkinlcall(n, asNode(n.Left.Type.FuncType().Nname), maxCost, inlMap)
+
+	case OCALLINTER:
+		if pgoProfile != nil {
+			n = pgoDevirtualize(n, maxCost, inlMap)
+		}
 	}
 
 	lineno = lno
@@ -860,12 +906,18 @@ func mkinlcall(n, fn *Node, maxCost int32, inlMap map[*Node]bool) *Node {
 		}
 		return n
 	}
-	if fn.Func.Inl.Cost > maxCost {
+	if inl := fn.Func.Inl; (inl.HotCost != 0 || inl.Cost > maxCost) && !pgoHotInline(n, fn) {
 		// The inlined function body is too big. Typically we use this check to restrict
 		// inlining into very big functions.  See issue 26546 and 17566.
+		// Functions given a hot budget by caninl end up here when
+		// the call site is not hot.
 		if logopt.Enabled() {
+			what := "max large caller cost"
+			if inl.HotCost != 0 {
+				what = "max cost of a call site that is not hot"
+			}
 			logopt.LogOpt(n.Pos, "cannotInlineCall", "inline", Curfn.funcname(),
-				fmt.Sprintf("cost %d of %s exceeds max large caller cost %d", fn.Func.Inl.Cost, fn.pkgFuncName(), maxCost))
+				fmt.Sprintf("cost %d of %s exceeds %s %d", inl.inlCost(), fn.pkgFuncName(), what, maxCost))
 		}
 		return n
 	}
@@ -1240,6 +1292,7 @@ func (subst *inlsubst) list(ll Nodes) []*Node {
 	for _, n := range ll.Slice() {
 		s = append(s, subst.node(n))
 	}
+	relinkLabels(ll.Slice(), s)
 	return s
 }
 
@@ -1301,12 +1354,21 @@ func (subst *inlsubst) node(n *Node) *Node {
 		//		dump("Return after substitution", m);
 		return m
 
-	case OGOTO, OLABEL:
+	case OGOTO, OLABEL, OBREAK, OCONTINUE:
 		m := n.copy()
 		m.Pos = subst.updatedPos(m.Pos)
 		m.Ninit.Set(nil)
-		p := fmt.Sprintf("%s·%d", n.Sym.Name, inlgen)
-		m.Sym = lookup(p)
+		// Labels must be unique within the function they are
+		// inlined into; break and continue may have no label.
+		if n.Sym != nil {
+			p := fmt.Sprintf("%s·%d", n.Sym.Name, inlgen)
+			m.Sym = lookup(p)
+		}
+		if n.Op == OLABEL {
+			// Defn is updated by relinkLabels.
+			name := *n.Name
+			m.Name = &name
+		}
 
 		return m
 	}
@@ -216,6 +216,7 @@ func Main(archInit func(*Arch)) {
 	objabi.Flagcount("C", "disable printing of columns in error messages", &Debug['C']) // TODO(gri) remove eventually
 	flag.StringVar(&localimport, "D", "", "set relative `path` for local imports")
 	objabi.Flagcount("E", "debug symbol export", &Debug['E'])
+	flag.BoolVar(&flagG, "G", false, "accept generic code, type checked with types2")
 	objabi.Flagfn1("I", "add `directory` to import search path", addidir)
 	objabi.Flagcount("K", "debug missing line numbers", &Debug['K'])
 	objabi.Flagcount("L", "show full file names in error messages", &Debug['L'])
@@ -274,7 +275,9 @@ func Main(archInit func(*Arch)) {
 	var goversion string
 	flag.StringVar(&goversion, "goversion", "", "required version of the runtime")
 	var symabisPath string
+	var pgoProfilePath string
 	flag.StringVar(&symabisPath, "symabis", "", "read symbol ABIs from `file`")
+	flag.StringVar(&pgoProfilePath, "pgoprofile", "", "read CPU profile from `file` for profile-guided optimization")
 	flag.StringVar(&traceprofile, "traceprofile", "", "write an execution trace to `file`")
 	flag.StringVar(&blockprofile, "blockprofile", "", "write block profile to `file`")
 	flag.StringVar(&mutexprofile, "mutexprofile", "", "write mutex profile to `file`")
@@ -353,6 +356,10 @@ func Main(archInit func(*Arch)) {
 		readSymABIs(symabisPath, myimportpath)
 	}
 
+	if pgoProfilePath != "" {
+		readPGOProfile(pgoProfilePath, myimportpath)
+	}
+
 	thearch.LinkArch.Init(Ctxt)
 
 	if outfile == "" {
@@ -609,6 +616,7 @@ func Main(archInit func(*Arch)) {
 			xtop[i] = typecheck(n, ctxStmt)
 		}
 	}
+	typecheckInstTypes()
 
 	// Phase 3: Type check function bodies.
 	// Don't use range--typecheck can add closures to xtop.
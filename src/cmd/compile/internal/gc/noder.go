@@ -53,6 +53,19 @@ func parseFiles(filenames []string) uint {
 		}(filename)
 	}
 
+	if flagG {
+		// Report all syntax errors before type checking.
+		for _, p := range noders {
+			for e := range p.err {
+				p.yyerrorpos(e.Pos, "%s", e.Msg)
+			}
+		}
+		if nsyntaxerrors != 0 {
+			errorexit()
+		}
+		checkFiles(noders)
+	}
+
 	var lines uint
 	for _, p := range noders {
 		for e := range p.err {
@@ -446,7 +459,7 @@ func (p *noder) constDecl(decl *syntax.ConstDecl, cs *constState) []*Node {
 
 func (p *noder) typeDecl(decl *syntax.TypeDecl) *Node {
 	if decl.TParamList != nil {
-		yyerrorl(p.makeXPos(decl.Pos()), "generic types cannot be compiled yet")
+		yyerrorl(p.makeXPos(decl.Pos()), "generic types require the -G flag")
 		errorexit()
 	}
 
@@ -472,6 +485,13 @@ func (p *noder) typeDecl(decl *syntax.TypeDecl) *Node {
 	if param.Alias && !langSupported(1, 9, localpkg) {
 		yyerrorl(nod.Pos, "type aliases only supported as of -lang=go1.9")
 	}
+	if inst := instDecls[decl]; inst != nil {
+		inst.n = n
+		for _, targ := range inst.targs {
+			inst.ntargs = append(inst.ntargs, p.typeExpr(targ))
+		}
+		notedInsts = append(notedInsts, inst)
+	}
 	return nod
 }
 
@@ -491,7 +511,7 @@ func (p *noder) declName(name *syntax.Name) *Node {
 
 func (p *noder) funcDecl(fun *syntax.FuncDecl) *Node {
 	if fun.TParamList != nil {
-		yyerrorl(p.makeXPos(fun.Pos()), "generic functions cannot be compiled yet")
+		yyerrorl(p.makeXPos(fun.Pos()), "generic functions require the -G flag")
 		errorexit()
 	}
 
@@ -521,6 +541,12 @@ func (p *noder) funcDecl(fun *syntax.FuncDecl) *Node {
 	f.Func.Nname.Name.Defn = f
 	f.Func.Nname.Name.Param.Ntype = t
 
+	// Copies of generic code may be made by every package that
+	// instantiates it.
+	if isInstSym(f.Func.Nname.Sym) || f.Func.Shortname != nil && isInstRecv(fun.Recv) {
+		f.Func.SetDupok(true)
+	}
+
 	if pragma, ok := fun.Pragma.(*Pragma); ok {
 		f.Func.Pragma = pragma.Flag & FuncPragmas
 		if pragma.Flag&Systemstack != 0 && pragma.Flag&Nosplit != 0 {
@@ -869,6 +895,10 @@ func (p *noder) interfaceType(expr *syntax.InterfaceType) *Node {
 		if method.Name == nil {
 			n = p.nodSym(method, ODCLFIELD, oldname(p.packname(method.Type)), nil)
 		} else {
+			if method.Type.(*syntax.FuncType).TParamList != nil {
+				yyerrorl(p.makeXPos(method.Pos()), "methods cannot have type parameters")
+				errorexit()
+			}
 			mname := p.name(method.Name)
 			sig := p.typeExpr(method.Type)
 			sig.Left = fakeRecv()
@@ -921,7 +951,12 @@ func (p *noder) embedded(typ syntax.Expr) *Node {
 	}
 
 	sym := p.packname(typ)
-	n := p.nodSym(typ, ODCLFIELD, oldname(sym), lookup(sym.Name))
+	fsym := lookup(sym.Name)
+	if !types.IsExported(sym.Name) {
+		// As in copies of the generic code of another package.
+		fsym = sym
+	}
+	n := p.nodSym(typ, ODCLFIELD, oldname(sym), fsym)
 	n.SetEmbedded(true)
 
 	if isStar {
@@ -1439,6 +1474,9 @@ func (p *noder) basicLit(lit *syntax.BasicLit) Val {
 }
 
 func (p *noder) name(name *syntax.Name) *types.Sym {
+	if pkg := stenciledNames[name]; pkg != nil {
+		return pkg.Lookup(name.Value)
+	}
 	return lookup(name.Value)
 }
 
@@ -0,0 +1,500 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Profile-guided optimization.
+//
+// The -pgoprofile flag names a pprof CPU profile, as written by
+// runtime/pprof. The profile is reduced to a weighted call graph
+// whose edges are call sites (calling function and line) and their
+// callees. The hottest edges, those that together account for
+// pgoHotCDFThreshold of the total edge weight, are considered hot.
+//
+// Hot edges are used in two ways:
+//
+// - A function that is the callee of a hot edge is considered for
+//   inlining with budget inlineHotMaxBudget instead of inlineMaxBudget,
+//   and such a function is inlined at hot call sites only.
+//
+// - An interface method call whose hottest callee is a hot edge is
+//   devirtualized: the call is guarded by a type assertion to the
+//   callee's receiver type, and the concrete call is a candidate for
+//   inlining like any other.
+//
+// The profile also records the weight of each line, counting each
+// sample once for every line on its stack. An if statement whose
+// then branch starts on a line at least pgoBranchRatio times as heavy
+// as the line its else branch starts on, or the reverse, is marked as
+// likely to go that way, and the block layout pass places the likely
+// branch right after the condition. An if statement without an else
+// branch is marked as unlikely to be taken if its then branch has no
+// weight but the condition does.
+//
+// Decisions are reported with -m and through the -json optimizer log.
+
+package gc
+
+import (
+	"cmd/compile/internal/logopt"
+	"cmd/compile/internal/types"
+	"cmd/internal/objabi"
+	"cmd/internal/src"
+	"fmt"
+	"internal/profile"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// pgoHotCDFThreshold is the fraction of the total call edge
+	// weight covered by hot edges.
+	pgoHotCDFThreshold = 0.99
+
+	// inlineHotMaxBudget is the inlining budget of functions that
+	// are the callee of a hot call edge.
+	inlineHotMaxBudget = 2000
+
+	// pgoBranchRatio is how many times heavier than the other one
+	// a branch must be for it to be marked as likely.
+	pgoBranchRatio = 10
+)
+
+// pgoProfile is the call graph read from the -pgoprofile file,
+// or nil if there is none.
+var pgoProfile *pgoGraph
+
+// A pgoCallSite identifies a call, or any other line, in a profile.
+type pgoCallSite struct {
+	caller string // symbol name of the calling function
+	line   int64  // line of the call
+}
+
+// A pgoGraph is a weighted call graph read from a profile.
+// Function names are symbol names as used by the compiler,
+// with the local package written as `"".`.
+type pgoGraph struct {
+	weight     map[pgoCallSite]map[string]int64 // call site -> callee -> weight
+	hot        map[pgoCallSite]map[string]bool  // hot edges
+	hotCallees map[string]bool                  // callees of hot edges
+	lines      map[pgoCallSite]int64            // line -> weight
+}
+
+// readPGOProfile reads the CPU profile in file and sets pgoProfile.
+func readPGOProfile(file, myimportpath string) {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatalf("-pgoprofile: %v", err)
+	}
+	defer f.Close()
+	p, err := profile.Parse(f)
+	if err != nil {
+		log.Fatalf("-pgoprofile: %s: %v", file, err)
+	}
+	if len(p.SampleType) == 0 {
+		log.Fatalf("-pgoprofile: %s: profile has no sample types", file)
+	}
+
+	// CPU profiles written by runtime/pprof record both a sample
+	// count and the CPU time; use the latter.
+	index := len(p.SampleType) - 1
+	for i, st := range p.SampleType {
+		if st.Type == "cpu" {
+			index = i
+		}
+	}
+
+	localPrefix := ""
+	if myimportpath != "" {
+		// Match the compiler's names for symbols in this package.
+		localPrefix = objabi.PathToPrefix(myimportpath) + "."
+	}
+	name := func(fn *profile.Function) string {
+		if fn == nil {
+			return ""
+		}
+		if localPrefix != "" && strings.HasPrefix(fn.Name, localPrefix) {
+			return `"".` + fn.Name[len(localPrefix):]
+		}
+		return fn.Name
+	}
+
+	g := &pgoGraph{
+		weight:     make(map[pgoCallSite]map[string]int64),
+		hot:        make(map[pgoCallSite]map[string]bool),
+		hotCallees: make(map[string]bool),
+		lines:      make(map[pgoCallSite]int64),
+	}
+	seen := make(map[pgoCallSite]bool)
+	for _, s := range p.Sample {
+		w := s.Value[index]
+		if w <= 0 {
+			continue
+		}
+		// Flatten the stack, innermost frame first. Inlined
+		// frames of a location are listed before their callers.
+		var frames []profile.Line
+		for _, loc := range s.Location {
+			frames = append(frames, loc.Line...)
+		}
+		// Count recursive frames once.
+		for k := range seen {
+			delete(seen, k)
+		}
+		for _, f := range frames {
+			if fn := name(f.Function); fn != "" {
+				line := pgoCallSite{fn, f.Line}
+				if !seen[line] {
+					seen[line] = true
+					g.lines[line] += w
+				}
+			}
+		}
+		for i := 0; i+1 < len(frames); i++ {
+			callee, caller := name(frames[i].Function), name(frames[i+1].Function)
+			if callee == "" || caller == "" {
+				continue
+			}
+			site := pgoCallSite{caller, frames[i+1].Line}
+			m := g.weight[site]
+			if m == nil {
+				m = make(map[string]int64)
+				g.weight[site] = m
+			}
+			m[callee] += w
+		}
+	}
+
+	// Mark the heaviest edges hot.
+	type edge struct {
+		site   pgoCallSite
+		callee string
+		weight int64
+	}
+	var edges []edge
+	var total int64
+	for site, m := range g.weight {
+		for callee, w := range m {
+			edges = append(edges, edge{site, callee, w})
+			total += w
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.weight != b.weight {
+			return a.weight > b.weight
+		}
+		if a.site.caller != b.site.caller {
+			return a.site.caller < b.site.caller
+		}
+		if a.site.line != b.site.line {
+			return a.site.line < b.site.line
+		}
+		return a.callee < b.callee
+	})
+	var cum int64
+	for _, e := range edges {
+		if float64(cum) >= pgoHotCDFThreshold*float64(total) {
+			break
+		}
+		cum += e.weight
+		m := g.hot[e.site]
+		if m == nil {
+			m = make(map[string]bool)
+			g.hot[e.site] = m
+		}
+		m[e.callee] = true
+		g.hotCallees[e.callee] = true
+	}
+
+	pgoProfile = g
+}
+
+// isHotEdge reports whether the call from site to callee is hot.
+func (g *pgoGraph) isHotEdge(site pgoCallSite, callee string) bool {
+	return g.hot[site][callee]
+}
+
+// hottestCallee returns the callee with the largest weight at site,
+// and whether that edge is hot.
+func (g *pgoGraph) hottestCallee(site pgoCallSite) (string, bool) {
+	var callee string
+	var weight int64
+	for c, w := range g.weight[site] {
+		if w > weight || w == weight && c < callee {
+			callee, weight = c, w
+		}
+	}
+	return callee, callee != "" && g.hot[site][callee]
+}
+
+// pgoFuncName returns the symbol name of the function fn (an ONAME)
+// as used in a pgoGraph.
+func pgoFuncName(fn *Node) string {
+	pkg := fnpkg(fn)
+	if pkg == localpkg {
+		return `"".` + fn.Sym.Name
+	}
+	return pkg.Prefix + "." + fn.Sym.Name
+}
+
+// pgoCallSiteAt returns the call site of a call at pos in Curfn.
+// Calls in inlined bodies belong to the function they were
+// inlined from, as they do in a profile.
+func pgoCallSiteAt(pos src.XPos) pgoCallSite {
+	return pgoLineAt(Curfn, pos)
+}
+
+// pgoLineAt returns the line at pos in fn as a pgoCallSite.
+func pgoLineAt(fn *Node, pos src.XPos) pgoCallSite {
+	p := Ctxt.PosTable.Pos(pos)
+	caller := pgoFuncName(fn.Func.Nname)
+	if b := p.Base(); b != nil {
+		if ix := b.InliningIndex(); ix >= 0 {
+			caller = Ctxt.InlTree.InlinedFunction(ix).Name
+		}
+	}
+	return pgoCallSite{caller, int64(p.RelLine())}
+}
+
+// pgoHotCallee reports whether fn is the callee of a hot call edge.
+func pgoHotCallee(fn *Node) bool {
+	return pgoProfile != nil && pgoProfile.hotCallees[pgoFuncName(fn)]
+}
+
+// pgoHotInline reports whether the call n to fn may be inlined even
+// though fn's cost exceeds the caller's budget, because the call is
+// a hot edge in the profile.
+func pgoHotInline(n, fn *Node) bool {
+	if pgoProfile == nil || fn.Func.Inl.inlCost() > inlineHotMaxBudget {
+		return false
+	}
+	if !pgoProfile.isHotEdge(pgoCallSiteAt(n.Pos), pgoFuncName(fn)) {
+		return false
+	}
+	if Debug['m'] > 1 {
+		fmt.Printf("%v: hot call site allows inlining %v with cost %d\n", n.Line(), fn, fn.Func.Inl.inlCost())
+	}
+	if logopt.Enabled() {
+		logopt.LogOpt(n.Pos, "hotInlineCall", "pgo", Curfn.funcname(),
+			fmt.Sprintf("cost %d of %s is within hot budget %d", fn.Func.Inl.inlCost(), fn.pkgFuncName(), inlineHotMaxBudget))
+	}
+	return true
+}
+
+// pgoBranchLikely returns the likely direction of the if statement n
+// in fn, for use as the likely argument of condBranch: 1 if the
+// profile shows that the then branch is taken far more often than
+// not, -1 if it shows the reverse, and 0 if it shows neither.
+func pgoBranchLikely(fn, n *Node) int8 {
+	if n.Nbody.Len() == 0 || !n.Pos.IsKnown() || !n.Nbody.First().Pos.IsKnown() ||
+		n.Rlist.Len() != 0 && !n.Rlist.First().Pos.IsKnown() {
+		return 0
+	}
+	ifLine := pgoLineAt(fn, n.Pos)
+	thenLine := pgoLineAt(fn, n.Nbody.First().Pos)
+	then := pgoProfile.lines[thenLine]
+	var likely int8
+	if n.Rlist.Len() != 0 {
+		elseLine := pgoLineAt(fn, n.Rlist.First().Pos)
+		if thenLine == ifLine || elseLine == ifLine || thenLine == elseLine {
+			return 0
+		}
+		switch els := pgoProfile.lines[elseLine]; {
+		case then > 0 && then >= pgoBranchRatio*els:
+			likely = 1
+		case els > 0 && els >= pgoBranchRatio*then:
+			likely = -1
+		}
+	} else if thenLine != ifLine && then == 0 && pgoProfile.lines[ifLine] > 0 {
+		likely = -1
+	}
+	if likely != 0 && logopt.Enabled() {
+		branch := "then"
+		if likely < 0 {
+			branch = "else"
+		}
+		logopt.LogOpt(n.Pos, "profileLikelyBranch", "pgo", fn.funcname(), branch)
+	}
+	return likely
+}
+
+// pgoDevirtualize rewrites the interface method call n, if the
+// profile shows that its hottest callee is a hot method of a concrete
+// type T, into an OINLCALL equivalent to
+//
+//	r := x.(T).M(args) if x is a T, and x.M(args) otherwise
+//
+// The receiver and arguments are evaluated once, before the type
+// assertion. Otherwise n is returned unchanged.
+func pgoDevirtualize(n *Node, maxCost int32, inlMap map[*Node]bool) *Node {
+	sel := n.Left
+	if sel.Op != ODOTINTER {
+		return n
+	}
+	callee, hot := pgoProfile.hottestCallee(pgoCallSiteAt(n.Pos))
+	if !hot {
+		return n
+	}
+
+	reason := ""
+	typ := pgoMethodRecv(callee, sel.Sym.Name)
+	var missing, have *types.Field
+	var ptr int
+	switch {
+	case typ == nil:
+		reason = fmt.Sprintf("hot callee %s is not a method %s of a known type", callee, sel.Sym.Name)
+	case !implements(typ, sel.Left.Type, &missing, &have, &ptr):
+		reason = fmt.Sprintf("%v does not implement %v", typ, sel.Left.Type)
+	case n.List.Len() == 1 && n.List.First().Type.IsFuncArgStruct():
+		reason = "argument is a multi-valued call"
+	}
+	if reason != "" {
+		if Debug['m'] > 1 {
+			fmt.Printf("%v: cannot devirtualize %v: %s\n", n.Line(), sel, reason)
+		}
+		if logopt.Enabled() {
+			logopt.LogOpt(n.Pos, "cannotDevirtualizeCall", "pgo", Curfn.funcname(), reason)
+		}
+		return n
+	}
+
+	if Debug['m'] != 0 {
+		fmt.Printf("%v: PGO devirtualizing %v to %v\n", n.Line(), sel, typ)
+	}
+	if logopt.Enabled() {
+		logopt.LogOpt(n.Pos, "devirtualizedCall", "pgo", Curfn.funcname(), typ.String())
+	}
+
+	var init Nodes
+	init.Set(n.Ninit.Slice())
+	n.Ninit.Set(nil)
+
+	recv := pgoTemp(sel.Left.Type, &init)
+	init.Append(typecheck(nod(OAS, recv, sel.Left), ctxStmt))
+	args := make([]*Node, n.List.Len())
+	for i, a := range n.List.Slice() {
+		args[i] = pgoTemp(a.Type, &init)
+		init.Append(typecheck(nod(OAS, args[i], a), ctxStmt))
+	}
+
+	// The interface call is still made if the assertion fails;
+	// keep it from being devirtualized again.
+	sel.Left = recv
+	n.List.Set(append([]*Node(nil), args...))
+	n.SetNoInline(true)
+
+	c := pgoTemp(typ, &init)
+	ok := pgoTemp(types.Types[TBOOL], &init)
+	as := nod(OAS2, nil, nil)
+	as.List.Set2(c, ok)
+	as.Rlist.Set1(nod(ODOTTYPE, recv, typenod(typ)))
+
+	dcall := nod(OCALL, nodSym(OXDOT, c, sel.Sym), nil)
+	dcall.List.Set(args)
+	dcall.SetIsDDD(n.IsDDD())
+
+	var retvars []*Node
+	var then, els *Node = dcall, n
+	if results := sel.Type.Results().FieldSlice(); len(results) > 0 {
+		for _, f := range results {
+			retvars = append(retvars, pgoTemp(f.Type, &init))
+		}
+		then = pgoAssign(retvars, dcall)
+		els = pgoAssign(retvars, n)
+	}
+
+	nif := nod(OIF, ok, nil)
+	nif.Ninit.Set1(as)
+	nif.Nbody.Set1(then)
+	nif.Rlist.Set1(els)
+	nif = typecheck(nif, ctxStmt)
+
+	call := nod(OINLCALL, nil, nil)
+	call.Ninit.Set(init.Slice())
+	call.Nbody.Set1(nif)
+	call.Rlist.Set(retvars)
+	call.Type = n.Type
+	call.SetTypecheck(1)
+
+	// The concrete call is now a candidate for inlining.
+	inlnodelist(call.Nbody, maxCost, inlMap)
+	for _, n := range call.Nbody.Slice() {
+		if n.Op == OINLCALL {
+			inlconv2stmt(n)
+		}
+	}
+	return call
+}
+
+// pgoTemp declares a new temporary of type t in init.
+func pgoTemp(t *types.Type, init *Nodes) *Node {
+	v := temp(t)
+	init.Append(typecheck(nod(ODCL, v, nil), ctxStmt))
+	return v
+}
+
+// pgoAssign returns the typechecked assignment of call to vars.
+func pgoAssign(vars []*Node, call *Node) *Node {
+	if len(vars) == 1 {
+		return typecheck(nod(OAS, vars[0], call), ctxStmt)
+	}
+	as := nod(OAS2, nil, nil)
+	as.List.Set(append([]*Node(nil), vars...))
+	as.Rlist.Set1(call)
+	return typecheck(as, ctxStmt)
+}
+
+// pgoMethodRecv returns the receiver type of callee, if callee is
+// the symbol name of method meth of a type in the local package or
+// a directly imported one, and nil otherwise.
+func pgoMethodRecv(callee, meth string) *types.Type {
+	// callee is pkgprefix.T.meth or pkgprefix.(*T).meth, where
+	// the first dot after the last slash ends pkgprefix.
+	i := strings.LastIndex(callee, "/") + 1
+	j := strings.Index(callee[i:], ".")
+	if j < 0 {
+		return nil
+	}
+	prefix, rest := callee[:i+j], callee[i+j+1:]
+	if !strings.HasSuffix(rest, "."+meth) {
+		return nil
+	}
+	name := rest[:len(rest)-len(meth)-1]
+	isPtr := strings.HasPrefix(name, "(*") && strings.HasSuffix(name, ")")
+	if isPtr {
+		name = name[2 : len(name)-1]
+	}
+	if name == "" || strings.ContainsAny(name, ".()*") {
+		return nil
+	}
+
+	var pkg *types.Pkg
+	if prefix == `""` {
+		pkg = localpkg
+	} else {
+		for _, p := range types.ImportedPkgList() {
+			if p.Prefix == prefix {
+				pkg = p
+				break
+			}
+		}
+	}
+	if pkg == nil {
+		return nil
+	}
+	s, existed := pkg.LookupOK(name)
+	if !existed {
+		return nil
+	}
+	d := asNode(s.Def)
+	if d == nil || d.Op != OTYPE || d.Type == nil || d.Type.IsInterface() {
+		return nil
+	}
+	t := d.Type
+	if isPtr {
+		t = types.NewPtr(t)
+	}
+	return t
+}
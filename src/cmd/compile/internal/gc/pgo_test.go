@@ -0,0 +1,219 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"bytes"
+	"fmt"
+	"internal/profile"
+	"internal/testenv"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pgoBigBody is a function body whose inlining cost exceeds
+// inlineMaxBudget, but not inlineHotMaxBudget.
+var pgoBigBody = strings.Repeat("\tx = x*7 + 3\n\tx ^= x >> 5\n", 20) + "\treturn x\n"
+
+var pgoSrc = `package main
+
+import "fmt"
+
+type I interface{ M(int) int }
+
+type T struct{ n int }
+
+func (t *T) M(x int) int {
+	x += t.n
+` + pgoBigBody + `}
+
+type U struct{}
+
+func (U) M(x int) int { return -x }
+
+func sum(i I, n int) int {
+	s := 0
+	for j := 0; j < n; j++ {
+		s += i.M(j) // hot interface call
+	}
+	return s
+}
+
+func big(x int) int {
+` + pgoBigBody + `}
+
+func hot(x int) int {
+	return big(x) // hot call
+}
+
+func cold(x int) int {
+	return big(x) // cold call
+}
+
+func sign(x int) string {
+	if x >= 0 {
+		return "non-negative" // common
+	} else {
+		return "negative" // rare
+	}
+}
+
+func clamp(x int) int {
+	if x > 100 {
+		x = 100 // never
+	}
+	return x
+}
+
+func main() {
+	fmt.Println(sum(&T{n: 1}, 10), sum(U{}, 10), hot(1), cold(2), sign(3), clamp(4))
+}
+`
+
+// pgoLine returns the line of the first occurrence of s in pgoSrc.
+func pgoLine(t *testing.T, s string) int {
+	i := strings.Index(pgoSrc, s)
+	if i < 0 {
+		t.Fatalf("%q not in source", s)
+	}
+	return strings.Count(pgoSrc[:i], "\n") + 1
+}
+
+// writePGOProfile writes a CPU profile in which each stack, listed
+// innermost frame first as function name and line pairs, has been
+// sampled the given number of times.
+func writePGOProfile(t *testing.T, file string, stacks map[int64][][2]interface{}) {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     1e7,
+	}
+	funcs := make(map[string]*profile.Function)
+	for n, stack := range stacks {
+		s := &profile.Sample{Value: []int64{n, n * 1e7}}
+		for _, frame := range stack {
+			name, line := frame[0].(string), frame[1].(int)
+			fn := funcs[name]
+			if fn == nil {
+				fn = &profile.Function{ID: uint64(len(p.Function) + 1), Name: name, SystemName: name, Filename: "x.go"}
+				funcs[name] = fn
+				p.Function = append(p.Function, fn)
+			}
+			loc := &profile.Location{
+				ID:   uint64(len(p.Location) + 1),
+				Line: []profile.Line{{Function: fn, Line: int64(line)}},
+			}
+			p.Location = append(p.Location, loc)
+			s.Location = append(s.Location, loc)
+		}
+		p.Sample = append(p.Sample, s)
+	}
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPGO(t *testing.T) {
+	t.Parallel()
+
+	testenv.MustHaveGoBuild(t)
+
+	dir, err := ioutil.TempDir("", "TestPGO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "x.go")
+	if err := ioutil.WriteFile(src, []byte(pgoSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prof := filepath.Join(dir, "cpu.pprof")
+	writePGOProfile(t, prof, map[int64][][2]interface{}{
+		1000: {{"main.(*T).M", pgoLine(t, "x += t.n")}, {"main.sum", pgoLine(t, "hot interface call")}, {"main.main", 1}},
+		999:  {{"main.big", pgoLine(t, "x = x*7")}, {"main.hot", pgoLine(t, "hot call")}, {"main.main", 1}},
+		1:    {{"main.big", pgoLine(t, "x = x*7")}, {"main.cold", pgoLine(t, "cold call")}, {"main.main", 1}},
+		50:   {{"main.sign", pgoLine(t, "// common")}, {"main.main", 1}},
+		2:    {{"main.sign", pgoLine(t, "// rare")}, {"main.main", 1}},
+		30:   {{"main.clamp", pgoLine(t, "x > 100")}, {"main.main", 1}},
+	})
+
+	// Compile and run the program with and without the profile.
+	var outputs [2]string
+	var diag string
+	for i, pgo := range []bool{false, true} {
+		obj := filepath.Join(dir, fmt.Sprintf("x%d.o", i))
+		exe := filepath.Join(dir, fmt.Sprintf("x%d.exe", i))
+		compile := []string{testenv.GoToolPath(t), "tool", "compile", "-p", "main", "-m", "-o", obj}
+		if pgo {
+			compile = append(compile, "-pgoprofile", prof, "-json=0,file://"+filepath.ToSlash(filepath.Join(dir, "log")))
+		}
+		compile = append(compile, src)
+		for _, run := range [][]string{
+			compile,
+			{testenv.GoToolPath(t), "tool", "link", "-o", exe, obj},
+		} {
+			out, err := exec.Command(run[0], run[1:]...).CombinedOutput()
+			if err != nil {
+				t.Fatalf("%v: %v\n%s", run, err, out)
+			}
+			if pgo && run[2] == "compile" {
+				diag = string(out)
+			}
+		}
+		out, err := exec.Command(exe).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%v\n%s", err, out)
+		}
+		outputs[i] = string(out)
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("output with profile\n%s\ndiffers from output without\n%s", outputs[1], outputs[0])
+	}
+
+	for _, want := range []string{
+		fmt.Sprintf("x.go:%d:11: PGO devirtualizing i.M to *T", pgoLine(t, "hot interface call")),
+		fmt.Sprintf("x.go:%d:11: inlining call to (*T).M", pgoLine(t, "hot interface call")),
+		fmt.Sprintf("x.go:%d:12: inlining call to big", pgoLine(t, "hot call")),
+		// A caller of big that is not hot itself is charged the
+		// cost of a call to big, and stays inlinable.
+		fmt.Sprintf("x.go:%d:6: can inline cold", pgoLine(t, "func cold")),
+	} {
+		if !strings.Contains(diag, want) {
+			t.Errorf("missing %q in -m output:\n%s", want, diag)
+		}
+	}
+	if unwant := fmt.Sprintf("x.go:%d:12: inlining call to big", pgoLine(t, "cold call")); strings.Contains(diag, unwant) {
+		t.Errorf("unexpected %q in -m output:\n%s", unwant, diag)
+	}
+
+	logged, err := ioutil.ReadFile(filepath.Join(dir, "log", "main", "x.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"code":"devirtualizedCall","source":"go compiler","message":"*T"`,
+		`"code":"hotInlineCall"`,
+		`is within hot budget 2000`,
+		`exceeds max cost of a call site that is not hot 80`,
+		fmt.Sprintf(`{"line":%d,"character":2},"end":{"line":%d,"character":2}},"severity":3,"code":"profileLikelyBranch","source":"go compiler","message":"then"`, pgoLine(t, "x >= 0"), pgoLine(t, "x >= 0")),
+		fmt.Sprintf(`{"line":%d,"character":2},"end":{"line":%d,"character":2}},"severity":3,"code":"profileLikelyBranch","source":"go compiler","message":"else"`, pgoLine(t, "x > 100"), pgoLine(t, "x > 100")),
+	} {
+		if !strings.Contains(string(logged), want) {
+			t.Errorf("missing %s in -json log:\n%s", want, logged)
+		}
+	}
+}
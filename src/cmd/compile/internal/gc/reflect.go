@@ -1161,13 +1161,15 @@ func dtypesym(t *types.Type) *obj.LSym {
 		tbase = t.Elem()
 	}
 	dupok := 0
-	if tbase.Sym == nil {
+	if tbase.Sym == nil || isInstSym(tbase.Sym) {
+		// Instantiations of generic types are copied by every
+		// package that needs them.
 		dupok = obj.DUPOK
 	}
 
 	if myimportpath != "runtime" || (tbase != types.Types[tbase.Etype] && tbase != types.Bytetype && tbase != types.Runetype && tbase != types.Errortype) { // int, float, etc
 		// named types from other files are defined only by those files
-		if tbase.Sym != nil && tbase.Sym.Pkg != localpkg {
+		if tbase.Sym != nil && tbase.Sym.Pkg != localpkg && dupok == 0 {
 			return lsym
 		}
 		// TODO(mdempsky): Investigate whether this can happen.
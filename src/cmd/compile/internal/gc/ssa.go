@@ -1303,6 +1303,8 @@ func (s *state) stmt(n *Node) {
 		var likely int8
 		if n.Likely() {
 			likely = 1
+		} else if pgoProfile != nil {
+			likely = pgoBranchLikely(s.curfn, n)
 		}
 		var bThen *ssa.Block
 		if n.Nbody.Len() != 0 {
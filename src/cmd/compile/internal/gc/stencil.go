@@ -0,0 +1,1367 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"cmd/compile/internal/importer"
+	"cmd/compile/internal/syntax"
+	"cmd/compile/internal/types"
+	"cmd/compile/internal/types2"
+	"cmd/internal/objabi"
+)
+
+// With the -G flag, the files of the package are type checked with
+// types2, which understands type parameters, before any of them is
+// noded. The generic functions and types that the package declares
+// are then stenciled: for each instantiation, as in Max(int) or
+// Set(string), a copy of the generic declaration is stamped out,
+// with the type arguments substituted for the type parameters, and
+// named after the instantiation, as in Max[int] or Set[string]. The
+// instantiation refers to that copy instead.
+//
+// The package is type checked once. The copies are made in a single
+// pass over the code, using the type information of the generic
+// declarations: an instantiation in generic code, as in Max(T), is
+// an instantiation with the type argument that T stands for in the
+// copy being made, which is stamped out in turn. The generic
+// declarations are then dropped, and the noder sees only ordinary
+// Go code.
+//
+// Export data cannot describe generic declarations, so their source
+// follows the export data, see genericSource. A package that imports
+// them type checks that source into the imported package, and stamps
+// out their instantiations like those of its own generic code, with
+// the objects of the imported package referred to through imports
+// that are added to the file. The unexported objects of its package
+// that generic code refers to are exported with it, see genericRefs.
+//
+// An instantiation has the same copy in every package that stamps it
+// out, so that it is the same function or type in all of them: the
+// copy is declared in the package of the generic code, with the type
+// arguments in its name qualified by package path, as in
+// Set[example.com/geo.Point], and the compiled functions and types
+// are dupok. Export data describes the copy of an instantiated type
+// as the instantiation it stands for, see instTypes, which types2
+// then finds identical to the instantiations of the importing package.
+
+// maxInstDepth limits how deeply instantiations may be nested in
+// the copies of generic code, to stop instantiation cycles such as
+// F(T) calling F(*T).
+const maxInstDepth = 100
+
+// genericSource holds the source of the generic declarations of the
+// package that other packages can instantiate, one file each, with
+// the imports they use. dumpexport writes it after the export data.
+var genericSource []string
+
+// genericRefs holds the names of the unexported objects of the package
+// that the generic declarations of genericSource refer to, with those
+// of methods qualified by their receiver base type, as in T.m. The
+// copies of the declarations are declared in the package too, so they
+// can refer to the objects, and iexport writes them to the export data.
+var genericRefs []string
+
+// genericRefNodes returns the declarations of genericRefs that are not
+// generic, and the methods among them, which are exported with their
+// receiver type.
+func genericRefNodes() (decls, methods []*Node) {
+	for _, name := range genericRefs {
+		if i := strings.Index(name, "."); i >= 0 {
+			t := asNode(localpkg.Lookup(name[:i]).Def)
+			if t == nil || t.Op != OTYPE || t.Type == nil {
+				continue
+			}
+			for _, m := range t.Type.Methods().Slice() {
+				if n := asNode(m.Type.Nname()); m.Sym.Name == name[i+1:] && n != nil {
+					methods = append(methods, n)
+				}
+			}
+			continue
+		}
+		if n := asNode(localpkg.Lookup(name).Def); n != nil {
+			decls = append(decls, n)
+		}
+	}
+	return decls, methods
+}
+
+// stenciledNames holds the packages that the names in the copies of
+// the generic code of other packages are declared in, for the noder.
+var stenciledNames = make(map[*syntax.Name]*types.Pkg)
+
+// stenciledPkgs holds the packages, other than the one being
+// compiled, that copies of instantiated generic code are declared in.
+var stenciledPkgs = make(map[*types.Pkg]bool)
+
+// An instType is the instantiation that the copy of an instantiated
+// type stands for.
+type instType struct {
+	generic string // name of the generic type, in the package of the copy
+	targs   []*types.Type
+}
+
+// instTypes holds the instantiations that the copies of instantiated
+// types stand for, both those the package declares and imported ones,
+// for the export data.
+var instTypes = make(map[*types.Type]*instType)
+
+// An instDecl is the declaration of the copy of an instantiated type
+// that the package declares. The noder notes the type and the type
+// arguments for typecheckInstTypes, which records them in instTypes.
+type instDecl struct {
+	generic string
+	targs   []syntax.Expr
+
+	n      *Node   // the type, once noded
+	ntargs []*Node // the type arguments, once noded
+}
+
+// instDecls maps the copies of instantiated types that the package
+// declares to their instDecls.
+var instDecls = make(map[*syntax.TypeDecl]*instDecl)
+
+// notedInsts holds the instDecls that the noder noted, in order.
+var notedInsts []*instDecl
+
+// isInstSym reports whether s names the copy of an instantiated
+// generic function or type, or a method of one.
+func isInstSym(s *types.Sym) bool {
+	return strings.Contains(s.Name, "[")
+}
+
+// stenciledDef reports whether s is declared by a copy of the generic
+// code of another package, in the package being compiled.
+func stenciledDef(s *types.Sym) bool {
+	if !stenciledPkgs[s.Pkg] {
+		return false
+	}
+	n := asNode(s.Def)
+	return n != nil && n.Op != ONONAME
+}
+
+// stenciledPkg returns the package of the names of fields, if they
+// are in a copy of the generic code of another package, or else nil.
+func stenciledPkg(fields []*types.Field) *types.Pkg {
+	for _, f := range fields {
+		if f.Sym != nil && stenciledPkgs[f.Sym.Pkg] {
+			return f.Sym.Pkg
+		}
+	}
+	return nil
+}
+
+// isInstRecv reports whether recv is the receiver of a method of the
+// copy of an instantiated generic type.
+func isInstRecv(recv *syntax.Field) bool {
+	typ := recv.Type
+	for {
+		switch t := typ.(type) {
+		case *syntax.ParenExpr:
+			typ = t.X
+		case *syntax.Operation:
+			typ = t.X
+		case *syntax.Name:
+			return strings.Contains(t.Value, "[")
+		default:
+			return false
+		}
+	}
+}
+
+// typecheckInstTypes records the instantiations that the copies of
+// instantiated types declared by the package stand for in instTypes.
+func typecheckInstTypes() {
+	for _, inst := range notedInsts {
+		if inst.n.Type == nil {
+			continue
+		}
+		it := &instType{generic: inst.generic}
+		for _, targ := range inst.ntargs {
+			it.targs = append(it.targs, typecheck(targ, ctxType).Type)
+		}
+		instTypes[inst.n.Type] = it
+	}
+}
+
+// checkFiles type checks the files of the package with types2 and
+// stencils its generic code, replacing the files of noders with
+// ordinary Go code. Type errors are reported and end compilation.
+func checkFiles(noders []*noder) {
+	st := &stenciler{
+		noders:  noders,
+		insts:   make(map[string]bool),
+		imports: make(map[*noder]map[string]string),
+		newImps: make(map[*noder][]syntax.Decl),
+		used:    make(map[*noder]map[*types2.Package]bool),
+		generic: make(map[types2.Object]*genericDecl),
+		dropped: make(map[syntax.Decl]bool),
+	}
+	st.conf = types2.Config{
+		Importer: &gcimports{
+			packages: make(map[string]*types2.Package),
+			generic:  st.importGeneric,
+		},
+		Sizes: types2.SizesFor("gc", objabi.GOARCH),
+		Error: st.error,
+	}
+	st.check()
+	st.stencil()
+}
+
+// A gcimports imports packages for types2, from the same export
+// data that the compiler reads.
+type gcimports struct {
+	packages map[string]*types2.Package
+	generic  func(pkg *types2.Package, src []string) error // imports the generic code of pkg
+}
+
+func (m *gcimports) Import(path string) (*types2.Package, error) {
+	return m.ImportFrom(path, "" /* no vendoring */, 0)
+}
+
+func (m *gcimports) ImportFrom(path_, srcDir string, mode types2.ImportMode) (*types2.Package, error) {
+	if mode != 0 {
+		panic("mode must be 0")
+	}
+	// Resolve the path as importfile does.
+	if mapped, ok := importMap[path_]; ok {
+		path_ = mapped
+	}
+	if islocalname(path_) {
+		prefix := Ctxt.Pathname
+		if localimport != "" {
+			prefix = localimport
+		}
+		path_ = path.Join(prefix, path_)
+	}
+	pkg, src, err := importer.ImportGeneric(m.packages, path_, srcDir, func(path string) (io.ReadCloser, error) {
+		file, ok := findpkg(path)
+		if !ok {
+			return nil, fmt.Errorf("can't find import: %q", path)
+		}
+		return os.Open(file)
+	})
+	if err == nil && len(src) > 0 {
+		err = m.generic(pkg, src)
+	}
+	return pkg, err
+}
+
+// A stenciler type checks a package with types2 and lowers its
+// generic code.
+type stenciler struct {
+	noders []*noder
+	conf   types2.Config
+	pkg    *types2.Package
+	info   *types2.Info
+
+	// generic maps the generic functions and types of the package,
+	// and those of imported packages, to their declarations.
+	generic map[types2.Object]*genericDecl
+
+	// dropped holds the declarations to drop once stenciling is done:
+	// the generic declarations, including the methods of generic
+	// types, and interfaces with type lists, which are only used as
+	// constraints.
+	dropped map[syntax.Decl]bool
+
+	insts   map[string]bool                     // qualified names of the instantiations made
+	pending []*instantiation                    // instantiations yet to be stamped out
+	imports map[*noder]map[string]string        // names of the imports added to files, by path
+	newImps map[*noder][]syntax.Decl            // imports yet to be added to files
+	used    map[*noder]map[*types2.Package]bool // imports used by the lowered code of files
+}
+
+// A genericDecl is the declaration of a generic function or type.
+type genericDecl struct {
+	pkg     *types2.Package // package declaring it
+	p       *noder          // noder of the file the copies are added to
+	decl    syntax.Decl     // *syntax.FuncDecl or *syntax.TypeDecl
+	tparams []types2.Object
+	methods []*syntax.FuncDecl // methods of a generic type
+}
+
+// An instantiation is a generic function or type, with type arguments,
+// to be stamped out.
+type instantiation struct {
+	name  string
+	gen   *genericDecl
+	targs []typeArg
+	depth int // number of instantiations it is nested in
+}
+
+// A typeArg is a type argument of an instantiation. The type is that
+// of the code that instantiates it, so it may mention the type
+// parameters of the generic code, as in Max(T), which then stand for
+// the type arguments of subst.
+type typeArg struct {
+	typ   types2.Type
+	subst *tsubst
+}
+
+// A tsubst substitutes the type arguments of an instantiation for
+// the type parameters of a generic declaration, while the copy of the
+// declaration is made. It is nil in code that is not generic.
+type tsubst struct {
+	pkg   *types2.Package           // package of the generic code
+	targs map[types2.Object]typeArg // by type parameter
+	depth int                       // depth of the instantiation
+}
+
+// lookup returns the type argument for the type parameter t.
+func (s *tsubst) lookup(t *types2.TypeParam) typeArg {
+	var a typeArg
+	ok := false
+	if s != nil {
+		a, ok = s.targs[t.Obj()]
+	}
+	if !ok {
+		Fatalf("no type argument for type parameter %v", t)
+	}
+	return a
+}
+
+// error reports a type checking error.
+func (st *stenciler) error(err error) {
+	terr := err.(types2.Error)
+	st.noderFor(terr.Pos).yyerrorpos(terr.Pos, "%s", terr.Msg)
+}
+
+// noderFor returns the noder of the file that holds pos.
+func (st *stenciler) noderFor(pos syntax.Pos) *noder {
+	base := fileBase(pos.Base())
+	for _, p := range st.noders {
+		if fileBase(p.file.Pos().Base()) == base {
+			return p
+		}
+	}
+	return st.noders[0]
+}
+
+// fileBase returns the base of the file that b, which may be the
+// base of a line directive, is in.
+func fileBase(b *syntax.PosBase) *syntax.PosBase {
+	for b != nil && !b.IsFileBase() {
+		b = b.Pos().Base()
+	}
+	return b
+}
+
+// check type checks the files of the package. It ends compilation
+// if there are errors.
+func (st *stenciler) check() {
+	files := make([]*syntax.File, len(st.noders))
+	for i, p := range st.noders {
+		files[i] = p.file
+	}
+	st.info = newInfo()
+	pkg, err := st.conf.Check(myimportpath, files, st.info)
+	if err != nil {
+		errorexit()
+	}
+	st.pkg = pkg
+}
+
+// newInfo returns an Info that records the type information that
+// stenciling uses.
+func newInfo() *types2.Info {
+	return &types2.Info{
+		Types:     make(map[syntax.Expr]types2.TypeAndValue),
+		Inferred:  make(map[*syntax.CallExpr]types2.Inferred),
+		Defs:      make(map[*syntax.Name]types2.Object),
+		Uses:      make(map[*syntax.Name]types2.Object),
+		Implicits: make(map[syntax.Node]types2.Object),
+	}
+}
+
+// stencil stamps out the instantiations of the generic functions and
+// types of the package, and then drops the generic declarations.
+func (st *stenciler) stencil() {
+	files := make([]*syntax.File, len(st.noders))
+	for i, p := range st.noders {
+		files[i] = p.file
+	}
+	st.findGeneric(st.pkg, st.noders, files)
+	if len(st.dropped) == 0 {
+		return
+	}
+	genericSource, genericRefs = st.exportGeneric()
+
+	// Replace instantiations by references to their copies. Imports
+	// are kept, for dropGeneric to look up.
+	for _, p := range st.noders {
+		file := *p.file
+		file.DeclList = nil
+		c := &copier{lower: st.lowerer(p, nil, nil, nil)}
+		for _, d := range p.file.DeclList {
+			if _, ok := d.(*syntax.ImportDecl); !ok && !st.dropped[d] {
+				d = c.decl(d)
+			}
+			file.DeclList = append(file.DeclList, d)
+		}
+		p.file = &file
+	}
+	for len(st.pending) > 0 {
+		inst := st.pending[0]
+		st.pending = st.pending[1:]
+		st.stamp(inst)
+	}
+	for p, imps := range st.newImps {
+		p.file.DeclList = append(imps, p.file.DeclList...)
+		delete(st.newImps, p)
+	}
+	st.dropGeneric()
+}
+
+// findGeneric finds the generic declarations of files, of package
+// pkg. The copies of those of files[i] are added to the file of ps[i].
+func (st *stenciler) findGeneric(pkg *types2.Package, ps []*noder, files []*syntax.File) {
+	for i, file := range files {
+		p := ps[i]
+		for _, d := range file.DeclList {
+			var name *syntax.Name
+			var tparams []*syntax.Field
+			switch d := d.(type) {
+			case *syntax.FuncDecl:
+				name, tparams = d.Name, d.TParamList
+			case *syntax.TypeDecl:
+				name, tparams = d.Name, d.TParamList
+				if isConstraint(d.Type) {
+					st.dropped[d] = true
+				}
+			}
+			if tparams == nil {
+				continue
+			}
+			g := &genericDecl{pkg: pkg, p: p, decl: d}
+			for _, f := range tparams {
+				g.tparams = append(g.tparams, st.info.Defs[f.Name])
+			}
+			if obj := st.info.Defs[name]; obj != nil {
+				st.generic[obj] = g
+			}
+			st.dropped[d] = true
+		}
+	}
+	for _, file := range files {
+		for _, d := range file.DeclList {
+			if d, ok := d.(*syntax.FuncDecl); ok && d.Recv != nil {
+				if g := st.generic[st.info.Uses[recvBase(d.Recv.Type)]]; g != nil {
+					g.methods = append(g.methods, d)
+					st.dropped[d] = true
+				}
+			}
+		}
+	}
+}
+
+// isConstraint reports whether typ is an interface type with a type
+// list, which may only be used as a constraint.
+func isConstraint(typ syntax.Expr) bool {
+	if t, ok := typ.(*syntax.InterfaceType); ok {
+		for _, m := range t.MethodList {
+			if m.Name != nil && m.Name.Value == "type" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recvBase returns the name of the base type of a receiver type,
+// or nil if there is none.
+func recvBase(typ syntax.Expr) *syntax.Name {
+	for {
+		switch t := typ.(type) {
+		case *syntax.ParenExpr:
+			typ = t.X
+		case *syntax.Operation:
+			if t.Op != syntax.Mul || t.Y != nil {
+				return nil
+			}
+			typ = t.X
+		case *syntax.CallExpr:
+			typ = t.Fun
+		case *syntax.Name:
+			return t
+		default:
+			return nil
+		}
+	}
+}
+
+// recvInst returns the instantiation of the generic type in
+// a receiver type, as in Set(E), or nil if there is none.
+func recvInst(typ syntax.Expr) *syntax.CallExpr {
+	for {
+		switch t := typ.(type) {
+		case *syntax.ParenExpr:
+			typ = t.X
+		case *syntax.Operation:
+			typ = t.X
+		case *syntax.CallExpr:
+			return t
+		default:
+			return nil
+		}
+	}
+}
+
+// lowerer returns a function for a copier that lowers code copied into
+// the file of p: it replaces instantiations by references to their
+// copies, and records the imports that the code uses. In a copy of
+// generic code, it substitutes the type arguments of s for the type
+// parameters, and inst for the instantiation recv in the receiver of
+// a method.
+func (st *stenciler) lowerer(p *noder, s *tsubst, recv *syntax.CallExpr, inst *syntax.Name) func(*copier, syntax.Expr) syntax.Expr {
+	return func(c *copier, x syntax.Expr) syntax.Expr {
+		if recv != nil && x == recv {
+			return inst
+		}
+		switch x := x.(type) {
+		case *syntax.Name:
+			if s == nil {
+				break
+			}
+			obj := st.info.Uses[x]
+			if tname, ok := obj.(*types2.TypeName); ok {
+				if tparam, ok := tname.Type().(*types2.TypeParam); ok {
+					a := s.lookup(tparam)
+					return st.typeExpr(p, a.typ, a.subst, x.Pos())
+				}
+			}
+			if s.pkg != st.pkg {
+				if obj != nil && obj.Pkg() == s.pkg && obj.Parent() == s.pkg.Scope() {
+					return st.importedName(p, obj, x.Pos())
+				}
+				return st.copiedName(s.pkg, x)
+			}
+		case *syntax.SelectorExpr:
+			if name, ok := x.X.(*syntax.Name); ok {
+				if pkgName, ok := st.info.Uses[name].(*types2.PkgName); ok {
+					return st.qualifiedName(p, pkgName, x)
+				}
+			}
+		case *syntax.CallExpr:
+			return st.lower(c, p, s, x)
+		}
+		return nil
+	}
+}
+
+// importedName returns a reference to obj, a package-level object of
+// the package of imported generic code, for the file of p.
+func (st *stenciler) importedName(p *noder, obj types2.Object, pos syntax.Pos) syntax.Expr {
+	if !obj.Exported() {
+		// The copy is declared in the package of obj, whose
+		// export data declares obj, see genericRefs.
+		return st.declaredIn(obj.Pkg(), syntaxName(pos, obj.Name()))
+	}
+	x := &syntax.SelectorExpr{
+		X:   syntaxName(pos, st.importName(p, obj.Pkg().Path())),
+		Sel: syntaxName(pos, obj.Name()),
+	}
+	x.SetPos(pos)
+	return x
+}
+
+// copiedName returns a copy of x, a name in the generic code of pkg,
+// another package. The names that the code declares, and those of
+// unexported fields and methods, are declared in pkg in the copy too.
+func (st *stenciler) copiedName(pkg *types2.Package, x *syntax.Name) *syntax.Name {
+	obj := st.info.Uses[x]
+	if obj == nil {
+		obj = st.info.Defs[x]
+	}
+	n := syntaxName(x.Pos(), x.Value)
+	switch {
+	case x.Value == "_" || obj != nil && obj.Pkg() == nil:
+		// blank or predeclared
+	case obj != nil && obj.Exported() && isFieldOrMethod(obj):
+		// exported names of fields and methods are not qualified
+	default:
+		st.declaredIn(pkg, n)
+	}
+	return n
+}
+
+// isFieldOrMethod reports whether obj is a field or a method.
+func isFieldOrMethod(obj types2.Object) bool {
+	switch obj := obj.(type) {
+	case *types2.Var:
+		return obj.IsField()
+	case *types2.Func:
+		return obj.Type().(*types2.Signature).Recv() != nil
+	}
+	return false
+}
+
+// declaredIn records that the name n, in a copy of the generic code
+// of pkg, is declared in pkg, and returns n.
+func (st *stenciler) declaredIn(pkg *types2.Package, n *syntax.Name) *syntax.Name {
+	if pkg != st.pkg {
+		tpkg := types.NewPkg(pkg.Path(), "")
+		stenciledNames[n] = tpkg
+		stenciledPkgs[tpkg] = true
+	}
+	return n
+}
+
+// qualifiedName returns a copy of the qualified identifier x, which
+// refers to an import of pkgName, for the file of p. In imported
+// generic code, the import is one of the file it came from, so an
+// import is added to the file of p instead.
+func (st *stenciler) qualifiedName(p *noder, pkgName *types2.PkgName, x *syntax.SelectorExpr) syntax.Expr {
+	name := x.X.(*syntax.Name).Value
+	if pkgName.Pkg() == st.pkg {
+		st.use(p, pkgName.Imported())
+	} else {
+		name = st.importName(p, pkgName.Imported().Path())
+	}
+	nx := *x
+	nx.X = syntaxName(x.X.Pos(), name)
+	nx.Sel = syntaxName(x.Sel.Pos(), x.Sel.Value)
+	return &nx
+}
+
+// use records that the lowered code of the file of p uses the
+// import of pkg.
+func (st *stenciler) use(p *noder, pkg *types2.Package) {
+	used := st.used[p]
+	if used == nil {
+		used = make(map[*types2.Package]bool)
+		st.used[p] = used
+	}
+	used[pkg] = true
+}
+
+// lower returns the reference to the copy of a generic function or
+// type that call instantiates, for the file of p, or nil if call is
+// not an instantiation.
+func (st *stenciler) lower(c *copier, p *noder, s *tsubst, call *syntax.CallExpr) syntax.Expr {
+	// An instantiated type, as in Set(int).
+	if tv, ok := st.info.Types[call]; ok && tv.IsType() {
+		if named, ok := tv.Type.(*types2.Named); ok && len(named.TArgs()) > 0 {
+			return st.instType(named, s, call.Pos())
+		}
+		return nil
+	}
+
+	// A call with inferred type arguments, as in Max(1, 2).
+	if inf, ok := st.info.Inferred[call]; ok {
+		ncall := *call
+		ncall.Fun = st.instantiate(st.genericFunc(call.Fun), inf.Targs, s, call.Fun.Pos())
+		ncall.ArgList = c.exprList(call.ArgList)
+		return &ncall
+	}
+
+	// A function instantiated explicitly, as in Max(int).
+	if tv, ok := st.info.Types[call.Fun]; ok {
+		if sig, ok := tv.Type.(*types2.Signature); ok && len(sig.TParams()) > 0 {
+			targs := make([]types2.Type, len(call.ArgList))
+			for i, arg := range call.ArgList {
+				targs[i] = st.info.Types[arg].Type
+			}
+			return st.instantiate(st.genericFunc(call.Fun), targs, s, call.Pos())
+		}
+	}
+	return nil
+}
+
+// genericFunc returns the generic function that fun refers to.
+func (st *stenciler) genericFunc(fun syntax.Expr) types2.Object {
+	for {
+		switch f := fun.(type) {
+		case *syntax.ParenExpr:
+			fun = f.X
+		case *syntax.SelectorExpr:
+			return st.info.Uses[f.Sel]
+		case *syntax.Name:
+			return st.info.Uses[f]
+		default:
+			return nil
+		}
+	}
+}
+
+// genericType returns the generic type that named instantiates.
+// The instantiation has a type name of its own.
+func (st *stenciler) genericType(named *types2.Named) types2.Object {
+	obj := named.Obj()
+	if obj.Pkg() == nil {
+		return nil
+	}
+	return obj.Pkg().Scope().Lookup(obj.Name())
+}
+
+// instType returns a reference to the copy of the instantiated type
+// named, at pos in code copied with s. If the generic code is not at
+// hand, named was read from export data, which declares the copy too.
+func (st *stenciler) instType(named *types2.Named, s *tsubst, pos syntax.Pos) syntax.Expr {
+	obj := st.genericType(named)
+	if st.generic[obj] == nil && named.Obj().Pkg() != nil && named.Obj().Pkg() != st.pkg {
+		name := named.Obj().Name() + "[" + st.typeListString(named.TArgs(), s) + "]"
+		return st.declaredIn(named.Obj().Pkg(), syntaxName(pos, name))
+	}
+	return st.instantiate(obj, named.TArgs(), s, pos)
+}
+
+// instantiate records the instantiation of the generic function or
+// type obj with type arguments targs, at pos in code copied with s,
+// to be stamped out if it hasn't been, and returns a reference to the
+// copy.
+func (st *stenciler) instantiate(obj types2.Object, targs []types2.Type, s *tsubst, pos syntax.Pos) syntax.Expr {
+	g := st.generic[obj]
+	if g == nil {
+		name := "generic code"
+		if obj != nil {
+			name = obj.Name()
+		}
+		st.noderFor(pos).yyerrorpos(pos, "cannot instantiate %s: no generic code for it", name)
+		errorexit()
+	}
+
+	name := obj.Name() + "[" + st.typeListString(targs, s) + "]"
+	if key := obj.Pkg().Path() + "." + name; !st.insts[key] {
+		depth := 1
+		if s != nil {
+			depth = s.depth + 1
+		}
+		if depth > maxInstDepth {
+			st.noderFor(pos).yyerrorpos(pos, "instantiation cycle, or instantiations nested too deeply: %s", name)
+			errorexit()
+		}
+		st.insts[key] = true
+		inst := &instantiation{name: name, gen: g, depth: depth}
+		for _, targ := range targs {
+			inst.targs = append(inst.targs, typeArg{targ, s})
+		}
+		st.pending = append(st.pending, inst)
+	}
+	return st.declaredIn(obj.Pkg(), syntaxName(pos, name))
+}
+
+// stamp stamps out an instantiation, adding the copies of the generic
+// declarations to the file that holds them.
+func (st *stenciler) stamp(inst *instantiation) {
+	g := inst.gen
+	s := &tsubst{pkg: g.pkg, targs: make(map[types2.Object]typeArg), depth: inst.depth}
+	for i, tparam := range g.tparams {
+		s.targs[tparam] = inst.targs[i]
+	}
+	// The type parameters are not copied, so that their constraints
+	// do not count as uses of imports.
+	c := &copier{lower: st.lowerer(g.p, s, nil, nil)}
+	switch d := g.decl.(type) {
+	case *syntax.FuncDecl:
+		nd := *d
+		nd.TParamList = nil
+		nd.Name = st.declaredIn(g.pkg, syntaxName(d.Name.Pos(), inst.name))
+		g.p.file.DeclList = append(g.p.file.DeclList, c.decl(&nd))
+	case *syntax.TypeDecl:
+		nd := *d
+		nd.TParamList = nil
+		nd.Name = st.declaredIn(g.pkg, syntaxName(d.Name.Pos(), inst.name))
+		cd := c.decl(&nd).(*syntax.TypeDecl)
+		g.p.file.DeclList = append(g.p.file.DeclList, cd)
+
+		// Note the type arguments for the export data.
+		idecl := &instDecl{generic: d.Name.Value}
+		for _, targ := range inst.targs {
+			idecl.targs = append(idecl.targs, st.typeExpr(g.p, targ.typ, targ.subst, d.Name.Pos()))
+		}
+		instDecls[cd] = idecl
+	}
+
+	// The methods of a generic type declare type parameters of their
+	// own in the receiver, as in func (s *Set(E)) Len() int.
+	for _, m := range g.methods {
+		s := &tsubst{pkg: g.pkg, targs: make(map[types2.Object]typeArg), depth: inst.depth}
+		recv := recvInst(m.Recv.Type)
+		for i, arg := range recv.ArgList {
+			if name, ok := arg.(*syntax.Name); ok && i < len(inst.targs) {
+				if obj := st.info.Defs[name]; obj != nil {
+					s.targs[obj] = inst.targs[i]
+				}
+			}
+		}
+		c := &copier{lower: st.lowerer(g.p, s, recv, st.declaredIn(g.pkg, syntaxName(recv.Pos(), inst.name)))}
+		g.p.file.DeclList = append(g.p.file.DeclList, c.decl(m))
+	}
+}
+
+// dropGeneric drops the generic declarations. Imports that only the
+// generic code used are then unused, and are replaced by blank imports.
+func (st *stenciler) dropGeneric() {
+	for _, p := range st.noders {
+		var decls []syntax.Decl
+		for _, d := range p.file.DeclList {
+			if !st.dropped[d] {
+				decls = append(decls, d)
+			}
+		}
+		for i, d := range decls {
+			imp, ok := d.(*syntax.ImportDecl)
+			if !ok || imp.LocalPkgName != nil && (imp.LocalPkgName.Value == "_" || imp.LocalPkgName.Value == ".") {
+				continue
+			}
+			var obj types2.Object
+			if imp.LocalPkgName != nil {
+				obj = st.info.Defs[imp.LocalPkgName]
+			} else {
+				obj = st.info.Implicits[imp]
+			}
+			if pkgName, ok := obj.(*types2.PkgName); ok && !st.used[p][pkgName.Imported()] {
+				nimp := *imp
+				nimp.LocalPkgName = syntaxName(imp.Path.Pos(), "_")
+				decls[i] = &nimp
+			}
+		}
+		p.file.DeclList = decls
+	}
+}
+
+// exportGeneric returns the source of the generic declarations that
+// other packages can instantiate, see genericSource, and the names of
+// the unexported objects that they refer to, see genericRefs. A
+// declaration is left out if it refers, directly or through other
+// generic declarations, to an object of a dot import.
+func (st *stenciler) exportGeneric() (src, refs []string) {
+	// A node is a generic function or constraint, or a generic type
+	// with its methods.
+	type node struct {
+		decls  []syntax.Decl
+		refs   []types2.Object // nodes it refers to
+		hidden []types2.Object // unexported objects it refers to
+		imps   []types2.Object // imports it uses
+		bad    bool            // not exported
+	}
+	nodes := make(map[types2.Object]*node)
+	nodeOf := make(map[syntax.Decl]*node)
+	for _, p := range st.noders {
+		for _, d := range p.file.DeclList {
+			if !st.dropped[d] {
+				continue
+			}
+			var obj types2.Object
+			switch d := d.(type) {
+			case *syntax.FuncDecl:
+				if d.Recv != nil {
+					obj = st.info.Uses[recvBase(d.Recv.Type)]
+				} else {
+					obj = st.info.Defs[d.Name]
+				}
+			case *syntax.TypeDecl:
+				obj = st.info.Defs[d.Name]
+			}
+			if obj == nil {
+				continue
+			}
+			n := nodes[obj]
+			if n == nil {
+				n = new(node)
+				nodes[obj] = n
+			}
+			n.decls = append(n.decls, d)
+			nodeOf[d] = n
+		}
+	}
+
+	for _, n := range nodes {
+		c := &copier{lower: func(c *copier, x syntax.Expr) syntax.Expr {
+			switch x := x.(type) {
+			case *syntax.SelectorExpr:
+				if name, ok := x.X.(*syntax.Name); ok {
+					if pkgName, ok := st.info.Uses[name].(*types2.PkgName); ok {
+						n.imps = append(n.imps, pkgName)
+						return x
+					}
+				}
+				if fn, ok := st.info.Uses[x.Sel].(*types2.Func); ok && fn.Pkg() == st.pkg && !fn.Exported() {
+					n.hidden = append(n.hidden, fn)
+				}
+			case *syntax.Name:
+				obj := st.info.Uses[x]
+				switch {
+				case obj == nil || obj.Pkg() == nil:
+					// universe
+				case nodes[obj] != nil:
+					n.refs = append(n.refs, obj)
+				case obj.Parent() != obj.Pkg().Scope():
+					// not package-level
+				case obj.Pkg() != st.pkg:
+					n.bad = true
+				case !obj.Exported():
+					n.hidden = append(n.hidden, obj)
+				}
+			}
+			return nil
+		}}
+		for _, d := range n.decls {
+			c.decl(d)
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, n := range nodes {
+			for _, obj := range n.refs {
+				if !n.bad && nodes[obj].bad {
+					n.bad = true
+					changed = true
+				}
+			}
+		}
+	}
+	used := make(map[types2.Object]bool)
+	for _, n := range nodes {
+		if !n.bad {
+			for _, imp := range n.imps {
+				used[imp] = true
+			}
+		}
+	}
+
+	hidden := make(map[types2.Object]bool)
+	for _, p := range st.noders {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "package %s\n", p.file.PkgName.Value)
+		for _, d := range p.file.DeclList {
+			if imp, ok := d.(*syntax.ImportDecl); ok {
+				var obj types2.Object
+				if imp.LocalPkgName != nil {
+					obj = st.info.Defs[imp.LocalPkgName]
+				} else {
+					obj = st.info.Implicits[imp]
+				}
+				if pkgName, ok := obj.(*types2.PkgName); ok && used[pkgName] {
+					fmt.Fprintf(&buf, "import %s %q\n", pkgName.Name(), pkgName.Imported().Path())
+				}
+			}
+		}
+		empty := true
+		for _, d := range p.file.DeclList {
+			n := nodeOf[d]
+			if n == nil || n.bad {
+				continue
+			}
+			for _, obj := range n.hidden {
+				if !hidden[obj] {
+					hidden[obj] = true
+					refs = append(refs, refName(obj))
+				}
+			}
+			if td, ok := d.(*syntax.TypeDecl); ok && td.Group != nil {
+				ntd := *td
+				ntd.Group = nil
+				d = &ntd
+			}
+			pos := d.Pos()
+			fmt.Fprintf(&buf, "\n//line %s:%d\n", pos.RelFilename(), pos.RelLine())
+			syntax.Fprint(&buf, d, true)
+			buf.WriteString("\n")
+			empty = false
+		}
+		if !empty {
+			src = append(src, buf.String())
+		}
+	}
+	return src, refs
+}
+
+// refName returns the name of obj in genericRefs.
+func refName(obj types2.Object) string {
+	if fn, ok := obj.(*types2.Func); ok {
+		if recv := fn.Type().(*types2.Signature).Recv(); recv != nil {
+			t := recv.Type()
+			if ptr, ok := t.(*types2.Pointer); ok {
+				t = ptr.Elem()
+			}
+			if named, ok := t.(*types2.Named); ok {
+				return named.Obj().Name() + "." + fn.Name()
+			}
+		}
+	}
+	return obj.Name()
+}
+
+// importGeneric type checks the source of the generic declarations
+// of pkg, which follows its export data, into pkg, so that the package
+// being compiled can use them, and records them to be stamped out. The
+// copies are added to the first file of the package being compiled.
+func (st *stenciler) importGeneric(pkg *types2.Package, src []string) error {
+	var files []*syntax.File
+	for _, s := range src {
+		base := syntax.NewFileBase("<generic code of " + pkg.Path() + ">")
+		file, err := syntax.Parse(base, strings.NewReader(s), nil, nil, syntax.CheckBranches)
+		if err != nil {
+			return fmt.Errorf("cannot import generic code of %q: %v", pkg.Path(), err)
+		}
+		files = append(files, file)
+	}
+
+	// The info of the package being compiled is recorded while it
+	// is type checked, so the generic code is checked with its own.
+	conf := st.conf
+	conf.Error = nil
+	info := newInfo()
+	if err := types2.NewChecker(&conf, pkg, info).Files(files); err != nil {
+		return fmt.Errorf("cannot import generic code of %q: %v", pkg.Path(), err)
+	}
+	for x, tv := range info.Types {
+		st.info.Types[x] = tv
+	}
+	for x, inf := range info.Inferred {
+		st.info.Inferred[x] = inf
+	}
+	for x, obj := range info.Defs {
+		st.info.Defs[x] = obj
+	}
+	for x, obj := range info.Uses {
+		st.info.Uses[x] = obj
+	}
+	for x, obj := range info.Implicits {
+		st.info.Implicits[x] = obj
+	}
+
+	// The copies are declared in pkg, so the package being compiled
+	// imports it, if only for that.
+	p := st.noders[0]
+	pos := p.file.PkgName.Pos()
+	lit := &syntax.BasicLit{Value: strconv.Quote(pkg.Path()), Kind: syntax.StringLit}
+	lit.SetPos(pos)
+	imp := &syntax.ImportDecl{LocalPkgName: syntaxName(pos, "_"), Path: lit}
+	imp.SetPos(pos)
+	st.newImps[p] = append(st.newImps[p], imp)
+
+	ps := make([]*noder, len(files))
+	for i := range ps {
+		ps[i] = p
+	}
+	st.findGeneric(pkg, ps, files)
+	return nil
+}
+
+// importName returns the name of an import of the package with the
+// given path that is added to the file of p for the type arguments
+// of instantiations. The name is the quoted path, which cannot
+// conflict with any name in the file.
+func (st *stenciler) importName(p *noder, path string) string {
+	names := st.imports[p]
+	if names == nil {
+		names = make(map[string]string)
+		st.imports[p] = names
+	}
+	if name, ok := names[path]; ok {
+		return name
+	}
+	name := strconv.Quote(path)
+	names[path] = name
+
+	pos := p.file.PkgName.Pos()
+	lit := &syntax.BasicLit{Value: name, Kind: syntax.StringLit}
+	lit.SetPos(pos)
+	imp := &syntax.ImportDecl{LocalPkgName: syntaxName(pos, name), Path: lit}
+	imp.SetPos(pos)
+	st.newImps[p] = append(st.newImps[p], imp)
+	return name
+}
+
+// syntaxName returns a new name at pos.
+func syntaxName(pos syntax.Pos, value string) *syntax.Name {
+	n := &syntax.Name{Value: value}
+	n.SetPos(pos)
+	return n
+}
+
+// typeListString returns the type arguments of an instantiation as
+// they appear in its name, with the type parameters of generic code
+// replaced by the type arguments of s. Identical types are written the
+// same way in every package, with named types qualified by the path of
+// their package, so that Set[example.com/geo.Point] is the name of
+// Set(geo.Point) wherever it is instantiated.
+func (st *stenciler) typeListString(list []types2.Type, s *tsubst) string {
+	var buf bytes.Buffer
+	for i, t := range list {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		st.writeType(&buf, t, s)
+	}
+	return buf.String()
+}
+
+func (st *stenciler) writeType(buf *bytes.Buffer, t types2.Type, s *tsubst) {
+	switch t := t.(type) {
+	case *types2.Basic:
+		if t.Kind() == types2.UnsafePointer {
+			buf.WriteString("unsafe.Pointer")
+		} else {
+			// Not byte or rune, but the types they stand for.
+			buf.WriteString(types2.Typ[t.Kind()].Name())
+		}
+	case *types2.Pointer:
+		buf.WriteString("*")
+		st.writeType(buf, t.Elem(), s)
+	case *types2.Slice:
+		buf.WriteString("[]")
+		st.writeType(buf, t.Elem(), s)
+	case *types2.Array:
+		fmt.Fprintf(buf, "[%d]", t.Len())
+		st.writeType(buf, t.Elem(), s)
+	case *types2.Map:
+		buf.WriteString("map[")
+		st.writeType(buf, t.Key(), s)
+		buf.WriteString("]")
+		st.writeType(buf, t.Elem(), s)
+	case *types2.Chan:
+		switch t.Dir() {
+		case types2.SendRecv:
+			buf.WriteString("chan ")
+		case types2.SendOnly:
+			buf.WriteString("chan<- ")
+		case types2.RecvOnly:
+			buf.WriteString("<-chan ")
+		}
+		if c, ok := t.Elem().(*types2.Chan); ok && c.Dir() == types2.RecvOnly {
+			buf.WriteString("(")
+			st.writeType(buf, c, s)
+			buf.WriteString(")")
+		} else {
+			st.writeType(buf, t.Elem(), s)
+		}
+	case *types2.Signature:
+		buf.WriteString("func")
+		st.writeSignature(buf, t, s)
+	case *types2.Struct:
+		buf.WriteString("struct{")
+		for i := 0; i < t.NumFields(); i++ {
+			if i > 0 {
+				buf.WriteString("; ")
+			}
+			f := t.Field(i)
+			if !f.Embedded() {
+				st.writeName(buf, f)
+				buf.WriteString(" ")
+			}
+			st.writeType(buf, f.Type(), s)
+			if tag := t.Tag(i); tag != "" {
+				buf.WriteString(" " + strconv.Quote(tag))
+			}
+		}
+		buf.WriteString("}")
+	case *types2.Interface:
+		buf.WriteString("interface{")
+		for i := 0; i < t.NumMethods(); i++ {
+			if i > 0 {
+				buf.WriteString("; ")
+			}
+			m := t.Method(i)
+			st.writeName(buf, m)
+			st.writeSignature(buf, m.Type().(*types2.Signature), s)
+		}
+		buf.WriteString("}")
+	case *types2.Named:
+		obj := t.Obj()
+		if obj.Pkg() != nil {
+			buf.WriteString(pkgPath(obj.Pkg()) + ".")
+		}
+		buf.WriteString(obj.Name())
+		if len(t.TArgs()) > 0 {
+			buf.WriteString("[" + st.typeListString(t.TArgs(), s) + "]")
+		}
+	case *types2.TypeParam:
+		a := s.lookup(t)
+		st.writeType(buf, a.typ, a.subst)
+	default:
+		buf.WriteString(t.String())
+	}
+}
+
+// writeName writes the name of a field or method, qualified by its
+// package if it is not exported.
+func (st *stenciler) writeName(buf *bytes.Buffer, obj types2.Object) {
+	if !obj.Exported() && obj.Pkg() != nil {
+		buf.WriteString(pkgPath(obj.Pkg()) + ".")
+	}
+	buf.WriteString(obj.Name())
+}
+
+// pkgPath returns the path of pkg as it appears in the names of
+// instantiations, or its name if it has no path.
+func pkgPath(pkg *types2.Package) string {
+	if pkg.Path() == "" {
+		return pkg.Name()
+	}
+	return pkg.Path()
+}
+
+func (st *stenciler) writeSignature(buf *bytes.Buffer, sig *types2.Signature, s *tsubst) {
+	st.writeTuple(buf, sig.Params(), sig.Variadic(), s)
+	if sig.Results().Len() > 0 {
+		buf.WriteString(" ")
+		st.writeTuple(buf, sig.Results(), false, s)
+	}
+}
+
+func (st *stenciler) writeTuple(buf *bytes.Buffer, tuple *types2.Tuple, variadic bool, s *tsubst) {
+	buf.WriteString("(")
+	for i := 0; i < tuple.Len(); i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		t := tuple.At(i).Type()
+		if variadic && i == tuple.Len()-1 {
+			buf.WriteString("...")
+			t = t.(*types2.Slice).Elem()
+		}
+		st.writeType(buf, t, s)
+	}
+	buf.WriteString(")")
+}
+
+// typeExpr returns an expression for the type t, a type argument of
+// an instantiation at pos, for the file of p, with the type parameters
+// of generic code replaced by the type arguments of s. Types from other
+// packages are referred to through imports that are added to the file.
+func (st *stenciler) typeExpr(p *noder, t types2.Type, s *tsubst, pos syntax.Pos) syntax.Expr {
+	var x syntax.Expr
+	switch t := t.(type) {
+	case *types2.Basic:
+		if t.Kind() == types2.UnsafePointer {
+			x = &syntax.SelectorExpr{
+				X:   syntaxName(pos, st.importName(p, "unsafe")),
+				Sel: syntaxName(pos, "Pointer"),
+			}
+		} else {
+			x = syntaxName(pos, types2.Typ[t.Kind()].Name())
+		}
+	case *types2.Pointer:
+		x = &syntax.Operation{Op: syntax.Mul, X: st.typeExpr(p, t.Elem(), s, pos)}
+	case *types2.Slice:
+		x = &syntax.SliceType{Elem: st.typeExpr(p, t.Elem(), s, pos)}
+	case *types2.Array:
+		n := &syntax.BasicLit{Value: strconv.FormatInt(t.Len(), 10), Kind: syntax.IntLit}
+		n.SetPos(pos)
+		x = &syntax.ArrayType{Len: n, Elem: st.typeExpr(p, t.Elem(), s, pos)}
+	case *types2.Map:
+		x = &syntax.MapType{Key: st.typeExpr(p, t.Key(), s, pos), Value: st.typeExpr(p, t.Elem(), s, pos)}
+	case *types2.Chan:
+		var dir syntax.ChanDir
+		switch t.Dir() {
+		case types2.SendOnly:
+			dir = syntax.SendOnly
+		case types2.RecvOnly:
+			dir = syntax.RecvOnly
+		}
+		x = &syntax.ChanType{Dir: dir, Elem: st.typeExpr(p, t.Elem(), s, pos)}
+	case *types2.Signature:
+		x = st.funcTypeExpr(p, t, s, pos)
+	case *types2.Struct:
+		styp := new(syntax.StructType)
+		for i := 0; i < t.NumFields(); i++ {
+			f := t.Field(i)
+			field := &syntax.Field{Type: st.typeExpr(p, f.Type(), s, pos)}
+			field.SetPos(pos)
+			if !f.Embedded() {
+				field.Name = syntaxName(pos, st.fieldName(f, pos))
+			}
+			styp.FieldList = append(styp.FieldList, field)
+			if tag := t.Tag(i); tag != "" {
+				for len(styp.TagList) < i {
+					styp.TagList = append(styp.TagList, nil)
+				}
+				lit := &syntax.BasicLit{Value: strconv.Quote(tag), Kind: syntax.StringLit}
+				lit.SetPos(pos)
+				styp.TagList = append(styp.TagList, lit)
+			}
+		}
+		x = styp
+	case *types2.Interface:
+		it := new(syntax.InterfaceType)
+		for i := 0; i < t.NumMethods(); i++ {
+			m := t.Method(i)
+			field := &syntax.Field{
+				Name: syntaxName(pos, st.fieldName(m, pos)),
+				Type: st.funcTypeExpr(p, m.Type().(*types2.Signature), s, pos),
+			}
+			field.SetPos(pos)
+			it.MethodList = append(it.MethodList, field)
+		}
+		x = it
+	case *types2.Named:
+		obj := t.Obj()
+		switch {
+		case len(t.TArgs()) > 0:
+			return st.instType(t, s, pos)
+		case obj.Pkg() == nil:
+			x = syntaxName(pos, obj.Name()) // error
+		case obj.Parent() != obj.Pkg().Scope():
+			st.noderFor(pos).yyerrorpos(pos, "cannot use local type %s as a type argument", obj.Name())
+			errorexit()
+		case obj.Pkg() == st.pkg:
+			x = syntaxName(pos, obj.Name())
+		case !obj.Exported():
+			st.noderFor(pos).yyerrorpos(pos, "cannot use unexported type %s.%s as a type argument", obj.Pkg().Name(), obj.Name())
+			errorexit()
+		default:
+			x = &syntax.SelectorExpr{
+				X:   syntaxName(pos, st.importName(p, obj.Pkg().Path())),
+				Sel: syntaxName(pos, obj.Name()),
+			}
+		}
+	case *types2.TypeParam:
+		a := s.lookup(t)
+		return st.typeExpr(p, a.typ, a.subst, pos)
+	default:
+		Fatalf("unexpected type argument %v", t)
+	}
+	x.SetPos(pos)
+	return x
+}
+
+// funcTypeExpr returns an expression for the function type sig.
+func (st *stenciler) funcTypeExpr(p *noder, sig *types2.Signature, s *tsubst, pos syntax.Pos) *syntax.FuncType {
+	fields := func(tuple *types2.Tuple, variadic bool) []*syntax.Field {
+		var list []*syntax.Field
+		for i := 0; i < tuple.Len(); i++ {
+			t := tuple.At(i).Type()
+			var typ syntax.Expr
+			if variadic && i == tuple.Len()-1 {
+				typ = &syntax.DotsType{Elem: st.typeExpr(p, t.(*types2.Slice).Elem(), s, pos)}
+				typ.SetPos(pos)
+			} else {
+				typ = st.typeExpr(p, t, s, pos)
+			}
+			field := &syntax.Field{Type: typ}
+			field.SetPos(pos)
+			list = append(list, field)
+		}
+		return list
+	}
+	ft := &syntax.FuncType{
+		ParamList:  fields(sig.Params(), sig.Variadic()),
+		ResultList: fields(sig.Results(), false),
+	}
+	ft.SetPos(pos)
+	return ft
+}
+
+// fieldName returns the name of a field or method in a type argument.
+// Unexported names of other packages cannot be written.
+func (st *stenciler) fieldName(obj types2.Object, pos syntax.Pos) string {
+	if !obj.Exported() && obj.Pkg() != st.pkg {
+		st.noderFor(pos).yyerrorpos(pos, "cannot use a type with unexported field or method %s.%s as a type argument", obj.Pkg().Name(), obj.Name())
+		errorexit()
+	}
+	return obj.Name()
+}
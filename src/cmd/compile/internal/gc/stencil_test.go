@@ -0,0 +1,327 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"internal/testenv"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const stencilSrc = `
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type Ordered interface {
+	type int, int64, float64, string
+}
+
+func Max(type T Ordered)(a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+type Set(type E comparable) struct {
+	m map[E]struct{}
+}
+
+func NewSet(type E comparable)(elems ...E) *Set(E) {
+	s := &Set(E){m: make(map[E]struct{})}
+	for _, e := range elems {
+		s.Add(e)
+	}
+	return s
+}
+
+func (s *Set(E)) Add(e E) { s.m[e] = struct{}{} }
+
+func (s *Set(E)) Len() int { return len(s.m) }
+
+type List(type T) struct {
+	next *List(T)
+	val  T
+}
+
+func (l *List(T)) Push(v T) *List(T) { return &List(T){next: l, val: v} }
+
+func Greatest(type T Ordered)(l *List(T)) T {
+	m := l.val
+	for ; l != nil; l = l.next {
+		m = Max(m, l.val)
+	}
+	return m
+}
+
+func main() {
+	fmt.Println(Max(1, 2), Max("a", "b"), Max(float64)(1.5, 0.5))
+	fmt.Println(NewSet(1, 2, 2, 3).Len())
+	ds := NewSet(time.Second, time.Minute)
+	fmt.Println(ds.Len())
+	var l *List(time.Duration)
+	fmt.Println(Greatest(l.Push(time.Minute).Push(time.Second)))
+	sets := NewSet(ds, NewSet(time.Hour))
+	fmt.Println(sets.Len())
+}
+`
+
+const stencilOut = `2 b 1.5
+3
+2
+1m0s
+2
+`
+
+const stencilErrSrc = `
+package main
+
+func Max(type T interface{ type int })(a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func main() {
+	_ = Max(1, 2)
+	_ = Max("a", "b")
+}
+`
+
+const stencilCycleSrc = `
+package main
+
+func F(type T)(n int) {
+	if n > 0 {
+		F(*T)(n - 1)
+	}
+}
+
+func main() {
+	F(int)(3)
+}
+`
+
+func TestStencil(t *testing.T) {
+	t.Parallel()
+
+	testenv.MustHaveGoBuild(t)
+
+	dir, err := ioutil.TempDir("", "TestStencil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "x.go2")
+	if err := ioutil.WriteFile(src, []byte(stencilSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	obj := filepath.Join(dir, "x.o")
+	exe := filepath.Join(dir, "x.exe")
+	for _, run := range [][]string{
+		{testenv.GoToolPath(t), "tool", "compile", "-G", "-p", "main", "-o", obj, src},
+		{testenv.GoToolPath(t), "tool", "link", "-o", exe, obj},
+	} {
+		if out, err := exec.Command(run[0], run[1:]...).CombinedOutput(); err != nil {
+			t.Fatalf("%v: %v\n%s", run, err, out)
+		}
+	}
+	out, err := exec.Command(exe).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if string(out) != stencilOut {
+		t.Errorf("got output\n%s\nwant\n%s", out, stencilOut)
+	}
+
+	// Type errors are reported at their position in the source.
+	if err := ioutil.WriteFile(src, []byte(stencilErrSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = exec.Command(testenv.GoToolPath(t), "tool", "compile", "-G", "-p", "main", "-o", obj, src).CombinedOutput()
+	if err == nil {
+		t.Fatal("compilation succeeded unexpectedly")
+	}
+	if want := "x.go2:13:9: string does not satisfy"; !strings.Contains(string(out), want) {
+		t.Errorf("got errors\n%s\nwant %q", out, want)
+	}
+
+	// Instantiation cycles are reported rather than stamped out
+	// forever.
+	if err := ioutil.WriteFile(src, []byte(stencilCycleSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = exec.Command(testenv.GoToolPath(t), "tool", "compile", "-G", "-p", "main", "-o", obj, src).CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "instantiation cycle") {
+		t.Errorf("compilation of an instantiation cycle: got %v\n%s", err, out)
+	}
+
+	// Without -G, generic code is rejected.
+	if err := ioutil.WriteFile(src, []byte(stencilErrSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = exec.Command(testenv.GoToolPath(t), "tool", "compile", "-p", "main", "-o", obj, src).CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "require the -G flag") {
+		t.Errorf("compilation without -G: got %v\n%s", err, out)
+	}
+}
+
+// stencilImportSrc holds packages that instantiate the generic code of
+// the packages that they import, in the order they are compiled.
+var stencilImportSrc = []struct{ path, src string }{
+	{"a", `
+package a
+
+import (
+	"fmt"
+	str "strings"
+)
+
+type Ordered interface {
+	type int, string
+}
+
+func Max(type T Ordered)(a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+const Sep = ","
+
+func Join(type T fmt.Stringer)(xs []T) string {
+	var s []string
+	for _, x := range xs {
+		s = append(s, x.String())
+	}
+	return str.Join(s, Sep)
+}
+
+type Stack(type T) struct {
+	elems []T
+}
+
+func (s *Stack(T)) Push(v T) { s.elems = append(s.elems, v) }
+
+func (s *Stack(T)) Len() int { return len(s.elems) }
+
+var calls int
+
+func internal() int {
+	calls++
+	return calls
+}
+
+func Hidden(type T)(x T) int { return internal() }
+`},
+	{"b", `
+package b
+
+import "a"
+
+func Biggest(type T a.Ordered)(xs ...T) T {
+	m := xs[0]
+	for _, x := range xs {
+		m = a.Max(m, x)
+	}
+	return m
+}
+
+func Words() *a.Stack(string) {
+	s := new(a.Stack(string))
+	s.Push("b")
+	return s
+}
+`},
+	{"c", `
+package c
+
+import "a"
+
+func Count(s *a.Stack(string)) int {
+	s.Push("c")
+	return s.Len()
+}
+`},
+	{"main", `
+package main
+
+import (
+	"a"
+	"b"
+	"c"
+	"fmt"
+	"time"
+)
+
+func Max(type T)(x T) T { return x }
+
+func main() {
+	fmt.Println(a.Max(1, 2), Max(3), b.Biggest("p", "q"))
+	var s a.Stack(time.Duration)
+	s.Push(time.Second)
+	fmt.Println(s.Len(), a.Join([]time.Duration{time.Second, time.Minute}))
+	var w interface{} = b.Words()
+	_, ok := w.(*a.Stack(string))
+	fmt.Println(c.Count(b.Words()), ok)
+	fmt.Println(a.Hidden(1), a.Hidden("x"))
+}
+`},
+}
+
+const stencilImportOut = `2 3 q
+1 1s,1m0s
+2 true
+1 2
+`
+
+func TestStencilImport(t *testing.T) {
+	t.Parallel()
+
+	testenv.MustHaveGoBuild(t)
+
+	dir, err := ioutil.TempDir("", "TestStencilImport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	compile := func(path, src string) ([]byte, error) {
+		file := filepath.Join(dir, path+".go2")
+		if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(testenv.GoToolPath(t), "tool", "compile", "-G", "-I", dir, "-p", path, "-o", filepath.Join(dir, path+".o"), file)
+		return cmd.CombinedOutput()
+	}
+	for _, p := range stencilImportSrc {
+		if out, err := compile(p.path, p.src); err != nil {
+			t.Fatalf("compiling %s: %v\n%s", p.path, err, out)
+		}
+	}
+	exe := filepath.Join(dir, "main.exe")
+	link := exec.Command(testenv.GoToolPath(t), "tool", "link", "-L", dir, "-o", exe, filepath.Join(dir, "main.o"))
+	if out, err := link.CombinedOutput(); err != nil {
+		t.Fatalf("%v: %v\n%s", link.Args, err, out)
+	}
+	out, err := exec.Command(exe).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if string(out) != stencilImportOut {
+		t.Errorf("got output\n%s\nwant\n%s", out, stencilImportOut)
+	}
+}
@@ -1530,9 +1530,10 @@ func genwrapper(rcvr *types.Type, method *types.Field, newnam *types.Sym) {
 		fmt.Printf("genwrapper rcvrtype=%v method=%v newnam=%v\n", rcvr, method, newnam)
 	}
 
-	// Only generate (*T).M wrappers for T.M in T's own package.
+	// Only generate (*T).M wrappers for T.M in T's own package,
+	// or in every package that copies T, if it is an instantiation.
 	if rcvr.IsPtr() && rcvr.Elem() == method.Type.Recv().Type &&
-		rcvr.Elem().Sym != nil && rcvr.Elem().Sym.Pkg != localpkg {
+		rcvr.Elem().Sym != nil && rcvr.Elem().Sym.Pkg != localpkg && !isInstSym(rcvr.Elem().Sym) {
 		return
 	}
 
@@ -0,0 +1,430 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"cmd/compile/internal/syntax"
+)
+
+// A copier makes deep copies of syntax trees, for stenciling.
+// Copies keep the positions of the original nodes.
+type copier struct {
+	// lower, if not nil, is called for each expression before it is
+	// copied. If it returns an expression, that is used instead of
+	// a copy.
+	lower func(c *copier, x syntax.Expr) syntax.Expr
+
+	nodes    map[syntax.Node]syntax.Node // copies of shared nodes and statements
+	branches []*syntax.BranchStmt        // copied branches, to update their targets
+}
+
+// decl returns a copy of d.
+func (c *copier) decl(d syntax.Decl) syntax.Decl {
+	var n syntax.Decl
+	switch d := d.(type) {
+	case *syntax.ImportDecl:
+		nd := *d
+		n = &nd
+	case *syntax.ConstDecl:
+		nd := *d
+		nd.NameList = c.names(d.NameList)
+		nd.Type = c.expr(d.Type)
+		nd.Values = c.expr(d.Values)
+		n = &nd
+	case *syntax.TypeDecl:
+		nd := *d
+		nd.Name = c.name(d.Name)
+		nd.TParamList = c.fields(d.TParamList)
+		nd.Type = c.expr(d.Type)
+		n = &nd
+	case *syntax.VarDecl:
+		nd := *d
+		nd.NameList = c.names(d.NameList)
+		nd.Type = c.expr(d.Type)
+		nd.Values = c.expr(d.Values)
+		n = &nd
+	case *syntax.FuncDecl:
+		nd := *d
+		if d.Recv != nil {
+			nd.Recv = c.field(d.Recv)
+		}
+		nd.Name = c.name(d.Name)
+		nd.TParamList = c.fields(d.TParamList)
+		nd.Type = c.funcType(d.Type)
+		nd.Body = c.block(d.Body)
+		n = &nd
+	default:
+		Fatalf("unexpected declaration %T", d)
+	}
+
+	// The targets of branches are statements of the same function.
+	for _, b := range c.branches {
+		if t, ok := c.nodes[b.Target]; ok {
+			b.Target = t.(syntax.Stmt)
+		}
+	}
+	c.branches = nil
+	return n
+}
+
+// expr returns a copy of x.
+func (c *copier) expr(x syntax.Expr) syntax.Expr {
+	if x == nil {
+		return nil
+	}
+	if n, ok := c.nodes[x]; ok {
+		return n.(syntax.Expr)
+	}
+	if c.lower != nil {
+		if n := c.lower(c, x); n != nil {
+			return n
+		}
+	}
+
+	var n syntax.Expr
+	switch x := x.(type) {
+	case *syntax.BadExpr:
+		nx := *x
+		n = &nx
+	case *syntax.Name:
+		nx := *x
+		n = &nx
+	case *syntax.BasicLit:
+		nx := *x
+		n = &nx
+	case *syntax.CompositeLit:
+		nx := *x
+		nx.Type = c.expr(x.Type)
+		nx.ElemList = c.exprList(x.ElemList)
+		n = &nx
+	case *syntax.KeyValueExpr:
+		nx := *x
+		nx.Key = c.expr(x.Key)
+		nx.Value = c.expr(x.Value)
+		n = &nx
+	case *syntax.FuncLit:
+		nx := *x
+		nx.Type = c.funcType(x.Type)
+		nx.Body = c.block(x.Body)
+		n = &nx
+	case *syntax.ParenExpr:
+		nx := *x
+		nx.X = c.expr(x.X)
+		n = &nx
+	case *syntax.SelectorExpr:
+		nx := *x
+		nx.X = c.expr(x.X)
+		nx.Sel = c.name(x.Sel)
+		n = &nx
+	case *syntax.IndexExpr:
+		nx := *x
+		nx.X = c.expr(x.X)
+		nx.Index = c.expr(x.Index)
+		n = &nx
+	case *syntax.SliceExpr:
+		nx := *x
+		nx.X = c.expr(x.X)
+		for i, index := range x.Index {
+			nx.Index[i] = c.expr(index)
+		}
+		n = &nx
+	case *syntax.AssertExpr:
+		nx := *x
+		nx.X = c.expr(x.X)
+		nx.Type = c.expr(x.Type)
+		n = &nx
+	case *syntax.TypeSwitchGuard:
+		nx := *x
+		if x.Lhs != nil {
+			nx.Lhs = c.name(x.Lhs)
+		}
+		nx.X = c.expr(x.X)
+		n = &nx
+	case *syntax.Operation:
+		nx := *x
+		nx.X = c.expr(x.X)
+		nx.Y = c.expr(x.Y)
+		n = &nx
+	case *syntax.CallExpr:
+		nx := *x
+		nx.Fun = c.expr(x.Fun)
+		nx.ArgList = c.exprList(x.ArgList)
+		n = &nx
+	case *syntax.ListExpr:
+		nx := *x
+		nx.ElemList = c.exprList(x.ElemList)
+		n = &nx
+	case *syntax.ArrayType:
+		nx := *x
+		nx.Len = c.expr(x.Len)
+		nx.Elem = c.expr(x.Elem)
+		n = &nx
+	case *syntax.SliceType:
+		nx := *x
+		nx.Elem = c.expr(x.Elem)
+		n = &nx
+	case *syntax.DotsType:
+		nx := *x
+		nx.Elem = c.expr(x.Elem)
+		n = &nx
+	case *syntax.StructType:
+		nx := *x
+		nx.FieldList = c.fields(x.FieldList)
+		if x.TagList != nil {
+			nx.TagList = make([]*syntax.BasicLit, len(x.TagList))
+			for i, tag := range x.TagList {
+				if tag != nil {
+					nx.TagList[i] = c.expr(tag).(*syntax.BasicLit)
+				}
+			}
+		}
+		n = &nx
+	case *syntax.InterfaceType:
+		nx := *x
+		nx.MethodList = c.fields(x.MethodList)
+		n = &nx
+	case *syntax.FuncType:
+		n = c.funcType(x)
+	case *syntax.MapType:
+		nx := *x
+		nx.Key = c.expr(x.Key)
+		nx.Value = c.expr(x.Value)
+		n = &nx
+	case *syntax.ChanType:
+		nx := *x
+		nx.Elem = c.expr(x.Elem)
+		n = &nx
+	default:
+		Fatalf("unexpected expression %T", x)
+	}
+	return n
+}
+
+// exprList returns a copy of list.
+func (c *copier) exprList(list []syntax.Expr) []syntax.Expr {
+	if list == nil {
+		return nil
+	}
+	n := make([]syntax.Expr, len(list))
+	for i, x := range list {
+		n[i] = c.expr(x)
+	}
+	return n
+}
+
+// name returns a copy of x, which lower may only replace by a name.
+func (c *copier) name(x *syntax.Name) *syntax.Name {
+	if n, ok := c.expr(x).(*syntax.Name); ok {
+		return n
+	}
+	nx := *x
+	return &nx
+}
+
+func (c *copier) names(list []*syntax.Name) []*syntax.Name {
+	if list == nil {
+		return nil
+	}
+	n := make([]*syntax.Name, len(list))
+	for i, x := range list {
+		n[i] = c.name(x)
+	}
+	return n
+}
+
+func (c *copier) funcType(x *syntax.FuncType) *syntax.FuncType {
+	if x == nil {
+		return nil
+	}
+	nx := *x
+	nx.TParamList = c.fields(x.TParamList)
+	nx.ParamList = c.fields(x.ParamList)
+	nx.ResultList = c.fields(x.ResultList)
+	return &nx
+}
+
+// fields returns a copy of list. Fields declared together, as in
+// a, b int, keep sharing their type.
+func (c *copier) fields(list []*syntax.Field) []*syntax.Field {
+	if list == nil {
+		return nil
+	}
+	n := make([]*syntax.Field, len(list))
+	for i, f := range list {
+		if i > 0 && f.Type == list[i-1].Type {
+			c.share(f.Type, n[i-1].Type)
+		}
+		n[i] = c.field(f)
+	}
+	return n
+}
+
+func (c *copier) field(f *syntax.Field) *syntax.Field {
+	nf := *f
+	if f.Name != nil {
+		nf.Name = c.name(f.Name)
+	}
+	nf.Type = c.expr(f.Type)
+	return &nf
+}
+
+// share records n as the copy of x, so that other references to x
+// refer to n as well.
+func (c *copier) share(x, n syntax.Node) {
+	if c.nodes == nil {
+		c.nodes = make(map[syntax.Node]syntax.Node)
+	}
+	c.nodes[x] = n
+}
+
+// stmt returns a copy of s.
+func (c *copier) stmt(s syntax.Stmt) syntax.Stmt {
+	if s == nil {
+		return nil
+	}
+	var n syntax.Stmt
+	switch s := s.(type) {
+	case syntax.SimpleStmt:
+		n = c.simpleStmt(s)
+	case *syntax.LabeledStmt:
+		ns := *s
+		ns.Label = c.name(s.Label)
+		ns.Stmt = c.stmt(s.Stmt)
+		n = &ns
+	case *syntax.BlockStmt:
+		n = c.block(s)
+	case *syntax.DeclStmt:
+		ns := *s
+		ns.DeclList = make([]syntax.Decl, len(s.DeclList))
+		for i, d := range s.DeclList {
+			ns.DeclList[i] = c.localDecl(d)
+		}
+		n = &ns
+	case *syntax.BranchStmt:
+		ns := *s
+		if s.Label != nil {
+			ns.Label = c.name(s.Label)
+		}
+		if s.Target != nil {
+			c.branches = append(c.branches, &ns)
+		}
+		n = &ns
+	case *syntax.CallStmt:
+		ns := *s
+		ns.Call = c.expr(s.Call).(*syntax.CallExpr)
+		n = &ns
+	case *syntax.ReturnStmt:
+		ns := *s
+		ns.Results = c.expr(s.Results)
+		n = &ns
+	case *syntax.IfStmt:
+		ns := *s
+		ns.Init = c.simpleStmt(s.Init)
+		ns.Cond = c.expr(s.Cond)
+		ns.Then = c.block(s.Then)
+		ns.Else = c.stmt(s.Else)
+		n = &ns
+	case *syntax.ForStmt:
+		ns := *s
+		ns.Init = c.simpleStmt(s.Init)
+		ns.Cond = c.expr(s.Cond)
+		ns.Post = c.simpleStmt(s.Post)
+		ns.Body = c.block(s.Body)
+		n = &ns
+	case *syntax.SwitchStmt:
+		ns := *s
+		ns.Init = c.simpleStmt(s.Init)
+		ns.Tag = c.expr(s.Tag)
+		ns.Body = make([]*syntax.CaseClause, len(s.Body))
+		for i, cc := range s.Body {
+			ncc := *cc
+			ncc.Cases = c.expr(cc.Cases)
+			ncc.Body = c.stmtList(cc.Body)
+			ns.Body[i] = &ncc
+		}
+		n = &ns
+	case *syntax.SelectStmt:
+		ns := *s
+		ns.Body = make([]*syntax.CommClause, len(s.Body))
+		for i, cc := range s.Body {
+			ncc := *cc
+			ncc.Comm = c.simpleStmt(cc.Comm)
+			ncc.Body = c.stmtList(cc.Body)
+			ns.Body[i] = &ncc
+		}
+		n = &ns
+	default:
+		Fatalf("unexpected statement %T", s)
+	}
+	c.share(s, n)
+	return n
+}
+
+// localDecl returns a copy of d, a declaration in a function body.
+// Unlike decl, it leaves the targets of branches to the enclosing
+// declaration.
+func (c *copier) localDecl(d syntax.Decl) syntax.Decl {
+	branches := c.branches
+	c.branches = nil
+	n := c.decl(d)
+	c.branches = branches
+	return n
+}
+
+func (c *copier) simpleStmt(s syntax.SimpleStmt) syntax.SimpleStmt {
+	if s == nil {
+		return nil
+	}
+	var n syntax.SimpleStmt
+	switch s := s.(type) {
+	case *syntax.EmptyStmt:
+		ns := *s
+		n = &ns
+	case *syntax.ExprStmt:
+		ns := *s
+		ns.X = c.expr(s.X)
+		n = &ns
+	case *syntax.SendStmt:
+		ns := *s
+		ns.Chan = c.expr(s.Chan)
+		ns.Value = c.expr(s.Value)
+		n = &ns
+	case *syntax.AssignStmt:
+		ns := *s
+		ns.Lhs = c.expr(s.Lhs)
+		if s.Rhs != syntax.ImplicitOne {
+			ns.Rhs = c.expr(s.Rhs)
+		}
+		n = &ns
+	case *syntax.RangeClause:
+		ns := *s
+		ns.Lhs = c.expr(s.Lhs)
+		ns.X = c.expr(s.X)
+		n = &ns
+	default:
+		Fatalf("unexpected statement %T", s)
+	}
+	return n
+}
+
+func (c *copier) block(s *syntax.BlockStmt) *syntax.BlockStmt {
+	if s == nil {
+		return nil
+	}
+	ns := *s
+	ns.List = c.stmtList(s.List)
+	return &ns
+}
+
+func (c *copier) stmtList(list []syntax.Stmt) []syntax.Stmt {
+	if list == nil {
+		return nil
+	}
+	n := make([]syntax.Stmt, len(list))
+	for i, s := range list {
+		n[i] = c.stmt(s)
+	}
+	return n
+}
@@ -584,6 +584,13 @@ type Func struct {
 type Inline struct {
 	Cost int32 // heuristic cost of inlining this function
 
+	// HotCost is nonzero for a function that exceeds the regular
+	// inlining budget but not the budget of hot callees. It is the
+	// cost of inlining the function, which is done at hot call sites
+	// only; Cost is then the cost of a call, so that the function
+	// does not use up the budget of callers that do not inline it.
+	HotCost int32
+
 	// Copies of Func.Dcl and Nbody for use during inlining.
 	Dcl  []*Node
 	Body []*Node
@@ -7,130 +7,52 @@ package importer
 
 import (
 	"bufio"
+	"bytes"
 	"cmd/compile/internal/types2"
 	"fmt"
-	"go/build"
 	"io"
 	"io/ioutil"
-	"os"
-	"path/filepath"
-	"strings"
+	"strconv"
 )
 
 // debugging/development support
 const debug = false
 
-var pkgExts = [...]string{".a", ".o"}
-
-// FindPkg returns the filename and unique package id for an import
-// path based on package information provided by build.Import (using
-// the build.Default build.Context). A relative srcDir is interpreted
-// relative to the current working directory.
-// If no file was found, an empty filename is returned.
-//
-func FindPkg(path, srcDir string) (filename, id string) {
-	if path == "" {
-		return
-	}
-
-	var noext string
-	switch {
-	default:
-		// "x" -> "$GOPATH/pkg/$GOOS_$GOARCH/x.ext", "x"
-		// Don't require the source files to be present.
-		if abs, err := filepath.Abs(srcDir); err == nil { // see issue 14282
-			srcDir = abs
-		}
-		bp, _ := build.Import(path, srcDir, build.FindOnly|build.AllowBinary)
-		if bp.PkgObj == "" {
-			id = path // make sure we have an id to print in error message
-			return
-		}
-		noext = strings.TrimSuffix(bp.PkgObj, ".a")
-		id = bp.ImportPath
-
-	case build.IsLocalImport(path):
-		// "./x" -> "/this/directory/x.ext", "/this/directory/x"
-		noext = filepath.Join(srcDir, path)
-		id = noext
-
-	case filepath.IsAbs(path):
-		// for completeness only - go/build.Import
-		// does not support absolute imports
-		// "/x" -> "/x.ext", "/x"
-		noext = path
-		id = path
-	}
-
-	if false { // for debugging
-		if path != id {
-			fmt.Printf("%s -> %s\n", path, id)
-		}
-	}
-
-	// try extensions
-	for _, ext := range pkgExts {
-		filename = noext + ext
-		if f, err := os.Stat(filename); err == nil && !f.IsDir() {
-			return
-		}
-	}
-
-	filename = "" // not found
-	return
-}
-
 // Import imports a gc-generated package given its import path and srcDir, adds
 // the corresponding package object to the packages map, and returns the object.
-// The packages map must contain all packages already imported.
+// The packages map must contain all packages already imported. The lookup
+// function opens the object file of a package; see package findpkg for
+// finding it as go/importer does.
 //
 func Import(packages map[string]*types2.Package, path, srcDir string, lookup func(path string) (io.ReadCloser, error)) (pkg *types2.Package, err error) {
-	var rc io.ReadCloser
-	var id string
-	if lookup != nil {
-		// With custom lookup specified, assume that caller has
-		// converted path to a canonical import path for use in the map.
-		if path == "unsafe" {
-			return types2.Unsafe, nil
-		}
-		id = path
-
-		// No need to re-import if the package was imported completely before.
-		if pkg = packages[id]; pkg != nil && pkg.Complete() {
-			return
-		}
-		f, err := lookup(path)
-		if err != nil {
-			return nil, err
-		}
-		rc = f
-	} else {
-		var filename string
-		filename, id = FindPkg(path, srcDir)
-		if filename == "" {
-			if path == "unsafe" {
-				return types2.Unsafe, nil
-			}
-			return nil, fmt.Errorf("can't find import: %q", id)
-		}
+	pkg, _, err = ImportGeneric(packages, path, srcDir, lookup)
+	return
+}
 
-		// no need to re-import if the package was imported completely before
-		if pkg = packages[id]; pkg != nil && pkg.Complete() {
-			return
-		}
+// GenericHeader starts the section that follows the export data of a
+// package compiled with -G, if it declares generic functions or types
+// that other packages can instantiate. Each line of the section holds
+// the source of a file of such declarations, as a quoted string. The
+// section ends with a line holding $$.
+const GenericHeader = "\n$$  // generic\n"
+
+// ImportGeneric is like Import, but also returns the source of the
+// generic declarations of the package, if it has a GenericHeader
+// section. If the package was imported before, no source is returned.
+func ImportGeneric(packages map[string]*types2.Package, path, srcDir string, lookup func(path string) (io.ReadCloser, error)) (pkg *types2.Package, generic []string, err error) {
+	// The caller has converted path to a canonical
+	// import path for use in the map.
+	if path == "unsafe" {
+		return types2.Unsafe, nil, nil
+	}
 
-		// open file
-		f, err := os.Open(filename)
-		if err != nil {
-			return nil, err
-		}
-		defer func() {
-			if err != nil {
-				// add file name to error
-				err = fmt.Errorf("%s: %v", filename, err)
-			}
-		}()
-		rc = f
+	// No need to re-import if the package was imported completely before.
+	if pkg = packages[path]; pkg != nil && pkg.Complete() {
+		return
+	}
+	rc, err := lookup(path)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer rc.Close()
 
@@ -155,7 +77,11 @@ func Import(packages map[string]*types2.Package, path, srcDir string, lookup fun
 		// binary export format starts with a 'c', 'd', or 'v'
 		// (from "version"). Select appropriate importer.
 		if len(data) > 0 && data[0] == 'i' {
-			_, pkg, err = iImportData(packages, data[1:], id)
+			var n int
+			n, pkg, err = iImportData(packages, data[1:], path)
+			if err == nil {
+				generic, err = readGeneric(data[1+n:], path)
+			}
 		} else {
 			err = fmt.Errorf("import %q: old binary export format no longer supported (recompile library)", path)
 		}
@@ -167,6 +93,33 @@ func Import(packages map[string]*types2.Package, path, srcDir string, lookup fun
 	return
 }
 
+// readGeneric returns the source held by the GenericHeader section
+// at the start of data, after the end of the export data.
+func readGeneric(data []byte, path string) ([]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\n$$\n"))
+	if !bytes.HasPrefix(data, []byte(GenericHeader)) {
+		return nil, nil
+	}
+	data = data[len(GenericHeader):]
+	var generic []string
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return nil, fmt.Errorf("import %q: missing end of generic code", path)
+		}
+		line := string(data[:i])
+		data = data[i+1:]
+		if line == "$$" {
+			return generic, nil
+		}
+		src, err := strconv.Unquote(line)
+		if err != nil {
+			return nil, fmt.Errorf("import %q: invalid generic code: %v", path, err)
+		}
+		generic = append(generic, src)
+	}
+}
+
 type byPath []*types2.Package
 
 func (a byPath) Len() int           { return len(a) }
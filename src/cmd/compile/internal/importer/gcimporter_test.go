@@ -6,9 +6,11 @@ package importer
 
 import (
 	"bytes"
+	"cmd/compile/internal/importer/findpkg"
 	"cmd/compile/internal/types2"
 	"fmt"
 	"internal/testenv"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -48,9 +50,26 @@ func compile(t *testing.T, dirname, filename, outdirname string) string {
 	return outname
 }
 
+// testImport imports the package path, finding its object file
+// with findpkg.Find.
+func testImport(packages map[string]*types2.Package, path, srcDir string) (*types2.Package, error) {
+	filename, id := findpkg.Find(path, srcDir)
+	pkg, err := Import(packages, id, srcDir, func(string) (io.ReadCloser, error) {
+		if filename == "" {
+			return nil, fmt.Errorf("can't find import: %q", id)
+		}
+		return os.Open(filename)
+	})
+	if err != nil && filename != "" {
+		// add file name to error
+		err = fmt.Errorf("%s: %v", filename, err)
+	}
+	return pkg, err
+}
+
 func testPath(t *testing.T, path, srcDir string) *types2.Package {
 	t0 := time.Now()
-	pkg, err := Import(make(map[string]*types2.Package), path, srcDir, nil)
+	pkg, err := testImport(make(map[string]*types2.Package), path, srcDir)
 	if err != nil {
 		t.Errorf("testPath(%s): %s", path, err)
 		return nil
@@ -75,7 +94,7 @@ func testDir(t *testing.T, dir string, endTime time.Time) (nimports int) {
 		switch {
 		case !f.IsDir():
 			// try extensions
-			for _, ext := range pkgExts {
+			for _, ext := range [...]string{".a", ".o"} {
 				if strings.HasSuffix(f.Name(), ext) {
 					name := f.Name()[0 : len(f.Name())-len(ext)] // remove extension
 					if testPath(t, filepath.Join(dir, name), dir) != nil {
@@ -168,7 +187,7 @@ func TestVersionHandling(t *testing.T) {
 		}
 
 		// test that export data can be imported
-		_, err := Import(make(map[string]*types2.Package), pkgpath, dir, nil)
+		_, err := testImport(make(map[string]*types2.Package), pkgpath, dir)
 		if err != nil {
 			// ok to fail if it fails with a no longer supported error for select files
 			if strings.Contains(err.Error(), "no longer supported") {
@@ -214,7 +233,7 @@ func TestVersionHandling(t *testing.T) {
 		ioutil.WriteFile(filename, data, 0666)
 
 		// test that importing the corrupted file results in an error
-		_, err = Import(make(map[string]*types2.Package), pkgpath, corruptdir, nil)
+		_, err = testImport(make(map[string]*types2.Package), pkgpath, corruptdir)
 		if err == nil {
 			t.Errorf("import corrupted %q succeeded", pkgpath)
 		} else if msg := err.Error(); !strings.Contains(msg, "version skew") {
@@ -280,7 +299,7 @@ func TestImportedTypes(t *testing.T) {
 		importPath := s[0]
 		objName := s[1]
 
-		pkg, err := Import(make(map[string]*types2.Package), importPath, ".", nil)
+		pkg, err := testImport(make(map[string]*types2.Package), importPath, ".")
 		if err != nil {
 			t.Error(err)
 			continue
@@ -377,7 +396,7 @@ func TestCorrectMethodPackage(t *testing.T) {
 	}
 
 	imports := make(map[string]*types2.Package)
-	_, err := Import(imports, "net/http", ".", nil)
+	_, err := testImport(imports, "net/http", ".")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -440,7 +459,7 @@ func TestIssue13898(t *testing.T) {
 
 	// import go/internal/gcimporter which imports go/types partially
 	imports := make(map[string]*types2.Package)
-	_, err := Import(imports, "go/internal/gcimporter", ".", nil)
+	_, err := testImport(imports, "go/internal/gcimporter", ".")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -509,7 +528,7 @@ func TestIssue15517(t *testing.T) {
 	// The same issue occurs with vendoring.)
 	imports := make(map[string]*types2.Package)
 	for i := 0; i < 3; i++ {
-		if _, err := Import(imports, "./././testdata/p", tmpdir, nil); err != nil {
+		if _, err := testImport(imports, "./././testdata/p", tmpdir); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -588,7 +607,7 @@ func TestIssue25596(t *testing.T) {
 }
 
 func importPkg(t *testing.T, path, srcDir string) *types2.Package {
-	pkg, err := Import(make(map[string]*types2.Package), path, srcDir, nil)
+	pkg, err := testImport(make(map[string]*types2.Package), path, srcDir)
 	if err != nil {
 		t.Fatal(err)
 	}
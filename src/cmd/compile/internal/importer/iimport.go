@@ -99,9 +99,10 @@ func iImportData(imports map[string]*types2.Package, data []byte, path string) (
 		stringCache: make(map[uint64]string),
 		pkgCache:    make(map[uint64]*types2.Package),
 
-		declData: declData,
-		pkgIndex: make(map[*types2.Package]map[string]uint64),
-		typCache: make(map[uint64]types2.Type),
+		declData:  declData,
+		pkgIndex:  make(map[*types2.Package]map[string]uint64),
+		typCache:  make(map[uint64]types2.Type),
+		instances: make(map[instanceKey]*types2.Named),
 	}
 
 	for i, pt := range predeclared {
@@ -161,6 +162,17 @@ func iImportData(imports map[string]*types2.Package, data []byte, path string) (
 	// package was imported completely and without errors
 	localpkg.MarkComplete()
 
+	// Skip the inline body index and the fingerprint that follow,
+	// so that all of the export data is consumed.
+	for nPkgs := r.uint64(); nPkgs > 0; nPkgs-- {
+		_ = r.uint64() // package path
+		for nSyms := r.uint64(); nSyms > 0; nSyms-- {
+			_ = r.uint64() // name
+			_ = r.uint64() // offset
+		}
+	}
+	r.Seek(8, io.SeekCurrent)
+
 	consumed, _ := r.Seek(0, io.SeekCurrent)
 	return int(consumed), localpkg, nil
 }
@@ -177,14 +189,27 @@ type iimporter struct {
 	pkgIndex map[*types2.Package]map[string]uint64
 	typCache map[uint64]types2.Type
 
+	// instances holds the instantiated types read so far, by the
+	// name of their copy, as in Set[int]. They are not declared in
+	// the scope of their package, which holds the generic type.
+	instances map[instanceKey]*types2.Named
+
 	interfaceList []*types2.Interface
 }
 
+type instanceKey struct {
+	pkg  *types2.Package
+	name string
+}
+
 func (p *iimporter) doDecl(pkg *types2.Package, name string) {
 	// See if we've already imported this declaration.
 	if obj := pkg.Scope().Lookup(name); obj != nil {
 		return
 	}
+	if _, ok := p.instances[instanceKey{pkg, name}]; ok {
+		return
+	}
 
 	off, ok := p.pkgIndex[pkg][name]
 	if !ok {
@@ -269,12 +294,27 @@ func (r *importReader) obj(name string) {
 
 		r.declare(types2.NewFunc(pos, r.currPkg, name, sig))
 
-	case 'T':
+	case 'T', 'I':
 		// Types can be recursive. We need to setup a stub
 		// declaration before recursing.
-		obj := types2.NewTypeName(pos, r.currPkg, name, nil)
-		named := types2.NewNamed(obj, nil, nil)
-		r.declare(obj)
+		var named *types2.Named
+		if tag == 'I' {
+			// The copy of an instantiated type, named after the
+			// instantiation, is read as the instantiation of the
+			// generic type, with its methods instantiated already.
+			obj := types2.NewTypeName(pos, r.currPkg, r.string(), nil)
+			named = types2.NewNamed(obj, nil, nil)
+			r.p.instances[instanceKey{r.currPkg, name}] = named
+			targs := make([]types2.Type, r.uint64())
+			for i := range targs {
+				targs[i] = r.typ()
+			}
+			named.SetTArgs(targs)
+		} else {
+			obj := types2.NewTypeName(pos, r.currPkg, name, nil)
+			named = types2.NewNamed(obj, nil, nil)
+			r.declare(obj)
+		}
 
 		underlying := r.p.typAt(r.uint64(), named).Underlying()
 		named.SetUnderlying(underlying)
@@ -495,6 +535,9 @@ func (r *importReader) doType(base *types2.Named) types2.Type {
 	case definedType:
 		pkg, name := r.qualifiedIdent()
 		r.p.doDecl(pkg, name)
+		if named := r.p.instances[instanceKey{pkg, name}]; named != nil {
+			return named
+		}
 		return pkg.Scope().Lookup(name).(*types2.TypeName).Type()
 	case pointerType:
 		return types2.NewPointer(r.typ())
@@ -9,8 +9,6 @@ package importer
 import (
 	"cmd/compile/internal/types2"
 	"fmt"
-	"go/token"
-	"sync"
 )
 
 func errorf(format string, args ...interface{}) {
@@ -19,47 +17,6 @@ func errorf(format string, args ...interface{}) {
 
 const deltaNewFile = -64 // see cmd/compile/internal/gc/bexport.go
 
-// Synthesize a token.Pos
-type fakeFileSet struct {
-	fset  *token.FileSet
-	files map[string]*token.File
-}
-
-func (s *fakeFileSet) pos(file string, line, column int) token.Pos {
-	// TODO(mdempsky): Make use of column.
-
-	// Since we don't know the set of needed file positions, we
-	// reserve maxlines positions per file.
-	const maxlines = 64 * 1024
-	f := s.files[file]
-	if f == nil {
-		f = s.fset.AddFile(file, -1, maxlines)
-		s.files[file] = f
-		// Allocate the fake linebreak indices on first use.
-		// TODO(adonovan): opt: save ~512KB using a more complex scheme?
-		fakeLinesOnce.Do(func() {
-			fakeLines = make([]int, maxlines)
-			for i := range fakeLines {
-				fakeLines[i] = i
-			}
-		})
-		f.SetLines(fakeLines)
-	}
-
-	if line > maxlines {
-		line = 1
-	}
-
-	// Treat the file as if it contained only newlines
-	// and column=1: use the line number as the offset.
-	return f.Pos(line - 1)
-}
-
-var (
-	fakeLines     []int
-	fakeLinesOnce sync.Once
-)
-
 func chanDir(d int) types2.ChanDir {
 	// tag values must match the constants in cmd/compile/internal/gc/go.go
 	switch d {
@@ -135,6 +92,7 @@ func (anyType) Struct() *types2.Struct       { return nil }
 func (anyType) Pointer() *types2.Pointer     { return nil }
 func (anyType) Tuple() *types2.Tuple         { return nil }
 func (anyType) Signature() *types2.Signature { return nil }
+func (anyType) Sum() *types2.Sum             { return nil }
 func (anyType) Interface() *types2.Interface { return nil }
 func (anyType) Map() *types2.Map             { return nil }
 func (anyType) Chan() *types2.Chan           { return nil }
@@ -666,7 +666,7 @@ func (p *parser) funcDeclOrNil() *FuncDecl {
 	tpos := p.pos()
 	p.want(_Lparen)
 	if p.got(_Type) {
-		f.TParamList = p.paramList(true)
+		f.TParamList = p.tparamList()
 		p.want(_Lparen)
 	}
 	f.Type = p.funcType(tpos, true)
@@ -1490,7 +1490,7 @@ func (p *parser) oliteral() *BasicLit {
 	return nil
 }
 
-// MethodSpec        = MethodName Signature | InterfaceTypeName .
+// MethodSpec        = MethodName [ TypeParams ] Signature | InterfaceTypeName .
 // MethodName        = identifier .
 // InterfaceTypeName = TypeName .
 func (p *parser) methodDecl() *Field {
@@ -1523,7 +1523,14 @@ func (p *parser) methodDecl() *Field {
 		if p.got(_Lparen) {
 			// method
 			f.Name = name
-			f.Type = p.funcType(tpos, true)
+			var tparams []*Field
+			if p.got(_Type) {
+				tparams = p.tparamList()
+				p.want(_Lparen)
+			}
+			typ := p.funcType(tpos, true)
+			typ.TParamList = tparams
+			f.Type = typ
 		} else {
 			// embedded interface
 			f.Type = p.qualifiedName(name)
@@ -1542,6 +1549,17 @@ func (p *parser) methodDecl() *Field {
 	return f
 }
 
+// tparamList parses a type parameter list following "(type".
+// An empty list is returned as a non-nil slice so that it is not
+// mistaken for a missing one.
+func (p *parser) tparamList() []*Field {
+	list := p.paramList(true)
+	if list == nil {
+		list = []*Field{}
+	}
+	return list
+}
+
 // ParameterDecl = [ IdentifierList ] [ "..." ] Type .
 func (p *parser) paramDeclOrNil() *Field {
 	if trace {
@@ -622,7 +622,11 @@ func (p *printer) printRawNode(n Node) {
 		if n.Group == nil {
 			p.print(_Type, blank)
 		}
-		p.print(n.Name, blank)
+		p.print(n.Name)
+		if n.TParamList != nil {
+			p.printParameterList(n.TParamList, true)
+		}
+		p.print(blank)
 		if n.Alias {
 			p.print(_Assign, blank)
 		}
@@ -651,6 +655,9 @@ func (p *printer) printRawNode(n Node) {
 			p.print(_Rparen, blank)
 		}
 		p.print(n.Name)
+		if n.TParamList != nil {
+			p.printParameterList(n.TParamList, true)
+		}
 		p.printSignature(n.Type)
 		if n.Body != nil {
 			p.print(blank, n.Body)
@@ -684,7 +691,7 @@ func (p *printer) printRawNode(n Node) {
 func (p *printer) printFields(fields []*Field, tags []*BasicLit, i, j int) {
 	if i+1 == j && fields[i].Name == nil {
 		// anonymous field
-		p.printNode(fields[i].Type)
+		p.printEmbedded(fields[i].Type)
 	} else {
 		for k, f := range fields[i:j] {
 			if k > 0 {
@@ -720,17 +727,39 @@ func (p *printer) printFieldList(fields []*Field, tags []*BasicLit) {
 func (p *printer) printMethodList(methods []*Field) {
 	for i, m := range methods {
 		if i > 0 {
+			if m.Name != nil && m.Name.Value == "type" && m.Name == methods[i-1].Name {
+				// next type in a type list
+				p.print(_Comma, blank, m.Type)
+				continue
+			}
 			p.print(_Semi, newline)
 		}
-		if m.Name != nil {
+		if m.Name != nil && m.Name.Value == "type" {
+			p.print(_Type, blank, m.Type)
+		} else if m.Name != nil {
 			p.printNode(m.Name)
-			p.printSignature(m.Type.(*FuncType))
+			sig := m.Type.(*FuncType)
+			if sig.TParamList != nil {
+				p.printParameterList(sig.TParamList, true)
+			}
+			p.printSignature(sig)
 		} else {
-			p.printNode(m.Type)
+			p.printEmbedded(m.Type)
 		}
 	}
 }
 
+// printEmbedded prints an embedded type. An instantiated type,
+// as in (T(int)), is parenthesized so that it is not mistaken
+// for a field or method named T.
+func (p *printer) printEmbedded(typ Expr) {
+	if _, ok := typ.(*CallExpr); ok {
+		p.print(_Lparen, typ, _Rparen)
+	} else {
+		p.printNode(typ)
+	}
+}
+
 func (p *printer) printNameList(list []*Name) {
 	for i, x := range list {
 		if i > 0 {
@@ -834,20 +863,29 @@ func (p *printer) printDeclList(list []Decl) {
 }
 
 func (p *printer) printSignature(sig *FuncType) {
-	p.printParameterList(sig.ParamList)
+	p.printParameterList(sig.ParamList, false)
 	if list := sig.ResultList; list != nil {
 		p.print(blank)
 		if len(list) == 1 && list[0].Name == nil {
 			p.printNode(list[0].Type)
 		} else {
-			p.printParameterList(list)
+			p.printParameterList(list, false)
 		}
 	}
 }
 
-func (p *printer) printParameterList(list []*Field) {
+// printParameterList prints a list of parameters, or of type
+// parameters if tparams is set. Unbounded type parameters have
+// no type.
+func (p *printer) printParameterList(list []*Field, tparams bool) {
 	p.print(_Lparen)
+	if tparams {
+		p.print(_Type)
+	}
 	if len(list) > 0 {
+		if tparams {
+			p.print(blank)
+		}
 		for i, f := range list {
 			if i > 0 {
 				p.print(_Comma, blank)
@@ -860,6 +898,9 @@ func (p *printer) printParameterList(list []*Field) {
 						continue // no need to print type
 					}
 				}
+				if f.Type == nil {
+					continue // unbounded type parameter
+				}
 				p.print(blank)
 			}
 			p.printNode(f.Type)
@@ -34,6 +34,15 @@ func TestPrintString(t *testing.T) {
 	for _, want := range []string{
 		"package p",
 		"package p; type _ = int; type T1 = struct{}; type ( _ = *struct{}; T2 = float32 )",
+		"package p; func Max(type T Ordered)(a, b T) T { return a }",
+		"package p; type List(type T) struct{ next *List(T); val T }",
+		"package p; func (l *List(T)) Push(v T) { _ = List(T){} }",
+		"package p; type Pair(type A, B interface{}) struct{}",
+		"package p; type Ordered interface{ type int, string; String() string }",
+		"package p; type _ struct{ (T(int)); f T(int) }",
+		"package p; type _ interface{ (I(int)) }",
+		"package p; type _ interface{ m(type T C)(x T) }",
+		"package p; func init(type)() {}",
 		// TODO(gri) expand
 	} {
 		ast, err := Parse(nil, strings.NewReader(want), nil, nil, 0)
@@ -33,9 +33,6 @@ import (
 	"go/constant"
 )
 
-// If AcceptContracts is set, contracts are accepted.
-const AcceptContracts = false
-
 // An Error describes a type-checking error; it implements the error interface.
 // A "soft" error is an error that still permits a valid interpretation of a
 // package (such as "unused variable"); "hard" errors may lead to unpredictable
@@ -105,6 +102,9 @@ type Config struct {
 	// type-checked.
 	IgnoreFuncBodies bool
 
+	// If AcceptMethodTypeParams is set, methods may have type parameters.
+	AcceptMethodTypeParams bool
+
 	// If FakeImportC is set, `import "C"` (for packages requiring Cgo)
 	// declares an empty "C" package and errors are omitted for qualified
 	// identifiers referring to package C (which won't find an object).
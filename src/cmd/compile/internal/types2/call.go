@@ -41,6 +41,13 @@ func (check *Checker) call(x *operand, e *syntax.CallExpr) exprKind {
 		case 1:
 			check.expr(x, e.ArgList[0])
 			if x.mode != invalid {
+				if t := T.Interface(); t != nil {
+					check.completeInterface(nopos, t)
+					if t.IsConstraint() {
+						check.errorf(e.Pos(), "cannot use interface %s in conversion (contains type list or is comparable)", T)
+						break
+					}
+				}
 				check.conversion(x, T)
 			}
 		default:
@@ -477,8 +484,6 @@ func (check *Checker) selector(x *operand, e *syntax.SelectorExpr) {
 					why = check.sprintf("type bound for %s has no method %s", x.typ, sel)
 				case *TypeName:
 					why = check.sprintf("interface %s has no method %s", obj.name, sel)
-				case *Contract:
-					why = check.sprintf("contract %s has no method %s", obj.name, sel)
 				}
 			} else {
 				why = check.sprintf("type %s has no field or method %s", x.typ, sel)
@@ -544,7 +549,7 @@ func (check *Checker) selector(x *operand, e *syntax.SelectorExpr) {
 			obj = &copy
 		}
 		// TODO(gri) we also need to do substitution for parameterized interface methods
-		//           (this breaks code in testdata/linalg.go2 at the moment)
+		//           (this breaks code in go/types/testdata/linalg.go2 at the moment)
 		//           12/20/2019: Is this TODO still correct?
 	}
 
@@ -669,8 +674,8 @@ Error:
 func (check *Checker) use(arg ...syntax.Expr) {
 	var x operand
 	for _, e := range arg {
-		if e, _ := e.(*syntax.ListExpr); e != nil {
-			check.use(e.ElemList...)
+		if l, _ := e.(*syntax.ListExpr); l != nil {
+			check.use(l.ElemList...)
 		} else {
 			// The nil check below is necessary since certain AST fields
 			// may legally be nil (e.g., the ast.SliceExpr.High field).
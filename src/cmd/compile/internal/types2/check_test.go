@@ -9,8 +9,14 @@
 // Expected errors are indicated in the test files by putting a comment
 // of the form /* ERROR "rx" */ immediately following an offending token.
 // The harness will verify that an error matching the regular expression
-// rx is reported at that source position. Consecutive comments may be
-// used to indicate multiple errors for the same token position.
+// rx is reported at that source position, or failing that, on the same
+// line. Consecutive comments may be used to indicate multiple errors for
+// the same token position.
+//
+// The test files are shared with go/types, whose parser and type checker
+// report some errors at a different column of the same line. The errors
+// that go/parser and the syntax package report differently are listed in
+// parserErrors and parserOnlyErrors.
 //
 // For instance, the following test file indicates that a "not declared"
 // error should be reported for the undeclared variable x:
@@ -32,7 +38,9 @@ import (
 	"go/token"
 	"internal/testenv"
 	"io/ioutil"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -49,6 +57,10 @@ var (
 // to gofmt since they contain comments that must not change their
 // positions relative to surrounding tokens.
 
+// goTypesDir is the go/types directory. The test files are those of
+// go/types, and their paths below are relative to it.
+var goTypesDir = filepath.FromSlash("../../../../go/types")
+
 // Each tests entry is list of files belonging to the same package.
 var tests = [][]string{
 	{"testdata/errors.src"},
@@ -81,7 +93,6 @@ var tests = [][]string{
 	{"testdata/methodsets.src"},
 	{"testdata/shifts.src"},
 	{"testdata/builtins.src"},
-	{"testdata/builtins.go2"},
 	{"testdata/conversions.src"},
 	{"testdata/conversions2.src"},
 	{"testdata/stmt0.src"},
@@ -97,13 +108,19 @@ var tests = [][]string{
 	{"testdata/issue23203b.src"},
 	{"testdata/issue28251.src"},
 	{"testdata/issue6977.src"},
+}
 
-	// Go 2 tests (type parameters and contracts)
+// Each go2Tests entry is list of files belonging to the same package.
+var go2Tests = [][]string{
+	{"testdata/builtins.go2"},
+
+	// Go 2 tests (type parameters)
 	{"testdata/tmp.go2"}, // used for ad-hoc tests - file contents transient
+	{"testdata/typeargs.go2"},
 	{"testdata/typeparams.go2"},
+	{"testdata/mtypeparams.go2"},
 	{"testdata/typeinst.go2"},
 	{"testdata/typeinst2.go2"},
-	// {"testdata/contracts.go2"}, // disabled for now
 	{"testdata/issues.go2"},
 	{"testdata/todos.go2"},
 
@@ -115,7 +132,6 @@ var tests = [][]string{
 	{"testdata/linalg.go2"},
 
 	// Go 2 prototype examples
-	// {"examples/contracts.go2"}, disabled for now
 	{"examples/functions.go2"},
 	{"examples/methods.go2"},
 	{"examples/types.go2"},
@@ -142,21 +158,13 @@ func splitError(err error) (pos, msg string) {
 func parseFiles(t *testing.T, filenames []string) ([]*syntax.File, []error) {
 	var files []*syntax.File
 	var errlist []error
+	errh := func(err error) { errlist = append(errlist, err) }
 	for _, filename := range filenames {
-		file, err := syntax.ParseFile(filename, nil, nil, 0)
+		file, err := syntax.ParseFile(filename, errh, nil, 0)
 		if file == nil {
 			t.Fatalf("%s: %s", filename, err)
 		}
 		files = append(files, file)
-		if err != nil {
-			if list, _ := err.(scanner.ErrorList); len(list) > 0 {
-				for _, err := range list {
-					errlist = append(errlist, err)
-				}
-			} else {
-				errlist = append(errlist, err)
-			}
-		}
 	}
 	return files, errlist
 }
@@ -228,23 +236,115 @@ func errMap(t *testing.T, testname string, files []*syntax.File) map[string][]st
 	return errmap
 }
 
+// posRx matches a position string filename:line:column .
+var posRx = regexp.MustCompile(`^(.*:[0-9]+):([0-9]+)$`)
+
+// splitPos splits a position string into its filename:line prefix
+// and its column. The column is -1 if pos is not a position string.
+func splitPos(pos string) (line string, col int) {
+	m := posRx.FindStringSubmatch(pos)
+	if m == nil {
+		return pos, -1
+	}
+	col, _ = strconv.Atoi(m[2])
+	return m[1], col
+}
+
+// match returns the index of the first message in list matching msg,
+// or -1 if there is none.
+func match(t *testing.T, pos, msg string, list []string) int {
+	for i, wantRx := range list {
+		rx, err := regexp.Compile(wantRx)
+		if err != nil {
+			t.Errorf("%s: %v", pos, err)
+			continue
+		}
+		if rx.MatchString(msg) {
+			return i
+		}
+	}
+	return -1
+}
+
+// lookup returns the position in errmap of the expected error matching
+// the error msg reported at pos, and its index in errmap's list there.
+// An expected error at pos is preferred; otherwise the closest matching
+// one on the same line is used. The index is -1 if there is none.
+func lookup(t *testing.T, errmap map[string][]string, pos, msg string) (string, int) {
+	if index := match(t, pos, msg, errmap[pos]); index >= 0 {
+		return pos, index
+	}
+	line, col := splitPos(pos)
+	if col < 0 {
+		return pos, -1
+	}
+	best, bestIndex, bestDelta := pos, -1, 0
+	for p, list := range errmap {
+		pline, pcol := splitPos(p)
+		if pline != line {
+			continue
+		}
+		delta := pcol - col
+		if delta < 0 {
+			delta = -delta
+		}
+		if bestIndex >= 0 && (delta > bestDelta || delta == bestDelta && p > best) {
+			continue
+		}
+		if index := match(t, p, msg, list); index >= 0 {
+			best, bestIndex, bestDelta = p, index, delta
+		}
+	}
+	return best, bestIndex
+}
+
+// parserErrors maps expected errors, by their regular expression, that
+// go/parser reports differently from the syntax package to the regular
+// expressions of the errors reported in their place on the same line.
+// These include errors that types2 reports for the syntax tree that
+// the syntax package builds.
+var parserErrors = map[string][]string{
+	"2nd index required":        {"middle index required in 3-index slice", "2nd and 3rd index required in 3-index slice"},
+	"3rd index required":        {"final index required in 3-index slice"},
+	"cannot declare":            {"cannot declare in post statement"},
+	"exactly one receiver":      {"method has multiple receivers"},
+	"expected ';'":              {`syntax error: unexpected \( after top level declaration`},
+	"function must be invoked":  {"expression in (go|defer) must be function call", "cannot call non-function"},
+	"missing receiver":          {"method has no receiver"},
+	"missing type or init expr": {"syntax error: unexpected newline, expecting type"},
+	"missing variable type":     {"syntax error: unexpected newline, expecting type"},
+}
+
+// replaces reports whether msg, reported at pos, is listed in
+// parserErrors for one of the expected errors on the same line in
+// expected, which maps lines to their expected errors. If so, it adds
+// the line and the expected error to replaced.
+func replaces(t *testing.T, expected map[string][]string, pos, msg string, replaced map[string]bool) bool {
+	line, _ := splitPos(pos)
+	found := false
+	for _, wantRx := range expected[line] {
+		if match(t, pos, msg, parserErrors[wantRx]) >= 0 {
+			replaced[line+" "+wantRx] = true
+			found = true
+		}
+	}
+	return found
+}
+
 func eliminate(t *testing.T, errmap map[string][]string, errlist []error) {
+	// expected maps each line to the errors expected on it, including
+	// those eliminated below, which parserErrors may list.
+	expected := make(map[string][]string)
+	for pos, list := range errmap {
+		line, _ := splitPos(pos)
+		expected[line] = append(expected[line], list...)
+	}
+	replaced := make(map[string]bool)
+
 	for _, err := range errlist {
-		pos, gotMsg := splitError(err)
+		gotPos, gotMsg := splitError(err)
+		pos, index := lookup(t, errmap, gotPos, gotMsg)
 		list := errmap[pos]
-		index := -1 // list index of matching message, if any
-		// we expect one of the messages in list to match the error at pos
-		for i, wantRx := range list {
-			rx, err := regexp.Compile(wantRx)
-			if err != nil {
-				t.Errorf("%s: %v", pos, err)
-				continue
-			}
-			if rx.MatchString(gotMsg) {
-				index = i
-				break
-			}
-		}
 		if index >= 0 {
 			// eliminate from list
 			if n := len(list) - 1; n > 0 {
@@ -255,8 +355,51 @@ func eliminate(t *testing.T, errmap map[string][]string, errlist []error) {
 				// last entry - remove list from map
 				delete(errmap, pos)
 			}
+		} else if !replaces(t, expected, gotPos, gotMsg, replaced) {
+			t.Errorf("%s: no error expected: %q", gotPos, gotMsg)
+		}
+	}
+
+	// Expected errors reported differently by the syntax package
+	// are accounted for.
+	for pos, list := range errmap {
+		line, _ := splitPos(pos)
+		var rest []string
+		for _, rx := range list {
+			if !replaced[line+" "+rx] {
+				rest = append(rest, rx)
+			}
+		}
+		if len(rest) > 0 {
+			errmap[pos] = rest
 		} else {
-			t.Errorf("%s: no error expected: %q", pos, gotMsg)
+			delete(errmap, pos)
+		}
+	}
+}
+
+// parserOnlyErrors lists expected errors, by their regular expression,
+// that are reported by go/parser but not by the syntax package, which
+// leaves them to the compiler.
+var parserOnlyErrors = map[string]bool{
+	"invalid import path":    true,
+	"missing constant value": true,
+}
+
+// eliminateParserOnly removes from errmap one of the parserOnlyErrors
+// at each position.
+func eliminateParserOnly(errmap map[string][]string) {
+	for pos, list := range errmap {
+		for i, rx := range list {
+			if parserOnlyErrors[rx] {
+				list = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(list) > 0 {
+			errmap[pos] = list
+		} else {
+			delete(errmap, pos)
 		}
 	}
 }
@@ -280,13 +423,12 @@ func checkFiles(t *testing.T, testfiles []string) {
 	// typecheck and collect typechecker errors
 	var conf Config
 	// special case for importC.src
-	if len(testfiles) == 1 && testfiles[0] == "testdata/importC.src" {
+	if len(testfiles) == 1 && filepath.Base(testfiles[0]) == "importC.src" {
 		conf.FakeImportC = true
 	}
+	conf.AcceptMethodTypeParams = true
 	conf.Trace = testing.Verbose()
-	// We don't use importer.Default() below so we can eventually
-	// get testdata/map.go2 to import chans (still to be fixed).
-	//conf.Importer = importer.ForCompiler(fset, "source", nil)
+	conf.Importer = defaultImporter()
 	conf.Error = func(err error) {
 		if *haltOnError {
 			defer panic(err)
@@ -311,6 +453,7 @@ func checkFiles(t *testing.T, testfiles []string) {
 	// we are expecting the following errors
 	errmap := errMap(t, pkgName, files)
 	eliminate(t, errmap, errlist)
+	eliminateParserOnly(errmap)
 
 	// there should be no expected errors left
 	if len(errmap) > 0 {
@@ -335,10 +478,20 @@ func TestCheck(t *testing.T) {
 		return
 	}
 
-	t.Skip("requires error position corrections")
-
 	// Otherwise, run all the tests.
 	for _, files := range tests {
-		checkFiles(t, files)
+		checkFiles(t, testPaths(files))
+	}
+	for _, files := range go2Tests {
+		checkFiles(t, testPaths(files))
+	}
+}
+
+// testPaths returns the paths of the test files in the go/types directory.
+func testPaths(files []string) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = filepath.Join(goTypesDir, filepath.FromSlash(file))
 	}
+	return paths
 }
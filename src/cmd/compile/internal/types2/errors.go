@@ -7,6 +7,7 @@
 package types2
 
 import (
+	"bytes"
 	"cmd/compile/internal/syntax"
 	"fmt"
 	"strconv"
@@ -127,10 +128,10 @@ func (check *Checker) invalidOp(pos syntax.Pos, format string, args ...interface
 
 // stripAnnotations removes internal (type) annotations from s.
 func stripAnnotations(s string) string {
-	var b strings.Builder
+	var b bytes.Buffer
 	for _, r := range s {
 		// strip #'s and subscript digits
-		if r != '#' && !('₀' <= r && r < '₀'+10) { // '₀' == U+2080
+		if r != instanceMarker && !('₀' <= r && r < '₀'+10) { // '₀' == U+2080
 			b.WriteRune(r)
 		}
 	}
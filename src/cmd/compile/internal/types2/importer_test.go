@@ -8,8 +8,11 @@ package types2_test
 
 import (
 	gcimporter "cmd/compile/internal/importer"
+	"cmd/compile/internal/importer/findpkg"
 	"cmd/compile/internal/types2"
+	"fmt"
 	"io"
+	"os"
 )
 
 func defaultImporter() types2.Importer {
@@ -20,7 +23,6 @@ func defaultImporter() types2.Importer {
 
 type gcimports struct {
 	packages map[string]*types2.Package
-	lookup   func(path string) (io.ReadCloser, error)
 }
 
 func (m *gcimports) Import(path string) (*types2.Package, error) {
@@ -31,5 +33,11 @@ func (m *gcimports) ImportFrom(path, srcDir string, mode types2.ImportMode) (*ty
 	if mode != 0 {
 		panic("mode must be 0")
 	}
-	return gcimporter.Import(m.packages, path, srcDir, m.lookup)
+	filename, id := findpkg.Find(path, srcDir)
+	return gcimporter.Import(m.packages, id, srcDir, func(string) (io.ReadCloser, error) {
+		if filename == "" {
+			return nil, fmt.Errorf("can't find import: %q", id)
+		}
+		return os.Open(filename)
+	})
 }
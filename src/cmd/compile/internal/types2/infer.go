@@ -7,7 +7,10 @@
 
 package types2
 
-import "cmd/compile/internal/syntax"
+import (
+	"bytes"
+	"cmd/compile/internal/syntax"
+)
 
 // infer returns the list of actual type arguments for the given list of type parameters tparams
 // by inferring them from the actual arguments args for the parameters params. If infer fails to
@@ -15,18 +18,35 @@ import "cmd/compile/internal/syntax"
 func (check *Checker) infer(pos syntax.Pos, tparams []*TypeName, params *Tuple, args []*operand) []Type {
 	assert(params.Len() == len(args))
 
-	u := check.unifier()
+	u := check.newUnifier(false)
 	u.x.init(tparams)
 
 	errorf := func(kind string, tpar, targ Type, arg *operand) {
 		// provide a better error message if we can
-		if tpar, _ := tpar.(*TypeParam); tpar != nil {
-			if inferred := u.x.at(tpar.index); inferred != nil {
-				check.errorf(arg.pos(), "%s %s of %s does not match inferred type %s for %s", kind, targ, arg.expr, inferred, tpar)
+		targs, failed := u.x.types()
+		if failed == 0 {
+			// The first type parameter couldn't be inferred.
+			// If none of them could be inferred, don't try
+			// to provide the inferred type in the error msg.
+			allFailed := true
+			for _, targ := range targs {
+				if targ != nil {
+					allFailed = false
+					break
+				}
+			}
+			if allFailed {
+				check.errorf(arg.pos(), "%s %s of %s does not match %s (cannot infer %s)", kind, targ, arg.expr, tpar, typeNamesString(tparams))
 				return
 			}
 		}
-		check.errorf(arg.pos(), "%s %s of %s does not match %s", kind, targ, arg.expr, tpar)
+		smap := makeSubstMap(tparams, targs)
+		inferred := check.subst(arg.pos(), tpar, smap)
+		if inferred != tpar {
+			check.errorf(arg.pos(), "%s %s of %s does not match inferred type %s for %s", kind, targ, arg.expr, inferred, tpar)
+		} else {
+			check.errorf(arg.pos(), "%s %s of %s does not match %s", kind, targ, arg.expr, tpar)
+		}
 	}
 
 	// Terminology: generic parameter = function parameter with a type-parameterized type
@@ -96,24 +116,49 @@ func (check *Checker) infer(pos syntax.Pos, tparams []*TypeName, params *Tuple,
 
 	// Collect type arguments and check if they all have been determined.
 	// TODO(gri) consider moving this outside this function and then we won't need to pass in pos
-	var targs []Type // lazily allocated
-	for i, tpar := range tparams {
-		targ := u.x.at(i)
-		if targ == nil {
-			ppos := tpar.pos.String()
-			check.errorf(pos, "cannot infer %s (%s)", tpar.name, ppos)
-			return nil
-		}
-		if targs == nil {
-			targs = make([]Type, len(tparams))
-		}
-		targs[i] = targ
+	targs, failed := u.x.types()
+	if failed >= 0 {
+		tpar := tparams[failed]
+		ppos := tpar.pos.String()
+		check.errorf(pos, "cannot infer %s (%s)", tpar.name, ppos)
+		return nil
 	}
 
 	return targs
 }
 
+// typeNamesString produces a string containing all the
+// type names in list suitable for human consumption.
+func typeNamesString(list []*TypeName) string {
+	// common cases
+	n := len(list)
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return list[0].name
+	case 2:
+		return list[0].name + " and " + list[1].name
+	}
+
+	// general case (n > 2)
+	var b bytes.Buffer
+	for i, tname := range list[:n-1] {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(tname.name)
+	}
+	b.WriteString(", and ")
+	b.WriteString(list[n-1].name)
+	return b.String()
+}
+
 // IsParameterized reports whether typ contains any type parameters.
+// TODO(gri) This is not strictly correct. We only want the free
+// type parameters for a given type. (At the moment, the only way
+// to mix free and bound type parameters is through method type parameters
+// on parameterized receiver types - need to investigate.)
 func IsParameterized(typ Type) bool {
 	return isParameterized(typ, make(map[Type]bool))
 }
@@ -157,22 +202,35 @@ func isParameterized(typ Type, seen map[Type]bool) (res bool) {
 			}
 		}
 
+	case *Sum:
+		return isParameterizedList(t.types, seen)
+
 	case *Signature:
 		assert(t.tparams == nil) // TODO(gri) is this correct?
-		// TODO(gri) Rethink check below: contract interfaces
-		// have methods where the receiver is a contract type
-		// parameter, by design.
+		// TODO(gri) Rethink check below.
 		//assert(t.recv == nil || !isParameterized(t.recv.typ))
 		return isParameterized(t.params, seen) || isParameterized(t.results, seen)
 
 	case *Interface:
-		t.assertCompleteness()
-		for _, m := range t.allMethods {
-			if isParameterized(m.typ, seen) {
-				return true
+		if t.allMethods != nil {
+			// interface is complete - quick test
+			for _, m := range t.allMethods {
+				if isParameterized(m.typ, seen) {
+					return true
+				}
 			}
+			return isParameterizedList(unpack(t.allTypes), seen)
 		}
 
+		return t.iterate(func(t *Interface) bool {
+			for _, m := range t.methods {
+				if isParameterized(m.typ, seen) {
+					return true
+				}
+			}
+			return isParameterizedList(unpack(t.types), seen)
+		}, nil)
+
 	case *Map:
 		return isParameterized(t.key, seen) || isParameterized(t.elem, seen)
 
@@ -236,8 +236,8 @@ func (x *operand) assignableTo(check *Checker, T Type, reason *string) bool {
 		return true
 	}
 
-	Vu := V.Under()
-	Tu := T.Under()
+	Vu := optype(V.Under())
+	Tu := optype(T.Under())
 
 	// x is an untyped value representable by a value of type T
 	// TODO(gri) This is borrowing from checker.convertUntyped and
@@ -256,6 +256,11 @@ func (x *operand) assignableTo(check *Checker, T Type, reason *string) bool {
 			if Vb, _ := Vu.(*Basic); Vb != nil {
 				return Vb.kind == UntypedBool && isBoolean(Tu)
 			}
+		case *Sum:
+			return t.is(func(t Type) bool {
+				// TODO(gri) this could probably be more efficient
+				return x.assignableTo(check, t, reason)
+			})
 		case *Interface:
 			check.completeInterface(nopos, t)
 			return x.isNil() || t.Empty()
@@ -273,7 +278,7 @@ func (x *operand) assignableTo(check *Checker, T Type, reason *string) bool {
 
 	// T is an interface type and x implements T
 	if Ti, ok := Tu.(*Interface); ok {
-		if m, wrongType := check.missingMethod(V, false, Ti, true); m != nil /* Implements(V, Ti) */ {
+		if m, wrongType := check.missingMethod(V, Ti, true); m != nil /* Implements(V, Ti) */ {
 			if reason != nil {
 				if wrongType != nil {
 					if check.identical(m.typ, wrongType.typ) {
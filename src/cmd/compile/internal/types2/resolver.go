@@ -44,14 +44,17 @@ func (d *declInfo) addDep(obj Object) {
 }
 
 // arity checks that the lhs and rhs of a const or var decl
-// have a matching number of names and initialization values.
-// If inherited is set, the initialization values are from
-// another (constant) declaration.
-func (check *Checker) arity(pos syntax.Pos, names []*syntax.Name, inits []syntax.Expr, inherited bool) {
+// have the appropriate number of names and init exprs.
+// If inherited is set, the init exprs of a const decl are
+// from another declaration. A var decl without init exprs
+// must have a type.
+func (check *Checker) arity(pos syntax.Pos, names []*syntax.Name, inits []syntax.Expr, constDecl, inherited bool) {
 	l := len(names)
 	r := len(inits)
 
 	switch {
+	case !constDecl && r == 0:
+		check.errorf(pos, "missing type or init expr")
 	case l < r:
 		n := inits[l]
 		if inherited {
@@ -59,7 +62,7 @@ func (check *Checker) arity(pos syntax.Pos, names []*syntax.Name, inits []syntax
 		} else {
 			check.errorf(n.Pos(), "extra init expr %s", n)
 		}
-	case l > r && r != 1: // if r == 1 it may be a multi-valued function and we can't say anything yet
+	case l > r && (constDecl || r != 1): // if r == 1 it may be a multi-valued function and we can't say anything yet
 		n := names[r]
 		check.errorf(n.Pos(), "missing init expr for %s", n.Value)
 	}
@@ -336,7 +339,7 @@ func (check *Checker) collectObjects() {
 				}
 
 				// declare all constants
-				values := unpack(last.Values)
+				values := unpackExpr(last.Values)
 				for i, name := range s.NameList {
 					obj := NewConst(name.Pos(), pkg, name.Value, nil, iota)
 
@@ -350,11 +353,7 @@ func (check *Checker) collectObjects() {
 				}
 
 				// Constants must always have init values.
-				if values != nil {
-					check.arity(s.Pos(), s.NameList, values, inherited)
-				} else {
-					check.errorf(s.Pos(), "missing init expr")
-				}
+				check.arity(s.Pos(), s.NameList, values, true, inherited)
 
 			case *syntax.VarDecl:
 				lhs := make([]*Var, len(s.NameList))
@@ -371,7 +370,7 @@ func (check *Checker) collectObjects() {
 				}
 
 				// declare all variables
-				values := unpack(s.Values)
+				values := unpackExpr(s.Values)
 				for i, name := range s.NameList {
 					obj := NewVar(name.Pos(), pkg, name.Value, nil)
 					lhs[i] = obj
@@ -390,9 +389,8 @@ func (check *Checker) collectObjects() {
 				}
 
 				// If we have no type, we must have values.
-				// If we have no type and no values we got an error by the parser.
-				if s.Type == nil {
-					check.arity(s.Pos(), s.NameList, values, false)
+				if s.Type == nil || values != nil {
+					check.arity(s.Pos(), s.NameList, values, false, false)
 				}
 
 			case *syntax.TypeDecl:
@@ -51,7 +51,7 @@ func (s sanitizer) typ(typ Type) Type {
 	s[typ] = typ
 
 	switch t := typ.(type) {
-	case nil, *Basic:
+	case nil, *Basic, *bottom, *top:
 		// nothing to do
 
 	case *Array:
@@ -74,12 +74,15 @@ func (s sanitizer) typ(typ Type) Type {
 		s.tuple(t.params)
 		s.tuple(t.results)
 
+	case *Sum:
+		s.typeList(t.types)
+
 	case *Interface:
 		s.funcList(t.methods)
-		s.typeList(t.types)
+		s.typ(t.types)
 		s.typeList(t.embeddeds)
 		s.funcList(t.allMethods)
-		s.typeList(t.allTypes)
+		s.typ(t.allTypes)
 
 	case *Map:
 		t.key = s.typ(t.key)
@@ -102,7 +105,7 @@ func (s sanitizer) typ(typ Type) Type {
 		s[t] = typ
 
 	default:
-		unimplemented()
+		panic("unimplemented")
 	}
 
 	return typ
@@ -22,6 +22,8 @@ type substMap struct {
 	proj  map[*TypeParam]Type
 }
 
+// makeSubstMap creates a new substitution map mapping tpars[i] to targs[i].
+// If targs[i] is nil, tpars[i] is not substituted.
 func makeSubstMap(tpars []*TypeName, targs []Type) *substMap {
 	assert(len(tpars) == len(targs))
 	proj := make(map[*TypeParam]Type, len(tpars))
@@ -29,10 +31,9 @@ func makeSubstMap(tpars []*TypeName, targs []Type) *substMap {
 		// We must expand type arguments otherwise *Instance
 		// types end up as components in composite types.
 		// TODO(gri) explain why this causes problems, if it does
-		targ := expand(targs[i])
+		targ := expand(targs[i]) // possibly nil
 		targs[i] = targ
-		assert(targ != nil)
-		proj[tpar.typ.(*TypeParam)] = targs[i]
+		proj[tpar.typ.(*TypeParam)] = targ
 	}
 	return &substMap{targs, proj}
 }
@@ -60,7 +61,10 @@ func (check *Checker) instantiate(pos syntax.Pos, typ Type, targs []Type, poslis
 			check.indent--
 			var under Type
 			if res != nil {
-				under = res.Under()
+				// Calling Under() here may lead to endless instantiations.
+				// Test case: type T(type P) T(P)
+				// TODO(gri) investigate if that's a bug or to be expected.
+				under = res.Underlying()
 			}
 			check.trace(pos, "=> %s (under = %s)", res, under)
 		}()
@@ -135,43 +139,58 @@ func (check *Checker) instantiate(pos syntax.Pos, typ Type, targs []Type, poslis
 		iface = check.subst(pos, iface, smap).(*Interface)
 
 		// targ must implement iface (methods)
-		//
-		// Assume targ is addressable, per the draft design: "In a generic function
-		// body all method calls will be pointer method calls. If necessary, the
-		// function body will insert temporary variables, not seen by the user, in
-		// order to get an addressable variable to use to call the method."
-		//
-		// TODO(gri) Instead of the addressable (= true) flag, could we encode the
-		// same information by making targ a pointer type (and then get rid of the
-		// need for that extra flag)?
-		if m, _ := check.missingMethod(targ, true, iface, true); m != nil {
-			// TODO(gri) needs to print updated name to avoid major confusion in error message!
-			//           (print warning for now)
-			// check.softErrorf(pos, "%s does not satisfy %s (warning: name not updated) = %s (missing method %s)", targ, tpar.bound, iface, m)
-			if m.name == "==" {
-				// We don't want to report "missing method ==".
-				check.softErrorf(pos, "%s does not satisfy comparable", targ)
-			} else {
-				check.softErrorf(pos, "%s does not satisfy %s (missing method %s)", targ, tpar.bound, m.name)
+		// - check only if we have methods
+		check.completeInterface(nopos, iface)
+		if len(iface.allMethods) > 0 {
+			// If the type argument is a type parameter itself, its pointer designation
+			// must match the pointer designation of the callee's type parameter.
+			// If the type argument is a pointer to a type parameter, the type argument's
+			// method set is empty.
+			// TODO(gri) is this what we want? (spec question)
+			if tparg := targ.TypeParam(); tparg != nil {
+				if tparg.ptr != tpar.ptr {
+					check.errorf(pos, "pointer designation mismatch")
+					break
+				}
+			} else if base, isPtr := deref(targ); isPtr && base.TypeParam() != nil {
+				check.errorf(pos, "%s has no methods", targ)
+				break
+			}
+			// If a type parameter is marked as a pointer type, the type bound applies
+			// to a pointer of the type argument.
+			actual := targ
+			if tpar.ptr {
+				actual = NewPointer(targ)
+			}
+			if m, _ := check.missingMethod(actual, iface, true); m != nil {
+				// TODO(gri) needs to print updated name to avoid major confusion in error message!
+				//           (print warning for now)
+				// check.softErrorf(pos, "%s does not satisfy %s (warning: name not updated) = %s (missing method %s)", targ, tpar.bound, iface, m)
+				if m.name == "==" {
+					// We don't want to report "missing method ==".
+					check.softErrorf(pos, "%s does not satisfy comparable", targ)
+				} else {
+					check.softErrorf(pos, "%s does not satisfy %s (missing method %s)", targ, tpar.bound, m.name)
+				}
+				break
 			}
-			break
 		}
 
 		// targ's underlying type must also be one of the interface types listed, if any
-		if len(iface.allTypes) == 0 {
-			break // nothing to do
+		if iface.allTypes == nil {
+			continue // nothing to do
 		}
-		// len(iface.allTypes) > 0
+		// iface.allTypes != nil
 
 		// If targ is itself a type parameter, each of its possible types, but at least one, must be in the
 		// list of iface types (i.e., the targ type list must be a non-empty subset of the iface types).
 		if targ := targ.TypeParam(); targ != nil {
 			targBound := targ.Bound()
-			if len(targBound.allTypes) == 0 {
+			if targBound.allTypes == nil {
 				check.softErrorf(pos, "%s does not satisfy %s (%s has no type constraints)", targ, tpar.bound, targ)
 				break
 			}
-			for _, t := range targBound.allTypes {
+			for _, t := range unpack(targBound.allTypes) {
 				if !iface.includes(t.Under()) {
 					// TODO(gri) match this error message with the one below (or vice versa)
 					check.softErrorf(pos, "%s does not satisfy %s (%s type constraint %s not found in %s)", targ, tpar.bound, targ, t, iface.allTypes)
@@ -182,7 +201,6 @@ func (check *Checker) instantiate(pos syntax.Pos, typ Type, targs []Type, poslis
 		}
 
 		// Otherwise, targ's underlying type must also be one of the interface types listed, if any.
-		// TODO(gri) must it be the underlying type, or should it just be the type? (spec question)
 		if !iface.includes(targ.Under()) {
 			check.softErrorf(pos, "%s does not satisfy %s (%s not found in %s)", targ, tpar.bound, targ.Under(), iface.allTypes)
 			break
@@ -224,7 +242,7 @@ func (subst *subster) typ(typ Type) Type {
 	case nil:
 		panic("nil typ")
 
-	case *Basic:
+	case *Basic, *bottom, *top:
 		// nothing to do
 
 	case *Array:
@@ -271,11 +289,23 @@ func (subst *subster) typ(typ Type) Type {
 			}
 		}
 
+	case *Sum:
+		types, copied := subst.typeList(t.types)
+		if copied {
+			// Don't do it manually, with a Sum literal: the new
+			// types list may not be unique and NewSum may remove
+			// duplicates.
+			return NewSum(types)
+		}
+
 	case *Interface:
 		methods, mcopied := subst.funcList(t.methods)
-		types, tcopied := subst.typeList(t.types)
+		types := t.types
+		if t.types != nil {
+			types = subst.typ(t.types)
+		}
 		embeddeds, ecopied := subst.typeList(t.embeddeds)
-		if mcopied || tcopied || ecopied {
+		if mcopied || types != t.types || ecopied {
 			iface := &Interface{methods: methods, types: types, embeddeds: embeddeds}
 			subst.check.posMap[iface] = subst.check.posMap[t] // satisfy completeInterface requirement
 			subst.check.completeInterface(nopos, iface)
@@ -381,7 +411,7 @@ func (subst *subster) typ(typ Type) Type {
 		return subst.typ(t.expand())
 
 	default:
-		unimplemented()
+		panic("unimplemented")
 	}
 
 	return typ
@@ -395,7 +425,21 @@ func instantiatedHash(typ *Named, targs []Type) string {
 	buf.WriteByte('(')
 	writeTypeList(&buf, targs, nil, nil)
 	buf.WriteByte(')')
-	return buf.String()
+
+	// With respect to the represented type, whether a
+	// type is fully expanded or stored as instance
+	// does not matter - they are the same types.
+	// Remove the instanceMarkers printed for instances.
+	res := buf.Bytes()
+	i := 0
+	for _, b := range res {
+		if b != instanceMarker {
+			res[i] = b
+			i++
+		}
+	}
+
+	return string(res[:i])
 }
 
 func typeListString(list []Type) string {
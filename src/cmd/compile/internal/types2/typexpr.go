@@ -37,13 +37,6 @@ func (check *Checker) ident(x *operand, e *syntax.Name, def *Named, wantType boo
 	}
 	check.recordUse(e, obj)
 
-	// If we have a contract, don't bother type-checking it and avoid a
-	// possible cycle error in favor of the more informative error below.
-	if obj, _ := obj.(*Contract); obj != nil {
-		check.errorf(e.Pos(), "use of contract %s not in type parameter declaration", obj.name)
-		return
-	}
-
 	// Type-check the object.
 	// Only call Checker.objDecl if the object doesn't have a type yet
 	// (in which case we must actually determine it) or the object is a
@@ -105,9 +98,6 @@ func (check *Checker) ident(x *operand, e *syntax.Name, def *Named, wantType boo
 		}
 		x.mode = variable
 
-	case *Contract:
-		unreachable() // handled earlier
-
 	case *Func:
 		check.addDeclDep(obj)
 		x.mode = value
@@ -143,8 +133,12 @@ func (check *Checker) varType(e syntax.Expr) Type {
 		// interface methods. Delay this check to the end of type-checking.
 		check.atEnd(func() {
 			check.completeInterface(e.Pos(), t) // TODO(gri) is this the correct position?
-			if len(t.allTypes) != 0 {
-				check.softErrorf(e.Pos(), "interface type for variable cannot contain type constraints")
+			if t.allTypes != nil {
+				check.softErrorf(e.Pos(), "interface type for variable cannot contain type constraints (%s)", t.allTypes)
+				return
+			}
+			if t.IsComparable() {
+				check.softErrorf(e.Pos(), "interface type for variable cannot be (or embed) comparable")
 			}
 		})
 	}
@@ -301,8 +295,13 @@ func (check *Checker) funcType(sig *Signature, recvPar *syntax.Field, tparams []
 			// provide type parameter bounds
 			// - only do this if we have the right number (otherwise an error is reported elsewhere)
 			if len(sig.rparams) == len(recvTParams) {
+				// We have a list of *TypeNames but we need a list of Types.
+				// While creating this list, also update type parameter pointer designation
+				// for each (*TypeParam) list entry, by copying the information from the
+				// receiver base type's type parameters.
 				list := make([]Type, len(sig.rparams))
 				for i, t := range sig.rparams {
+					t.typ.(*TypeParam).ptr = recvTParams[i].typ.(*TypeParam).ptr
 					list[i] = t.typ
 				}
 				for i, tname := range sig.rparams {
@@ -323,6 +322,10 @@ func (check *Checker) funcType(sig *Signature, recvPar *syntax.Field, tparams []
 
 	if tparams != nil {
 		sig.tparams = check.collectTypeParams(tparams)
+		// Always type-check method type parameters but complain if they are not enabled.
+		if recvPar != nil && !check.conf.AcceptMethodTypeParams {
+			check.errorf(tparams[0].Pos(), "methods cannot have type parameters")
+		}
 	}
 
 	// Value (non-type) parameters' scope starts in the function body. Use a temporary scope for their
@@ -373,7 +376,7 @@ func (check *Checker) funcType(sig *Signature, recvPar *syntax.Field, tparams []
 				if T.obj.pkg != check.pkg {
 					err = "type not defined in this package"
 				} else {
-					switch u := T.Under().(type) {
+					switch u := optype(T.Under()).(type) {
 					case *Basic:
 						// unsafe.Pointer is treated like a regular pointer
 						if u.kind == UnsafePointer {
@@ -402,7 +405,7 @@ func (check *Checker) funcType(sig *Signature, recvPar *syntax.Field, tparams []
 // goTypeName returns the Go type name for typ and
 // removes any occurences of "types." from that name.
 func goTypeName(typ Type) string {
-	return strings.ReplaceAll(fmt.Sprintf("%T", typ), "types.", "")
+	return strings.Replace(fmt.Sprintf("%T", typ), "types.", "", -1)
 }
 
 // typInternal drives type checking of types.
@@ -414,7 +417,19 @@ func (check *Checker) typInternal(e syntax.Expr, def *Named) (T Type) {
 		check.indent++
 		defer func() {
 			check.indent--
-			check.trace(e.Pos(), "=> %s // %s", T, goTypeName(T))
+			var under Type
+			if T != nil {
+				// Calling Under() here may lead to endless instantiations.
+				// Test case: type T(type P) *T(P)
+				// TODO(gri) investigate if that's a bug or to be expected
+				// (see also analogous comment in Checker.instantiate).
+				under = T.Underlying()
+			}
+			if T == under {
+				check.trace(e.Pos(), "=> %s // %s", T, goTypeName(T))
+			} else {
+				check.trace(e.Pos(), "=> %s (under = %s) // %s", T, under, goTypeName(T))
+			}
 		}()
 	}
 
@@ -539,7 +554,7 @@ func (check *Checker) typInternal(e syntax.Expr, def *Named) (T Type) {
 	case *syntax.FuncType:
 		typ := new(Signature)
 		def.setUnderlying(typ)
-		check.funcType(typ, nil, nil, e)
+		check.funcType(typ, nil, e.TParamList, e)
 		return typ
 
 	case *syntax.InterfaceType:
@@ -678,6 +693,9 @@ func (check *Checker) collectParams(scope *Scope, list []*syntax.Field, type0 sy
 	}
 
 	var named, anonymous bool
+
+	var typ Type
+	var prev syntax.Expr
 	for i, field := range list {
 		ftype := field.Type
 		if i == 0 && type0 != nil {
@@ -685,14 +703,20 @@ func (check *Checker) collectParams(scope *Scope, list []*syntax.Field, type0 sy
 		}
 		if t, _ := ftype.(*syntax.DotsType); t != nil {
 			ftype = t.Elem
-			if variadicOk && i == len(list)-1 {
+			// Fields declared together, as in a, b ...int, share their type.
+			grouped := i > 0 && list[i-1].Type == field.Type
+			if variadicOk && i == len(list)-1 && !grouped {
 				variadic = true
-			} else {
+			} else if !grouped {
 				check.softErrorf(t.Pos(), "can only use ... with final parameter in list")
 				// ignore ... and continue
 			}
 		}
-		typ := check.varType(ftype)
+		// type-check type of grouped fields only once
+		if ftype != prev {
+			typ = check.varType(ftype)
+			prev = ftype
+		}
 		// The parser ensures that f.Tag is nil and we don't
 		// care if a constructed AST contains a non-nil tag.
 		if field.Name != nil {
@@ -742,6 +766,7 @@ func (check *Checker) declareInSet(oset *objset, pos syntax.Pos, obj Object) boo
 }
 
 func (check *Checker) interfaceType(ityp *Interface, iface *syntax.InterfaceType, def *Named) {
+	var tlist *syntax.Name // "type" name of first entry in a type list declaration
 	var types []syntax.Expr
 	for _, f := range iface.MethodList {
 		if f.Name != nil {
@@ -754,7 +779,14 @@ func (check *Checker) interfaceType(ityp *Interface, iface *syntax.InterfaceType
 			}
 
 			if name == "type" {
+				// Always collect all type list entries, even from
+				// different type lists, under the assumption that
+				// the author intended to include all types.
 				types = append(types, f.Type)
+				if tlist != nil && tlist != f.Name {
+					check.errorf(f.Name.Pos(), "cannot have multiple type lists in an interface")
+				}
+				tlist = f.Name
 				continue
 			}
 
@@ -767,6 +799,13 @@ func (check *Checker) interfaceType(ityp *Interface, iface *syntax.InterfaceType
 				continue // ignore
 			}
 
+			// Always type-check method type parameters but complain if they are not enabled.
+			// (This extra check is needed here because interface method signatures don't have
+			// a receiver specification.)
+			if sig.tparams != nil && !check.conf.AcceptMethodTypeParams {
+				check.errorf(f.Type.(*syntax.FuncType).TParamList[0].Pos(), "methods cannot have type parameters")
+			}
+
 			// use named receiver type if available (for better error messages)
 			var recvTyp Type = ityp
 			if def != nil {
@@ -786,9 +825,9 @@ func (check *Checker) interfaceType(ityp *Interface, iface *syntax.InterfaceType
 	}
 
 	// type constraints
-	ityp.types = check.collectTypeConstraints(iface.Pos(), ityp.types, types)
+	ityp.types = NewSum(check.collectTypeConstraints(iface.Pos(), types))
 
-	if len(ityp.methods) == 0 && len(ityp.types) == 0 && len(ityp.embeddeds) == 0 {
+	if len(ityp.methods) == 0 && ityp.types == nil && len(ityp.embeddeds) == 0 {
 		// empty interface
 		ityp.allMethods = markComplete
 		return
@@ -877,9 +916,7 @@ func (check *Checker) completeInterface(pos syntax.Pos, ityp *Interface) {
 	}
 
 	// collect types
-	// TODO(gri) report error for multiple explicitly declared identical types
-	var types []Type
-	types = append(types, ityp.types...)
+	allTypes := ityp.types
 
 	posList := check.posMap[ityp]
 	for i, typ := range ityp.embeddeds {
@@ -888,7 +925,13 @@ func (check *Checker) completeInterface(pos syntax.Pos, ityp *Interface) {
 		etyp := utyp.Interface()
 		if etyp == nil {
 			if utyp != Typ[Invalid] {
-				check.errorf(pos, "%s is not an interface", typ)
+				var format string
+				if _, ok := utyp.(*TypeParam); ok {
+					format = "%s is a type parameter, not an interface"
+				} else {
+					format = "%s is not an interface"
+				}
+				check.errorf(pos, format, typ)
 			}
 			continue
 		}
@@ -896,14 +939,49 @@ func (check *Checker) completeInterface(pos syntax.Pos, ityp *Interface) {
 		for _, m := range etyp.allMethods {
 			addMethod(pos, m, false) // use embedding position pos rather than m.pos
 		}
-		types = append(types, etyp.allTypes...)
+		allTypes = intersect(allTypes, etyp.allTypes)
 	}
 
 	if methods != nil {
 		sort.Sort(byUniqueMethodName(methods))
 		ityp.allMethods = methods
 	}
-	ityp.allTypes = types
+	ityp.allTypes = allTypes
+}
+
+func intersect(x, y Type) (r Type) {
+	defer func() {
+		if r == theTop {
+			r = nil
+		}
+	}()
+
+	switch {
+	case x == theBottom || y == theBottom:
+		return theBottom
+	case x == nil || x == theTop:
+		return y
+	case y == nil || x == theTop:
+		return x
+	}
+
+	xtypes := unpack(x)
+	ytypes := unpack(y)
+	// Compute the list rtypes which contains only
+	// types that are in both xtypes and ytypes.
+	// Quadratic algorithm, but good enough for now.
+	// TODO(gri) fix this
+	var rtypes []Type
+	for _, x := range xtypes {
+		if contains(ytypes, x) {
+			rtypes = append(rtypes, x)
+		}
+	}
+
+	if rtypes == nil {
+		return theBottom
+	}
+	return NewSum(rtypes)
 }
 
 // byUniqueTypeName named type lists can be sorted by their unique type names.
@@ -997,9 +1075,8 @@ func (check *Checker) structType(styp *Struct, e *syntax.StructType) {
 			name := embeddedFieldIdent(f.Type)
 			if name == nil {
 				check.errorf(pos, "invalid embedded field type %s", f.Type)
-				unimplemented()
-				//name = ast.NewIdent("_")
-				// name.pos = pos
+				name = &syntax.Name{Value: "_"}
+				name.SetPos(pos)
 				addInvalid(name, pos)
 				continue
 			}
@@ -1012,7 +1089,7 @@ func (check *Checker) structType(styp *Struct, e *syntax.StructType) {
 			embeddedPos := pos
 			check.atEnd(func() {
 				t, isPtr := deref(embeddedTyp)
-				switch t := t.Under().(type) {
+				switch t := optype(t.Under()).(type) {
 				case *Basic:
 					if t == Typ[Invalid] {
 						// error was reported before
@@ -1058,6 +1135,113 @@ func embeddedFieldIdent(e syntax.Expr) *syntax.Name {
 	return nil // invalid embedded field
 }
 
+func (check *Checker) collectTypeConstraints(pos syntax.Pos, types []syntax.Expr) []Type {
+	list := make([]Type, 0, len(types)) // assume all types are correct
+	for _, texpr := range types {
+		if texpr == nil {
+			check.invalidAST(pos, "missing type constraint")
+			continue
+		}
+		typ := check.typ(texpr)
+		// A type constraint may be a predeclared type or a
+		// composite type composed of only predeclared types.
+		// TODO(gri) If we enable this again it also must run
+		// at the end.
+		const restricted = false
+		var why string
+		if restricted && !check.typeConstraint(typ, &why) {
+			check.errorf(texpr.Pos(), "invalid type constraint %s (%s)", typ, why)
+			continue
+		}
+		list = append(list, typ)
+	}
+
+	// Ensure that each type is only present once in the type list.
+	// Types may be interfaces, which may not be complete yet. It's
+	// ok to do this check at the end because it's not a requirement
+	// for correctness of the code.
+	check.atEnd(func() {
+		uniques := make([]Type, 0, len(list)) // assume all types are unique
+		for i, t := range list {
+			if t := t.Interface(); t != nil {
+				check.completeInterface(types[i].Pos(), t)
+			}
+			if contains(uniques, t) {
+				check.softErrorf(types[i].Pos(), "duplicate type %s in type list", t)
+			}
+			uniques = append(uniques, t)
+		}
+	})
+
+	return list
+}
+
+// contains reports whether typ is in list
+func contains(list []Type, typ Type) bool {
+	for _, e := range list {
+		if Identical(typ, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeConstraint checks that typ may be used in a type list.
+// For now this just checks for the absence of defined (*Named) types.
+func (check *Checker) typeConstraint(typ Type, why *string) bool {
+	switch t := typ.(type) {
+	case *Basic:
+		// ok
+	case *Array:
+		return check.typeConstraint(t.elem, why)
+	case *Slice:
+		return check.typeConstraint(t.elem, why)
+	case *Struct:
+		for _, f := range t.fields {
+			if !check.typeConstraint(f.typ, why) {
+				return false
+			}
+		}
+	case *Pointer:
+		return check.typeConstraint(t.base, why)
+	case *Tuple:
+		if t == nil {
+			return true
+		}
+		for _, v := range t.vars {
+			if !check.typeConstraint(v.typ, why) {
+				return false
+			}
+		}
+	case *Signature:
+		if len(t.tparams) != 0 {
+			panic("type parameter in function type")
+		}
+		return (t.recv == nil || check.typeConstraint(t.recv.typ, why)) &&
+			check.typeConstraint(t.params, why) &&
+			check.typeConstraint(t.results, why)
+	case *Interface:
+		t.assertCompleteness()
+		for _, m := range t.allMethods {
+			if !check.typeConstraint(m.typ, why) {
+				return false
+			}
+		}
+	case *Map:
+		return check.typeConstraint(t.key, why) && check.typeConstraint(t.elem, why)
+	case *Chan:
+		return check.typeConstraint(t.elem, why)
+	case *Named:
+		*why = check.sprintf("contains defined type %s", t)
+		return false
+	case *TypeParam:
+		// ok, e.g.: func f (type T interface { type T }) ()
+	default:
+		unreachable()
+	}
+	return true
+}
+
 func ptrBase(x *syntax.Operation) syntax.Expr {
 	if x.Op == syntax.Mul && x.Y == nil {
 		return x.X
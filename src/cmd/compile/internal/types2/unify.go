@@ -6,22 +6,25 @@
 
 package types2
 
-import (
-	"sort"
-)
+import "sort"
 
 // A unifier maintains the current type parameters for x and y
 // and the respective types inferred for each type parameter.
 // A uninifier is created by calling Checker.unifier.
 type unifier struct {
 	check *Checker
+	exact bool
 	x, y  typeDesc // x and y must initialized via typeDesc.init
 	types []Type   // inferred types, shared by x and y
 }
 
-// unifier returns a new unifier.
-func (check *Checker) unifier() *unifier {
-	u := &unifier{check: check}
+// newUnifier returns a new unifier.
+// If exact is set, unification requires unified types to match
+// exactly. If exact is not set, a named type's underlying type
+// is considered if unification would fail otherwise, and the
+// direction of channels is ignored.
+func (check *Checker) newUnifier(exact bool) *unifier {
+	u := &unifier{check: check, exact: exact}
 	u.x.uplink = u
 	u.y.uplink = u
 	return u
@@ -58,6 +61,23 @@ func (d *typeDesc) at(i int) Type {
 	return nil
 }
 
+// types returns the list of inferred types (via unification) for the type parameters
+// described by d, and an index. If all types were inferred, the returned index is < 0.
+// Otherwise, it is the index of the first type parameter which couldn't be inferred
+// and for which list[index] is nil.
+func (d *typeDesc) types() (list []Type, index int) {
+	list = make([]Type, len(d.tparams))
+	index = -1
+	for i := range d.tparams {
+		t := d.at(i)
+		list[i] = t
+		if index < 0 && t == nil {
+			index = i
+		}
+	}
+	return
+}
+
 // set sets the type typ inferred (via unification) for the i'th type parameter; typ must not be nil.
 // The index i must be a valid type parameter index: 0 <= i < len(d.tparams).
 func (d *typeDesc) set(i int, typ Type) {
@@ -67,12 +87,12 @@ func (d *typeDesc) set(i int, typ Type) {
 	d.indices[i] = len(u.types)
 }
 
-// If typ is a type parameter in tparams, index returns the
-// corresponding tparams index. Otherwise, the result is < 0.
-func (u *unifier) index(tparams []*TypeName, typ Type) int {
+// If typ is a type parameter in d.tparams, index returns the
+// corresponding d.tparams index. Otherwise, the result is < 0.
+func (d *typeDesc) index(typ Type) int {
 	if t, ok := typ.(*TypeParam); ok {
 		// typ is a type parameter; check that it belongs to the (enclosing) type
-		if i := t.index; i < len(tparams) && tparams[i].typ == t {
+		if i := t.index; i < len(d.tparams) && d.tparams[i].typ == t {
 			return i
 		}
 	}
@@ -88,9 +108,24 @@ func (u *unifier) nify(x, y Type, p *ifacePair) bool {
 	x = expand(x)
 	y = expand(y)
 
+	if !u.exact {
+		// If exact unification is known to fail because we attempt to
+		// match a type name against an unnamed type literal, consider
+		// the underlying type of the named type.
+		// (Subtle: We use isNamed to include any type with a name (incl.
+		// basic types and type parameters. We use Named() because we only
+		// want *Named types.)
+		switch {
+		case !isNamed(x) && y != nil && y.Named() != nil:
+			return u.nify(x, y.Under(), p)
+		case x != nil && x.Named() != nil && !isNamed(y):
+			return u.nify(x.Under(), y, p)
+		}
+	}
+
 	//u.check.dump("### u.nify(%s, %s)", x, y)
-	i := u.index(u.x.tparams, x)
-	j := u.index(u.y.tparams, y)
+	i := u.x.index(x)
+	j := u.y.index(y)
 	switch {
 	case i >= 0 && j >= 0:
 		//u.check.dump("### i = %d, j = %d", i, j)
@@ -241,6 +276,10 @@ func (u *unifier) nify(x, y Type, p *ifacePair) bool {
 				u.nify(x.results, y.results, p)
 		}
 
+	case *Sum:
+		// This should not happen with the current internal use of sum types.
+		panic("type inference across sum types not implemented")
+
 	case *Interface:
 		// Two interface types are identical if they have the same set of methods with
 		// the same names and identical function types. Lower-case method names from
@@ -308,9 +347,8 @@ func (u *unifier) nify(x, y Type, p *ifacePair) bool {
 
 	case *Chan:
 		// Two channel types are identical if they have identical value types.
-		// For type unification, channel direction is ignored.
 		if y, ok := y.(*Chan); ok {
-			return u.nify(x.elem, y.elem, p)
+			return (!u.exact || x.dir == y.dir) && u.nify(x.elem, y.elem, p)
 		}
 
 	case *Named:
@@ -336,10 +374,11 @@ func (u *unifier) nify(x, y Type, p *ifacePair) bool {
 
 	case *TypeParam:
 		// Two type parameters (which are not part of the type parameters of the
-		// enclosing type) are identical if they originate in the same declaration.
+		// enclosing type as those are handled in the beginning of this function)
+		// are identical if they originate in the same declaration.
 		return x == y
 
-	// case *Instance:
+	// case *instance:
 	//	unreachable since types are expanded
 
 	case nil:
@@ -0,0 +1,409 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/build"
+	"go/go2go"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The cover tool records the positions of the blocks it counts using
+// the line directives of the file it instruments, so for a translated
+// file they would name lines of the .go2 files, without saying which
+// file, and without columns. "go2go test" with coverage therefore
+// runs the cover tool through go2go, using the -toolexec flag of the
+// go tool, and coverToolexec hides the line directives from it, so
+// that the profile records positions in the translated files. Once
+// the tests have run, rewriteCoverProfile maps those positions back
+// to the .go2 files through the line directives, merging the counts
+// of all instantiations of the same generic code.
+
+// coverToolexecArg, as the first argument, tells go2go to run the
+// rest of its arguments as a tool, see coverToolexec.
+const coverToolexecArg = "-go2go-cover-toolexec"
+
+// generatedHeader starts the files written by go2go.
+const generatedHeader = "// Code generated by go2go; DO NOT EDIT."
+
+// coverArgs prepares the arguments of "go test", args, for coverage.
+// If coverage is requested, it runs tools through go2go, and makes
+// the name of the coverage profile absolute. It returns the new
+// arguments, the name of the profile, if any, and the packages
+// listed by -coverpkg.
+func coverArgs(args []string) (nargs []string, profile string, coverPkgs []string) {
+	cover := false
+	toolexec := false
+	nargs = append([]string(nil), args...)
+	for i := 1; i < len(nargs); i++ {
+		arg := nargs[i]
+		if arg == "-args" || arg == "--args" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name, value, hasValue = name[:eq], name[eq+1:], true
+		}
+		valueAt := func() (string, int) {
+			if hasValue {
+				return value, i
+			}
+			if i+1 < len(nargs) {
+				i++
+				return nargs[i], i
+			}
+			return "", -1
+		}
+		switch name {
+		case "cover":
+			cover = true
+		case "covermode":
+			cover = true
+			valueAt()
+		case "coverpkg":
+			cover = true
+			if v, _ := valueAt(); v != "" {
+				coverPkgs = strings.Split(v, ",")
+			}
+		case "coverprofile":
+			cover = true
+			v, j := valueAt()
+			if j < 0 || v == "" {
+				continue
+			}
+			abs, err := filepath.Abs(v)
+			if err != nil {
+				die(err.Error())
+			}
+			profile = abs
+			if hasValue {
+				nargs[j] = "-coverprofile=" + abs
+			} else {
+				nargs[j] = abs
+			}
+		case "toolexec":
+			toolexec = true
+			valueAt()
+		default:
+			if goFlagsWithValue[name] && !hasValue {
+				i++
+			}
+		}
+	}
+	if !cover {
+		return args, "", nil
+	}
+	if toolexec {
+		die("go2go test does not support -toolexec with coverage")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		die(err.Error())
+	}
+	flag := fmt.Sprintf("-toolexec='%s' %s", exe, coverToolexecArg)
+	nargs = append([]string{nargs[0], flag}, nargs[1:]...)
+	return nargs, profile, coverPkgs
+}
+
+// coverToolexec runs the tool in args, as the -toolexec program of
+// the go tool. The cover tool is run on a copy of its input without
+// line directives, which are restored in its output.
+func coverToolexec(args []string) {
+	if len(args) == 0 {
+		die("no tool to run")
+	}
+	tool, args := args[0], args[1:]
+	var src, dst string
+	var data []byte
+	if strings.TrimSuffix(filepath.Base(tool), ".exe") == "cover" {
+		for i, arg := range args {
+			if arg == "-o" && i+2 < len(args) {
+				dst, src = args[i+1], args[len(args)-1]
+			}
+		}
+	}
+	if src != "" {
+		var err error
+		if data, err = ioutil.ReadFile(src); err != nil {
+			die(err.Error())
+		}
+		if !bytes.HasPrefix(data, []byte(generatedHeader)) {
+			src = ""
+		}
+	}
+	if src != "" {
+		tmp, err := ioutil.TempFile("", "go2go-cover-*.go")
+		if err != nil {
+			die(err.Error())
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := tmp.Write(replaceLinePrefix(data, "//line ", "//go2go:line ")); err != nil {
+			die(err.Error())
+		}
+		if err := tmp.Close(); err != nil {
+			die(err.Error())
+		}
+		args[len(args)-1] = tmp.Name()
+		defer func() {
+			out, err := ioutil.ReadFile(dst)
+			if err != nil {
+				die(err.Error())
+			}
+			out = replaceLinePrefix(out, "//go2go:line ", "//line ")
+			out = bytes.Replace(out, []byte("//line "+tmp.Name()+":1\n"), []byte("//line "+src+":1\n"), 1)
+			if err := ioutil.WriteFile(dst, out, 0666); err != nil {
+				die(err.Error())
+			}
+		}()
+	}
+
+	cmd := exec.Command(tool, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			os.Exit(ee.ExitCode())
+		}
+		die(err.Error())
+	}
+}
+
+// replaceLinePrefix returns a copy of data in which each line that
+// starts with old starts with new instead.
+func replaceLinePrefix(data []byte, old, new string) []byte {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines {
+		if bytes.HasPrefix(line, []byte(old)) {
+			lines[i] = append([]byte(new), line[len(old):]...)
+		}
+	}
+	return bytes.Join(lines, nil)
+}
+
+// profileLine matches a block of a coverage profile:
+// name:line.column,line.column statements count
+var profileLine = regexp.MustCompile(`^(.+):([0-9]+)\.([0-9]+),([0-9]+)\.([0-9]+) ([0-9]+) ([0-9]+)$`)
+
+// A coverBlock is a block of a coverage profile.
+type coverBlock struct {
+	name                                 string
+	startLine, startCol, endLine, endCol int
+}
+
+// rewriteCoverProfile rewrites the coverage profile written by "go test"
+// so that blocks in translated files refer to the .go2 files. Blocks of
+// instantiations of the same generic code are merged. The go tool run
+// by goCmd lists the packages pkgs, and their dependencies, to find
+// the translated files.
+func rewriteCoverProfile(importer *go2go.Importer, profile string, goCmd func(args ...string) *exec.Cmd, pkgs []string) error {
+	data, err := ioutil.ReadFile(profile)
+	if err != nil {
+		return err
+	}
+
+	cmd := goCmd(append([]string{"list", "-e", "-deps", "-f", "{{.ImportPath}}\t{{.Dir}}"}, pkgs...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("listing packages for coverage profile: %v", err)
+	}
+	dirs := make(map[string]string)    // import path to directory
+	imports := make(map[string]string) // directory to import path
+	for _, line := range strings.Split(string(out), "\n") {
+		if f := strings.Split(line, "\t"); len(f) == 2 && f[1] != "" {
+			dirs[f[0]] = f[1]
+			imports[f[1]] = f[0]
+		}
+	}
+
+	// importName returns the name of the .go2 file in a profile.
+	importName := func(file string) string {
+		dir := filepath.Dir(file)
+		if odir, err := importer.OutputDir(dir); err == nil {
+			dir = odir
+		}
+		if ipath, ok := imports[dir]; ok {
+			return ipath + "/" + filepath.Base(file)
+		}
+		// Imported packages are translated into a copy, so
+		// look for the original directory in the GOPATH.
+		roots := filepath.SplitList(os.Getenv("GO2PATH"))
+		roots = append(roots, filepath.SplitList(build.Default.GOPATH)...)
+		for _, root := range roots {
+			if root == "" {
+				continue
+			}
+			if rel, err := filepath.Rel(filepath.Join(root, "src"), file); err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel)
+			}
+		}
+		return file
+	}
+
+	// sourceLine returns the text of line in file.
+	sources := make(map[string][][]byte)
+	sourceLine := func(file string, line int) []byte {
+		lines, ok := sources[file]
+		if !ok {
+			if data, err := ioutil.ReadFile(file); err == nil {
+				lines = bytes.Split(data, []byte("\n"))
+			}
+			sources[file] = lines
+		}
+		if line < 1 || line > len(lines) {
+			return nil
+		}
+		return lines[line-1]
+	}
+
+	fset := token.NewFileSet()
+	files := make(map[string]*token.File) // nil if not translated
+	contents := make(map[string][]byte)
+	translated := func(name string) *token.File {
+		if tf, ok := files[name]; ok {
+			return tf
+		}
+		files[name] = nil
+		dir, ok := dirs[path.Dir(name)]
+		if !ok {
+			return nil
+		}
+		file := filepath.Join(dir, path.Base(name))
+		src, err := ioutil.ReadFile(file)
+		if err != nil || !bytes.HasPrefix(src, []byte(generatedHeader)) {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+		if err != nil {
+			return nil
+		}
+		files[name] = fset.File(f.Pos())
+		contents[name] = src
+		return files[name]
+	}
+
+	// position returns the .go2 position of line and col of tf,
+	// whose contents are src.
+	position := func(tf *token.File, src []byte, line, col int) (token.Position, bool) {
+		if line < 1 || line > tf.LineCount() {
+			return token.Position{}, false
+		}
+		start := tf.Offset(tf.LineStart(line))
+		off := start + col - 1
+		if off < start || off > len(src) {
+			return token.Position{}, false
+		}
+		pos := tf.PositionFor(tf.Pos(off), true)
+		if pos.Filename == tf.Name() {
+			return token.Position{}, false
+		}
+		if pos.Column == 0 {
+			// The line directives don't record columns.
+			// Instantiated lines differ from the .go2 line
+			// in names, so count from the nearest end of
+			// the line where they agree.
+			end := bytes.IndexByte(src[start:], '\n')
+			if end < 0 {
+				end = len(src) - start
+			}
+			pos.Column = translatedColumn(src[start:start+end], sourceLine(pos.Filename, pos.Line), col)
+		}
+		return pos, true
+	}
+
+	var mode string
+	var order []coverBlock
+	stmts := make(map[coverBlock]int)
+	counts := make(map[coverBlock]int)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "mode: ") && mode == "" {
+			mode = strings.TrimPrefix(line, "mode: ")
+			continue
+		}
+		m := profileLine.FindStringSubmatch(line)
+		if m == nil {
+			return fmt.Errorf("%s: bad coverage profile line %q", profile, line)
+		}
+		var n [6]int
+		for i := range n {
+			n[i], _ = strconv.Atoi(m[i+2])
+		}
+		b := coverBlock{m[1], n[0], n[1], n[2], n[3]}
+		if tf := translated(b.name); tf != nil {
+			start, ok1 := position(tf, contents[b.name], b.startLine, b.startCol)
+			end, ok2 := position(tf, contents[b.name], b.endLine, b.endCol)
+			if ok1 && ok2 && start.Filename == end.Filename {
+				b = coverBlock{importName(start.Filename), start.Line, start.Column, end.Line, end.Column}
+			}
+		}
+		if _, ok := counts[b]; !ok {
+			order = append(order, b)
+		}
+		if n[4] > stmts[b] {
+			stmts[b] = n[4]
+		}
+		if mode == "set" {
+			if n[5] > counts[b] {
+				counts[b] = n[5]
+			}
+		} else {
+			counts[b] += n[5]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "mode: %s\n", mode)
+	for _, b := range order {
+		fmt.Fprintf(&buf, "%s:%d.%d,%d.%d %d %d\n", b.name, b.startLine, b.startCol, b.endLine, b.endCol, stmts[b], counts[b])
+	}
+	return ioutil.WriteFile(profile, buf.Bytes(), 0666)
+}
+
+// translatedColumn returns the column in the source line src that
+// corresponds to column col in the translated line line. If the column
+// is in a common prefix of the lines, it is unchanged; otherwise it is
+// counted from the end of the line, as far as the lines agree there.
+func translatedColumn(line, src []byte, col int) int {
+	if bytes.Equal(line, src) {
+		return col
+	}
+	prefix := 0
+	for prefix < len(line) && prefix < len(src) && line[prefix] == src[prefix] {
+		prefix++
+	}
+	if col-1 <= prefix {
+		return col
+	}
+	suffix := 0
+	for suffix < len(line) && suffix < len(src) && line[len(line)-1-suffix] == src[len(src)-1-suffix] {
+		suffix++
+	}
+	fromEnd := len(line) + 1 - col
+	if fromEnd > suffix {
+		fromEnd = suffix
+	}
+	return len(src) + 1 - fromEnd
+}
@@ -7,16 +7,26 @@
 //
 // Usage:
 //
-//	go2go <command> [arguments]
+//	go2go [-shared] [-shapes] [-methodtparams] [-outroot dir] <command> [arguments]
 //
 // The commands are:
 //
 //      build      translate and then run "go build packages"
+//      doc        show documentation for a package or symbol
 //      run        translate and then run a list of files
+//      serve      run a language server for .go2 files
 //      test       translate and then run "go test packages"
 //      translate  translate .go2 files into .go files for listed packages
+//      vet        run the vet checks on the .go2 files of listed packages
 //
-// A package is expected to contain .go2 files but no .go files.
+// A package is expected to contain .go2 files. It may also contain
+// hand-written .go files, as well as assembly and C files, so that a
+// package can be converted to generics one file at a time. The .go
+// files are type checked along with the .go2 files, and compiled as
+// they are, next to the .go files translated from the .go2 files.
+// They may not declare or use generic functions or types. A hand-written
+// .go file may not have the name of a translated file: x.go and x.go2
+// can't be in the same package.
 //
 // Non-local imported packages will be first looked up using the GO2PATH
 // environment variable, which should point to a GOPATH-like directory.
@@ -25,37 +35,159 @@
 // imports will be looked up in the usual way. If an import includes
 // .go2 files, they will be translated into .go files.
 //
+// When run in module mode, which is to say when "go env GOMOD" reports
+// a go.mod file, GO2PATH is not used. Instead imports are resolved using
+// the modules in the build list of the main module, and the go tool is
+// run in module mode. Packages in the main module are translated in place.
+// Packages with .go2 files in other modules, including modules in the
+// module cache, are translated in a temporary copy of the module,
+// which replaces the original module when running the go tool.
+//
+// By default the translated .go files are written next to the .go2 files,
+// replacing any files that go2go wrote there before. With the -outroot
+// flag, source directories are left untouched. Instead each translated
+// package, including the packages it imports, is copied to, and
+// translated in, a directory under the given root, which is laid out
+// like a GOPATH: a package in GO2PATHDIR/src/x is translated in
+// ROOT/src/x. In module mode the main module is copied to ROOT/mod/path,
+// where path is its module path, and the go tool is run in that copy.
+// The build, test and run commands then write any output files under
+// the root as well.
+//
+// When "go2go test" writes a coverage profile, with the -coverprofile
+// flag, the profile refers to the .go2 files rather than the translated
+// files, and the counts of all instantiations of a generic function are
+// merged. As the go tool does not measure the coverage of test files,
+// generic code that is only instantiated by tests is not included.
+//
+// Translated packages are kept in a cache, and reused when neither the
+// package nor any package that it imports has changed. The cache is in
+// the go2go subdirectory of the user's cache directory; the GO2CACHE
+// environment variable names a different directory, or may be "off"
+// to disable the cache. It is always safe to remove the directory.
+// The cache also holds the export data of imported packages. The serve
+// command, which only type checks packages, loads unchanged imported
+// packages from their export data instead of type checking them again.
+// Export data holds types but not function bodies, so the build, run,
+// test, translate and vet commands don't use it: they still parse and
+// type check every .go2 package that they import, to instantiate its
+// generic code.
+//
+// The doc command shows documentation for a package with .go2 files,
+// or for a symbol in such a package, much as "go doc" does. It reads the
+// .go2 files rather than the translated code, so declarations are shown
+// with their type parameters and constraints, as in
+//
+//	go2go doc orderedmap.New
+//
+// The vet command runs the same checks as "go vet", except for those
+// that look at assembly and cgo, on the generic code as written,
+// before translation. Diagnostics refer to the .go2 files. The printf
+// check requires a verb to be appropriate for all the types in the
+// type list of a type parameter. Facts that one check records about
+// functions, such as which functions are printf wrappers, are recorded
+// for imported .go2 packages as well, but not for packages of .go files.
+//
+// The serve command runs a language server, speaking the Language
+// Server Protocol on standard input and output, for editors that support
+// it. It reports errors in the .go2 files of a package as you type, and
+// provides hover information, definitions, references, and completion.
+// Hovering over a call of a generic function, or over a generic type
+// with type arguments, shows the type arguments, explicit or inferred,
+// and the instantiated signature or type. Completion shows the fields
+// and methods of instantiated types with their instantiated types.
+// Open files are checked as edited; imported packages are read from
+// disk. References are only found in the package of the file.
+//
 // There is a sample GO2PATH in cmd/go2go/testdata/go2path. It provides
 // several packages that serve as examples of using generics, and may
 // be useful in experimenting with your own generic code.
 //
+// The generated .go files contain //line directives that refer to the
+// original .go2 files, so compiler errors, panics, stack traces and
+// the like report positions in the code that you wrote. This includes
+// the bodies of generic functions and types, which are reported at
+// their position in the package that defines them.
+//
 // Translation into standard Go requires generating Go code with mangled names.
 // The mangled names will always include Odia (Oriya) digits, such as ୦ and ୮.
 // Do not use Oriya digits in identifiers in your own code.
 //
 // Because this tool generates Go files, and because instantiated types
 // and functions need to refer to the types with which they are instantiated,
-// using function-local types as type arguments is not supported.
-// Similarly, function-local parameterized types do not work.
-// These are deficiencies of the tool, they will work as expected in
-// any complete implementation.
+// function-local types that are used as type arguments are moved to
+// package scope, with mangled names. Function-local parameterized types
+// are moved to package scope in the same way. This only works for local
+// types that refer to nothing else local to the function, except other
+// such types. Also, a local type declared in a generic function can't
+// be used as a type argument. These are deficiencies of the tool,
+// they will work as expected in any complete implementation.
+//
+// Generic functions and types are instantiated in the package that uses
+// them. When generic code refers to unexported, non-generic, package scope
+// names, the package that defines it exports them under mangled names,
+// and instantiations in other packages use those names. This does not
+// work for unexported fields and methods of non-generic types, so generic
+// function and type bodies that refer to those can't be instantiated by
+// different packages.
+//
+// A .go2 file may import "C". The tool runs cgo on the files that do, to
+// type check the package against the C declarations, and the translated
+// .go files keep the cgo preamble, so that the go command builds them as
+// usual. Generic code that refers to C names may be instantiated in other
+// packages, or in files without the preamble: the file with the preamble
+// exports those names under mangled names, much as for unexported names,
+// with a Go function wrapping each C function. Calls of C functions that
+// also return errno are not supported in such instantiations.
+//
+// With the -shared flag, an instantiation is instead emitted once, in the
+// package that defines the generic function or type, under an exported
+// mangled name, and all packages refer to that copy. Two packages that
+// use sets.Set(int) then see the same type, and can pass values of that
+// type to each other. This is only possible when the defining package can
+// refer to the type arguments: all the types they mention must be
+// predeclared, defined in the standard library, defined in the defining
+// package, or exported by a package that it imports. Other instantiations,
+// such as sets.Set(T) where T is defined by the package using it, are
+// emitted in each package as usual. Only packages found by import path
+// get shared instantiations, which they hold in the generated file
+// go2go_instances.go. The translation cache is not used with -shared.
+//
+// With the -shapes flag, instantiations of a generic function whose
+// type arguments differ only in pointer types share one copy of the
+// function, in which those type arguments are replaced by unsafe.Pointer.
+// Each instantiation is a small function that calls the shared copy,
+// passing it a dictionary of functions for the operations that depend
+// on the actual type arguments: converting a value to an interface
+// type, and calling a method of the constraint. A generic function is
+// still stamped out for each type argument when it does anything else
+// that depends on it, such as a type assertion, and generic types are
+// always stamped out. The shared copy is not inlined, so a call costs
+// an extra function call. This makes for smaller binaries and faster
+// builds when a generic function that is too large to be inlined anyway
+// is used with many pointer types; generic functions that are small
+// enough to be inlined get little or nothing from it.
+//
+// With the -methodtparams flag, methods may have their own type
+// parameters, as in
+//
+//     func (s *Set(E)) Map(type F)(f func(E) F) *Set(F)
 //
-// Similarly, generic function and type bodies that refer to unexported,
-// non-generic, names can't be instantiated by different packages.
+// Each instantiation of such a method, as in s.Map(string)(f), or
+// s.Map(f) with the type argument inferred, is translated to a function
+// that takes the receiver as its first argument. Method values and
+// method expressions of such methods are not supported, nor are calls
+// of them through an embedded field or an interface.
 //
 // Because this tool generates Go files, and because it generates type
 // and function instantiations alongside other code in the package that
 // instantiates those functions and types, and because those instantiatations
 // may refer to names in packages imported by the original generic code,
 // this tool will add imports as necessary to support the instantiations.
-// Therefore, packages that use generic code must not use top level
-// definitions whose names are the same as the names of packages imported
-// by the generic code. For example, don't write, in package scope,
+// If the name of such a package is already used by a top level definition
+// in the package that uses the generic code, as in
 //
 //     var strings = []string{"a", "b"}
 //
-// because if the generic code imports "strings", the variable name will
-// conflict with the package name, even if your code doesn't import "strings".
-// This is a deficiency of the tool, it will not be a deficiency in
-// any complete implementation.
+// the tool imports the package under a mangled name instead.
 package main
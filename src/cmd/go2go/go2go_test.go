@@ -5,6 +5,7 @@
 package main_test
 
 import (
+	"bytes"
 	"fmt"
 	"internal/testenv"
 	"io/ioutil"
@@ -12,6 +13,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
 	"sort"
 	"strings"
@@ -32,6 +34,9 @@ func testMain(m *testing.M) int {
 	defer os.RemoveAll(dir)
 	testTempDir = dir
 
+	// Don't use the user's translation cache.
+	os.Setenv("GO2CACHE", filepath.Join(dir, "go2cache"))
+
 	return m.Run()
 }
 
@@ -321,3 +326,1683 @@ func TestTransitiveGo1(t *testing.T) {
 		t.Fatalf(`error running "go2go build": %v`, err)
 	}
 }
+
+func TestModules(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	root := t.TempDir()
+	testFiles{
+		{
+			"lib/go.mod",
+			"module example.com/lib\n",
+		},
+		{
+			"lib/lib.go2",
+			`package lib; func Ident(type T)(v T) T { return v }`,
+		},
+		{
+			"hello/go.mod",
+			"module example.com/hello\nrequire example.com/lib v0.0.0\nreplace example.com/lib => ../lib\n",
+		},
+		{
+			"hello/hello.go2",
+			`package main; import ("fmt"; "example.com/lib"); func main() { fmt.Println(lib.Ident("hello")); fmt.Println(lib.Ident("world")) }`,
+		},
+	}.create(t, root)
+
+	t.Log("go2go build")
+	dir := filepath.Join(root, "src", "hello")
+	cmd := exec.Command(testGo2go, "build")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GO111MODULE=on",
+		"GOFLAGS=-mod=mod",
+		"GOPROXY=off",
+	)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Logf("%s", out)
+	}
+	if err != nil {
+		t.Fatalf(`error running "go2go build": %v`, err)
+	}
+
+	runHello(t, dir)
+
+	// The dependency module should have been translated in a copy.
+	libDir := filepath.Join(root, "src", "lib")
+	if _, err := os.Stat(filepath.Join(libDir, "lib.go")); err == nil {
+		t.Errorf("go2go build wrote translated file into dependency module %s", libDir)
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"a/a.go2",
+			`package a
+
+import "runtime"
+
+func Caller(type T)(v T) (string, int) {
+	_, file, line, _ := runtime.Caller(0)
+	return file, line
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"a"
+)
+
+func main() {
+	file, line := a.Caller(0)
+	fmt.Println(filepath.Base(file), line)
+	_, file, line, _ = runtime.Caller(0)
+	fmt.Println(filepath.Base(file), line)
+}
+`,
+		},
+	}.create(t, gopath)
+
+	t.Log("go2go run")
+	cmd := exec.Command(testGo2go, "run", "cmd.go2")
+	cmd.Dir = filepath.Join(gopath, "src", "cmd")
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Logf("%s", out)
+	}
+	if err != nil {
+		t.Fatalf(`error running "go2go run": %v`, err)
+	}
+	got := strings.Split(strings.TrimSpace(string(out)), "\n")
+	want := []string{"a.go2 6", "cmd.go2 14"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("go2go run output %v, want %v", got, want)
+	}
+}
+
+func TestLocalTypeArgs(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"a/a.go2",
+			`package a
+
+func Map(type T, U)(s []T, f func(T) U) []U {
+	r := make([]U, 0, len(s))
+	for _, v := range s {
+		r = append(r, f(v))
+	}
+	return r
+}
+
+type Box(type T) struct {
+	V T
+}
+
+func (b Box(T)) Get() T {
+	return b.V
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import (
+	"fmt"
+
+	"a"
+)
+
+func f1() {
+	type Point struct{ X, Y int }
+	ps := a.Map([]int{1, 2}, func(i int) Point { return Point{i, i} })
+	fmt.Println(ps)
+	b := a.Box(Point){Point{3, 4}}
+	fmt.Println(b.Get())
+}
+
+func f2() {
+	type Point struct{ X, Y, Z int }
+	b := a.Box(Point){Point{5, 6, 7}}
+	fmt.Println(b.Get())
+}
+
+func main() {
+	f1()
+	f2()
+}
+`,
+		},
+	}.create(t, gopath)
+
+	t.Log("go2go run")
+	cmd := exec.Command(testGo2go, "run", "cmd.go2")
+	cmd.Dir = filepath.Join(gopath, "src", "cmd")
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Logf("%s", out)
+	}
+	if err != nil {
+		t.Fatalf(`error running "go2go run": %v`, err)
+	}
+	got := strings.Split(strings.TrimSpace(string(out)), "\n")
+	want := []string{"[{1 1} {2 2}]", "{3 4}", "{5 6 7}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("go2go run output %v, want %v", got, want)
+	}
+}
+
+func TestLocalParameterizedType(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import "fmt"
+
+func main() {
+	type Pair(type K comparable, V interface{}) struct {
+		Key K
+		Val V
+	}
+	type Name string
+	p := Pair(Name, int){"a", 1}
+	q := Pair(string, []int){"b", []int{2, 3}}
+	fmt.Println(p.Key, p.Val)
+	fmt.Println(q.Key, q.Val)
+}
+`,
+		},
+	}.create(t, gopath)
+
+	t.Log("go2go run")
+	cmd := exec.Command(testGo2go, "run", "cmd.go2")
+	cmd.Dir = filepath.Join(gopath, "src", "cmd")
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Logf("%s", out)
+	}
+	if err != nil {
+		t.Fatalf(`error running "go2go run": %v`, err)
+	}
+	got := strings.Split(strings.TrimSpace(string(out)), "\n")
+	want := []string{"a 1", "b [2 3]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("go2go run output %v, want %v", got, want)
+	}
+}
+
+func TestUnexportedNames(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"list/list.go2",
+			`package list
+
+import "strconv"
+
+type List(type T) struct {
+	head *element(T)
+	len  int
+}
+
+type element(type T) struct {
+	next *element(T)
+	val  T
+}
+
+type count int
+
+const maxLen = 10
+
+var inits int
+
+func format(c count) string {
+	return strconv.Itoa(int(c))
+}
+
+func (l *List(T)) lazyInit() {
+	if l.head == nil {
+		inits++
+	}
+}
+
+func (l *List(T)) Push(v T) {
+	l.lazyInit()
+	if l.len >= maxLen {
+		panic("list full")
+	}
+	l.head = &element(T){l.head, v}
+	l.len++
+}
+
+func (l *List(T)) Len() string {
+	return format(count(l.len))
+}
+
+func Inits() int {
+	return inits
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import (
+	"fmt"
+
+	"list"
+)
+
+func main() {
+	var l list.List(string)
+	l.Push("a")
+	l.Push("b")
+	fmt.Println(l.Len(), list.Inits())
+}
+`,
+		},
+	}.create(t, gopath)
+
+	t.Log("go2go run")
+	cmd := exec.Command(testGo2go, "run", "cmd.go2")
+	cmd.Dir = filepath.Join(gopath, "src", "cmd")
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Logf("%s", out)
+	}
+	if err != nil {
+		t.Fatalf(`error running "go2go run": %v`, err)
+	}
+	got := strings.TrimSpace(string(out))
+	want := "2 1"
+	if got != want {
+		t.Errorf("go2go run output %q, want %q", got, want)
+	}
+}
+
+func TestImportCollision(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"join/join.go2",
+			`package join
+
+import (
+	"fmt"
+	"strings"
+)
+
+func Join(type T)(s []T, sep string) string {
+	var b strings.Builder
+	for i, v := range s {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(fmt.Sprint(v))
+	}
+	return b.String()
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import "join"
+
+var strings = []string{"a", "b"}
+
+func fmt(s string) string {
+	return "<" + s + ">"
+}
+
+func main() {
+	println(fmt(join.Join(strings, "-")))
+}
+`,
+		},
+		{
+			"mystr/mystr.go",
+			`package strings
+
+func Wrap(s string) string {
+	return "[" + s + "]"
+}
+`,
+		},
+		{
+			"imp/imp.go2",
+			`package main
+
+import (
+	"join"
+	"mystr"
+)
+
+func main() {
+	println(strings.Wrap(join.Join([]int{1, 2}, "-")))
+}
+`,
+		},
+	}.create(t, gopath)
+
+	for _, test := range []struct {
+		dir, file, want string
+	}{
+		{"cmd", "cmd.go2", "<a-b>"},
+		{"imp", "imp.go2", "[1-2]"},
+	} {
+		t.Logf("go2go run %s", test.file)
+		cmd := exec.Command(testGo2go, "run", test.file)
+		cmd.Dir = filepath.Join(gopath, "src", test.dir)
+		cmd.Env = append(os.Environ(),
+			"GO2PATH="+gopath,
+		)
+		out, err := cmd.CombinedOutput()
+		if len(out) > 0 {
+			t.Logf("%s", out)
+		}
+		if err != nil {
+			t.Errorf(`error running "go2go run %s": %v`, test.file, err)
+			continue
+		}
+		got := strings.TrimSpace(string(out))
+		if got != test.want {
+			t.Errorf("go2go run %s output %q, want %q", test.file, got, test.want)
+		}
+	}
+}
+
+func TestVet(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"w/w.go2",
+			`package w
+
+import "fmt"
+
+// Logf is a printf wrapper, which is only known
+// from the facts that vet records about package w.
+func Logf(format string, args ...interface{}) {
+	fmt.Printf(format, args...)
+}
+
+func Identity(type T)(v T) T {
+	return v
+}
+`,
+		},
+		{
+			"v/v.go2",
+			`package v
+
+import (
+	"fmt"
+	"sync"
+
+	"w"
+)
+
+type Integer interface {
+	type int, int64
+}
+
+func Sum(type T Integer)(s []T) T {
+	var r T
+	for _, v := range s {
+		r += v
+	}
+	fmt.Printf("%d\n", r)
+	return r
+}
+
+func Quote(type T Integer)(v T) string {
+	return fmt.Sprintf("%q %s", v, v)
+}
+
+type Locked(type T) struct {
+	mu sync.Mutex
+	v  T
+}
+
+func Get(type T)(l Locked(T)) T {
+	return l.v
+}
+
+func Log(type T Integer)(s []T, m map[string]T) {
+	fmt.Printf("%d %x %v\n", s, m, w.Identity(s))
+	w.Logf("%d", "zero")
+	w.Logf("%s", s)
+}
+`,
+		},
+	}.create(t, gopath)
+
+	t.Log("go2go vet")
+	cmd := exec.Command(testGo2go, "vet", "v")
+	cmd.Dir = gopath
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Logf("%s", out)
+	}
+	if err == nil {
+		t.Fatal(`"go2go vet" succeeded unexpectedly`)
+	}
+	got := strings.Split(strings.TrimSpace(string(out)), "\n")
+	// The type parameters are printed with subscripts that
+	// depend on the type checker, so only check a prefix.
+	want := []string{
+		"v.go2:24:9: Sprintf format %s has arg v of wrong type T",
+		"v.go2:32:20: Get passes lock by value: v.Locked(T",
+		"v.go2:38:2: Logf format %d has arg \"zero\" of wrong type string",
+		"v.go2:39:2: Logf format %s has arg s of wrong type []T",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("go2go vet reported %d diagnostics, want %d", len(got), len(want))
+	}
+	for i, line := range got {
+		if !strings.HasPrefix(filepath.Base(line), want[i]) {
+			t.Errorf("go2go vet output line %q, want prefix %q", filepath.Base(line), want[i])
+		}
+	}
+}
+
+func TestDoc(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"pair/pair.go2",
+			`// Package pair provides pairs of values.
+package pair
+
+// Ordered permits ordered types.
+type Ordered interface {
+	type int, float64, string
+}
+
+// Pair is a pair of values.
+type Pair(type K Ordered, V interface{}) struct {
+	Key K
+	val V
+}
+
+// New returns a new pair.
+func New(type K Ordered, V interface{})(k K, v V) *Pair(K, V) {
+	return &Pair(K, V){k, v}
+}
+
+// Value returns the value of the pair.
+func (p *Pair(K, V)) Value() V {
+	return p.val
+}
+`,
+		},
+	}.create(t, gopath)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			[]string{"pair"},
+			`package pair // import "pair"
+
+Package pair provides pairs of values.
+
+type Ordered interface{ ... }
+type Pair(type K Ordered, V interface{}) struct{ ... }
+    func New(type K Ordered, V interface{})(k K, v V) *Pair(K, V)
+`,
+		},
+		{
+			[]string{"pair.Ordered"},
+			`type Ordered interface {
+	type int, float64, string
+}
+    Ordered permits ordered types.
+`,
+		},
+		{
+			[]string{"pair", "Pair"},
+			`type Pair(type K Ordered, V interface{}) struct {
+	Key K
+	// contains filtered or unexported fields
+}
+    Pair is a pair of values.
+
+func New(type K Ordered, V interface{})(k K, v V) *Pair(K, V)
+func (p *Pair(K, V)) Value() V
+`,
+		},
+		{
+			[]string{"pair.Pair.Value"},
+			`func (p *Pair(K, V)) Value() V
+    Value returns the value of the pair.
+`,
+		},
+	}
+	for _, test := range tests {
+		cmd := exec.Command(testGo2go, append([]string{"doc"}, test.args...)...)
+		cmd.Dir = gopath
+		cmd.Env = append(os.Environ(),
+			"GO2PATH="+gopath,
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("go2go doc %v failed: %v\n%s", test.args, err, out)
+			continue
+		}
+		if got := string(out); got != test.want {
+			t.Errorf("go2go doc %v output:\n%s\nwant:\n%s", test.args, got, test.want)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	cache := t.TempDir()
+	testFiles{
+		{
+			"pair/pair.go2",
+			`package pair
+
+type Pair(type K, V interface{}) struct {
+	Key K
+	Val V
+}
+
+func Swap(type K, V interface{})(p Pair(K, V)) Pair(V, K) {
+	return Pair(V, K){p.Val, p.Key}
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import (
+	"fmt"
+
+	"pair"
+)
+
+func main() {
+	fmt.Println(pair.Swap(pair.Pair(int, string){1, "a"}))
+}
+`,
+		},
+	}.create(t, gopath)
+
+	run := func(want string) {
+		t.Helper()
+		cmd := exec.Command(testGo2go, "build")
+		cmd.Dir = filepath.Join(gopath, "src", "cmd")
+		cmd.Env = append(os.Environ(),
+			"GO2PATH="+gopath,
+			"GO2CACHE="+cache,
+		)
+		out, err := cmd.CombinedOutput()
+		if len(out) > 0 {
+			t.Logf("%s", out)
+		}
+		if err != nil {
+			t.Fatalf(`error running "go2go build": %v`, err)
+		}
+		out, err = exec.Command(filepath.Join(cmd.Dir, "cmd")).CombinedOutput()
+		if err != nil {
+			t.Fatalf("error running program: %v\n%s", err, out)
+		}
+		if got := strings.TrimSpace(string(out)); got != want {
+			t.Errorf("program output %q, want %q", got, want)
+		}
+	}
+
+	// entries returns the number of entries in the cache.
+	entries := func() int {
+		t.Helper()
+		matches, err := filepath.Glob(filepath.Join(cache, "*", "*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(matches)
+	}
+
+	t.Log("first build")
+	run("{a 1}")
+	if n := entries(); n != 2 {
+		t.Errorf("after first build, cache has %d entries, want 2", n)
+	}
+
+	// An unchanged build is served from the cache.
+	t.Log("second build")
+	run("{a 1}")
+	if n := entries(); n != 2 {
+		t.Errorf("after second build, cache has %d entries, want 2", n)
+	}
+
+	// Changing an imported package changes the key for both packages.
+	t.Log("build after change")
+	pairFile := filepath.Join(gopath, "src", "pair", "pair.go2")
+	data, err := ioutil.ReadFile(pairFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte("{p.Val, p.Key}"), []byte("{Key: p.Val}"), 1)
+	if err := ioutil.WriteFile(pairFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("{a 0}")
+	if n := entries(); n != 4 {
+		t.Errorf("after changed build, cache has %d entries, want 4", n)
+	}
+}
+
+func TestShared(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"set/set.go2",
+			`package set
+
+type Set(type E comparable) struct {
+	m map[E]bool
+}
+
+func New(type E comparable)(vs ...E) Set(E) {
+	s := Set(E){make(map[E]bool)}
+	for _, v := range vs {
+		s.m[v] = true
+	}
+	return s
+}
+
+func (s Set(E)) Len() int {
+	return len(s.m)
+}
+
+func Union(type E comparable)(a, b Set(E)) Set(E) {
+	s := New(E)()
+	for v := range a.m {
+		s.m[v] = true
+	}
+	for v := range b.m {
+		s.m[v] = true
+	}
+	return s
+}
+`,
+		},
+		{
+			"evens/evens.go2",
+			`package evens
+
+import (
+	"set"
+	"time"
+)
+
+func Ints() set.Set(int) {
+	return set.New(0, 2, 4)
+}
+
+func Durations() set.Set(time.Duration) {
+	return set.New(time.Second)
+}
+`,
+		},
+		{
+			"odds/odds.go2",
+			`package odds
+
+import "set"
+
+func Ints() set.Set(int) {
+	return set.New(1, 3)
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import (
+	"fmt"
+	"time"
+
+	"evens"
+	"odds"
+	"set"
+)
+
+type point struct{ x, y int }
+
+func main() {
+	var s set.Set(int) = set.Union(evens.Ints(), odds.Ints())
+	d := set.Union(evens.Durations(), set.New(time.Minute))
+	p := set.New(point{1, 2})
+	fmt.Println(s.Len(), d.Len(), p.Len())
+}
+`,
+		},
+	}.create(t, gopath)
+
+	t.Log("go2go -shared run")
+	cmd := exec.Command(testGo2go, "-shared", "run", "cmd.go2")
+	cmd.Dir = filepath.Join(gopath, "src", "cmd")
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Logf("%s", out)
+	}
+	if err != nil {
+		t.Fatalf(`error running "go2go -shared run": %v`, err)
+	}
+	got := strings.TrimSpace(string(out))
+	want := "5 2 1"
+	if got != want {
+		t.Errorf("go2go -shared run output %q, want %q", got, want)
+	}
+}
+
+func TestShapes(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"slices/slices.go2",
+			`package slices
+
+import "fmt"
+
+type Stringer interface {
+	String() string
+}
+
+func Index(type T comparable)(s []T, v T) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func Filter(type T)(s []T, keep func(T) bool) []T {
+	var r []T
+	for _, v := range s {
+		if keep(v) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+func Join(type T Stringer)(s []T) string {
+	r := ""
+	for _, v := range s {
+		r += v.String()
+	}
+	return r
+}
+
+func Types(type T)(vs ...T) string {
+	return fmt.Sprintf("%T", vs[0])
+}
+
+func Is(type T, U)(v U) bool {
+	_, ok := interface{}(v).(T)
+	return ok
+}
+
+func Counts(type T comparable)(s []T) map[T]int {
+	m := make(map[T]int)
+	for _, v := range s {
+		m[v]++
+	}
+	return m
+}
+
+func ByIndex(type T)(s []T) map[int]T {
+	m := make(map[int]T)
+	for i, v := range s {
+		m[i] = v
+	}
+	return m
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import (
+	"fmt"
+
+	"slices"
+)
+
+type A struct{ n int }
+
+func (a *A) String() string { return fmt.Sprint("a", a.n) }
+
+type B struct{ n int }
+
+func (b *B) String() string { return fmt.Sprint("b", b.n) }
+
+func main() {
+	as := []*A{{1}, {2}}
+	bs := []*B{{3}, {4}}
+	fmt.Println(slices.Index(as, as[1]), slices.Index(bs, bs[0]), slices.Index([]int{5}, 5))
+	fmt.Println(slices.Join(slices.Filter(as, func(a *A) bool { return a.n > 1 })), slices.Join(bs))
+	fmt.Println(slices.Types(as...), slices.Types(bs[0]))
+	fmt.Println(slices.Is(*A, *A)(as[0]), slices.Is(*A, *B)(bs[0]))
+	fmt.Println(slices.Counts(as)[as[0]], slices.Counts(bs)[bs[1]], slices.ByIndex(as)[1] == as[1], slices.ByIndex(bs)[0] == bs[0])
+}
+`,
+		},
+	}.create(t, gopath)
+
+	dir := filepath.Join(gopath, "src", "cmd")
+	go2go := func(args ...string) string {
+		t.Logf("go2go %s", strings.Join(args, " "))
+		cmd := exec.Command(testGo2go, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GO2PATH="+gopath,
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Logf("%s", out)
+			t.Fatalf(`error running "go2go %s": %v`, strings.Join(args, " "), err)
+		}
+		return string(out)
+	}
+
+	want := go2go("run", "cmd.go2")
+	if got := go2go("-shapes", "run", "cmd.go2"); got != want {
+		t.Errorf("go2go -shapes run output %q, want %q", got, want)
+	}
+
+	go2go("-shapes", "translate", "cmd.go2")
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cmd.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, fn := range []string{"Index", "Filter", "Join", "Types", "ByIndex"} {
+		// One shared copy for *A and *B.
+		if n := strings.Count(got, "func shape୦slices୦"+fn+"୦"); n != 1 {
+			t.Errorf("found %d shape instantiations of %s, want 1", n, fn)
+		}
+		if !regexp.MustCompile(`//go:noinline\n(//line .*\n)?func shape୦slices୦` + fn + "୦").MatchString(got) {
+			t.Errorf("shape instantiation of %s is not marked go:noinline", fn)
+		}
+	}
+	if strings.Contains(got, "func shape୦slices୦Is୦") {
+		t.Errorf("found shape instantiation of Is, which uses a type assertion")
+	}
+	if strings.Contains(got, "func shape୦slices୦Counts୦") {
+		t.Errorf("found shape instantiation of Counts, which uses a map keyed by its type parameter")
+	}
+}
+
+// TestShapesSize compares the size of a program that instantiates
+// generic functions with many pointer types, built with and without
+// grouping instantiations by shape.
+func TestShapesSize(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	const ntypes = 40
+	var types, calls strings.Builder
+	for i := 0; i < ntypes; i++ {
+		fmt.Fprintf(&types, "type T%d struct{ n int }\n\n", i)
+		fmt.Fprintf(&calls, "\tvar s%d []*T%d\n", i, i)
+		fmt.Fprintf(&calls, "\ts%d = slices.Insert(s%d, 0, &T%d{%d}, nil)\n", i, i, i, i)
+		fmt.Fprintf(&calls, "\tslices.Sort(s%d, func(a, b *T%d) bool { return a == nil || b != nil && a.n < b.n })\n", i, i)
+		fmt.Fprintf(&calls, "\tn += slices.Index(slices.Compact(slices.Reverse(s%d)), nil)\n", i)
+	}
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"slices/slices.go2",
+			`package slices
+
+func Insert(type T)(s []T, i int, vs ...T) []T {
+	if n := len(s) + len(vs); n <= cap(s) {
+		s2 := s[:n]
+		copy(s2[i+len(vs):], s[i:])
+		copy(s2[i:], vs)
+		return s2
+	}
+	s2 := make([]T, len(s)+len(vs))
+	copy(s2, s[:i])
+	copy(s2[i:], vs)
+	copy(s2[i+len(vs):], s[i:])
+	return s2
+}
+
+// Sort is a heapsort, too large to be inlined.
+func Sort(type T)(s []T, less func(T, T) bool) {
+	for i := len(s)/2 - 1; i >= 0; i-- {
+		siftDown(s, i, len(s), less)
+	}
+	for i := len(s) - 1; i > 0; i-- {
+		s[0], s[i] = s[i], s[0]
+		siftDown(s, 0, i, less)
+	}
+}
+
+func siftDown(type T)(s []T, root, n int, less func(T, T) bool) {
+	for {
+		child := 2*root + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && less(s[child], s[child+1]) {
+			child++
+		}
+		if !less(s[root], s[child]) {
+			return
+		}
+		s[root], s[child] = s[child], s[root]
+		root = child
+	}
+}
+
+func Reverse(type T)(s []T) []T {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+	return s
+}
+
+func Compact(type T comparable)(s []T) []T {
+	if len(s) < 2 {
+		return s
+	}
+	i := 1
+	for k := 1; k < len(s); k++ {
+		if s[k] != s[k-1] {
+			if i != k {
+				s[i] = s[k]
+			}
+			i++
+		}
+	}
+	return s[:i]
+}
+
+func Index(type T comparable)(s []T, v T) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			"package main\n\nimport \"slices\"\n\n" + types.String() +
+				"func main() {\n\tn := 0\n" + calls.String() + "\tprintln(n)\n}\n",
+		},
+	}.create(t, gopath)
+
+	dir := filepath.Join(gopath, "src", "cmd")
+	var sizes [2]int64
+	for i, flags := range [][]string{nil, {"-shapes"}} {
+		exe := filepath.Join(gopath, fmt.Sprintf("cmd%d.exe", i))
+		args := append(flags, "build", "-o", exe)
+		cmd := exec.Command(testGo2go, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GO2PATH="+gopath,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Logf("%s", out)
+			t.Fatalf(`error running "go2go %s": %v`, strings.Join(args, " "), err)
+		}
+		fi, err := os.Stat(exe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes[i] = fi.Size()
+	}
+	t.Logf("%d pointer types: %d bytes stamped out, %d bytes by shape (%.1f%%)", ntypes, sizes[0], sizes[1], 100*float64(sizes[1]-sizes[0])/float64(sizes[0]))
+	if sizes[1] >= sizes[0] {
+		t.Errorf("program built with -shapes is %d bytes, not smaller than %d bytes without", sizes[1], sizes[0])
+	}
+}
+
+func TestMethodTypeParams(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"sets/sets.go2",
+			`package sets
+
+type Set(type E comparable) struct {
+	m map[E]struct{}
+}
+
+func Make(type E comparable)(elems ...E) *Set(E) {
+	s := &Set(E){m: make(map[E]struct{})}
+	for _, e := range elems {
+		s.Add(e)
+	}
+	return s
+}
+
+func (s *Set(E)) Add(e E) { s.m[e] = struct{}{} }
+
+func (s *Set(E)) Len() int { return len(s.m) }
+
+func (s *Set(E)) Contains(e E) bool {
+	_, ok := s.m[e]
+	return ok
+}
+
+func (s *Set(E)) Map(type F comparable)(f func(E) F) *Set(F) {
+	r := Make(F)()
+	for e := range s.m {
+		r.Add(f(e))
+	}
+	return r
+}
+
+func (s Set(E)) Fold(type A)(a A, f func(A, E) A) A {
+	for e := range s.m {
+		a = f(a, e)
+	}
+	return a
+}
+
+func Count(type E comparable)(s *Set(E)) int {
+	return s.Fold(0, func(n int, _ E) int { return n + 1 })
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"sets"
+)
+
+type Ints []int
+
+func (is Ints) Apply(type T)(f func(int) T) []T {
+	var r []T
+	for _, i := range is {
+		r = append(r, f(i))
+	}
+	return r
+}
+
+func main() {
+	s := sets.Make(1, 2, 3)
+	t := s.Map(string)(strconv.Itoa)
+	fmt.Println(t.Len(), t.Contains("2"))
+	u := t.Map(func(s string) int { return len(s) })
+	fmt.Println(u.Len(), u.Contains(1))
+	fmt.Println(s.Fold(0, func(a, e int) int { return a + e }))
+	v := *t
+	fmt.Println(v.Fold(int)(0, func(a int, e string) int { return a + len(e) }))
+	fmt.Println(v.Map(func(s string) string { return s + s }).Contains("11"))
+	fmt.Println(sets.Count(u), Ints{1, 2}.Apply(strconv.Itoa))
+}
+`,
+		},
+	}.create(t, gopath)
+
+	dir := filepath.Join(gopath, "src", "cmd")
+	cmd := exec.Command(testGo2go, "run", "cmd.go2")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Fatalf("go2go run succeeded without -methodtparams:\n%s", out)
+	} else if !strings.Contains(string(out), "methods cannot have type parameters") {
+		t.Logf("%s", out)
+		t.Fatalf(`unexpected error running "go2go run": %v`, err)
+	}
+
+	cmd = exec.Command(testGo2go, "-methodtparams", "run", "cmd.go2")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Logf("%s", out)
+		t.Fatalf(`error running "go2go -methodtparams run": %v`, err)
+	}
+	got := string(out)
+	want := "3 true\n1 true\n6\n3\ntrue\n1 [1 2]\n"
+	if got != want {
+		t.Errorf("go2go -methodtparams run output %q, want %q", got, want)
+	}
+}
+
+func TestCoverProfile(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"slices/slices.go2",
+			`package slices
+
+func Filter(type T)(s []T, f func(T) bool) []T {
+	var r []T
+	for _, v := range s {
+		if f(v) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+`,
+		},
+		{
+			"stats/stats.go2",
+			`package stats
+
+import "slices"
+
+func Max(type T interface{ type int, float64 })(s []T) T {
+	var m T
+	for i, v := range s {
+		if i == 0 || v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+func IntMax(s []int) int {
+	if len(s) == 0 {
+		return -1
+	}
+	return Max(s)
+}
+
+func FloatMax(s []float64) float64 {
+	return Max(s)
+}
+
+func Positive(s []float64) []float64 {
+	return slices.Filter(s, func(v float64) bool { return v > 0 })
+}
+`,
+		},
+		{
+			"stats/stats_test.go2",
+			`package stats
+
+import "testing"
+
+func TestMax(t *testing.T) {
+	if got := IntMax([]int{1, 3, 2}); got != 3 {
+		t.Errorf("IntMax = %d, want 3", got)
+	}
+	if got := FloatMax(Positive([]float64{-1, 1})); got != 1 {
+		t.Errorf("FloatMax = %v, want 1", got)
+	}
+}
+`,
+		},
+	}.create(t, gopath)
+
+	profile := filepath.Join(gopath, "cover.out")
+	cmd := exec.Command(testGo2go, "test", "-coverprofile", profile, "stats")
+	cmd.Dir = gopath
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Logf("%s", out)
+		t.Fatalf(`error running "go2go test -coverprofile": %v`, err)
+	}
+
+	data, err := ioutil.ReadFile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s", data)
+	blocks := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n")[1:] {
+		f := strings.Fields(line)
+		if len(f) != 3 {
+			t.Fatalf("bad profile line %q", line)
+		}
+		if !strings.HasSuffix(strings.SplitN(f[0], ":", 2)[0], ".go2") {
+			t.Errorf("profile line %q does not refer to a .go2 file", line)
+		}
+		if _, dup := blocks[f[0]]; dup {
+			t.Errorf("block %s appears more than once", f[0])
+		}
+		blocks[f[0]] = f[2]
+	}
+	for _, want := range []struct{ block, count string }{
+		// Both instantiations of Max.
+		{"stats/stats.go2:5.58,7.22", "1"},
+		// The early return in IntMax.
+		{"stats/stats.go2:16.17,18.3", "0"},
+		// The instantiation of Filter.
+		{"slices/slices.go2:3.48,5.22", "1"},
+	} {
+		if got, ok := blocks[want.block]; !ok {
+			t.Errorf("no block %s in profile", want.block)
+		} else if got != want.count {
+			t.Errorf("count of block %s is %s, want %s", want.block, got, want.count)
+		}
+	}
+}
+
+func TestCgo(t *testing.T) {
+	testenv.MustHaveCGO(t)
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"cbuf/cbuf.go2",
+			`package cbuf
+
+// #include <stdlib.h>
+// #define LIMIT 7
+// int counter = 3;
+// static int twice(int x) { return 2 * x; }
+import "C"
+
+import "unsafe"
+
+type Number interface {
+	type int, int64, float64
+}
+
+type Buffer(type T) struct {
+	p   unsafe.Pointer
+	len int
+}
+
+func New(type T)(n int) *Buffer(T) {
+	var zero T
+	p := C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof(zero)))
+	return &Buffer(T){p: p, len: n}
+}
+
+func (b *Buffer(T)) Slice() []T {
+	return (*[1 << 28]T)(b.p)[:b.len:b.len]
+}
+
+func (b *Buffer(T)) Free() {
+	C.free(b.p)
+}
+
+func Limit(type T Number)() T {
+	return T(C.LIMIT)
+}
+
+func Next(type T Number)() T {
+	C.counter++
+	return T(C.counter)
+}
+
+func Twice(x int) int {
+	return int(C.twice(C.int(x)))
+}
+`,
+		},
+		{
+			"cbuf/floats.go2",
+			`package cbuf
+
+func Floats(n int) *Buffer(float64) {
+	return New(float64)(n)
+}
+`,
+		},
+		{
+			"cbuf/cbuf_test.go2",
+			`package cbuf
+
+import "testing"
+
+func TestBuffer(t *testing.T) {
+	b := New(int16)(3)
+	defer b.Free()
+	if got := len(b.Slice()); got != 3 {
+		t.Errorf("len = %d, want 3", got)
+	}
+	if got := Limit(int)(); got != 7 {
+		t.Errorf("Limit = %d, want 7", got)
+	}
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import (
+	"cbuf"
+	"fmt"
+)
+
+func main() {
+	b := cbuf.New(int32)(4)
+	s := b.Slice()
+	for i := range s {
+		s[i] = int32(i * i)
+	}
+	fmt.Println(s, cbuf.Twice(21))
+	b.Free()
+
+	f := cbuf.Floats(2)
+	fmt.Println(len(f.Slice()), cbuf.Limit(float64)(), cbuf.Next(int)(), cbuf.Next(int64)())
+	f.Free()
+}
+`,
+		},
+	}.create(t, gopath)
+
+	cmd := exec.Command(testGo2go, "run", "cmd.go2")
+	cmd.Dir = filepath.Join(gopath, "src", "cmd")
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Logf("%s", out)
+		t.Fatalf(`error running "go2go run": %v`, err)
+	}
+	got := string(out)
+	want := "[0 1 4 9] 42\n2 7 4 5\n"
+	if got != want {
+		t.Errorf("go2go run output %q, want %q", got, want)
+	}
+
+	cmd = exec.Command(testGo2go, "test", "cbuf")
+	cmd.Dir = gopath
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Logf("%s", out)
+		t.Fatalf(`error running "go2go test": %v`, err)
+	}
+}
+
+func TestMixedFiles(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	testFiles{
+		{
+			"stack/stack.go2",
+			`package stack
+
+type Stack(type T) struct {
+	s []T
+}
+
+func (s *Stack(T)) Push(v T) {
+	s.s = append(s.s, v)
+}
+
+func (s *Stack(T)) Pop() T {
+	v := s.s[len(s.s)-1]
+	s.s = s.s[:len(s.s)-1]
+	return v
+}
+
+func Sum() int {
+	var s Stack(int)
+	for _, v := range legacyValues() {
+		s.Push(double(v))
+	}
+	return s.Pop() + s.Pop()
+}
+`,
+		},
+		{
+			"stack/legacy.go",
+			`package stack
+
+// legacyValues is hand-written Go code.
+func legacyValues() []int {
+	return []int{1, 2}
+}
+`,
+		},
+		{
+			"stack/double_amd64.go",
+			`package stack
+
+func double(x int) int
+`,
+		},
+		{
+			"stack/double_amd64.s",
+			`#include "textflag.h"
+
+TEXT ·double(SB),NOSPLIT,$0-16
+	MOVQ x+0(FP), AX
+	ADDQ AX, AX
+	MOVQ AX, ret+8(FP)
+	RET
+`,
+		},
+		{
+			"stack/double_other.go",
+			`// +build !amd64
+
+package stack
+
+func double(x int) int {
+	return x + x
+}
+`,
+		},
+		{
+			"cmd/cmd.go2",
+			`package main
+
+import (
+	"fmt"
+
+	"stack"
+)
+
+func main() {
+	fmt.Println(stack.Sum())
+}
+`,
+		},
+	}.create(t, gopath)
+
+	run := func() ([]byte, error) {
+		cmd := exec.Command(testGo2go, "run", "cmd.go2")
+		cmd.Dir = filepath.Join(gopath, "src", "cmd")
+		cmd.Env = append(os.Environ(),
+			"GO2PATH="+gopath,
+			"GO2CACHE=off",
+		)
+		return cmd.CombinedOutput()
+	}
+
+	t.Log("go2go run")
+	out, err := run()
+	if len(out) > 0 {
+		t.Logf("%s", out)
+	}
+	if err != nil {
+		t.Fatalf(`error running "go2go run": %v`, err)
+	}
+	if got, want := strings.TrimSpace(string(out)), "6"; got != want {
+		t.Errorf("go2go run output %q, want %q", got, want)
+	}
+
+	// Hand-written .go files are not translated,
+	// so they may not use generic code.
+	t.Log("go2go run with generic code in .go file")
+	testFiles{
+		{
+			"stack/bad.go",
+			`package stack
+
+func bad() Stack(string) {
+	return Stack(string){}
+}
+`,
+		},
+	}.create(t, gopath)
+	out, err = run()
+	if err == nil {
+		t.Fatalf("go2go run succeeded unexpectedly:\n%s", out)
+	}
+	want := "bad.go:3:12: hand-written .go file uses generic Stack"
+	if !strings.Contains(string(out), want) {
+		t.Errorf("go2go run output %q, want %q", out, want)
+	}
+}
+
+func TestOutputRoot(t *testing.T) {
+	t.Parallel()
+	buildGo2go(t)
+
+	gopath := t.TempDir()
+	outroot := t.TempDir()
+	testFiles{
+		{
+			"pair/pair.go2",
+			`package pair; type Pair(type T interface{}) struct { A, B T }; func (p Pair(T)) Swap() Pair(T) { return Pair(T){p.B, p.A} }`,
+		},
+		{
+			"cmd/swap/swap.go2",
+			`package main; import ("fmt"; "pair"); func main() { fmt.Println(pair.Pair(int){1, 2}.Swap()) }`,
+		},
+	}.create(t, gopath)
+
+	t.Log("go2go -outroot build")
+	cmd := exec.Command(testGo2go, "-outroot", outroot, "build")
+	cmd.Dir = filepath.Join(gopath, "src", "cmd", "swap")
+	cmd.Env = append(os.Environ(),
+		"GO2PATH="+gopath,
+	)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Logf("%s", out)
+	}
+	if err != nil {
+		t.Fatalf(`error running "go2go -outroot build": %v`, err)
+	}
+
+	// Nothing should have been written to the source tree.
+	err = filepath.Walk(gopath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Ext(path) != ".go2" {
+			t.Errorf("go2go -outroot wrote %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both packages are translated under the root.
+	for _, file := range []string{"src/pair/pair.go", "src/cmd/swap/swap.go"} {
+		if _, err := os.Stat(filepath.Join(outroot, file)); err != nil {
+			t.Errorf("missing translated file: %v", err)
+		}
+	}
+
+	out, err = exec.Command(filepath.Join(outroot, "src", "cmd", "swap", "swap")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("error running program: %v\n%s", err, out)
+	}
+	if got, want := strings.TrimSpace(string(out)), "{2 1}"; got != want {
+		t.Errorf("program output %q, want %q", got, want)
+	}
+}
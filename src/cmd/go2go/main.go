@@ -7,6 +7,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"go/build"
 	"go/go2go"
 	"io/ioutil"
 	"log"
@@ -19,14 +20,30 @@ import (
 
 var gotool = filepath.Join(runtime.GOROOT(), "bin", "go")
 
+var sharedFlag = flag.Bool("shared", false, "share instantiations between packages")
+
+var shapesFlag = flag.Bool("shapes", false, "share instantiations of generic functions by shape")
+
+var methodTParamsFlag = flag.Bool("methodtparams", false, "accept methods with type parameters")
+
+var outrootFlag = flag.String("outroot", "", "write translated packages under this `directory`")
+
 var cmds = map[string]bool{
 	"build":     true,
+	"doc":       true,
 	"run":       true,
+	"serve":     true,
 	"test":      true,
 	"translate": true,
+	"vet":       true,
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == coverToolexecArg {
+		coverToolexec(os.Args[2:])
+		return
+	}
+
 	flag.Usage = usage
 	flag.Parse()
 
@@ -46,51 +63,232 @@ func main() {
 	defer os.RemoveAll(importerTmpdir)
 
 	importer := go2go.NewImporter(importerTmpdir)
+	gopathRoot := importerTmpdir
+	if *outrootFlag != "" {
+		if err := importer.SetOutputRoot(*outrootFlag); err != nil {
+			die(err.Error())
+		}
+		gopathRoot, err = filepath.Abs(*outrootFlag)
+		if err != nil {
+			die(err.Error())
+		}
+	}
+	gomod := goEnv("GOMOD")
+	modMode := gomod != "" && gomod != os.DevNull
+	configureImporter(importer, gomod)
+
+	if args[0] == "serve" {
+		runServe(func() *go2go.Importer {
+			importer := go2go.NewImporter(importerTmpdir)
+			configureImporter(importer, gomod)
+			importer.UseExportData()
+			return importer
+		})
+		return
+	}
+
+	if args[0] == "doc" {
+		runDoc(importer, modMode, args[1:])
+		return
+	}
+
+	if args[0] == "vet" {
+		if n := vet(importer, expandPackages(importer, modMode, args[1:])); n > 0 {
+			os.Exit(1)
+		}
+		return
+	}
 
 	var rundir string
 	if args[0] == "run" {
 		tmpdir := copyToTmpdir(args[1:])
 		defer os.RemoveAll(tmpdir)
+		if srcdir, ok := commonDir(args[1:]); ok {
+			importer.SetSourceDir(tmpdir, srcdir)
+		}
 		translate(importer, tmpdir)
+		outdir := outputDir(importer, tmpdir)
+		if outdir != tmpdir {
+			defer os.RemoveAll(outdir)
+		}
 		nargs := []string{"run"}
 		for _, arg := range args[1:] {
 			base := filepath.Base(arg)
 			f := strings.TrimSuffix(base, ".go2") + ".go"
+			if modMode {
+				// Run in the current directory, so that
+				// imports are resolved using the main module.
+				f = filepath.Join(outdir, f)
+			}
 			nargs = append(nargs, f)
 		}
 		args = nargs
-		rundir = tmpdir
+		if !modMode {
+			rundir = outdir
+		}
 	} else if args[0] == "translate" && isGo2Files(args[1:]...) {
 		for _, arg := range args[1:] {
 			translateFile(importer, arg)
 		}
 	} else {
-		for _, dir := range expandPackages(args[1:]) {
+		pkgs := args[1:]
+		if args[0] == "build" || args[0] == "test" {
+			pkgs = packageArgs(pkgs)
+		}
+		for _, dir := range expandPackages(importer, modMode, pkgs) {
 			translate(importer, dir)
 		}
 	}
 
 	if args[0] != "translate" {
-		cmd := exec.Command(gotool, args...)
+		if *outrootFlag != "" && rundir == "" {
+			// Run the go tool in the copy of the current
+			// directory, so that relative package paths,
+			// and in module mode the main module, refer
+			// to the translated copies.
+			rundir = outputDir(importer, ".")
+		}
+		var goFlags []string
+		if modMode {
+			if repl := importer.ModuleReplacements(); len(repl) > 0 || *outrootFlag != "" {
+				modfile := writeModfile(gomod, importerTmpdir, repl)
+				goFlags = append(goFlags, "-modfile="+modfile)
+			}
+		}
+		goCmd := func(args ...string) *exec.Cmd {
+			args = append(append([]string{args[0]}, goFlags...), args[1:]...)
+			cmd := exec.Command(gotool, args...)
+			cmd.Stderr = os.Stderr
+			cmd.Dir = rundir
+			if !modMode {
+				gopath := gopathRoot
+				if go2path := os.Getenv("GO2PATH"); go2path != "" {
+					gopath += string(os.PathListSeparator) + go2path
+				}
+				if oldGopath := os.Getenv("GOPATH"); oldGopath != "" {
+					gopath += string(os.PathListSeparator) + oldGopath
+				}
+				cmd.Env = append(os.Environ(),
+					"GOPATH="+gopath,
+					"GO111MODULE=off",
+				)
+			}
+			return cmd
+		}
+
+		var profile string
+		var coverPkgs []string
+		if args[0] == "test" {
+			args, profile, coverPkgs = coverArgs(args)
+		}
+		cmd := goCmd(args...)
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Dir = rundir
-		gopath := importerTmpdir
-		if go2path := os.Getenv("GO2PATH"); go2path != "" {
-			gopath += string(os.PathListSeparator) + go2path
+		err := cmd.Run()
+		if profile != "" {
+			if _, serr := os.Stat(profile); serr == nil {
+				pkgs := append(packageArgs(args[1:]), coverPkgs...)
+				if err := rewriteCoverProfile(importer, profile, goCmd, pkgs); err != nil {
+					die(err.Error())
+				}
+			}
+		}
+		if err != nil {
+			die(fmt.Sprintf("%s %v failed: %v", gotool, cmd.Args[1:], err))
+		}
+	}
+}
+
+// configureImporter sets up importer as the command line flags ask.
+// In module mode, gomod is the go.mod file of the main module.
+func configureImporter(importer *go2go.Importer, gomod string) {
+	if *shapesFlag {
+		importer.UseShapes()
+	}
+	if *methodTParamsFlag {
+		importer.AcceptMethodTypeParams()
+	}
+	if *sharedFlag {
+		importer.ShareInstantiations()
+	} else if dir := cacheDir(); dir != "" {
+		if err := importer.UseCache(dir); err != nil {
+			die(err.Error())
 		}
-		if oldGopath := os.Getenv("GOPATH"); oldGopath != "" {
-			gopath += string(os.PathListSeparator) + oldGopath
+	}
+	if gomod != "" && gomod != os.DevNull {
+		importer.UseModules(filepath.Dir(gomod))
+	}
+}
+
+// goFlagsWithValue are the flags of "go build" and "go test",
+// including those passed on to the test binary, that take a value,
+// which may be given as a separate argument.
+var goFlagsWithValue = map[string]bool{
+	"asmflags":             true,
+	"bench":                true,
+	"benchtime":            true,
+	"blockprofile":         true,
+	"blockprofilerate":     true,
+	"compiler":             true,
+	"count":                true,
+	"covermode":            true,
+	"coverpkg":             true,
+	"coverprofile":         true,
+	"cpu":                  true,
+	"cpuprofile":           true,
+	"exec":                 true,
+	"gccgoflags":           true,
+	"gcflags":              true,
+	"installsuffix":        true,
+	"ldflags":              true,
+	"list":                 true,
+	"memprofile":           true,
+	"memprofilerate":       true,
+	"mod":                  true,
+	"modfile":              true,
+	"mutexprofile":         true,
+	"mutexprofilefraction": true,
+	"o":                    true,
+	"outputdir":            true,
+	"p":                    true,
+	"parallel":             true,
+	"pkgdir":               true,
+	"run":                  true,
+	"tags":                 true,
+	"timeout":              true,
+	"toolexec":             true,
+	"trace":                true,
+	"vet":                  true,
+}
+
+// packageArgs returns the package arguments of a "go build" or
+// "go test" command line, leaving out flags and their values.
+func packageArgs(args []string) []string {
+	var pkgs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-args" || arg == "--args" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			pkgs = append(pkgs, arg)
+			continue
 		}
-		cmd.Env = append(os.Environ(),
-			"GOPATH="+gopath,
-			"GO111MODULE=off",
-		)
-		if err := cmd.Run(); err != nil {
-			die(fmt.Sprintf("%s %v failed: %v", gotool, args, err))
+		name := strings.TrimLeft(arg, "-")
+		if !strings.Contains(name, "=") && goFlagsWithValue[name] {
+			i++
 		}
 	}
+	return pkgs
+}
+
+// outputDir returns the directory holding the translation of dir.
+func outputDir(importer *go2go.Importer, dir string) string {
+	odir, err := importer.OutputDir(dir)
+	if err != nil {
+		die(err.Error())
+	}
+	return odir
 }
 
 // isGo2Files reports whether the arguments are a list of .go2 files.
@@ -103,11 +301,43 @@ func isGo2Files(args ...string) bool {
 	return true
 }
 
+// cacheDir returns the directory of the translation cache,
+// or the empty string if the cache should not be used.
+// It is set by the GO2CACHE environment variable, which may be
+// "off" to disable the cache, and defaults to a go2go directory
+// in the user's cache directory.
+func cacheDir() string {
+	dir := os.Getenv("GO2CACHE")
+	if dir == "off" {
+		return ""
+	}
+	if dir == "" {
+		ucd, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(ucd, "go2go")
+	}
+	return dir
+}
+
+// goEnv returns the value of a go tool environment variable.
+func goEnv(name string) string {
+	out, err := exec.Command(gotool, "env", name).Output()
+	if err != nil {
+		die(fmt.Sprintf("%s env %s failed: %v", gotool, name, err))
+	}
+	return strings.TrimSpace(string(out))
+}
+
 // expandPackages returns a list of directories expanded from packages.
-func expandPackages(pkgs []string) []string {
+func expandPackages(importer *go2go.Importer, modMode bool, pkgs []string) []string {
 	if len(pkgs) == 0 {
 		return []string{"."}
 	}
+	if modMode {
+		return expandModulePackages(importer, pkgs)
+	}
 	go2path := os.Getenv("GO2PATH")
 	var dirs []string
 pkgloop:
@@ -143,6 +373,27 @@ pkgloop:
 	return dirs
 }
 
+// expandModulePackages returns a list of directories expanded from
+// packages in module mode. The go tool doesn't consider a directory
+// with only .go2 files to be a package, so we can't use "go list".
+// Local paths are used as is; other paths are looked up in the
+// modules of the build list.
+func expandModulePackages(importer *go2go.Importer, pkgs []string) []string {
+	var dirs []string
+	for _, pkg := range pkgs {
+		if build.IsLocalImport(pkg) || filepath.IsAbs(pkg) {
+			dirs = append(dirs, pkg)
+			continue
+		}
+		dir, err := importer.ModulePackageDir(pkg)
+		if err != nil {
+			die(err.Error())
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
 // copyToTmpdir copies files into a temporary directory.
 func copyToTmpdir(files []string) string {
 	if len(files) == 0 {
@@ -164,16 +415,52 @@ func copyToTmpdir(files []string) string {
 	return tmpdir
 }
 
+// commonDir returns the absolute directory holding all the files,
+// and reports whether there is one.
+func commonDir(files []string) (string, bool) {
+	var dir string
+	for i, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return "", false
+		}
+		if i == 0 {
+			dir = filepath.Dir(abs)
+		} else if filepath.Dir(abs) != dir {
+			return "", false
+		}
+	}
+	return dir, dir != ""
+}
+
 // usage reports a usage message and exits with failure.
 func usage() {
-	fmt.Fprint(os.Stderr, `Usage: go2go <command> [arguments]
+	fmt.Fprint(os.Stderr, `Usage: go2go [-shared] [-shapes] [-methodtparams] [-outroot dir] <command> [arguments]
 
 The commands are:
 
 	build      translate and build packages
+	doc        show documentation for package or symbol
 	run        translate and run list of files
+	serve      run a language server for .go2 files
 	test       translate and test packages
 	translate  translate .go2 files into .go files
+	vet        report likely mistakes in packages
+
+The -shared flag emits each instantiation of a generic function or type
+in the package that defines it, where possible, rather than in each
+package that uses it.
+
+The -shapes flag shares one copy of a generic function between its
+instantiations with pointer type arguments, where possible, rather
+than stamping out a copy for each type argument.
+
+The -methodtparams flag accepts methods with type parameters. Each
+instantiation of such a method is translated to a function that takes
+the receiver as its first argument.
+
+The -outroot flag writes translated packages under the given directory,
+laid out like a GOPATH, rather than next to their .go2 files.
 `)
 	os.Exit(2)
 }
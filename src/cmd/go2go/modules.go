@@ -0,0 +1,88 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/mod/modfile"
+)
+
+// writeModfile writes a copy of the main module's go.mod file into dir,
+// replacing each module in replacements with the directory holding
+// its translated copy. It returns the name of the new file,
+// which is passed to the go tool using the -modfile option.
+// Relative directory replacements are made absolute, as the go tool
+// may be run in a copy of the main module, see the -outroot flag.
+func writeModfile(gomod, dir string, replacements map[string]string) string {
+	data, err := ioutil.ReadFile(gomod)
+	if err != nil {
+		die(err.Error())
+	}
+	f, err := modfile.Parse(gomod, data, nil)
+	if err != nil {
+		die(err.Error())
+	}
+
+	for _, r := range f.Replace {
+		if r.New.Version != "" || filepath.IsAbs(r.New.Path) || !modfile.IsDirectoryPath(r.New.Path) {
+			continue
+		}
+		abs := filepath.Join(filepath.Dir(gomod), filepath.FromSlash(r.New.Path))
+		if err := f.AddReplace(r.Old.Path, r.Old.Version, abs, ""); err != nil {
+			die(err.Error())
+		}
+	}
+
+	paths := make([]string, 0, len(replacements))
+	for path := range replacements {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		// Drop any existing replacement, as the go tool
+		// rejects conflicting replacements.
+		var versions []string
+		for _, r := range f.Replace {
+			if r.Old.Path == path {
+				versions = append(versions, r.Old.Version)
+			}
+		}
+		for _, v := range versions {
+			if err := f.DropReplace(path, v); err != nil {
+				die(err.Error())
+			}
+		}
+		if err := f.AddReplace(path, "", replacements[path], ""); err != nil {
+			die(err.Error())
+		}
+	}
+	f.Cleanup()
+	out, err := f.Format()
+	if err != nil {
+		die(err.Error())
+	}
+
+	newmod := filepath.Join(dir, "go2go.mod")
+	if err := ioutil.WriteFile(newmod, out, 0644); err != nil {
+		die(err.Error())
+	}
+
+	// The go tool expects the go.sum file to sit next to the
+	// -modfile file, with a .sum extension.
+	sum, err := ioutil.ReadFile(filepath.Join(filepath.Dir(gomod), "go.sum"))
+	if err != nil && !os.IsNotExist(err) {
+		die(err.Error())
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go2go.sum"), sum, 0644); err != nil {
+		die(err.Error())
+	}
+
+	return newmod
+}
@@ -0,0 +1,365 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/format"
+	"go/go2go"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// docArgs splits the arguments of "go2go doc" into a package
+// and a symbol, which may be empty. The forms are as for "go doc":
+//
+//	go2go doc
+//	go2go doc <pkg>
+//	go2go doc <sym>[.<method>]
+//	go2go doc <pkg>.<sym>[.<method>]
+//	go2go doc <pkg> <sym>[.<method>]
+func docArgs(args []string) (pkg, sym string) {
+	switch len(args) {
+	case 0:
+		return ".", ""
+	case 1:
+		arg := args[0]
+		if build.IsLocalImport(arg) || filepath.IsAbs(arg) {
+			return arg, ""
+		}
+		slash := strings.LastIndex(arg, "/")
+		if dot := strings.Index(arg[slash+1:], "."); dot >= 0 {
+			return arg[:slash+1+dot], arg[slash+1+dot+1:]
+		}
+		if r, _ := utf8.DecodeRuneInString(arg); slash < 0 && unicode.IsUpper(r) {
+			return ".", arg
+		}
+		return arg, ""
+	case 2:
+		return args[0], args[1]
+	}
+	usage()
+	return "", ""
+}
+
+// pkgDoc prints the documentation for the package in dir, or for the
+// symbol sym in that package if sym is not empty. Only the .go2 files
+// are read, and they are not translated, so the documentation shows
+// the type parameters as written.
+func pkgDoc(w io.Writer, importPath, dir, sym string) error {
+	fset := token.NewFileSet()
+	names, err := filepath.Glob(filepath.Join(dir, "*.go2"))
+	if err != nil {
+		return err
+	}
+	var files []*ast.File
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go2") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		if len(files) > 0 && f.Name.Name != files[0].Name.Name {
+			return fmt.Errorf("found packages %s and %s in %s", files[0].Name.Name, f.Name.Name, dir)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no .go2 files in %s", dir)
+	}
+	dpkg, err := doc.NewFromFiles(fset, files, importPath)
+	if err != nil {
+		return err
+	}
+
+	p := &docPrinter{w: w, fset: fset, pkg: dpkg}
+	if sym == "" {
+		p.packageDoc()
+	} else if !p.symbolDoc(sym) {
+		return fmt.Errorf("no symbol %s in package %s", sym, importPath)
+	}
+	return p.err
+}
+
+// A docPrinter prints documentation in the format used by "go doc".
+type docPrinter struct {
+	w    io.Writer
+	fset *token.FileSet
+	pkg  *doc.Package
+	err  error
+}
+
+// printf writes formatted output, recording the first error.
+func (p *docPrinter) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+// node prints the source of an AST node followed by a newline.
+func (p *docPrinter) node(n interface{}) {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, p.fset, n); err != nil {
+		if p.err == nil {
+			p.err = err
+		}
+		return
+	}
+	p.printf("%s\n", buf.Bytes())
+}
+
+// text prints documentation text indented by four spaces.
+func (p *docPrinter) text(text string) {
+	if text == "" {
+		return
+	}
+	var buf bytes.Buffer
+	doc.ToText(&buf, text, "    ", "\t", 80-4)
+	p.printf("%s", buf.Bytes())
+}
+
+// packageDoc prints the package comment and a summary of the
+// exported declarations of the package.
+func (p *docPrinter) packageDoc() {
+	p.printf("package %s // import %q\n\n", p.pkg.Name, p.pkg.ImportPath)
+	if p.pkg.Doc != "" {
+		var buf bytes.Buffer
+		doc.ToText(&buf, p.pkg.Doc, "", "    ", 80)
+		p.printf("%s\n", buf.Bytes())
+	}
+	for _, v := range p.pkg.Consts {
+		p.printf("%s\n", p.oneLine(v.Decl))
+	}
+	for _, v := range p.pkg.Vars {
+		p.printf("%s\n", p.oneLine(v.Decl))
+	}
+	for _, f := range p.pkg.Funcs {
+		p.printf("%s\n", p.oneLine(f.Decl))
+	}
+	for _, t := range p.pkg.Types {
+		p.printf("%s\n", p.oneLine(t.Decl))
+		for _, v := range t.Consts {
+			p.printf("    %s\n", p.oneLine(v.Decl))
+		}
+		for _, v := range t.Vars {
+			p.printf("    %s\n", p.oneLine(v.Decl))
+		}
+		for _, f := range t.Funcs {
+			p.printf("    %s\n", p.oneLine(f.Decl))
+		}
+	}
+}
+
+// symbolDoc prints the documentation for sym, which is the name
+// of a package level declaration or of a method, as Type.Method.
+// It reports whether the symbol was found.
+func (p *docPrinter) symbolDoc(sym string) bool {
+	if dot := strings.Index(sym, "."); dot >= 0 {
+		return p.methodDoc(sym[:dot], sym[dot+1:])
+	}
+	for _, values := range [][]*doc.Value{p.pkg.Consts, p.pkg.Vars} {
+		for _, v := range values {
+			if contains(v.Names, sym) {
+				p.valueDoc(v)
+				return true
+			}
+		}
+	}
+	for _, f := range p.pkg.Funcs {
+		if f.Name == sym {
+			p.funcDoc(f)
+			return true
+		}
+	}
+	for _, t := range p.pkg.Types {
+		for _, values := range [][]*doc.Value{t.Consts, t.Vars} {
+			for _, v := range values {
+				if contains(v.Names, sym) {
+					p.valueDoc(v)
+					return true
+				}
+			}
+		}
+		for _, f := range t.Funcs {
+			if f.Name == sym {
+				p.funcDoc(f)
+				return true
+			}
+		}
+		if t.Name == sym {
+			p.typeDoc(t)
+			return true
+		}
+	}
+	return false
+}
+
+// methodDoc prints the documentation for the method name of
+// the type typ, and reports whether it was found.
+func (p *docPrinter) methodDoc(typ, name string) bool {
+	for _, t := range p.pkg.Types {
+		if t.Name != typ {
+			continue
+		}
+		for _, m := range t.Methods {
+			if m.Name == name {
+				p.funcDoc(m)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// valueDoc prints the declaration and documentation of
+// a group of constants or variables.
+func (p *docPrinter) valueDoc(v *doc.Value) {
+	decl := *v.Decl
+	decl.Doc = nil
+	p.node(&decl)
+	p.text(v.Doc)
+}
+
+// funcDoc prints the declaration and documentation of a function
+// or method, including any type parameters.
+func (p *docPrinter) funcDoc(f *doc.Func) {
+	decl := *f.Decl
+	decl.Doc = nil
+	decl.Body = nil
+	p.node(&decl)
+	p.text(f.Doc)
+}
+
+// typeDoc prints the declaration and documentation of a type,
+// followed by its associated constants, variables, functions
+// and methods. A constraint interface is shown with its type list.
+func (p *docPrinter) typeDoc(t *doc.Type) {
+	decl := *t.Decl
+	decl.Doc = nil
+	if len(decl.Specs) > 1 {
+		// Only show the spec for this type.
+		for _, spec := range decl.Specs {
+			if ts := spec.(*ast.TypeSpec); ts.Name.Name == t.Name {
+				ts := *ts
+				ts.Doc = nil
+				decl.Specs = []ast.Spec{&ts}
+				decl.Lparen, decl.Rparen = token.NoPos, token.NoPos
+				break
+			}
+		}
+	}
+	p.node(&decl)
+	p.text(t.Doc)
+
+	if len(t.Consts)+len(t.Vars)+len(t.Funcs)+len(t.Methods) > 0 {
+		p.printf("\n")
+	}
+	for _, values := range [][]*doc.Value{t.Consts, t.Vars} {
+		for _, v := range values {
+			p.printf("%s\n", p.oneLine(v.Decl))
+		}
+	}
+	for _, f := range t.Funcs {
+		p.printf("%s\n", p.oneLine(f.Decl))
+	}
+	for _, m := range t.Methods {
+		p.printf("%s\n", p.oneLine(m.Decl))
+	}
+}
+
+// oneLine returns a one-line summary of a declaration:
+// functions without their bodies, and struct and interface
+// types without their fields and methods.
+func (p *docPrinter) oneLine(decl ast.Decl) string {
+	var n interface{}
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		fd := *d
+		fd.Doc = nil
+		fd.Body = nil
+		n = &fd
+	case *ast.GenDecl:
+		if len(d.Specs) == 0 {
+			return ""
+		}
+		switch s := d.Specs[0].(type) {
+		case *ast.TypeSpec:
+			ts := *s
+			ts.Doc, ts.Comment = nil, nil
+			switch ts.Type.(type) {
+			case *ast.StructType:
+				ts.Type = ast.NewIdent("struct{ ... }")
+			case *ast.InterfaceType:
+				ts.Type = ast.NewIdent("interface{ ... }")
+			}
+			n = &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{&ts}}
+		case *ast.ValueSpec:
+			more := len(d.Specs) > 1 || len(s.Names) > 1
+			vs := *s
+			vs.Doc, vs.Comment = nil, nil
+			vs.Names = vs.Names[:1]
+			if len(vs.Values) > 0 {
+				vs.Values = []ast.Expr{ast.NewIdent("...")}
+			}
+			var buf bytes.Buffer
+			if err := format.Node(&buf, p.fset, &ast.GenDecl{Tok: d.Tok, Specs: []ast.Spec{&vs}}); err != nil {
+				return err.Error()
+			}
+			if more {
+				buf.WriteString(" ...")
+			}
+			return buf.String()
+		}
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, p.fset, n); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// runDoc implements "go2go doc".
+func runDoc(importer *go2go.Importer, modMode bool, args []string) {
+	pkg, sym := docArgs(args)
+	dir, importPath := pkg, pkg
+	if build.IsLocalImport(pkg) || filepath.IsAbs(pkg) {
+		abs, err := filepath.Abs(pkg)
+		if err != nil {
+			die(err.Error())
+		}
+		importPath = filepath.Base(abs)
+	} else {
+		dirs := expandPackages(importer, modMode, []string{pkg})
+		if len(dirs) == 0 || dirs[0] == "" {
+			die(fmt.Sprintf("cannot find package %q", pkg))
+		}
+		dir = dirs[0]
+	}
+	if err := pkgDoc(os.Stdout, importPath, dir, sym); err != nil {
+		die(err.Error())
+	}
+}
@@ -0,0 +1,1007 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/go2go"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// "go2go serve" is a language server for .go2 files. It type checks
+// the package holding an open file, using the contents of the open
+// files rather than what is on disk, and answers requests from that.
+// Imported packages are read from disk, translated by a new
+// go2go.Importer for each check, so that edits to them are seen once
+// they are saved. Imported .go2 packages that are unchanged since
+// they were translated are loaded from the export data in the cache.
+// References are only found within the package.
+
+// A server is a language server for .go2 files.
+type server struct {
+	conn *conn
+
+	// newImporter returns the importer to use for a check.
+	newImporter func() *go2go.Importer
+
+	// Whether methods may have type parameters.
+	methodTParams bool
+
+	// Map from file name to the contents of an open file.
+	open map[string][]byte
+
+	// Map from directory to the last check of the
+	// packages in it; cleared when any file changes.
+	checked map[string][]*checkedPackage
+
+	// Whether we got a shutdown request.
+	shutdown bool
+}
+
+// A checkedPackage is a package that has been parsed and type checked.
+// Only the files of the package are recorded, not those of the
+// external test package or of the package under test, even though
+// they are checked together.
+type checkedPackage struct {
+	fset  *token.FileSet
+	files map[string]*ast.File // by file name
+	pkg   *types.Package
+	info  *types.Info
+	diags map[string][]fileDiagnostic // by file name
+}
+
+// A fileDiagnostic is an error in a file.
+type fileDiagnostic struct {
+	pos token.Position
+	msg string
+}
+
+// serve runs a language server that reads requests from r
+// and writes responses to w, until it gets an exit notification
+// or r is closed.
+func serve(r io.Reader, w io.Writer, newImporter func() *go2go.Importer, methodTParams bool) error {
+	s := &server{
+		conn:          newConn(r, w),
+		newImporter:   newImporter,
+		methodTParams: methodTParams,
+		open:          make(map[string][]byte),
+		checked:       make(map[string][]*checkedPackage),
+	}
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit without shutdown")
+			}
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			// A notification; there is no one to tell
+			// about errors.
+			continue
+		}
+		if err := s.conn.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles a request or notification, returning
+// the result of a request.
+func (s *server) handle(msg *message) (interface{}, error) {
+	if s.shutdown && msg.ID != nil {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+	switch msg.Method {
+	case "initialize":
+		var r initializeResult
+		r.Capabilities.TextDocumentSync.OpenClose = true
+		r.Capabilities.TextDocumentSync.Change = syncFull
+		r.Capabilities.TextDocumentSync.Save = true
+		r.Capabilities.HoverProvider = true
+		r.Capabilities.DefinitionProvider = true
+		r.Capabilities.ReferencesProvider = true
+		r.Capabilities.CompletionProvider.TriggerCharacters = []string{"."}
+		r.ServerInfo.Name = "go2go"
+		return &r, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := unmarshalParams(msg, &p); err != nil {
+			return nil, err
+		}
+		filename, err := uriFilename(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		s.open[filename] = []byte(p.TextDocument.Text)
+		s.changed()
+		return nil, s.publishDiagnostics(filename)
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := unmarshalParams(msg, &p); err != nil {
+			return nil, err
+		}
+		filename, err := uriFilename(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		src := s.open[filename]
+		for _, c := range p.ContentChanges {
+			if c.Range == nil {
+				src = []byte(c.Text)
+				continue
+			}
+			start := byteOffset(src, c.Range.Start)
+			end := byteOffset(src, c.Range.End)
+			src = append(append(append([]byte(nil), src[:start]...), c.Text...), src[end:]...)
+		}
+		s.open[filename] = src
+		s.changed()
+		return nil, s.publishDiagnostics(filename)
+	case "textDocument/didSave":
+		var p didCloseParams
+		if err := unmarshalParams(msg, &p); err != nil {
+			return nil, err
+		}
+		filename, err := uriFilename(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		s.changed()
+		return nil, s.publishDiagnostics(filename)
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := unmarshalParams(msg, &p); err != nil {
+			return nil, err
+		}
+		filename, err := uriFilename(p.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		delete(s.open, filename)
+		s.changed()
+		return nil, nil
+
+	case "textDocument/hover":
+		var p positionParams
+		if err := unmarshalParams(msg, &p); err != nil {
+			return nil, err
+		}
+		return s.hover(p)
+	case "textDocument/definition":
+		var p positionParams
+		if err := unmarshalParams(msg, &p); err != nil {
+			return nil, err
+		}
+		return s.definition(p)
+	case "textDocument/references":
+		var p referenceParams
+		if err := unmarshalParams(msg, &p); err != nil {
+			return nil, err
+		}
+		return s.references(p)
+	case "textDocument/completion":
+		var p positionParams
+		if err := unmarshalParams(msg, &p); err != nil {
+			return nil, err
+		}
+		return s.completion(p)
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+}
+
+// unmarshalParams decodes the parameters of msg into p.
+func unmarshalParams(msg *message, p interface{}) error {
+	if err := json.Unmarshal(msg.Params, p); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// uriFilename returns the file name for a file URI.
+func uriFilename(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	if u.Scheme != "file" {
+		return "", &responseError{Code: codeInvalidParams, Message: "not a file URI: " + uri}
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// filenameURI returns the file URI for a file name.
+func filenameURI(filename string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}
+	return u.String()
+}
+
+// changed records that the contents of a file have changed.
+func (s *server) changed() {
+	s.checked = make(map[string][]*checkedPackage)
+}
+
+// contents returns the contents of a file, using the
+// contents of the open file if it is open.
+func (s *server) contents(filename string) ([]byte, error) {
+	if src, ok := s.open[filename]; ok {
+		return src, nil
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// check returns the package holding filename, type checked.
+func (s *server) check(filename string) (*checkedPackage, error) {
+	dir := filepath.Dir(filename)
+	pkgs, ok := s.checked[dir]
+	if !ok {
+		var err error
+		pkgs, err = s.checkDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		s.checked[dir] = pkgs
+	}
+	for _, pkg := range pkgs {
+		if _, ok := pkg.files[filename]; ok {
+			return pkg, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not in a package in %s", filename, dir)
+}
+
+// checkDir parses and type checks the .go2 files in dir, and any
+// hand-written .go files. There may be a package and its external
+// test package. Errors in the files are recorded, not returned.
+func (s *server) checkDir(dir string) ([]*checkedPackage, error) {
+	names, err := s.packageFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	importer := s.newImporter()
+	fset := importer.FileSet()
+	byName := make(map[string]*checkedPackage)
+	var pkgNames []string
+	for _, name := range names {
+		src, err := s.contents(name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, name, src, parser.AllErrors|parser.ParseComments)
+		if f == nil {
+			return nil, err
+		}
+		cp, ok := byName[f.Name.Name]
+		if !ok {
+			cp = &checkedPackage{
+				fset:  fset,
+				files: make(map[string]*ast.File),
+				diags: make(map[string][]fileDiagnostic),
+			}
+			byName[f.Name.Name] = cp
+			pkgNames = append(pkgNames, f.Name.Name)
+		}
+		cp.files[name] = f
+		if list, ok := err.(scanner.ErrorList); ok {
+			for _, e := range list {
+				cp.diags[name] = append(cp.diags[name], fileDiagnostic{e.Pos, e.Msg})
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// Check the package before its external test package,
+	// which may import it.
+	sort.Strings(pkgNames)
+	var pkgs []*checkedPackage
+	for _, name := range pkgNames {
+		cp := byName[name]
+		var files []*ast.File
+		for _, n := range names {
+			if f, ok := cp.files[n]; ok {
+				files = append(files, f)
+			}
+		}
+		cp.info = &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Inferred:   make(map[*ast.CallExpr]types.Inferred),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+		}
+		conf := types.Config{
+			Importer:    importer,
+			FakeImportC: true,
+			Error: func(err error) {
+				if terr, ok := err.(types.Error); ok {
+					pos := terr.Fset.Position(terr.Pos)
+					cp.diags[pos.Filename] = append(cp.diags[pos.Filename], fileDiagnostic{pos, terr.Msg})
+				}
+			},
+			AcceptMethodTypeParams: s.methodTParams,
+		}
+		// Errors are reported to conf.Error.
+		cp.pkg, _ = conf.Check(name, fset, files, cp.info)
+		pkgs = append(pkgs, cp)
+	}
+	return pkgs, nil
+}
+
+// packageFiles returns the names of the .go2 files in dir, and of
+// the .go files that are not generated by go2go, along with any such
+// files that are open but not yet saved.
+func (s *server) packageFiles(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, fi := range fis {
+		name := filepath.Join(dir, fi.Name())
+		switch filepath.Ext(name) {
+		case ".go2":
+			add(name)
+		case ".go":
+			src, err := s.contents(name)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.HasPrefix(src, []byte(generatedHeader)) {
+				add(name)
+			}
+		}
+	}
+	for name := range s.open {
+		if filepath.Dir(name) == dir && filepath.Ext(name) == ".go2" {
+			add(name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// publishDiagnostics publishes the errors in the files of the
+// package that holds filename. Files without errors get an empty
+// list, which clears any errors reported before.
+func (s *server) publishDiagnostics(filename string) error {
+	if filepath.Ext(filename) != ".go2" && filepath.Ext(filename) != ".go" {
+		return nil
+	}
+	cp, err := s.check(filename)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name := range cp.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		src, err := s.contents(name)
+		if err != nil {
+			return err
+		}
+		diags := []diagnostic{}
+		for _, d := range cp.diags[name] {
+			diags = append(diags, diagnostic{
+				Range:    wordRange(src, d.pos.Offset),
+				Severity: severityError,
+				Source:   "go2go",
+				Message:  d.msg,
+			})
+		}
+		if err := s.conn.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+			URI:         filenameURI(name),
+			Diagnostics: diags,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wordRange returns the range of the identifier or other token
+// that starts at offset in src, or an empty range at offset
+// if there isn't one.
+func wordRange(src []byte, offset int) lspRange {
+	end := offset
+	for end < len(src) {
+		r, size := utf8.DecodeRune(src[end:])
+		if !isIdentRune(r) {
+			break
+		}
+		end += size
+	}
+	return lspRange{lspPosition(src, offset), lspPosition(src, end)}
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// pathAt returns the nodes of f that enclose pos,
+// innermost first.
+func pathAt(f *ast.File, pos token.Pos) []ast.Node {
+	var path []ast.Node
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || pos > n.End() {
+			return false
+		}
+		path = append(path, n)
+		return true
+	})
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// identAt returns the identifier at the position in a file,
+// along with the nodes that enclose it, innermost first.
+func (s *server) identAt(p positionParams) (*checkedPackage, *ast.Ident, []ast.Node, error) {
+	filename, err := uriFilename(p.TextDocument.URI)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cp, err := s.check(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	src, err := s.contents(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	f := cp.files[filename]
+	pos := cp.fset.File(f.Package).Pos(byteOffset(src, p.Position))
+	path := pathAt(f, pos)
+	if len(path) == 0 {
+		return cp, nil, nil, nil
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return cp, nil, nil, nil
+	}
+	return cp, id, path, nil
+}
+
+// objectOf returns the object that id denotes.
+func (cp *checkedPackage) objectOf(id *ast.Ident) types.Object {
+	if obj := cp.info.Uses[id]; obj != nil {
+		return obj
+	}
+	return cp.info.Defs[id]
+}
+
+// objectString returns the declaration of obj. Unlike
+// types.ObjectString, it shows the type parameters of a generic
+// type, and it omits the subscripts that go/types adds to the names
+// of type parameters to tell them apart.
+func (cp *checkedPackage) objectString(obj types.Object) string {
+	var s string
+	if isGenericObject(obj) {
+		if _, ok := obj.(*types.TypeName); ok {
+			s = "type " + types.TypeString(obj.Type(), cp.qualifier) + " " + types.TypeString(obj.Type().Underlying(), cp.qualifier)
+		}
+	}
+	if s == "" {
+		s = types.ObjectString(obj, cp.qualifier)
+	}
+	return stripSubscripts(s)
+}
+
+// stripSubscripts removes subscript digits from s.
+func stripSubscripts(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '₀' && r <= '₉' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// qualifier qualifies names of packages other than cp's by
+// their package name.
+func (cp *checkedPackage) qualifier(pkg *types.Package) string {
+	if pkg == cp.pkg {
+		return ""
+	}
+	return pkg.Name()
+}
+
+// hover returns the declaration of the object at a position.
+// If the object is a generic function or type that is instantiated
+// there, either with explicit type arguments or by inference,
+// it also shows the instantiation.
+func (s *server) hover(p positionParams) (interface{}, error) {
+	cp, id, path, err := s.identAt(p)
+	if err != nil || id == nil {
+		return nil, err
+	}
+	obj := cp.objectOf(id)
+	if obj == nil {
+		return nil, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("```go\n")
+	sb.WriteString(cp.objectString(obj))
+	sb.WriteString("\n```")
+	if inst := cp.instantiation(id, path); inst != "" {
+		sb.WriteString("\n\ninstantiated as\n\n```go\n")
+		sb.WriteString(stripSubscripts(inst))
+		sb.WriteString("\n```")
+	}
+
+	src, err := s.contents(cp.fset.Position(id.Pos()).Filename)
+	if err != nil {
+		return nil, err
+	}
+	start := cp.fset.Position(id.Pos()).Offset
+	r := lspRange{lspPosition(src, start), lspPosition(src, start+len(id.Name))}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: sb.String()},
+		Range:    &r,
+	}, nil
+}
+
+// instantiation returns a description of the instantiation of the
+// generic function, method, or type that id, with enclosing nodes
+// path, refers to, or "" if there is none.
+func (cp *checkedPackage) instantiation(id *ast.Ident, path []ast.Node) string {
+	var fun ast.Expr = id
+	i := 1
+	if i < len(path) {
+		if sel, ok := path[i].(*ast.SelectorExpr); ok && sel.Sel == id {
+			fun = sel
+			i++
+		}
+	}
+	for i < len(path) {
+		paren, ok := path[i].(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		fun = paren
+		i++
+	}
+	if sel, ok := fun.(*ast.SelectorExpr); ok {
+		if m := cp.methodInstantiation(sel); m != "" {
+			return m
+		}
+	}
+	if i >= len(path) {
+		return ""
+	}
+	call, ok := path[i].(*ast.CallExpr)
+	if !ok || call.Fun != fun {
+		return ""
+	}
+	if inf, ok := cp.info.Inferred[call]; ok {
+		return cp.typeArgs(inf.Targs) + types.TypeString(inf.Sig, cp.qualifier)
+	}
+
+	// An explicit instantiation, as in F(int) or Set(int).
+	obj := cp.objectOf(id)
+	if !isGenericObject(obj) {
+		return ""
+	}
+	tv, ok := cp.info.Types[call]
+	if !ok {
+		return ""
+	}
+	if tv.IsType() {
+		return types.TypeString(tv.Type, cp.qualifier) + " " + types.TypeString(tv.Type.Underlying(), cp.qualifier)
+	}
+	if _, ok := tv.Type.(*types.Signature); ok {
+		var targs []types.Type
+		for _, arg := range call.Args {
+			targs = append(targs, cp.info.Types[arg].Type)
+		}
+		return cp.typeArgs(targs) + types.TypeString(tv.Type, cp.qualifier)
+	}
+	return ""
+}
+
+// methodInstantiation returns the signature of the method that sel
+// selects, if the method is declared on a generic type and selected
+// from an instantiation of it, or "" if it isn't.
+func (cp *checkedPackage) methodInstantiation(sel *ast.SelectorExpr) string {
+	selection, ok := cp.info.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return ""
+	}
+	recv := selection.Recv()
+	if p, ok := recv.(*types.Pointer); ok {
+		recv = p.Elem()
+	}
+	named, ok := recv.(*types.Named)
+	if !ok || len(named.TArgs()) == 0 {
+		return ""
+	}
+	return cp.typeArgs(named.TArgs()) + "func (" + types.TypeString(selection.Recv(), cp.qualifier) + ")." +
+		sel.Sel.Name + strings.TrimPrefix(types.TypeString(selection.Type(), cp.qualifier), "func")
+}
+
+// typeArgs returns a comment that lists type arguments.
+func (cp *checkedPackage) typeArgs(targs []types.Type) string {
+	var sb strings.Builder
+	sb.WriteString("// type arguments: ")
+	for i, targ := range targs {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(types.TypeString(targ, cp.qualifier))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// isGenericObject reports whether obj is a generic function,
+// method, or type.
+func isGenericObject(obj types.Object) bool {
+	switch obj := obj.(type) {
+	case *types.Func:
+		sig, ok := obj.Type().(*types.Signature)
+		return ok && len(sig.TParams()) > 0
+	case *types.TypeName:
+		named, ok := obj.Type().(*types.Named)
+		return ok && len(named.TParams()) > 0 && len(named.TArgs()) == 0
+	}
+	return false
+}
+
+// definition returns the location of the declaration of
+// the object at a position.
+func (s *server) definition(p positionParams) (interface{}, error) {
+	cp, id, _, err := s.identAt(p)
+	if err != nil || id == nil {
+		return nil, err
+	}
+	obj := cp.objectOf(id)
+	if obj == nil || !obj.Pos().IsValid() {
+		return nil, nil
+	}
+	loc, err := s.location(cp.fset, obj.Pos(), len(obj.Name()))
+	if err != nil {
+		return nil, nil
+	}
+	return []location{loc}, nil
+}
+
+// location returns the location of n bytes at pos.
+func (s *server) location(fset *token.FileSet, pos token.Pos, n int) (location, error) {
+	position := fset.Position(pos)
+	src, err := s.contents(position.Filename)
+	if err != nil {
+		return location{}, err
+	}
+	return location{
+		URI: filenameURI(position.Filename),
+		Range: lspRange{
+			Start: lspPosition(src, position.Offset),
+			End:   lspPosition(src, position.Offset+n),
+		},
+	}, nil
+}
+
+// references returns the locations of the references in the
+// package to the object at a position. The objects for the fields
+// and methods of an instantiated type are not those of the generic
+// type, so objects are compared by their declaration.
+func (s *server) references(p referenceParams) (interface{}, error) {
+	cp, id, _, err := s.identAt(p.positionParams)
+	if err != nil || id == nil {
+		return nil, err
+	}
+	obj := cp.objectOf(id)
+	if obj == nil || !obj.Pos().IsValid() {
+		return nil, nil
+	}
+	same := func(o types.Object) bool {
+		return o != nil && o.Pos() == obj.Pos() && o.Name() == obj.Name()
+	}
+
+	var ids []*ast.Ident
+	for id, o := range cp.info.Uses {
+		if same(o) {
+			ids = append(ids, id)
+		}
+	}
+	if p.Context.IncludeDeclaration {
+		for id, o := range cp.info.Defs {
+			if same(o) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Pos() < ids[j].Pos()
+	})
+
+	locs := []location{}
+	for _, id := range ids {
+		loc, err := s.location(cp.fset, id.Pos(), len(id.Name))
+		if err != nil {
+			return nil, err
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+// completion returns the completions at a position: the fields and
+// methods of a value, or the members of a package, after a period,
+// and otherwise the names in scope. The details of the fields and
+// methods of an instantiated type show their instantiated types.
+func (s *server) completion(p positionParams) (interface{}, error) {
+	filename, err := uriFilename(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := s.check(filename)
+	if err != nil {
+		return nil, err
+	}
+	src, err := s.contents(filename)
+	if err != nil {
+		return nil, err
+	}
+	f := cp.files[filename]
+	tf := cp.fset.File(f.Package)
+
+	offset := byteOffset(src, p.Position)
+	start := offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRune(src[:start])
+		if !isIdentRune(r) {
+			break
+		}
+		start -= size
+	}
+	prefix := string(src[start:offset])
+
+	var items []completionItem
+	if start > 0 && src[start-1] == '.' {
+		items = cp.memberCompletions(f, tf.Pos(start-1), prefix)
+	} else {
+		items = cp.scopeCompletions(tf.Pos(start), prefix)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return &completionList{Items: items}, nil
+}
+
+// memberCompletions returns the completions starting with prefix
+// for the selector expression in f whose period is at dot.
+func (cp *checkedPackage) memberCompletions(f *ast.File, dot token.Pos, prefix string) []completionItem {
+	var sel *ast.SelectorExpr
+	ast.Inspect(f, func(n ast.Node) bool {
+		if se, ok := n.(*ast.SelectorExpr); ok && se.X.End() == dot {
+			sel = se
+		}
+		return sel == nil
+	})
+	if sel == nil {
+		return nil
+	}
+
+	items := []completionItem{}
+	if id, ok := sel.X.(*ast.Ident); ok {
+		if pn, ok := cp.info.Uses[id].(*types.PkgName); ok {
+			scope := pn.Imported().Scope()
+			for _, name := range scope.Names() {
+				obj := scope.Lookup(name)
+				if obj.Exported() && strings.HasPrefix(name, prefix) {
+					items = append(items, cp.completionItem(obj, ""))
+				}
+			}
+			return items
+		}
+	}
+
+	tv, ok := cp.info.Types[sel.X]
+	if !ok || tv.Type == nil {
+		return nil
+	}
+	var xsrc bytes.Buffer
+	if err := format.Node(&xsrc, cp.fset, sel.X); err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	add := func(obj types.Object) {
+		name := obj.Name()
+		if seen[name] || !strings.HasPrefix(name, prefix) || !obj.Exported() && obj.Pkg() != cp.pkg {
+			return
+		}
+		seen[name] = true
+		// Evaluate the selector, to get the type of the
+		// field or method of the instantiated type.
+		detail := ""
+		if etv, err := types.Eval(cp.fset, cp.pkg, sel.Pos(), xsrc.String()+"."+name); err == nil && etv.Type != nil {
+			detail = stripSubscripts(types.TypeString(etv.Type, cp.qualifier))
+		}
+		items = append(items, cp.completionItem(obj, detail))
+	}
+
+	typ := tv.Type
+	if tp, ok := typ.(*types.TypeParam); ok {
+		typ = tp.Bound()
+	}
+	if !tv.IsType() {
+		for _, f := range fields(typ) {
+			add(f)
+		}
+	}
+	mset := types.NewMethodSet(typ)
+	if _, isPtr := typ.Underlying().(*types.Pointer); !isPtr && !types.IsInterface(typ) && !tv.IsType() {
+		mset = types.NewMethodSet(types.NewPointer(typ))
+	}
+	for i := 0; i < mset.Len(); i++ {
+		add(mset.At(i).Obj())
+	}
+	return items
+}
+
+// fields returns the fields of a struct type, or of the type that
+// a pointer type points to, including promoted fields. A field
+// hides the fields of the same name at greater depth.
+func fields(typ types.Type) []*types.Var {
+	var r []*types.Var
+	seen := make(map[string]bool)
+	visited := make(map[types.Type]bool)
+	level := []types.Type{typ}
+	for len(level) > 0 {
+		var next []types.Type
+		var found []*types.Var
+		for _, t := range level {
+			if p, ok := t.Underlying().(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if visited[t] {
+				continue
+			}
+			visited[t] = true
+			st, ok := t.Underlying().(*types.Struct)
+			if !ok {
+				continue
+			}
+			for i := 0; i < st.NumFields(); i++ {
+				f := st.Field(i)
+				if !seen[f.Name()] {
+					found = append(found, f)
+				}
+				if f.Embedded() {
+					next = append(next, f.Type())
+				}
+			}
+		}
+		for _, f := range found {
+			seen[f.Name()] = true
+			r = append(r, f)
+		}
+		level = next
+	}
+	return r
+}
+
+// scopeCompletions returns the completions starting with prefix
+// for the names in scope at pos.
+func (cp *checkedPackage) scopeCompletions(pos token.Pos, prefix string) []completionItem {
+	items := []completionItem{}
+	seen := make(map[string]bool)
+	scope := cp.pkg.Scope().Innermost(pos)
+	if scope == nil {
+		scope = cp.pkg.Scope()
+	}
+	for ; scope != nil; scope = scope.Parent() {
+		local := scope != cp.pkg.Scope() && scope != types.Universe && scope.Parent() != types.Universe
+		for _, name := range scope.Names() {
+			if seen[name] || !strings.HasPrefix(name, prefix) || name == "_" {
+				continue
+			}
+			obj := scope.Lookup(name)
+			if local && obj.Pos() > pos && !isTypeParamName(obj) {
+				// Not yet declared.
+				continue
+			}
+			seen[name] = true
+			items = append(items, cp.completionItem(obj, ""))
+		}
+	}
+	return items
+}
+
+// isTypeParamName reports whether obj is the name of a type parameter.
+func isTypeParamName(obj types.Object) bool {
+	_, ok := obj.Type().(*types.TypeParam)
+	_, isTypeName := obj.(*types.TypeName)
+	return ok && isTypeName
+}
+
+// completionItem returns the completion item for obj. If the detail
+// is empty, it is derived from obj.
+func (cp *checkedPackage) completionItem(obj types.Object, detail string) completionItem {
+	item := completionItem{Label: obj.Name(), Detail: detail}
+	switch obj := obj.(type) {
+	case *types.Func:
+		item.Kind = completionFunction
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			item.Kind = completionMethod
+		}
+	case *types.Var:
+		item.Kind = completionVariable
+		if obj.IsField() {
+			item.Kind = completionField
+		}
+	case *types.Const:
+		item.Kind = completionConstant
+	case *types.PkgName:
+		item.Kind = completionModule
+	case *types.TypeName:
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct:
+			item.Kind = completionStruct
+		case *types.Interface:
+			item.Kind = completionInterface
+		default:
+			item.Kind = completionClass
+		}
+		if isTypeParamName(obj) {
+			item.Kind = completionTypeParameter
+		}
+	}
+	if item.Detail == "" {
+		item.Detail = cp.objectString(obj)
+	}
+	return item
+}
+
+// runServe runs "go2go serve" on standard input and output.
+func runServe(newImporter func() *go2go.Importer) {
+	if err := serve(os.Stdin, os.Stdout, newImporter, *methodTParamsFlag); err != nil {
+		die(err.Error())
+	}
+}
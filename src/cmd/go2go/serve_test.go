@@ -0,0 +1,423 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"go/go2go"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A testClient is a language client that talks to
+// a server running in the same process.
+type testClient struct {
+	t     *testing.T
+	conn  *conn
+	msgs  chan *message // messages from the server
+	id    int
+	diags map[string][]diagnostic // published diagnostics, by URI
+	done  chan error
+}
+
+func newTestClient(t *testing.T, newImporter func() *go2go.Importer) *testClient {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	c := &testClient{
+		t:     t,
+		conn:  newConn(cr, cw),
+		msgs:  make(chan *message),
+		diags: make(map[string][]diagnostic),
+		done:  make(chan error, 1),
+	}
+	// Read messages as they arrive, as the pipes are not buffered
+	// and the server may send notifications at any time.
+	go func() {
+		for {
+			msg, err := c.conn.read()
+			if err != nil {
+				close(c.msgs)
+				return
+			}
+			c.msgs <- msg
+		}
+	}()
+	go func() {
+		err := serve(sr, sw, newImporter, false)
+		sw.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+// call sends a request and decodes the response into result,
+// recording the diagnostics published before the response.
+func (c *testClient) call(method string, params, result interface{}) {
+	c.t.Helper()
+	c.id++
+	if err := c.conn.call(c.id, method, params); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg, ok := <-c.msgs
+		if !ok {
+			c.t.Fatalf("%s: connection closed", method)
+		}
+		if msg.Method == "textDocument/publishDiagnostics" {
+			var p publishDiagnosticsParams
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				c.t.Fatal(err)
+			}
+			c.diags[p.URI] = p.Diagnostics
+			continue
+		}
+		if msg.ID == nil {
+			continue
+		}
+		var id int
+		if err := json.Unmarshal(*msg.ID, &id); err != nil || id != c.id {
+			c.t.Fatalf("%s: response has ID %s, want %d", method, *msg.ID, c.id)
+		}
+		if msg.Error != nil {
+			c.t.Fatalf("%s: %v", method, msg.Error)
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatalf("%s: %v", method, err)
+			}
+		}
+		return
+	}
+}
+
+// notify sends a notification.
+func (c *testClient) notify(method string, params interface{}) {
+	c.t.Helper()
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// at returns the position params for the start of the n'th
+// occurrence, counting from 1, of s in src, plus delta bytes.
+func at(t *testing.T, uri, src, s string, n, delta int) positionParams {
+	t.Helper()
+	offset := -1
+	for i := 0; i < n; i++ {
+		j := strings.Index(src[offset+1:], s)
+		if j < 0 {
+			t.Fatalf("%q does not occur %d times", s, n)
+		}
+		offset += 1 + j
+	}
+	return positionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     lspPosition([]byte(src), offset+delta),
+	}
+}
+
+const serveSets = `package sets
+
+type Set(type E comparable) struct {
+	m map[E]struct{}
+}
+
+func Make(type E comparable)(elems ...E) *Set(E) {
+	s := &Set(E){m: make(map[E]struct{})}
+	for _, e := range elems {
+		s.m[e] = struct{}{}
+	}
+	return s
+}
+
+func (s *Set(E)) Len() int {
+	return len(s.m)
+}
+
+func (s *Set(E)) Contains(e E) bool {
+	_, ok := s.m[e]
+	return ok
+}
+`
+
+const serveCmd = `package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"sets"
+)
+
+func Map(type T1, T2)(s []T1, f func(T1) T2) []T2 {
+	r := make([]T2, 0, len(s))
+	for _, v := range s {
+		r = append(r, f(v))
+	}
+	return r
+}
+
+func main() {
+	var s *sets.Set(int) = sets.Make(1, 2, 3)
+	fmt.Println(s.Len(), s.Contains(2))
+	fmt.Println(Map([]int{1, 2}, strconv.Itoa), s.Len())
+}
+`
+
+func TestServe(t *testing.T) {
+	gopath := t.TempDir()
+	for name, src := range map[string]string{
+		"sets/sets.go2": serveSets,
+		"cmd/cmd.go2":   strings.Replace(serveCmd, "func main", "func oldMain", 1),
+	} {
+		name = filepath.Join(gopath, "src", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer os.Setenv("GO2PATH", os.Getenv("GO2PATH"))
+	os.Setenv("GO2PATH", gopath)
+
+	c := newTestClient(t, func() *go2go.Importer {
+		return go2go.NewImporter(t.TempDir())
+	})
+
+	var init initializeResult
+	c.call("initialize", map[string]interface{}{}, &init)
+	if caps := init.Capabilities; !caps.HoverProvider || !caps.DefinitionProvider || !caps.ReferencesProvider {
+		t.Errorf("initialize: got capabilities %+v", caps)
+	}
+	c.notify("initialized", map[string]interface{}{})
+
+	setsFile := filepath.Join(gopath, "src", "sets", "sets.go2")
+	cmdFile := filepath.Join(gopath, "src", "cmd", "cmd.go2")
+	uri := filenameURI(cmdFile)
+
+	// Diagnostics use the contents of the open file,
+	// not the file on disk.
+	bad := strings.Replace(serveCmd, "s.Contains(2)", `s.Contains("2")`, 1)
+	c.notify("textDocument/didOpen", &didOpenParams{
+		TextDocument: textDocumentItem{URI: uri, LanguageID: "go2", Version: 1, Text: bad},
+	})
+	c.call("textDocument/hover", at(t, uri, bad, "Println", 1, 0), nil)
+	diags := c.diags[uri]
+	if len(diags) != 1 {
+		t.Fatalf("got diagnostics %v, want one", diags)
+	}
+	if want := lspPosition([]byte(bad), strings.Index(bad, `"2"`)); diags[0].Range.Start != want {
+		t.Errorf("diagnostic %q at %v, want %v", diags[0].Message, diags[0].Range.Start, want)
+	}
+
+	c.notify("textDocument/didChange", &didChangeParams{
+		TextDocument:   textDocumentIdentifier{URI: uri},
+		ContentChanges: []contentChange{{Text: serveCmd}},
+	})
+	c.call("textDocument/hover", at(t, uri, serveCmd, "Println", 1, 0), nil)
+	if diags := c.diags[uri]; len(diags) != 0 {
+		t.Fatalf("got diagnostics %v after fixing the error, want none", diags)
+	}
+
+	// Hover shows the instantiation of generic functions and types.
+	hoverTests := []struct {
+		s     string
+		n     int
+		wants []string
+	}{
+		{"Map([]int", 1, []string{
+			"func Map(type T1, T2)(s []T1, f func(T1) T2) []T2",
+			"// type arguments: int, string\nfunc(s []int, f func(int) string) []string",
+		}},
+		{"Set(int)", 1, []string{
+			"type sets.Set(type E comparable) struct{m map[E]struct{}}",
+			"sets.Set(int) struct{m map[int]struct{}}",
+		}},
+		{"Make(1", 1, []string{
+			"// type arguments: int\nfunc(elems ...int) *sets.Set(int)",
+		}},
+		{"Len()", 1, []string{
+			"func (*sets.Set(E)).Len() int",
+			"// type arguments: int\nfunc (*sets.Set(int)).Len() int",
+		}},
+	}
+	for _, test := range hoverTests {
+		var h hover
+		c.call("textDocument/hover", at(t, uri, serveCmd, test.s, test.n, 0), &h)
+		for _, want := range test.wants {
+			if !strings.Contains(h.Contents.Value, want) {
+				t.Errorf("hover on %s: got %q, want %q", test.s, h.Contents.Value, want)
+			}
+		}
+	}
+
+	// The definition of a method of an instantiated type
+	// is the method of the generic type.
+	var locs []location
+	c.call("textDocument/definition", at(t, uri, serveCmd, "Len()", 1, 0), &locs)
+	wantLoc := location{
+		URI: filenameURI(setsFile),
+		Range: lspRange{
+			Start: lspPosition([]byte(serveSets), strings.Index(serveSets, "Len")),
+			End:   lspPosition([]byte(serveSets), strings.Index(serveSets, "Len")+len("Len")),
+		},
+	}
+	if len(locs) != 1 || locs[0] != wantLoc {
+		t.Errorf("definition of Len: got %v, want %v", locs, wantLoc)
+	}
+
+	var refs []location
+	c.call("textDocument/references", &referenceParams{
+		positionParams: at(t, uri, serveCmd, "Len()", 2, 0),
+	}, &refs)
+	if len(refs) != 2 {
+		t.Errorf("got %d references to Len, want 2: %v", len(refs), refs)
+	}
+	refs = nil
+	c.call("textDocument/references", &referenceParams{
+		positionParams: at(t, uri, serveCmd, "T2", 1, 0),
+	}, &refs)
+	if len(refs) != 3 {
+		t.Errorf("got %d references to T2, want 3: %v", len(refs), refs)
+	}
+
+	// Completion of the methods of an instantiated type shows
+	// their instantiated signatures.
+	incomplete := strings.Replace(serveCmd, "\tfmt.Println(s.Len(), s.Contains(2))\n", "\ts.\n", 1)
+	c.notify("textDocument/didChange", &didChangeParams{
+		TextDocument:   textDocumentIdentifier{URI: uri},
+		ContentChanges: []contentChange{{Text: incomplete}},
+	})
+	var list completionList
+	c.call("textDocument/completion", at(t, uri, incomplete, "s.\n", 1, 2), &list)
+	got := make(map[string]string)
+	for _, item := range list.Items {
+		got[item.Label] = item.Detail
+	}
+	want := map[string]string{
+		"Contains": "func(e int) bool",
+		"Len":      "func() int",
+	}
+	for label, detail := range want {
+		if got[label] != detail {
+			t.Errorf("completion %s: got detail %q, want %q", label, got[label], detail)
+		}
+	}
+	if _, ok := got["m"]; ok {
+		t.Errorf("completion offered unexported field m of another package")
+	}
+
+	list = completionList{}
+	c.call("textDocument/completion", at(t, uri, incomplete, "Map([]int", 1, 2), &list)
+	got = make(map[string]string)
+	for _, item := range list.Items {
+		got[item.Label] = item.Detail
+	}
+	if _, ok := got["Map"]; !ok {
+		t.Errorf("completion of Ma: got %v, want Map", got)
+	}
+	if _, ok := got["main"]; ok {
+		t.Errorf("completion of Ma: got main")
+	}
+
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("serve: %v", err)
+	}
+}
+
+var exportDataLib = `package lib
+
+import "time"
+
+type List(type T) struct {
+	elems []T
+}
+
+func (l *List(T)) Push(v T) {
+	l.elems = append(l.elems, v)
+}
+
+type Ordered interface {
+	type int, float64, string
+}
+
+func Max(type T Ordered)(a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+var Timeout = 2 * time.Second
+`
+
+var exportDataUse = `package use
+
+import (
+	"time"
+
+	"lib"
+)
+
+var (
+	M = lib.Max(1, 2)
+	L lib.List(string)
+	T time.Duration = lib.Timeout
+)
+
+func F() {
+	L.Push("x")
+}
+`
+
+// TestServeExportData tests that the importers used by "go2go serve",
+// which only type check packages, load imported .go2 packages from the
+// export data in the cache.
+func TestServeExportData(t *testing.T) {
+	gopath := t.TempDir()
+	for name, src := range map[string]string{
+		"lib/lib.go2": exportDataLib,
+		"use/use.go2": exportDataUse,
+	} {
+		name = filepath.Join(gopath, "src", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer os.Setenv("GO2PATH", os.Getenv("GO2PATH"))
+	os.Setenv("GO2PATH", gopath)
+
+	cache := t.TempDir()
+	for i, exportData := range []bool{false, true} {
+		importer := go2go.NewImporter(t.TempDir())
+		if err := importer.UseCache(cache); err != nil {
+			t.Fatal(err)
+		}
+		if exportData {
+			importer.UseExportData()
+		}
+		pkgs, err := go2go.CheckPackages(importer, filepath.Join(gopath, "src", "use"))
+		if err != nil {
+			t.Fatalf("check %d: %v", i, err)
+		}
+		if got, want := pkgs[0].Types.Scope().Lookup("M").Type().String(), "int"; got != want {
+			t.Errorf("check %d: type of M is %s, want %s", i, got, want)
+		}
+		translated := importer.TranslatedDir("lib") != ""
+		if translated == exportData {
+			t.Errorf("check %d: lib translated = %t, want %t", i, translated, !exportData)
+		}
+	}
+}
@@ -7,6 +7,7 @@ package main
 import (
 	"go/go2go"
 	"io/ioutil"
+	"path/filepath"
 	"strings"
 )
 
@@ -27,7 +28,9 @@ func translateFile(importer *go2go.Importer, file string) {
 	if err != nil {
 		die(err.Error())
 	}
-	if err := ioutil.WriteFile(strings.TrimSuffix(file, ".go2")+".go", out, 0644); err != nil {
+	name := strings.TrimSuffix(filepath.Base(file), ".go2") + ".go"
+	dir := outputDir(importer, filepath.Dir(file))
+	if err := ioutil.WriteFile(filepath.Join(dir, name), out, 0644); err != nil {
 		die(err.Error())
 	}
 }
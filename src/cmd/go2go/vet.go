@@ -0,0 +1,321 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"go/go2go"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/assign"
+	"golang.org/x/tools/go/analysis/passes/atomic"
+	"golang.org/x/tools/go/analysis/passes/bools"
+	"golang.org/x/tools/go/analysis/passes/buildtag"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/copylock"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/httpresponse"
+	"golang.org/x/tools/go/analysis/passes/ifaceassert"
+	"golang.org/x/tools/go/analysis/passes/loopclosure"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilfunc"
+	"golang.org/x/tools/go/analysis/passes/shift"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+	"golang.org/x/tools/go/analysis/passes/stringintconv"
+	"golang.org/x/tools/go/analysis/passes/structtag"
+	"golang.org/x/tools/go/analysis/passes/tests"
+	"golang.org/x/tools/go/analysis/passes/unmarshal"
+	"golang.org/x/tools/go/analysis/passes/unreachable"
+	"golang.org/x/tools/go/analysis/passes/unsafeptr"
+	"golang.org/x/tools/go/analysis/passes/unusedresult"
+)
+
+// vetAnalyzers are the analyzers run by "go2go vet".
+// This is the list used by cmd/vet, less the ones that
+// look at assembly or cgo files, with the printf checker
+// extended to type parameters.
+var vetAnalyzers = []*analysis.Analyzer{
+	assign.Analyzer,
+	atomic.Analyzer,
+	bools.Analyzer,
+	buildtag.Analyzer,
+	composite.Analyzer,
+	copylock.Analyzer,
+	errorsas.Analyzer,
+	httpresponse.Analyzer,
+	ifaceassert.Analyzer,
+	loopclosure.Analyzer,
+	lostcancel.Analyzer,
+	nilfunc.Analyzer,
+	printfAnalyzer,
+	shift.Analyzer,
+	stdmethods.Analyzer,
+	stringintconv.Analyzer,
+	structtag.Analyzer,
+	tests.Analyzer,
+	unmarshal.Analyzer,
+	unreachable.Analyzer,
+	unsafeptr.Analyzer,
+	unusedresult.Analyzer,
+}
+
+// vet runs the vet analyzers on the .go2 packages in dirs.
+// The analyzers see the generic code as written, not the
+// translated code, so diagnostics refer to the .go2 files.
+// It reports diagnostics on standard error, and returns
+// the number of diagnostics.
+func vet(importer *go2go.Importer, dirs []string) int {
+	v := &vetter{
+		importer: importer,
+		fset:     importer.FileSet(),
+		objFacts: make(map[sharedObjFactKey]analysis.Fact),
+		pkgFacts: make(map[pkgFactKey]analysis.Fact),
+		checked:  make(map[string]bool),
+	}
+	var diags []vetDiagnostic
+	for _, dir := range dirs {
+		pkgs, err := go2go.CheckPackages(importer, dir)
+		if err != nil {
+			die(err.Error())
+		}
+		for _, pkg := range pkgs {
+			v.checkImports(pkg.Types)
+			diags = append(diags, v.runAnalyzers(pkg, "", vetAnalyzers)...)
+		}
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Pos < diags[j].Pos
+	})
+	for _, d := range diags {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", v.fset.Position(d.Pos), d.Message)
+	}
+	return len(diags)
+}
+
+// A vetter holds the state of "go2go vet".
+type vetter struct {
+	importer *go2go.Importer
+	fset     *token.FileSet
+
+	// Facts about the objects and packages of the imported
+	// .go2 packages, by import path.
+	objFacts map[sharedObjFactKey]analysis.Fact
+	pkgFacts map[pkgFactKey]analysis.Fact
+
+	// Import paths of the .go2 packages whose facts
+	// have been recorded.
+	checked map[string]bool
+}
+
+// checkImports runs the analyzers on the .go2 packages imported,
+// directly or indirectly, by pkg, to record the facts they export,
+// such as which functions are printf wrappers. Their diagnostics
+// are not reported.
+func (v *vetter) checkImports(pkg *types.Package) {
+	for _, ipkg := range pkg.Imports() {
+		path := ipkg.Path()
+		if v.checked[path] {
+			continue
+		}
+		v.checked[path] = true
+		dir := v.importer.TranslatedDir(path)
+		if dir == "" {
+			// Not a .go2 package. The standard vet checks
+			// of Go 1 packages are a matter for "go vet".
+			continue
+		}
+		v.checkImports(ipkg)
+		pkgs, err := go2go.CheckPackages(v.importer, dir)
+		if err != nil {
+			die(err.Error())
+		}
+		for _, p := range pkgs {
+			if !strings.HasSuffix(p.Types.Name(), "_test") {
+				v.runAnalyzers(p, path, vetAnalyzers)
+			}
+		}
+	}
+}
+
+// A vetDiagnostic is a diagnostic reported by an analyzer.
+type vetDiagnostic struct {
+	analysis.Diagnostic
+	analyzer *analysis.Analyzer
+}
+
+// An action is the application of an analyzer to a package.
+type action struct {
+	analyzer *analysis.Analyzer
+	result   interface{}
+	err      error
+	done     bool
+}
+
+// runAnalyzers runs the analyzers, and those they require,
+// on a single package, and returns the diagnostics they report.
+// If path is not empty, pkg is the package of that import path
+// imported by other packages, and the facts that the analyzers
+// export are recorded for them.
+//
+// Facts about imported objects are only available for the
+// objects that sharedObjKey can identify in a different type
+// check of their package.
+func (v *vetter) runAnalyzers(pkg *go2go.Package, path string, analyzers []*analysis.Analyzer) []vetDiagnostic {
+	actions := make(map[*analysis.Analyzer]*action)
+	objFacts := make(map[objFactKey]analysis.Fact)
+	pkgFacts := make(map[pkgFactKey]analysis.Fact)
+	var diags []vetDiagnostic
+
+	var run func(a *analysis.Analyzer) *action
+	run = func(a *analysis.Analyzer) *action {
+		act, ok := actions[a]
+		if ok {
+			return act
+		}
+		act = &action{analyzer: a}
+		actions[a] = act
+
+		resultOf := make(map[*analysis.Analyzer]interface{})
+		for _, req := range a.Requires {
+			ract := run(req)
+			if ract.err != nil {
+				act.err = fmt.Errorf("failed prerequisite %s: %v", req.Name, ract.err)
+				return act
+			}
+			resultOf[req] = ract.result
+		}
+
+		pass := &analysis.Pass{
+			Analyzer:   a,
+			Fset:       v.fset,
+			Files:      pkg.Files,
+			Pkg:        pkg.Types,
+			TypesInfo:  pkg.Info,
+			TypesSizes: types.SizesFor("gc", runtime.GOARCH),
+			ResultOf:   resultOf,
+			Report: func(d analysis.Diagnostic) {
+				diags = append(diags, vetDiagnostic{d, a})
+			},
+			ImportObjectFact: func(obj types.Object, fact analysis.Fact) bool {
+				f, ok := objFacts[objFactKey{obj, reflect.TypeOf(fact)}]
+				if !ok && obj.Pkg() != nil && obj.Pkg() != pkg.Types {
+					if key := sharedObjKey(obj); key != "" {
+						f, ok = v.objFacts[sharedObjFactKey{obj.Pkg().Path(), key, reflect.TypeOf(fact)}]
+					}
+				}
+				if ok {
+					reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(f).Elem())
+				}
+				return ok
+			},
+			ExportObjectFact: func(obj types.Object, fact analysis.Fact) {
+				objFacts[objFactKey{obj, reflect.TypeOf(fact)}] = fact
+			},
+			ImportPackageFact: func(p *types.Package, fact analysis.Fact) bool {
+				f, ok := pkgFacts[pkgFactKey{p.Path(), reflect.TypeOf(fact)}]
+				if !ok && p != pkg.Types {
+					f, ok = v.pkgFacts[pkgFactKey{p.Path(), reflect.TypeOf(fact)}]
+				}
+				if ok {
+					reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(f).Elem())
+				}
+				return ok
+			},
+			ExportPackageFact: func(fact analysis.Fact) {
+				pkgFacts[pkgFactKey{pkg.Types.Path(), reflect.TypeOf(fact)}] = fact
+			},
+			AllObjectFacts: func() []analysis.ObjectFact {
+				var r []analysis.ObjectFact
+				for k, f := range objFacts {
+					r = append(r, analysis.ObjectFact{Object: k.obj, Fact: f})
+				}
+				return r
+			},
+			AllPackageFacts: func() []analysis.PackageFact {
+				var r []analysis.PackageFact
+				for _, f := range pkgFacts {
+					r = append(r, analysis.PackageFact{Package: pkg.Types, Fact: f})
+				}
+				return r
+			},
+		}
+		act.result, act.err = a.Run(pass)
+		act.done = true
+		return act
+	}
+
+	for _, a := range analyzers {
+		if act := run(a); act.err != nil {
+			die(fmt.Sprintf("%s: analyzer %s failed: %v", pkg.Types.Path(), a.Name, act.err))
+		}
+	}
+
+	if path != "" {
+		for k, f := range objFacts {
+			if key := sharedObjKey(k.obj); key != "" {
+				v.objFacts[sharedObjFactKey{path, key, k.typ}] = f
+			}
+		}
+		for k, f := range pkgFacts {
+			v.pkgFacts[pkgFactKey{path, k.typ}] = f
+		}
+	}
+	return diags
+}
+
+// sharedObjKey returns a key that identifies obj, an object of
+// a package, in all type checks of the package: the name of a
+// package-level object, or the type and method name of a method.
+// It returns the empty string for other objects.
+func sharedObjKey(obj types.Object) string {
+	if obj.Pkg() == nil {
+		return ""
+	}
+	if obj.Parent() == obj.Pkg().Scope() {
+		return obj.Name()
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		recv := fn.Type().(*types.Signature).Recv()
+		if recv == nil {
+			return ""
+		}
+		t := recv.Type()
+		if p, ok := t.(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		if n, ok := t.(*types.Named); ok && n.Obj().Parent() == obj.Pkg().Scope() {
+			return n.Obj().Name() + "." + fn.Name()
+		}
+	}
+	return ""
+}
+
+// An objFactKey is the key of an object fact.
+type objFactKey struct {
+	obj types.Object
+	typ reflect.Type
+}
+
+// A sharedObjFactKey is the key of a fact about an object
+// of an imported package, see sharedObjKey.
+type sharedObjFactKey struct {
+	path string
+	obj  string
+	typ  reflect.Type
+}
+
+// A pkgFactKey is the key of a package fact.
+type pkgFactKey struct {
+	path string
+	typ  reflect.Type
+}
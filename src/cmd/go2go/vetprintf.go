@@ -0,0 +1,171 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/printf"
+)
+
+// printfAnalyzer is printf.Analyzer, extended to check arguments
+// whose types are, or are composed of, type parameters.
+//
+// The printf checker doesn't know about type parameters, so it is
+// run with type information in which each type parameter is replaced
+// by a named type of the same name, with the methods of its
+// constraint. The underlying type of the named type is a type in the
+// type list of the constraint, or the constraint itself if it has
+// no type list, which the checker treats like any interface. A verb
+// must be appropriate for every type in the type list, so the checker
+// is run once for each of them, and the diagnostics are merged.
+var printfAnalyzer = &analysis.Analyzer{
+	Name:       printf.Analyzer.Name,
+	Doc:        printf.Analyzer.Doc,
+	Requires:   printf.Analyzer.Requires,
+	ResultType: printf.Analyzer.ResultType,
+	FactTypes:  printf.Analyzer.FactTypes,
+	Run:        runPrintf,
+}
+
+func runPrintf(pass *analysis.Pass) (interface{}, error) {
+	s := &tparamSubst{named: make(map[*types.TypeParam]*types.Named)}
+	info := s.info(pass.TypesInfo)
+	if len(s.named) == 0 {
+		return printf.Analyzer.Run(pass)
+	}
+
+	type diagKey struct {
+		pos, end token.Pos
+		msg      string
+	}
+	seen := make(map[diagKey]bool)
+	var diags []analysis.Diagnostic
+	var result interface{}
+	for {
+		p := *pass
+		p.TypesInfo = info
+		p.Report = func(d analysis.Diagnostic) {
+			k := diagKey{d.Pos, d.End, d.Message}
+			if !seen[k] {
+				seen[k] = true
+				diags = append(diags, d)
+			}
+		}
+		var err error
+		result, err = printf.Analyzer.Run(&p)
+		if err != nil {
+			return nil, err
+		}
+		s.round++
+		if s.round >= s.rounds {
+			break
+		}
+		s.named = make(map[*types.TypeParam]*types.Named)
+		info = s.info(pass.TypesInfo)
+	}
+	for _, d := range diags {
+		pass.Report(d)
+	}
+	return result, nil
+}
+
+// A tparamSubst replaces type parameters by named types,
+// as described at printfAnalyzer.
+type tparamSubst struct {
+	round  int                               // use the round'th type of type lists
+	rounds int                               // length of the longest type list seen
+	named  map[*types.TypeParam]*types.Named // replacements of this round
+}
+
+// info returns a copy of info in which the type parameters
+// in the types of expressions have been replaced.
+func (s *tparamSubst) info(info *types.Info) *types.Info {
+	r := *info
+	r.Types = make(map[ast.Expr]types.TypeAndValue, len(info.Types))
+	for e, tv := range info.Types {
+		tv.Type = s.typ(tv.Type)
+		r.Types[e] = tv
+	}
+	return &r
+}
+
+// typ returns t with its type parameters replaced. Only the
+// types that the printf checker looks into are rebuilt.
+func (s *tparamSubst) typ(t types.Type) types.Type {
+	switch t := t.(type) {
+	case *types.TypeParam:
+		return s.tparam(t)
+	case *types.Pointer:
+		if elem := s.typ(t.Elem()); elem != t.Elem() {
+			return types.NewPointer(elem)
+		}
+	case *types.Array:
+		if elem := s.typ(t.Elem()); elem != t.Elem() {
+			return types.NewArray(elem, t.Len())
+		}
+	case *types.Slice:
+		if elem := s.typ(t.Elem()); elem != t.Elem() {
+			return types.NewSlice(elem)
+		}
+	case *types.Map:
+		key, elem := s.typ(t.Key()), s.typ(t.Elem())
+		if key != t.Key() || elem != t.Elem() {
+			return types.NewMap(key, elem)
+		}
+	case *types.Chan:
+		if elem := s.typ(t.Elem()); elem != t.Elem() {
+			return types.NewChan(t.Dir(), elem)
+		}
+	case *types.Struct:
+		var fields []*types.Var
+		var tags []string
+		changed := false
+		for i := 0; i < t.NumFields(); i++ {
+			f := t.Field(i)
+			ft := s.typ(f.Type())
+			if ft != f.Type() {
+				changed = true
+				f = types.NewField(f.Pos(), f.Pkg(), f.Name(), ft, f.Embedded())
+			}
+			fields = append(fields, f)
+			tags = append(tags, t.Tag(i))
+		}
+		if changed {
+			return types.NewStruct(fields, tags)
+		}
+	}
+	return t
+}
+
+// tparam returns the replacement of the type parameter t.
+func (s *tparamSubst) tparam(t *types.TypeParam) types.Type {
+	if n, ok := s.named[t]; ok {
+		return n
+	}
+	bound := t.Bound()
+	var under types.Type = bound
+	if tlist := bound.AllTypes(); len(tlist) > 0 {
+		if len(tlist) > s.rounds {
+			s.rounds = len(tlist)
+		}
+		i := s.round
+		if i >= len(tlist) {
+			i = len(tlist) - 1
+		}
+		under = tlist[i].Underlying()
+	}
+	var methods []*types.Func
+	for i := 0; i < bound.NumMethods(); i++ {
+		methods = append(methods, bound.Method(i))
+	}
+	obj := types.NewTypeName(t.Obj().Pos(), nil, t.String(), nil)
+	n := types.NewNamed(obj, under, methods)
+	s.named[t] = n
+	return n
+}
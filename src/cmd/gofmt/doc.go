@@ -8,8 +8,9 @@ It uses tabs for indentation and blanks for alignment.
 Alignment assumes that an editor is using a fixed-width font.
 
 Without an explicit path, it processes the standard input.  Given a file,
-it operates on that file; given a directory, it operates on all .go files in
-that directory, recursively.  (Files starting with a period are ignored.)
+it operates on that file; given a directory, it operates on all .go and .go2
+files in that directory, recursively.  (Files starting with a period are
+ignored.)
 By default, gofmt prints the reformatted sources to standard output.
 
 Usage:
@@ -18,7 +18,7 @@ func (s simplifier) Visit(node ast.Node) ast.Visitor {
 		// array, slice, and map composite literals may be simplified
 		outer := n
 		var keyType, eltType ast.Expr
-		switch typ := outer.Type.(type) {
+		switch typ := literalType(outer.Type).(type) {
 		case *ast.ArrayType:
 			eltType = typ.Elt
 		case *ast.MapType:
@@ -125,6 +125,50 @@ func (s simplifier) simplifyLiteral(typ reflect.Value, astType, x ast.Expr, px *
 	}
 }
 
+// literalType returns the type of a composite literal with type x.
+// A literal type such as []*List(T) is parsed as the conversion
+// ([]*List)(T); literalType returns the equivalent []*(List(T)),
+// so that the element type is the instantiated type.
+// Any other x is returned unchanged.
+func literalType(x ast.Expr) ast.Expr {
+	call, ok := x.(*ast.CallExpr)
+	if !ok || call.Ellipsis.IsValid() {
+		return x
+	}
+	switch typ := call.Fun.(type) {
+	case *ast.ArrayType:
+		return &ast.ArrayType{
+			Lbrack: typ.Lbrack,
+			Len:    typ.Len,
+			Elt:    instantiatedType(typ.Elt, call),
+		}
+	case *ast.MapType:
+		return &ast.MapType{
+			Map:   typ.Map,
+			Key:   typ.Key,
+			Value: instantiatedType(typ.Value, call),
+		}
+	}
+	return x
+}
+
+// instantiatedType returns the type x, which may be a pointer type,
+// instantiated with the type arguments of call.
+func instantiatedType(x ast.Expr, call *ast.CallExpr) ast.Expr {
+	if ptr, ok := x.(*ast.StarExpr); ok {
+		return &ast.StarExpr{
+			Star: ptr.Star,
+			X:    instantiatedType(ptr.X, call),
+		}
+	}
+	return &ast.CallExpr{
+		Fun:    x,
+		Lparen: call.Lparen,
+		Args:   call.Args,
+		Rparen: call.Rparen,
+	}
+}
+
 func isBlank(x ast.Expr) bool {
 	ident, ok := x.(*ast.Ident)
 	return ok && ident.Name == "_"
@@ -242,11 +242,11 @@ var pkgDeps = map[string][]string{
 	// Go type checking.
 	"go/constant":               {"L4", "go/token", "math/big"},
 	"go/importer":               {"L4", "go/build", "go/internal/gccgoimporter", "go/internal/gcimporter", "go/internal/srcimporter", "go/token", "go/types"},
-	"go/internal/gcimporter":    {"L4", "OS", "go/build", "go/constant", "go/token", "go/types", "text/scanner"},
+	"go/internal/gcimporter":    {"L4", "OS", "go/build", "go/constant", "go/token", "go/types", "math/big", "text/scanner"},
 	"go/internal/gccgoimporter": {"L4", "OS", "debug/elf", "go/constant", "go/token", "go/types", "internal/xcoff", "text/scanner"},
 	"go/internal/srcimporter":   {"L4", "OS", "fmt", "go/ast", "go/build", "go/parser", "go/token", "go/types", "path/filepath"},
 	"go/types":                  {"L4", "GOPARSER", "container/heap", "go/constant"},
-	"go/go2go":                  {"L4", "GOPARSER", "OS", "go/build", "go/importer", "go/types", "internal/goroot"},
+	"go/go2go":                  {"L4", "GOPARSER", "OS", "crypto/sha256", "go/build", "go/importer", "go/internal/gcimporter", "go/types", "internal/goroot"},
 
 	// One of a kind.
 	"archive/tar":               {"L4", "OS", "syscall", "os/user"},
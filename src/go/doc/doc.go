@@ -72,8 +72,8 @@ type Func struct {
 
 	// methods
 	// (for functions, these fields have the respective zero value)
-	Recv  string // actual   receiver "T" or "*T"
-	Orig  string // original receiver "T" or "*T"
+	Recv  string // actual   receiver "T", "*T", "T(P)" or "*T(P)"
+	Orig  string // original receiver "T", "*T", "T(P)" or "*T(P)"
 	Level int    // embedding level; 0 means not embedded
 
 	// Examples is a sorted list of examples associated with this
@@ -146,6 +146,9 @@ func New(pkg *ast.Package, importPath string, mode Mode) *Package {
 // the desired GOOS and GOARCH values, and other build constraints.
 // The import path of the package is specified by importPath.
 //
+// Files with a .go2 extension, which may use type parameters, are
+// treated like .go files, and _test.go2 files like _test.go files.
+//
 // Examples found in _test.go files are associated with the corresponding
 // type, function, method, or the package, based on their name.
 // If the example has a suffix in its name, it is set in the
@@ -176,7 +179,7 @@ func NewFromFiles(fset *token.FileSet, files []*ast.File, importPath string, opt
 		panic(fmt.Errorf("doc.NewFromFiles: there must not be more than 1 option argument"))
 	}
 
-	// Collect .go and _test.go files.
+	// Collect .go and _test.go files, and their .go2 counterparts.
 	var (
 		goFiles     = make(map[string]*ast.File)
 		testGoFiles []*ast.File
@@ -186,13 +189,13 @@ func NewFromFiles(fset *token.FileSet, files []*ast.File, importPath string, opt
 		if f == nil {
 			return nil, fmt.Errorf("file files[%d] is not found in the provided file set", i)
 		}
-		switch name := f.Name(); {
+		switch name := strings.TrimSuffix(f.Name(), "2"); {
 		case strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go"):
-			goFiles[name] = files[i]
+			goFiles[f.Name()] = files[i]
 		case strings.HasSuffix(name, "_test.go"):
 			testGoFiles = append(testGoFiles, files[i])
 		default:
-			return nil, fmt.Errorf("file files[%d] filename %q does not have a .go extension", i, name)
+			return nil, fmt.Errorf("file files[%d] filename %q does not have a .go or .go2 extension", i, f.Name())
 		}
 	}
 
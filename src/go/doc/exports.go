@@ -129,7 +129,14 @@ func (r *reader) filterFieldList(parent *namedType, fields *ast.FieldList, ityp
 				// it can be fixed if error is also defined locally
 				keepField = true
 				r.remember(ityp)
+			} else if ityp != nil && fname == "comparable" {
+				// the predeclared constraint comparable
+				keepField = true
 			}
+		} else if ityp != nil && isTypeList(field) {
+			// part of the type list of a constraint interface;
+			// always keep it, as it describes the permitted types
+			keepField = true
 		} else {
 			field.Names = filterIdentList(field.Names)
 			if len(field.Names) < n {
@@ -152,6 +159,13 @@ func (r *reader) filterFieldList(parent *namedType, fields *ast.FieldList, ityp
 	return
 }
 
+// isTypeList reports whether field is an entry in the type list
+// of an interface. All entries in a type list are named "type".
+//
+func isTypeList(field *ast.Field) bool {
+	return len(field.Names) == 1 && field.Names[0].Name == "type"
+}
+
 // filterParamList applies filterType to each parameter type in fields.
 //
 func (r *reader) filterParamList(fields *ast.FieldList) {
@@ -220,6 +234,7 @@ func (r *reader) filterSpec(spec ast.Spec) bool {
 		}
 	case *ast.TypeSpec:
 		if name := s.Name.Name; token.IsExported(name) {
+			r.filterParamList(s.TParams)
 			r.filterType(r.lookupType(s.Name.Name), s.Type)
 			return true
 		} else if name == "error" {
@@ -10,6 +10,7 @@ import (
 	"internal/lazyregexp"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 // ----------------------------------------------------------------------------
@@ -23,7 +24,8 @@ import (
 type methodSet map[string]*Func
 
 // recvString returns a string representation of recv of the
-// form "T", "*T", or "BADRECV" (if not a proper receiver type).
+// form "T", "*T", "T(P)", "*T(P)", or "BADRECV" (if not a proper
+// receiver type).
 //
 func recvString(recv ast.Expr) string {
 	switch t := recv.(type) {
@@ -31,10 +33,33 @@ func recvString(recv ast.Expr) string {
 		return t.Name
 	case *ast.StarExpr:
 		return "*" + recvString(t.X)
+	case *ast.CallExpr:
+		// parameterized receiver type
+		var b strings.Builder
+		b.WriteString(recvString(t.Fun))
+		b.WriteByte('(')
+		for i, arg := range t.Args {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(recvParam(arg))
+		}
+		b.WriteByte(')')
+		return b.String()
 	}
 	return "BADRECV"
 }
 
+// recvParam returns the name of the receiver type parameter p,
+// or "BADPARAM" (if not a proper receiver type parameter).
+//
+func recvParam(p ast.Expr) string {
+	if id, ok := p.(*ast.Ident); ok {
+		return id.Name
+	}
+	return "BADPARAM"
+}
+
 // set creates the corresponding Func for f and adds it to mset.
 // If there are multiple f's with the same name, set keeps the first
 // one with documentation; conflicts are ignored. The boolean
@@ -111,6 +136,9 @@ func baseTypeName(x ast.Expr) (name string, imported bool) {
 		return baseTypeName(t.X)
 	case *ast.StarExpr:
 		return baseTypeName(t.X)
+	case *ast.CallExpr:
+		// instantiated parameterized type
+		return baseTypeName(t.Fun)
 	}
 	return
 }
@@ -417,7 +445,7 @@ func (r *reader) readFunc(fun *ast.FuncDecl) {
 				// T (or pointers to T) as factory functions of T.
 				factoryType = t.Elt
 			}
-			if n, imp := baseTypeName(factoryType); !imp && r.isVisible(n) && !r.isPredeclared(n) {
+			if n, imp := baseTypeName(factoryType); !imp && r.isVisible(n) && !r.isPredeclared(n) && !isTypeParam(fun, n) {
 				if t := r.lookupType(n); t != nil {
 					typ = t
 					numResultTypes++
@@ -439,6 +467,21 @@ func (r *reader) readFunc(fun *ast.FuncDecl) {
 	r.funcs.set(fun, r.mode&PreserveAST != 0)
 }
 
+// isTypeParam reports whether name is a type parameter of fun.
+//
+func isTypeParam(fun *ast.FuncDecl, name string) bool {
+	if fun.Type.TParams != nil {
+		for _, f := range fun.Type.TParams.List {
+			for _, id := range f.Names {
+				if id.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 var (
 	noteMarker    = `([A-Z][A-Z]+)\(([^)]+)\):?`                // MARKER(uid), MARKER at least 2 chars, uid at least 1 char
 	noteMarkerRx  = lazyregexp.New(`^[ \t]*` + noteMarker)      // MARKER(uid) at text start
@@ -0,0 +1,61 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The package generics is a go/doc test for parameterized
+// types and functions.
+package generics
+
+// Ordered permits any ordered type.
+type Ordered interface {
+	type int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, string
+}
+
+// Keyed is a constraint for map keys.
+type Keyed interface {
+	comparable
+	Key() string
+	hidden()
+}
+
+// Map is an ordered map.
+type Map(type K, V) struct {
+	root    *node(K, V)
+	compare func(K, K) int
+	Len     int
+}
+
+type node(type K, V) struct {
+	key         K
+	val         V
+	left, right *node(K, V)
+}
+
+// New returns a new map.
+func New(type K, V)(compare func(K, K) int) *Map(K, V) {
+	return &Map(K, V){compare: compare}
+}
+
+// Insert inserts a new key/value into the map.
+func (m *Map(K, V)) Insert(key K, val V) bool {
+	return false
+}
+
+// Find returns the value associated with a key.
+func (m *Map(K, V)) Find(key K) (V, bool) {
+	var zero V
+	return zero, false
+}
+
+// Max returns the maximum of two values.
+func Max(type T Ordered)(a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Set is a set of keys.
+type Set(type K Keyed) struct {
+	*Map(K, struct{})
+}
@@ -148,6 +148,12 @@ var tests = []string{
 	"\n\n", // issue #11275
 	"\t\n", // issue #11275
 
+	// type parameters
+	"type List(type T) struct {\n\tnext *List(T)\n\tval  T\n}",
+	"func (l *List(T)) Push(v T) *List(T) {\n\treturn &List(T){l, v}\n}",
+	"func Map(type T, U)(s []T, f func(T) U) []U",
+	"var l List(int)\nr := Map(int, string)(s, strconv.Itoa)",
+
 	// erroneous programs
 	"ERROR1 + 2 +",
 	"ERRORx :=  0",
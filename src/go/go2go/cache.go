@@ -0,0 +1,379 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"internal/goroot"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Translating a package requires type checking it, which requires
+// translating all the .go2 packages that it imports, so a small
+// change can be expensive. The importer can therefore keep a
+// persistent cache of translated packages, see UseCache.
+//
+// A cache entry holds the .go files translated from the .go2 files
+// of one directory, and, for an imported package, its export data,
+// see UseExportData. The entry is keyed by a hash of
+//
+//   - the version of the Go toolchain and of the running program,
+//   - whether instantiations are grouped by shape, see UseShapes,
+//   - the names and contents of the .go2 files, and of any
+//     hand-written .go files type checked along with them,
+//   - for each imported package that is not in the standard library,
+//     the key of that package, computed the same way from its .go
+//     and .go2 files.
+//
+// The translated code of a package holds the instantiations of the
+// generic code that the package uses, and those are determined by
+// the package and the packages it imports, so the key covers them.
+//
+// When all the .go2 packages needed by a package are in the cache,
+// Rewrite writes out the cached files without parsing or type
+// checking anything. Otherwise packages are type checked as usual,
+// but the translation is skipped for any package that is in the cache.
+
+// cacheVersion is changed when the format of cache entries changes.
+const cacheVersion = "go2go-cache-1"
+
+// A cacheKey is the key of a cache entry.
+type cacheKey [sha256.Size]byte
+
+// A translationCache is a persistent cache of translated packages.
+type translationCache struct {
+	dir string // root directory of the cache
+}
+
+// A cachedPackage holds the cache key of an imported package.
+type cachedPackage struct {
+	key  cacheKey
+	go2  bool     // whether the package has .go2 files
+	deps []string // imported packages not in the standard library
+	err  error    // error computing the key
+}
+
+// UseCache tells the importer to keep translated packages in the
+// cache rooted at dir, and to reuse them when translating packages
+// whose sources, and the sources of whose dependencies, are unchanged.
+// The directory is created if it does not exist.
+func (imp *Importer) UseCache(dir string) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	imp.cache = &translationCache{dir: dir}
+	imp.cacheKeys = make(map[string]*cachedPackage)
+	return nil
+}
+
+// useCache reports whether to use the cache. It is not used when
+// instantiations are shared, see ShareInstantiations.
+func (imp *Importer) useCache() bool {
+	return imp.cache != nil && imp.shared == nil
+}
+
+// entryDir returns the directory holding the cache entry for key.
+func (c *translationCache) entryDir(key cacheKey) string {
+	hex := fmt.Sprintf("%x", key)
+	return filepath.Join(c.dir, hex[:2], hex)
+}
+
+// get reports whether the cache holds an entry for key,
+// and returns the directory holding its files.
+func (c *translationCache) get(key cacheKey) (string, bool) {
+	dir := c.entryDir(key)
+	fi, err := os.Stat(dir)
+	return dir, err == nil && fi.IsDir()
+}
+
+// put adds an entry for key to the cache, holding the named files
+// from dir. An existing entry is left alone. Errors are ignored,
+// as the cache is only an optimization.
+func (c *translationCache) put(key cacheKey, dir string, names []string) {
+	entry := c.entryDir(key)
+	if _, err := os.Stat(entry); err == nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(entry), 0777); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(entry), "tmp-")
+	if err != nil {
+		return
+	}
+	if err := copyFiles(dir, tmp, names); err != nil {
+		os.RemoveAll(tmp)
+		return
+	}
+	// Another process may have added the entry meanwhile.
+	if err := os.Rename(tmp, entry); err != nil {
+		os.RemoveAll(tmp)
+	}
+}
+
+// copyFiles copies the named files from the directory from
+// into the directory to.
+func copyFiles(from, to string, names []string) error {
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(from, name))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(to, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goFileNames returns the names of the .go files
+// translated from go2files.
+func goFileNames(go2files []string) []string {
+	names := make([]string, len(go2files))
+	for i, name := range go2files {
+		names[i] = strings.TrimSuffix(filepath.Base(name), ".go2") + ".go"
+	}
+	return names
+}
+
+// dirKey returns the cache key for translating the package in dir,
+// whose .go2 and hand-written .go files are files, along with the
+// import paths of the non-standard packages that the files import,
+// directly or indirectly, that have .go2 files.
+func (imp *Importer) dirKey(dir string, files []string) (cacheKey, []string, error) {
+	h := sha256.New()
+	deps, err := imp.hashFiles(h, dir, files)
+	if err != nil {
+		return cacheKey{}, nil, err
+	}
+	var key cacheKey
+	h.Sum(key[:0])
+
+	seen := make(map[string]bool)
+	var go2deps []string
+	var walk func([]string)
+	walk = func(paths []string) {
+		for _, path := range paths {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			cp := imp.cacheKeys[path]
+			if cp.go2 {
+				go2deps = append(go2deps, path)
+			}
+			walk(cp.deps)
+		}
+	}
+	walk(deps)
+	sort.Strings(go2deps)
+	return key, go2deps, nil
+}
+
+// packageKey returns the cache key information for the package
+// importPath, imported by a package in the directory dir.
+// Keys are computed once per import path. A cycle through test
+// files is broken by only using the import path.
+func (imp *Importer) packageKey(importPath, dir string) *cachedPackage {
+	if cp, ok := imp.cacheKeys[importPath]; ok {
+		return cp
+	}
+	cp := &cachedPackage{}
+	imp.cacheKeys[importPath] = cp
+	cp.err = func() error {
+		pdir, _, err := imp.findPackage(importPath, dir)
+		if err != nil {
+			return err
+		}
+		go2files, gofiles, err := go2Files(pdir)
+		if err != nil {
+			return err
+		}
+		files := go2files
+		if len(go2files) > 0 {
+			cp.go2 = true
+			handWritten, err := handWrittenGofiles(pdir, gofiles)
+			if err != nil {
+				return err
+			}
+			files = append(files, handWritten...)
+		} else {
+			for _, name := range gofiles {
+				if !strings.HasSuffix(name, "_test.go") {
+					files = append(files, name)
+				}
+			}
+		}
+		h := sha256.New()
+		cp.deps, err = imp.hashFiles(h, pdir, files)
+		h.Sum(cp.key[:0])
+		return err
+	}()
+	return cp
+}
+
+// hashFiles writes the names and contents of files in dir to h,
+// followed by the keys of the packages that they import.
+// It returns the import paths of those packages that are not
+// in the standard library.
+func (imp *Importer) hashFiles(h io.Writer, dir string, files []string) ([]string, error) {
+	fmt.Fprintf(h, "%s %s %x\n", cacheVersion, runtime.Version(), toolID())
+	if imp.shapes != nil {
+		fmt.Fprintf(h, "shapes\n")
+	}
+	if imp.methodTParams {
+		fmt.Fprintf(h, "methodtparams\n")
+	}
+	files = append([]string(nil), files...)
+	sort.Strings(files)
+	fset := token.NewFileSet()
+	imports := make(map[string]bool)
+	for _, name := range files {
+		filename := filepath.Join(dir, name)
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		// The //line directives in the translated files refer to the
+		// original source files, so their names are part of the key.
+		fmt.Fprintf(h, "file %s %d\n", imp.sourceFile(filename), len(data))
+		h.Write(data)
+
+		f, err := parser.ParseFile(fset, filename, data, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+			imports[path] = true
+		}
+	}
+
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var deps []string
+	for _, path := range paths {
+		if path == "C" || build.IsLocalImport(path) || goroot.IsStandardPackage(runtime.GOROOT(), "gc", path) {
+			fmt.Fprintf(h, "import %s\n", path)
+			continue
+		}
+		cp := imp.packageKey(path, dir)
+		if cp.err != nil {
+			return nil, cp.err
+		}
+		fmt.Fprintf(h, "import %s %x\n", path, cp.key)
+		deps = append(deps, path)
+	}
+	return deps, nil
+}
+
+// rewriteFromCache writes out the translation of the .go2 files in dir,
+// and of all the .go2 packages that they import, from the cache.
+// It reports whether it was able to do so. If it returns false,
+// nothing has been written.
+func (imp *Importer) rewriteFromCache(dir string) (bool, error) {
+	go2files, gofiles, err := go2Files(dir)
+	if err != nil {
+		return false, err
+	}
+	handWritten, err := handWrittenGofiles(dir, gofiles)
+	if err != nil {
+		return false, err
+	}
+	key, deps, err := imp.dirKey(dir, append(go2files, handWritten...))
+	if err != nil {
+		// Let the translation report the problem.
+		return false, nil
+	}
+	entry, ok := imp.cache.get(key)
+	if !ok {
+		return false, nil
+	}
+	depEntries := make([]string, len(deps))
+	for i, dep := range deps {
+		depEntries[i], ok = imp.cache.get(imp.cacheKeys[dep].key)
+		if !ok {
+			return false, nil
+		}
+	}
+
+	for i, dep := range deps {
+		pdir, mpkg, err := imp.findPackage(dep, dir)
+		if err != nil {
+			return false, err
+		}
+		depGo2files, depGofiles, err := go2Files(pdir)
+		if err != nil {
+			return false, err
+		}
+		tdir, err := imp.translationDir(dep, pdir, mpkg)
+		if err != nil {
+			return false, err
+		}
+		if tdir == pdir {
+			if err := checkAndRemoveGofiles(tdir, depGo2files, depGofiles); err != nil {
+				return false, err
+			}
+		}
+		if err := copyFiles(depEntries[i], tdir, goFileNames(depGo2files)); err != nil {
+			return false, err
+		}
+	}
+
+	if err := checkAndRemoveGofiles(dir, go2files, gofiles); err != nil {
+		return false, err
+	}
+	if err := copyFiles(entry, dir, goFileNames(go2files)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+var (
+	toolIDOnce sync.Once
+	toolIDHash cacheKey
+)
+
+// toolID returns a hash of the running executable, so that cache
+// entries written by one version of the translator are not used
+// by another. If the executable can't be read, it returns zero,
+// and the Go version alone distinguishes entries.
+func toolID() cacheKey {
+	toolIDOnce.Do(func() {
+		exe, err := os.Executable()
+		if err != nil {
+			return
+		}
+		f, err := os.Open(exe)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return
+		}
+		h.Sum(toolIDHash[:0])
+	})
+	return toolIDHash
+}
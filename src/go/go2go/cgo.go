@@ -0,0 +1,299 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	_ "unsafe" // for go:linkname
+)
+
+// A .go2 file may import "C". We run cmd/cgo on the files of the
+// package that import "C", with .go2 files copied to .go files, and
+// type check the package along with the resulting _cgo_gotypes.go
+// file, as the go command would after running cgo on the translated
+// .go files. That gives us real types for the references to C names,
+// which we need to instantiate generic code that uses them. The
+// translated files keep the cgo preamble and the import of "C", and
+// the go command runs cgo on them as usual.
+//
+// cgo turns C.name into a reference to a Go name like _Cfunc_name,
+// declared in _cgo_gotypes.go, and that is the object that the type
+// checker records for the name. Generic code that refers to C names
+// may be instantiated in some other package, which can't refer to
+// them. So, as for other unexported names (see exported.go), the
+// package that defines the generic code exports trampolines:
+//
+//	C.T         ->  type Exported୦_Ctype_T = C.T
+//	C.K         ->  const Exported୦_Ciconst_K = C.K
+//	C.v         ->  var Exported୦_Cvar_v = &C.v
+//	C.f         ->  func Exported୦_Cfunc_f(p0 C.int) C.int { return C.f(p0) }
+//
+// Since the names in a cgo preamble are only visible in the file that
+// has the preamble, the trampolines for the C names used by generic
+// code are added to the file that holds that code.
+
+// setUsesCgo tells the type checker to resolve references to C names
+// to the declarations in _cgo_gotypes.go.
+//go:linkname setUsesCgo go/types.go2go_setUsesCgo
+func setUsesCgo(conf *types.Config)
+
+// cgoPrefixes are the prefixes that cgo adds to C names,
+// as in go/types.
+var cgoPrefixes = [...]string{
+	"_Ciconst_",
+	"_Cfconst_",
+	"_Csconst_",
+	"_Ctype_",
+	"_Cvar_",
+	"_Cfunc_",
+	"_Cmacro_",
+}
+
+// importsC reports whether file imports "C".
+func importsC(file *ast.File) bool {
+	for _, imp := range file.Imports {
+		if imp.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+// cgoName returns the C name that obj, a package scope object
+// declared in _cgo_gotypes.go, stands for, or "" if obj is not
+// such an object.
+func cgoName(obj types.Object) string {
+	name := obj.Name()
+	if name == "_Cfunc__CMalloc" {
+		// cgo rewrites C.malloc to _CMalloc.
+		return "malloc"
+	}
+	for _, prefix := range cgoPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimPrefix(name, prefix)
+		}
+	}
+	return ""
+}
+
+// cgoRef returns the expression C.name for obj, a package scope
+// object declared in _cgo_gotypes.go.
+func cgoRef(obj types.Object, pos token.Pos) *ast.SelectorExpr {
+	return &ast.SelectorExpr{
+		X:   &ast.Ident{NamePos: pos, Name: "C"},
+		Sel: &ast.Ident{NamePos: pos, Name: cgoName(obj)},
+	}
+}
+
+// isCPackage reports whether e refers to the package "C".
+func (t *translator) isCPackage(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	pn, ok := t.importer.info.Uses[id].(*types.PkgName)
+	return ok && pn.Imported().Path() == "C"
+}
+
+// cgoFuncDecl returns a trampoline named name for the C function
+// declared as fn in _cgo_gotypes.go.
+func (t *translator) cgoFuncDecl(name *ast.Ident, fn *types.Func, pos token.Pos) *ast.FuncDecl {
+	sig := fn.Type().(*types.Signature)
+	params := &ast.FieldList{Opening: pos, Closing: pos}
+	var args []ast.Expr
+	for i := 0; i < sig.Params().Len(); i++ {
+		pname := &ast.Ident{NamePos: pos, Name: "p" + strconv.Itoa(i)}
+		params.List = append(params.List, &ast.Field{
+			Names: []*ast.Ident{pname},
+			Type:  t.typeToAST(sig.Params().At(i).Type()),
+		})
+		args = append(args, pname)
+	}
+	call := &ast.CallExpr{
+		Fun:    cgoRef(fn, pos),
+		Lparen: pos,
+		Args:   args,
+		Rparen: pos,
+	}
+
+	var results *ast.FieldList
+	var stmt ast.Stmt = &ast.ExprStmt{X: call}
+	// A C function returning void has a result of type
+	// _Ctype_void, which a Go function can't mention.
+	if sig.Results().Len() == 1 && !isCgoVoid(sig.Results().At(0).Type()) {
+		results = &ast.FieldList{
+			List: []*ast.Field{
+				{Type: t.typeToAST(sig.Results().At(0).Type())},
+			},
+		}
+		stmt = &ast.ReturnStmt{
+			Return:  pos,
+			Results: []ast.Expr{call},
+		}
+	}
+
+	return &ast.FuncDecl{
+		Name: name,
+		Type: &ast.FuncType{
+			Func:    pos,
+			Params:  params,
+			Results: results,
+		},
+		Body: &ast.BlockStmt{
+			Lbrace: pos,
+			List:   []ast.Stmt{stmt},
+			Rbrace: pos,
+		},
+	}
+}
+
+// isCgoVoid reports whether typ is the type that cgo uses for
+// the result of a C function that returns void.
+func isCgoVoid(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	return ok && named.Obj().Name() == "_Ctype_void"
+}
+
+// cgoTypes runs cmd/cgo on the files in dir that import "C",
+// and returns the parsed _cgo_gotypes.go file, which declares
+// the Go names that cgo uses for the C names in the files.
+func (imp *Importer) cgoTypes(dir string, files []string) (*ast.File, error) {
+	tmpdir, err := ioutil.TempDir("", "go2go-cgo")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// cmd/cgo and go/build only look at .go files.
+	srcdir := filepath.Join(tmpdir, "src")
+	if err := os.Mkdir(srcdir, 0755); err != nil {
+		return nil, err
+	}
+	for _, name := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		goname := strings.TrimSuffix(name, ".go2")
+		if !strings.HasSuffix(goname, ".go") {
+			goname += ".go"
+		}
+		if err := ioutil.WriteFile(filepath.Join(srcdir, goname), data, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	// Let go/build handle the #cgo directives. Expand ${SRCDIR}
+	// to the directory that holds the sources, not the copies.
+	bp, err := build.ImportDir(srcdir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(bp.CgoFiles) == 0 {
+		return nil, fmt.Errorf("%s: cgo is not enabled, but files import \"C\"", dir)
+	}
+	srcFlags := func(flags []string) []string {
+		r := make([]string, len(flags))
+		for i, f := range flags {
+			r[i] = strings.ReplaceAll(f, srcdir, dir)
+		}
+		return r
+	}
+
+	objdir := filepath.Join(tmpdir, "obj")
+	if err := os.Mkdir(objdir, 0755); err != nil {
+		return nil, err
+	}
+	gotool := filepath.Join(runtime.GOROOT(), "bin", "go")
+	args := []string{"tool", "cgo", "-objdir", objdir, "--"}
+	args = append(args, strings.Fields(os.Getenv("CGO_CPPFLAGS"))...)
+	args = append(args, srcFlags(bp.CgoCPPFLAGS)...)
+	if len(bp.CgoPkgConfig) > 0 {
+		cmd := exec.Command("pkg-config", append([]string{"--cflags"}, bp.CgoPkgConfig...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("pkg-config %s: %v\n%s", strings.Join(bp.CgoPkgConfig, " "), err, out)
+		}
+		args = append(args, strings.Fields(string(out))...)
+	}
+	args = append(args, "-I", dir)
+	args = append(args, strings.Fields(os.Getenv("CGO_CFLAGS"))...)
+	args = append(args, srcFlags(bp.CgoCFLAGS)...)
+	args = append(args, bp.CgoFiles...)
+
+	cmd := exec.Command(gotool, args...)
+	cmd.Dir = srcdir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("running cgo in %s: %v\n%s", dir, err, out)
+	}
+
+	return parser.ParseFile(imp.fset, filepath.Join(objdir, "_cgo_gotypes.go"), nil, 0)
+}
+
+// useCgo prepares conf to type check pkgfiles, the files of a package
+// in dir. If any of the files import "C", it runs cgo, and returns
+// the _cgo_gotypes.go file to type check along with the files.
+func (imp *Importer) useCgo(conf *types.Config, dir string, pkgfiles []namedAST) (*ast.File, error) {
+	var files []string
+	for _, f := range pkgfiles {
+		if importsC(f.ast) {
+			files = append(files, filepath.Base(f.name))
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	gotypes, err := imp.cgoTypes(dir, files)
+	if err != nil {
+		return nil, err
+	}
+	conf.FakeImportC = false
+	setUsesCgo(conf)
+	return gotypes, nil
+}
+
+// importsCSource reports whether the Go source src imports "C".
+func importsCSource(src []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	return err == nil && importsC(f)
+}
+
+// keepCgoPreamble drops the comments of file, which imports "C",
+// other than the cgo preamble.
+func keepCgoPreamble(file *ast.File) {
+	var keep []*ast.CommentGroup
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			imp := spec.(*ast.ImportSpec)
+			if imp.Path.Value != `"C"` {
+				continue
+			}
+			doc := imp.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				doc = gen.Doc
+			}
+			if doc != nil {
+				keep = append(keep, doc)
+			}
+		}
+	}
+	file.Comments = keep
+}
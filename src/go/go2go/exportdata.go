@@ -0,0 +1,183 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"bytes"
+	"go/internal/gcimporter"
+	"go/token"
+	"go/types"
+	"internal/goroot"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// The translation of a package needs the syntax trees of the generic
+// functions and types that it instantiates, along with their type
+// information, so translating requires type checking all the .go2
+// packages that a package imports. Type checking a package does not:
+// the types of the imported packages suffice, and export data, which
+// can describe parameterized functions and types, holds them.
+//
+// The importer therefore stores the export data of each .go2 package
+// that it imports in the package's cache entry, see UseCache. After
+// UseExportData, an importer that is only used to type check packages
+// loads imported .go2 packages whose export data is in the cache from
+// it, without parsing or type checking their files.
+//
+// Export data does not hold the bodies of generic functions and
+// methods, so it does not replace the source for translation. Loading
+// generic packages for translation from export data would require
+// encoding those bodies together with their type information.
+
+// exportDataFile is the name of the file holding the export data
+// of a package in its cache entry.
+const exportDataFile = "_exportdata"
+
+// exportDataHeader precedes the export data in exportDataFile,
+// in the format of an object file that gcimporter expects.
+const exportDataHeader = "go object go2go\n$$B\ni"
+
+// An exportDataImporter imports packages from export data.
+// Packages in the standard library are imported with
+// gcimporter, like the default importer does, but with a map of
+// packages that is shared with the export data of .go2 packages,
+// so that they refer to the same types.
+type exportDataImporter struct {
+	fset     *token.FileSet
+	packages map[string]*types.Package
+	loading  map[string]bool // .go2 packages being imported
+}
+
+func (m *exportDataImporter) Import(path string) (*types.Package, error) {
+	return m.ImportFrom(path, "", 0)
+}
+
+func (m *exportDataImporter) ImportFrom(path, srcDir string, mode types.ImportMode) (*types.Package, error) {
+	return gcimporter.Import(m.fset, m.packages, path, srcDir, nil)
+}
+
+// UseExportData tells the importer that it is only used to type check
+// packages, not to translate them, so that .go2 packages it imports
+// may be loaded from the export data in the cache. This has no effect
+// unless the importer uses a cache, see UseCache. Packages that are
+// not in the cache are translated, and added to it, as usual.
+func (imp *Importer) UseExportData() {
+	ei := &exportDataImporter{
+		fset:     imp.fset,
+		packages: make(map[string]*types.Package),
+		loading:  make(map[string]bool),
+	}
+	imp.exportData = ei
+	imp.defaultImporter = ei
+}
+
+// addExportDataPackage records a package that was type checked from
+// source, so that export data that refers to it uses its types.
+func (imp *Importer) addExportDataPackage(pkg *types.Package) {
+	if imp.exportData != nil {
+		imp.exportData.packages[pkg.Path()] = pkg
+	}
+}
+
+// importExportData imports the .go2 package importPath, imported by
+// a package in dir, from the export data in its cache entry. It
+// returns nil, and no error, if the package is not a .go2 package,
+// or its export data is not in the cache.
+func (imp *Importer) importExportData(importPath, dir string) (*types.Package, error) {
+	if !imp.useCache() || goroot.IsStandardPackage(runtime.GOROOT(), "gc", importPath) {
+		return nil, nil
+	}
+	if pkg := imp.exportData.packages[importPath]; pkg != nil && pkg.Complete() {
+		return pkg, nil
+	}
+	if imp.exportData.loading[importPath] {
+		// An import cycle through test files. Let
+		// the package be type checked from source.
+		return nil, nil
+	}
+	imp.exportData.loading[importPath] = true
+	defer delete(imp.exportData.loading, importPath)
+
+	cp := imp.packageKey(importPath, dir)
+	if cp.err != nil || !cp.go2 {
+		return nil, nil
+	}
+	entry, ok := imp.cache.get(cp.key)
+	if !ok {
+		return nil, nil
+	}
+	filename := filepath.Join(entry, exportDataFile)
+	if _, err := os.Stat(filename); err != nil {
+		return nil, nil
+	}
+
+	// Import the packages it imports first, so that the
+	// export data refers to them rather than creating
+	// incomplete copies of those loaded from source.
+	pdir, _, err := imp.findPackage(importPath, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, dep := range cp.deps {
+		if _, err := imp.ImportFrom(dep, pdir, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	lookup := func(string) (io.ReadCloser, error) {
+		return os.Open(filename)
+	}
+	return gcimporter.Import(imp.fset, imp.exportData.packages, importPath, pdir, lookup)
+}
+
+// putExportData adds the export data of the package importPath,
+// one of pkgs, to the cache entry for key. Nothing is added for
+// a package that is not imported, which has no import path.
+func (imp *Importer) putExportData(key cacheKey, importPath string, pkgs []*types.Package) {
+	if importPath == "" {
+		return
+	}
+	for _, pkg := range pkgs {
+		if pkg.Path() == importPath {
+			imp.cache.putExportData(key, imp.fset, pkg)
+		}
+	}
+}
+
+// putExportData adds the export data of pkg to the cache entry for
+// key, if the entry exists and does not yet have it. As with put,
+// errors are ignored.
+func (c *translationCache) putExportData(key cacheKey, fset *token.FileSet, pkg *types.Package) {
+	entry, ok := c.get(key)
+	if !ok {
+		return
+	}
+	filename := filepath.Join(entry, exportDataFile)
+	if _, err := os.Stat(filename); err == nil {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString(exportDataHeader)
+	if err := gcimporter.IExportData(&buf, fset, pkg); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(entry, "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	// Another process may have added the file meanwhile,
+	// with the same contents.
+	if err != nil || os.Rename(tmp.Name(), filename) != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// Generic functions and types are instantiated in the package that
+// uses them, not the package that defines them. If the generic code
+// refers to unexported names in the defining package, the
+// instantiation can't refer to them directly. So for each such name
+// the defining package exports a trampoline with a mangled name
+// (see exportedName), and the instantiation refers to that instead.
+//
+//	type t          ->  type Exported୦t = t
+//	const c         ->  const Exported୦c = c
+//	func f          ->  var Exported୦f = f
+//	var v           ->  var Exported୦v = &v
+//
+// A reference to the unexported variable v becomes (*p.Exported୦v).
+
+// unexportedRefs returns the unexported non-generic package scope
+// objects of the current package that are referred to by generic
+// functions and types defined in the package.
+func (t *translator) unexportedRefs() []types.Object {
+	info := t.importer.info
+	seen := make(map[types.Object]bool)
+	var r []types.Object
+	find := func(n ast.Node) {
+		ast.Inspect(n, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := info.Uses[id]
+			if obj == nil || seen[obj] || !needsExport(t.tpkg, obj) || cgoName(obj) != "" {
+				return true
+			}
+			seen[obj] = true
+			r = append(r, obj)
+			return true
+		})
+	}
+	for obj, fd := range t.importer.idToFunc {
+		if obj.Pkg() == t.tpkg {
+			find(fd)
+		}
+	}
+	for obj, ts := range t.importer.idToTypeSpec {
+		if obj.Pkg() == t.tpkg && isParameterizedTypeDecl(ts, info) {
+			find(ts)
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Name() < r[j].Name()
+	})
+	return r
+}
+
+// cgoRefs returns the objects declared by cgo for the C names
+// that are referred to by generic functions and types defined
+// in file, along with the C types that their types refer to.
+func (t *translator) cgoRefs(file *ast.File) []types.Object {
+	info := t.importer.info
+	seen := make(map[types.Object]bool)
+	var r []types.Object
+	var add func(obj types.Object)
+	var addType func(typ types.Type)
+	add = func(obj types.Object) {
+		if obj == nil || seen[obj] || obj.Pkg() != t.tpkg || obj.Parent() != t.tpkg.Scope() || cgoName(obj) == "" {
+			return
+		}
+		seen[obj] = true
+		r = append(r, obj)
+		addType(obj.Type())
+	}
+	addType = func(typ types.Type) {
+		switch typ := typ.(type) {
+		case *types.Named:
+			if !isCgoVoid(typ) {
+				add(typ.Obj())
+			}
+		case *types.Pointer:
+			addType(typ.Elem())
+		case *types.Array:
+			addType(typ.Elem())
+		case *types.Signature:
+			for i := 0; i < typ.Params().Len(); i++ {
+				addType(typ.Params().At(i).Type())
+			}
+			for i := 0; i < typ.Results().Len(); i++ {
+				addType(typ.Results().At(i).Type())
+			}
+		}
+	}
+	find := func(n ast.Node) {
+		ast.Inspect(n, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				add(info.Uses[id])
+			}
+			return true
+		})
+	}
+	inFile := func(n ast.Node) bool {
+		return t.fset.File(n.Pos()) == t.file
+	}
+	for obj, fd := range t.importer.idToFunc {
+		if obj.Pkg() == t.tpkg && inFile(fd) {
+			find(fd)
+		}
+	}
+	for obj, ts := range t.importer.idToTypeSpec {
+		if obj.Pkg() == t.tpkg && inFile(ts) && isParameterizedTypeDecl(ts, info) {
+			find(ts)
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Name() < r[j].Name()
+	})
+	return r
+}
+
+// needsExport reports whether obj is an unexported non-generic
+// package scope object of pkg, which means that instantiations in
+// other packages must refer to it through a trampoline.
+func needsExport(pkg *types.Package, obj types.Object) bool {
+	if obj.Pkg() != pkg || obj.Parent() != pkg.Scope() || obj.Exported() {
+		return false
+	}
+	switch obj := obj.(type) {
+	case *types.TypeName:
+		if named, ok := obj.Type().(*types.Named); ok && len(named.TParams()) > 0 {
+			return false
+		}
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); ok && len(sig.TParams()) > 0 {
+			return false
+		}
+	case *types.Const, *types.Var:
+	default:
+		return false
+	}
+	return obj.Name() != "_"
+}
+
+// exportedDecls returns the declarations of the trampolines for the
+// unexported names in objs.
+func (t *translator) exportedDecls(objs []types.Object, pos token.Pos) []ast.Decl {
+	var decls []ast.Decl
+	for _, obj := range objs {
+		name := ast.NewIdent(exportedName(obj.Name()))
+		var ref ast.Expr = ast.NewIdent(obj.Name())
+		if cgoName(obj) != "" {
+			ref = cgoRef(obj, pos)
+		}
+		var decl *ast.GenDecl
+		switch obj := obj.(type) {
+		case *types.TypeName:
+			decl = &ast.GenDecl{
+				Tok: token.TYPE,
+				Specs: []ast.Spec{
+					&ast.TypeSpec{
+						Name:   name,
+						Assign: pos,
+						Type:   ref,
+					},
+				},
+			}
+		case *types.Const:
+			decl = &ast.GenDecl{
+				Tok: token.CONST,
+				Specs: []ast.Spec{
+					&ast.ValueSpec{
+						Names:  []*ast.Ident{name},
+						Values: []ast.Expr{ref},
+					},
+				},
+			}
+		case *types.Func:
+			if cgoName(obj) != "" {
+				// A C function can only be called.
+				decls = append(decls, t.cgoFuncDecl(name, obj, pos))
+				continue
+			}
+			decl = &ast.GenDecl{
+				Tok: token.VAR,
+				Specs: []ast.Spec{
+					&ast.ValueSpec{
+						Names:  []*ast.Ident{name},
+						Values: []ast.Expr{ref},
+					},
+				},
+			}
+		case *types.Var:
+			decl = &ast.GenDecl{
+				Tok: token.VAR,
+				Specs: []ast.Spec{
+					&ast.ValueSpec{
+						Names: []*ast.Ident{name},
+						Values: []ast.Expr{
+							&ast.UnaryExpr{
+								Op: token.AND,
+								X:  ref,
+							},
+						},
+					},
+				},
+			}
+		}
+		decl.TokPos = pos
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+// exportedRef returns an expression that refers to the unexported
+// package scope object obj, defined in some other package, through
+// its trampoline. The object may also be a C name used in a file of
+// the current package that does not import "C", see cgo.go.
+func (t *translator) exportedRef(obj types.Object, pos token.Pos) ast.Expr {
+	var ref ast.Expr = &ast.Ident{NamePos: pos, Name: exportedName(obj.Name())}
+	if obj.Pkg() != t.tpkg {
+		ref = &ast.SelectorExpr{
+			X:   &ast.Ident{NamePos: pos, Name: t.importName(obj.Pkg())},
+			Sel: ref.(*ast.Ident),
+		}
+	}
+	if _, ok := obj.(*types.Var); ok {
+		return &ast.ParenExpr{
+			Lparen: pos,
+			X: &ast.StarExpr{
+				Star: pos,
+				X:    ref,
+			},
+		}
+	}
+	return ref
+}
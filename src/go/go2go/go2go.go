@@ -9,10 +9,12 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
 	"go/types"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -26,8 +28,22 @@ const rewritePrefix = "// Code generated by go2go; DO NOT EDIT.\n\n"
 // It looks for all files with the extension .go2, and parses
 // them as a single package. It writes out a .go file with any
 // polymorphic code rewritten into normal code.
+// If the importer uses a cache, see UseCache, and the translation of
+// the package and of all the .go2 packages that it imports is in the
+// cache, the cached files are written out instead.
+// If the importer has an output root, see SetOutputRoot, the files
+// are written to a copy of dir under the root, and dir is not changed.
 func Rewrite(importer *Importer, dir string) error {
-	_, err := rewriteToPkgs(importer, "", dir)
+	dir, err := importer.outputCopy(dir, nil)
+	if err != nil {
+		return err
+	}
+	if importer.useCache() {
+		if ok, err := importer.rewriteFromCache(dir); ok || err != nil {
+			return err
+		}
+	}
+	_, err = rewriteToPkgs(importer, "", dir)
 	return err
 }
 
@@ -39,11 +55,15 @@ func rewriteToPkgs(importer *Importer, importPath, dir string) ([]*types.Package
 		return nil, err
 	}
 
-	if err := checkAndRemoveGofiles(dir, gofiles); err != nil {
+	handWritten, err := handWrittenGofiles(dir, gofiles)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAndRemoveGofiles(dir, go2files, gofiles); err != nil {
 		return nil, err
 	}
 
-	return rewriteFilesInPath(importer, importPath, dir, go2files)
+	return rewriteFilesInPath(importer, importPath, dir, go2files, handWritten)
 }
 
 // namedAST holds a file name and the AST parsed from that file.
@@ -52,15 +72,24 @@ type namedAST struct {
 	ast  *ast.File
 }
 
-// rewriteFiles rewrites a set of .go2 files in dir.
+// RewriteFiles rewrites a set of .go2 files in dir.
+// As with Rewrite, if the importer has an output root, the files are
+// copied to, and translated in, the corresponding directory under the root.
 func RewriteFiles(importer *Importer, dir string, go2files []string) ([]*types.Package, error) {
-	return rewriteFilesInPath(importer, "", dir, go2files)
+	dir, err := importer.outputCopy(dir, go2files)
+	if err != nil {
+		return nil, err
+	}
+	return rewriteFilesInPath(importer, "", dir, go2files, nil)
 }
 
 // rewriteFilesInPath rewrites a set of .go2 files in dir for importPath.
-func rewriteFilesInPath(importer *Importer, importPath, dir string, go2files []string) ([]*types.Package, error) {
-	fset := token.NewFileSet()
-	pkgs, err := parseFiles(dir, go2files, fset)
+// The hand-written .go files gofiles in dir, if any, are type checked
+// along with the .go2 files, but are not rewritten.
+func rewriteFilesInPath(importer *Importer, importPath, dir string, go2files, gofiles []string) ([]*types.Package, error) {
+	fset := importer.fset
+	files := append(append([]string(nil), go2files...), gofiles...)
+	pkgs, err := parseFiles(importer, dir, files, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -83,40 +112,168 @@ func rewriteFilesInPath(importer *Importer, importPath, dir string, go2files []s
 
 		var merr multiErr
 		conf := types.Config{
-			Importer: importer,
-			Error:    merr.add,
+			Importer:               importer,
+			Error:                  merr.add,
+			FakeImportC:            true,
+			AcceptMethodTypeParams: importer.methodTParams,
+		}
+		checkFiles := asts
+		gotypes, err := importer.useCgo(&conf, dir, pkgfiles)
+		if err != nil {
+			return nil, err
+		}
+		if gotypes != nil {
+			checkFiles = append(asts[:len(asts):len(asts)], gotypes)
 		}
 		path := importPath
 		if path == "" {
 			path = pkg.Name
 		}
-		tpkg, err := conf.Check(path, fset, asts, importer.info)
+		tpkg, err := conf.Check(path, fset, checkFiles, importer.info)
 		if err != nil {
 			return nil, fmt.Errorf("type checking failed for %s\n%v", pkg.Name, merr)
 		}
+		if err := checkGo1Files(fset, importer.info, pkgfiles); err != nil {
+			return nil, err
+		}
 
 		importer.record(pkg.Name, pkgfiles, importPath, tpkg, asts)
 
+		if err := hoistLocalTypes(importer, fset, tpkg, asts); err != nil {
+			return nil, err
+		}
+
 		rpkgs = append(rpkgs, tpkg)
 		tpkgs = append(tpkgs, pkgfiles)
 	}
 
+	// If the translation is in the cache, we still need the
+	// type information for packages that import this one,
+	// but we can skip translating the files.
+	var key cacheKey
+	cacheable := false
+	if importer.useCache() {
+		var err error
+		key, _, err = importer.dirKey(dir, files)
+		cacheable = err == nil
+	}
+	if cacheable {
+		if entry, ok := importer.cache.get(key); ok {
+			if err := copyFiles(entry, dir, goFileNames(go2files)); err != nil {
+				return nil, err
+			}
+			importer.putExportData(key, importPath, rpkgs)
+			return rpkgs, nil
+		}
+	}
+
 	for i, tpkg := range tpkgs {
 		addImportable := 0
 		for j, pkgfile := range tpkg {
-			if !strings.HasSuffix(pkgfile.name, "_test.go2") {
+			if isGo2File(pkgfile.name) && !strings.HasSuffix(pkgfile.name, "_test.go2") {
 				addImportable = j
 				break
 			}
 		}
 
 		for j, pkgfile := range tpkg {
+			if !isGo2File(pkgfile.name) {
+				// A hand-written .go file, compiled as is.
+				continue
+			}
 			if err := rewriteFile(dir, fset, importer, importPath, rpkgs[i], pkgfile.name, pkgfile.ast, j == addImportable); err != nil {
 				return nil, err
 			}
 		}
 	}
 
+	if cacheable {
+		importer.cache.put(key, dir, goFileNames(go2files))
+		importer.putExportData(key, importPath, rpkgs)
+	}
+
+	if err := importer.writeShared(); err != nil {
+		return nil, err
+	}
+
+	return rpkgs, nil
+}
+
+// A Package is a package of .go2 files that has been parsed
+// and type checked, but not translated.
+type Package struct {
+	Files []*ast.File    // files, sorted by name
+	Types *types.Package // type information for the package
+	Info  *types.Info    // type information for the files
+}
+
+// CheckPackages parses and type checks the .go2 files in dir, and
+// any hand-written .go files, including comments, without translating
+// them. Packages imported by the files are translated as usual.
+// This returns more than one package if dir holds an external test
+// package. The positions in the files refer to the importer's FileSet.
+func CheckPackages(importer *Importer, dir string) ([]*Package, error) {
+	go2files, gofiles, err := go2Files(dir)
+	if err != nil {
+		return nil, err
+	}
+	handWritten, err := handWrittenGofiles(dir, gofiles)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := parseFiles(importer, dir, append(go2files, handWritten...), parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var rpkgs []*Package
+	for _, pkg := range pkgs {
+		names := make([]string, 0, len(pkg.Files))
+		for n := range pkg.Files {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		files := make([]*ast.File, 0, len(names))
+		pkgfiles := make([]namedAST, 0, len(names))
+		for _, n := range names {
+			files = append(files, pkg.Files[n])
+			pkgfiles = append(pkgfiles, namedAST{n, pkg.Files[n]})
+		}
+
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Inferred:   make(map[*ast.CallExpr]types.Inferred),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+		}
+		var merr multiErr
+		conf := types.Config{
+			Importer:               importer,
+			Error:                  merr.add,
+			FakeImportC:            true,
+			AcceptMethodTypeParams: importer.methodTParams,
+		}
+		checkFiles := files
+		gotypes, err := importer.useCgo(&conf, dir, pkgfiles)
+		if err != nil {
+			return nil, err
+		}
+		if gotypes != nil {
+			checkFiles = append(files[:len(files):len(files)], gotypes)
+		}
+		tpkg, err := conf.Check(pkg.Name, importer.fset, checkFiles, info)
+		if err != nil {
+			return nil, fmt.Errorf("type checking failed for %s\n%v", pkg.Name, merr)
+		}
+		rpkgs = append(rpkgs, &Package{
+			Files: files,
+			Types: tpkg,
+			Info:  info,
+		})
+	}
 	return rpkgs, nil
 }
 
@@ -124,27 +281,31 @@ func rewriteFilesInPath(importer *Importer, importPath, dir string, go2files []s
 // It returns a modified buffer. The filename parameter is only used
 // for error messages.
 func RewriteBuffer(importer *Importer, filename string, file []byte) ([]byte, error) {
-	fset := token.NewFileSet()
+	fset := importer.fset
 	pf, err := parser.ParseFile(fset, filename, file, 0)
 	if err != nil {
 		return nil, err
 	}
 	var merr multiErr
 	conf := types.Config{
-		Importer: importer,
-		Error:    merr.add,
+		Importer:               importer,
+		Error:                  merr.add,
+		AcceptMethodTypeParams: importer.methodTParams,
 	}
 	tpkg, err := conf.Check(pf.Name.Name, fset, []*ast.File{pf}, importer.info)
 	if err != nil {
 		return nil, fmt.Errorf("type checking failed for %s\n%v", pf.Name.Name, merr)
 	}
 	importer.addIDs(pf)
+	if err := hoistLocalTypes(importer, fset, tpkg, []*ast.File{pf}); err != nil {
+		return nil, err
+	}
 	if err := rewriteAST(fset, importer, "", tpkg, pf, true); err != nil {
 		return nil, err
 	}
 	var buf bytes.Buffer
 	fmt.Fprintln(&buf, rewritePrefix)
-	if err := config.Fprint(&buf, fset, pf); err != nil {
+	if err := printFile(&buf, fset, pf); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
@@ -152,8 +313,6 @@ func RewriteBuffer(importer *Importer, filename string, file []byte) ([]byte, er
 
 // go2Files returns the list of files in dir with a .go2 extension
 // and a list of files with a .go extension.
-// This returns an error if it finds any .go files that do not start
-// with rewritePrefix.
 func go2Files(dir string) (go2files []string, gofiles []string, err error) {
 	f, err := os.Open(dir)
 	if err != nil {
@@ -181,16 +340,34 @@ func go2Files(dir string) (go2files []string, gofiles []string, err error) {
 	return go2files, gofiles, nil
 }
 
+// isGo2File reports whether the file name has a .go2 extension.
+func isGo2File(name string) bool {
+	return filepath.Ext(name) == ".go2"
+}
+
 // checkAndRemoveGofiles looks through all the .go files.
 // Any .go file that starts with rewritePrefix is removed.
-// Any other .go file is reported as an error.
+// Any other .go file is a hand-written file that is compiled along
+// with the translated .go2 files, and is left alone. It is reported
+// as an error if translating go2files would overwrite it.
 // This is intended to make it harder for go2go to break a
 // traditional Go package.
-func checkAndRemoveGofiles(dir string, gofiles []string) error {
+func checkAndRemoveGofiles(dir string, go2files, gofiles []string) error {
+	translated := make(map[string]bool)
+	for _, f := range goFileNames(go2files) {
+		translated[f] = true
+	}
 	for _, f := range gofiles {
-		if err := checkGoFile(dir, f); err != nil {
+		generated, err := isGenerated(dir, f)
+		if err != nil {
 			return err
 		}
+		if !generated {
+			if translated[f] {
+				return fmt.Errorf("Go file %s was not created by go2go", f)
+			}
+			continue
+		}
 		if err := os.Remove(filepath.Join(dir, f)); err != nil {
 			return err
 		}
@@ -198,33 +375,125 @@ func checkAndRemoveGofiles(dir string, gofiles []string) error {
 	return nil
 }
 
-// checkGofile reports an error if the file does not start with rewritePrefix.
-func checkGoFile(dir, f string) error {
+// isGenerated reports whether the file starts with rewritePrefix.
+func isGenerated(dir, f string) (bool, error) {
 	o, err := os.Open(filepath.Join(dir, f))
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer o.Close()
 	var buf [100]byte
 	n, err := o.Read(buf[:])
-	if n > 0 && !strings.HasPrefix(string(buf[:n]), rewritePrefix) {
-		return fmt.Errorf("Go file %s was not created by go2go", f)
-	}
 	if err != nil && err != io.EOF {
-		return err
+		return false, err
+	}
+	return strings.HasPrefix(string(buf[:n]), rewritePrefix), nil
+}
+
+// handWrittenGofiles returns the .go files among gofiles in dir that
+// were not created by go2go, and that match the build constraints of
+// the default build context. Those files are type checked along
+// with the .go2 files of the package, and compiled as they are.
+func handWrittenGofiles(dir string, gofiles []string) ([]string, error) {
+	var r []string
+	for _, f := range gofiles {
+		generated, err := isGenerated(dir, f)
+		if err != nil {
+			return nil, err
+		}
+		if generated {
+			continue
+		}
+		match, err := build.Default.MatchFile(dir, f)
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			r = append(r, f)
+		}
+	}
+	sort.Strings(r)
+	return r, nil
+}
+
+// checkGo1Files reports an error if any of the hand-written .go files
+// among files declares or uses generic functions or types.
+// Only .go2 files are translated, so such uses would not compile.
+func checkGo1Files(fset *token.FileSet, info *types.Info, files []namedAST) error {
+	for _, f := range files {
+		if isGo2File(f.name) {
+			continue
+		}
+		var err error
+		ast.Inspect(f.ast, func(n ast.Node) bool {
+			if err != nil {
+				return false
+			}
+			var what string
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if n.Type.TParams != nil {
+					what = "declares generic function " + n.Name.Name
+				}
+			case *ast.TypeSpec:
+				if n.TParams != nil {
+					what = "declares generic type " + n.Name.Name
+				}
+			case *ast.Ident:
+				if obj := info.Uses[n]; obj != nil && isGeneric(obj) {
+					what = "uses generic " + n.Name
+				}
+			}
+			if what != "" {
+				err = fmt.Errorf("%s: hand-written .go file %s; only .go2 files may use generics", fset.Position(n.Pos()), what)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// parseFiles parses a list of .go2 files.
-func parseFiles(dir string, go2files []string, fset *token.FileSet) ([]*ast.Package, error) {
+// isGeneric reports whether obj is a generic function or type.
+func isGeneric(obj types.Object) bool {
+	switch obj := obj.(type) {
+	case *types.TypeName:
+		named, ok := obj.Type().(*types.Named)
+		return ok && len(named.TParams()) > 0 && len(named.TArgs()) == 0
+	case *types.Func:
+		sig, ok := obj.Type().(*types.Signature)
+		return ok && len(sig.TParams()) > 0
+	}
+	return false
+}
+
+// parseFiles parses a list of .go2 files, and any hand-written .go files.
+// The files are recorded in the importer's FileSet under the names
+// of the original source files, so that positions, and the //line
+// directives we write, refer to the original .go2 files even when
+// we are translating a copy. The cgo preamble of a file that imports
+// "C" is kept even if mode doesn't ask for comments.
+func parseFiles(importer *Importer, dir string, files []string, mode parser.Mode) ([]*ast.Package, error) {
 	pkgs := make(map[string]*ast.Package)
-	for _, go2f := range go2files {
-		filename := filepath.Join(dir, go2f)
-		pf, err := parser.ParseFile(fset, filename, nil, 0)
+	for _, name := range files {
+		filename := filepath.Join(dir, name)
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		fmode := mode
+		if mode&parser.ParseComments == 0 && importsCSource(src) {
+			fmode |= parser.ParseComments
+		}
+		pf, err := parser.ParseFile(importer.fset, importer.sourceFile(filename), src, fmode)
 		if err != nil {
 			return nil, err
 		}
+		if fmode != mode {
+			keepCgoPreamble(pf)
+		}
 
 		name := pf.Name.Name
 		pkg, ok := pkgs[name]
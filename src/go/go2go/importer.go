@@ -24,7 +24,8 @@ import (
 )
 
 // Importer implements the types.ImporterFrom interface.
-// It looks for Go2 packages using GO2PATH.
+// It looks for Go2 packages using GO2PATH, or, in module mode,
+// using the build list of the main module.
 // Imported Go2 packages are rewritten to normal Go packages.
 // This type also tracks references across imported packages.
 type Importer struct {
@@ -34,9 +35,30 @@ type Importer struct {
 	// Temporary directory used to rewrite packages.
 	tmpdir string
 
+	// Root directory of the main module, if in module mode.
+	// This is empty in GOPATH mode.
+	modRoot string
+
+	// Modules in the build list of the main module.
+	// Only used in module mode; loaded on first use.
+	buildList []*module
+
+	// Map from module path to the directory holding a translated
+	// copy of the module. Only used in module mode.
+	replacements map[string]string
+
+	// FileSet shared by all parsed files, so that positions
+	// in generic code remain valid when it is instantiated
+	// in a different package.
+	fset *token.FileSet
+
 	// Aggregated info from go/types.
 	info *types.Info
 
+	// Map from a directory holding copies of source files
+	// to the directory holding the original source files.
+	sourceDirs map[string]string
+
 	// Map from import path to directory holding rewritten files.
 	translated map[string]string
 
@@ -53,6 +75,47 @@ type Importer struct {
 	// Map from Object to AST type definition for parameterized types.
 	idToTypeSpec map[types.Object]*ast.TypeSpec
 
+	// Map from a function-local type to its hoisted
+	// package scope version.
+	hoisted map[*types.TypeName]*hoistedType
+
+	// Map from a Package to its hoisted local types,
+	// indexed by package scope name.
+	hoistedNames map[*types.Package]map[string]*types.TypeName
+
+	// Whether tmpdir is an output root holding all translated
+	// packages, see SetOutputRoot.
+	outputRoot bool
+
+	// Directory holding the copy of the main module under
+	// the output root, once it has been made.
+	mainCopy string
+
+	// Persistent cache of translated packages; nil if not used.
+	cache *translationCache
+
+	// Importer of export data; nil unless packages may be
+	// loaded from export data, see UseExportData.
+	exportData *exportDataImporter
+
+	// Map from import path to cache key information.
+	// Only used if cache is not nil.
+	cacheKeys map[string]*cachedPackage
+
+	// Map from a Package to its shared instantiations;
+	// nil unless instantiations are shared.
+	shared map[*types.Package]*sharedInstances
+
+	// Map from a generic function and its shaped type
+	// parameters to whether and how the function can be
+	// shared by type arguments of the same shape;
+	// nil unless instantiations are grouped by shape.
+	shapes map[shapeKey]*shapeFunc
+
+	// Whether methods may have type parameters,
+	// see AcceptMethodTypeParams.
+	methodTParams bool
+
 	// Map from a Package to the instantiations we've created
 	// for that package. This doesn't really belong here,
 	// since it doesn't deal with import information,
@@ -64,22 +127,30 @@ var _ types.ImporterFrom = &Importer{}
 
 // NewImporter returns a new Importer.
 // The tmpdir will become a GOPATH with translated files.
+// In module mode, see UseModules, it will instead hold
+// translated copies of modules.
 func NewImporter(tmpdir string) *Importer {
 	info := &types.Info{
-		Types:    make(map[ast.Expr]types.TypeAndValue),
-		Inferred: make(map[*ast.CallExpr]types.Inferred),
-		Defs:     make(map[*ast.Ident]types.Object),
-		Uses:     make(map[*ast.Ident]types.Object),
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Inferred:   make(map[*ast.CallExpr]types.Inferred),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
 	}
 	return &Importer{
 		defaultImporter: importer.Default().(types.ImporterFrom),
 		tmpdir:          tmpdir,
+		fset:            token.NewFileSet(),
 		info:            info,
+		sourceDirs:      make(map[string]string),
 		translated:      make(map[string]string),
 		packages:        make(map[string]*types.Package),
 		imports:         make(map[string][]string),
 		idToFunc:        make(map[types.Object]*ast.FuncDecl),
 		idToTypeSpec:    make(map[types.Object]*ast.TypeSpec),
+		hoisted:         make(map[*types.TypeName]*hoistedType),
+		hoistedNames:    make(map[*types.Package]map[string]*types.TypeName),
 		instantiations:  make(map[*types.Package]*instantiations),
 	}
 }
@@ -107,63 +178,31 @@ func (imp *Importer) ImportFrom(importPath, dir string, mode types.ImportMode) (
 		return tpkg, nil
 	}
 
-	var pdir string
-	if go2path := os.Getenv("GO2PATH"); go2path != "" {
-		pdir = imp.findFromPath(go2path, importPath)
-	}
-	if pdir == "" {
-		bpkg, err := build.Import(importPath, dir, build.FindOnly)
-		if err != nil {
-			return nil, err
+	if imp.exportData != nil {
+		if tpkg, err := imp.importExportData(importPath, dir); tpkg != nil || err != nil {
+			return tpkg, err
 		}
-		pdir = bpkg.Dir
 	}
 
-	// If the directory holds .go2 files, we need to translate them.
-	fdir, err := os.Open(pdir)
+	pdir, mpkg, err := imp.findPackage(importPath, dir)
 	if err != nil {
 		return nil, err
 	}
-	defer fdir.Close()
-	names, err := fdir.Readdirnames(-1)
+
+	// If the directory holds .go2 files, we need to translate them.
+	go2files, gofiles, err := go2Files(pdir)
 	if err != nil {
 		return nil, err
 	}
-	var gofiles, go2files []string
-	for _, name := range names {
-		switch filepath.Ext(name) {
-		case ".go":
-			gofiles = append(gofiles, name)
-		case ".go2":
-			go2files = append(go2files, name)
-		}
-	}
 
 	if len(go2files) == 0 {
 		return imp.importGo1Package(importPath, dir, mode, pdir, gofiles)
 	}
 
-	if len(gofiles) > 0 {
-		for _, gofile := range gofiles {
-			if err := checkGoFile(pdir, gofile); err != nil {
-				return nil, err
-			}
-		}
-	}
-
-	tdir := filepath.Join(imp.tmpdir, "src", importPath)
-	if err := os.MkdirAll(tdir, 0755); err != nil {
+	tdir, err := imp.translationDir(importPath, pdir, mpkg)
+	if err != nil {
 		return nil, err
 	}
-	for _, name := range go2files {
-		data, err := ioutil.ReadFile(filepath.Join(pdir, name))
-		if err != nil {
-			return nil, err
-		}
-		if err := ioutil.WriteFile(filepath.Join(tdir, name), data, 0644); err != nil {
-			return nil, err
-		}
-	}
 
 	imp.translated[importPath] = tdir
 
@@ -186,6 +225,110 @@ func (imp *Importer) ImportFrom(importPath, dir string, mode types.ImportMode) (
 	return nil, fmt.Errorf("unexpected number of packages (%d) for %q (directory %q)", len(tpkgs), importPath, pdir)
 }
 
+// findPackage returns the directory holding the package importPath,
+// imported by a package in dir. In module mode, if the package is not
+// in the standard library, it also returns the module that holds it.
+func (imp *Importer) findPackage(importPath, dir string) (string, *modulePackage, error) {
+	if imp.modRoot != "" && !goroot.IsStandardPackage(runtime.GOROOT(), "gc", importPath) {
+		mpkg, err := imp.findModulePackage(importPath)
+		if err != nil {
+			return "", nil, err
+		}
+		return mpkg.dir, mpkg, nil
+	}
+	if go2path := os.Getenv("GO2PATH"); go2path != "" && imp.modRoot == "" {
+		if pdir := imp.findFromPath(go2path, importPath); pdir != "" {
+			return pdir, nil, nil
+		}
+	}
+	bpkg, err := build.Import(importPath, dir, build.FindOnly)
+	if err != nil {
+		return "", nil, err
+	}
+	return bpkg.Dir, nil, nil
+}
+
+// translationDir returns the directory in which to translate the
+// .go2 files of the package importPath, found in pdir. In GOPATH mode
+// this is a directory in the importer's GOPATH, holding copies of
+// the source files of the package. In module mode, see
+// moduleTranslationDir.
+func (imp *Importer) translationDir(importPath, pdir string, mpkg *modulePackage) (string, error) {
+	if mpkg != nil {
+		return imp.moduleTranslationDir(mpkg)
+	}
+	tdir := filepath.Join(imp.tmpdir, "src", importPath)
+	if err := copySourceFiles(pdir, tdir); err != nil {
+		return "", err
+	}
+	imp.SetSourceDir(tdir, pdir)
+	return tdir, nil
+}
+
+// sourceFiles returns the names of the regular files in dir, other
+// than .go files created by go2go. Besides the .go2 files, a package
+// may have hand-written .go files, and assembly or C files.
+func sourceFiles(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		if filepath.Ext(fi.Name()) == ".go" {
+			generated, err := isGenerated(dir, fi.Name())
+			if err != nil {
+				return nil, err
+			}
+			if generated {
+				continue
+			}
+		}
+		names = append(names, fi.Name())
+	}
+	return names, nil
+}
+
+// FileSet returns the FileSet that records the positions
+// of all the files that the importer reads.
+func (imp *Importer) FileSet() *token.FileSet {
+	return imp.fset
+}
+
+// TranslatedDir returns the directory in which the importer has
+// translated the .go2 files of the package importPath, or the empty
+// string if it has not imported importPath as a .go2 package.
+func (imp *Importer) TranslatedDir(importPath string) string {
+	return imp.translated[importPath]
+}
+
+// SetSourceDir records that the files in dir are copies of files
+// in srcdir. Translated files refer to the original files in srcdir,
+// so that error messages, stack traces and the like point at the
+// .go2 files that the user actually wrote.
+// If srcdir itself holds copies, the files refer to its originals.
+func (imp *Importer) SetSourceDir(dir, srcdir string) {
+	dir, srcdir = filepath.Clean(dir), filepath.Clean(srcdir)
+	if orig, ok := imp.sourceDirs[srcdir]; ok {
+		srcdir = orig
+	}
+	if dir != srcdir {
+		imp.sourceDirs[dir] = srcdir
+	}
+}
+
+// sourceFile returns the name of the original source file
+// for the file name.
+func (imp *Importer) sourceFile(name string) string {
+	if srcdir, ok := imp.sourceDirs[filepath.Dir(name)]; ok {
+		return filepath.Join(srcdir, filepath.Base(name))
+	}
+	return name
+}
+
 // findFromPath looks for a directory under gopath.
 func (imp *Importer) findFromPath(gopath, dir string) string {
 	if filepath.IsAbs(dir) || build.IsLocalImport(dir) {
@@ -215,7 +358,7 @@ func (imp *Importer) importGo1Package(importPath, dir string, mode types.ImportM
 		return nil, fmt.Errorf("importing %q: no Go files in %s", importPath, pdir)
 	}
 
-	fset := token.NewFileSet()
+	fset := imp.fset
 	filter := func(fi os.FileInfo) bool {
 		return !strings.HasSuffix(fi.Name(), "_test.go")
 	}
@@ -242,13 +385,15 @@ func (imp *Importer) importGo1Package(importPath, dir string, mode types.ImportM
 
 	var merr multiErr
 	conf := types.Config{
-		Importer: imp,
-		Error:    merr.add,
+		Importer:               imp,
+		Error:                  merr.add,
+		AcceptMethodTypeParams: imp.methodTParams,
 	}
-	tpkg, err := conf.Check(apkg.Name, fset, asts, imp.info)
+	tpkg, err := conf.Check(importPath, fset, asts, imp.info)
 	if err != nil {
 		return nil, merr
 	}
+	imp.addExportDataPackage(tpkg)
 
 	return tpkg, nil
 }
@@ -300,6 +445,7 @@ func (imp *Importer) record(pkgName string, pkgfiles []namedAST, importPath stri
 	if !strings.HasSuffix(pkgName, "_test") {
 		if importPath != "" {
 			imp.packages[importPath] = tpkg
+			imp.addExportDataPackage(tpkg)
 		}
 		imp.imports[importPath] = imp.collectImports(asts)
 	}
@@ -324,6 +470,10 @@ func (imp *Importer) collectImports(asts []*ast.File) []string {
 					continue
 				}
 				path := strings.TrimPrefix(strings.TrimSuffix(imp.Path.Value, `"`), `"`)
+				if path == "C" {
+					// Only used by hand-written cgo files.
+					continue
+				}
 				m[path] = true
 			}
 		}
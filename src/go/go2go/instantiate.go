@@ -18,6 +18,12 @@ type typeArgs struct {
 	types []types.Type // type arguments in order
 	toAST map[types.Object]ast.Expr
 	toTyp map[*types.TypeParam]types.Type
+
+	// shape is set when building a shape instantiation,
+	// see shape.go; skip is an expression being instantiated
+	// as part of its shape operation.
+	shape *shapeFunc
+	skip  ast.Expr
 }
 
 // newTypeArgs returns a new typeArgs value.
@@ -101,6 +107,12 @@ func (t *translator) instantiateFunction(qid qualifiedIdent, astTypes []ast.Expr
 		return nil, err
 	}
 
+	if t.importer.shapes != nil {
+		if instIdent := t.instantiateShaped(qid, decl, name, astTypes, typeTypes); instIdent != nil {
+			return instIdent, nil
+		}
+	}
+
 	ta := typeArgsFromFields(t, astTypes, typeTypes, decl.Type.TParams.List)
 
 	instIdent := ast.NewIdent(name)
@@ -141,7 +153,13 @@ func (t *translator) findTypesObject(qid qualifiedIdent) types.Object {
 				return obj
 			}
 		}
-		return t.tpkg.Scope().Lookup(qid.ident.Name)
+		if obj := t.tpkg.Scope().Lookup(qid.ident.Name); obj != nil {
+			return obj
+		}
+		if obj := t.importer.lookupHoisted(t.tpkg, qid.ident.Name); obj != nil {
+			return obj
+		}
+		return nil
 	} else {
 		return qid.pkg.Scope().Lookup(qid.ident.Name)
 	}
@@ -164,8 +182,9 @@ func (t *translator) instantiateTypeDecl(qid qualifiedIdent, typ *types.Named, a
 		Comment: spec.Comment,
 	}
 	newDecl := &ast.GenDecl{
-		Tok:   token.TYPE,
-		Specs: []ast.Spec{newSpec},
+		TokPos: spec.Pos(),
+		Tok:    token.TYPE,
+		Specs:  []ast.Spec{newSpec},
 	}
 	t.newDecls = append(t.newDecls, newDecl)
 
@@ -183,16 +202,28 @@ func (t *translator) instantiateTypeDecl(qid qualifiedIdent, typ *types.Named, a
 	nm := typ.NumMethods()
 	for i := 0; i < nm; i++ {
 		method := typ.Method(i)
+		if len(method.Type().(*types.Signature).TParams()) > 0 {
+			// Instantiated when called, see methods.go.
+			continue
+		}
 		mast, ok := t.importer.lookupFunc(method)
 		if !ok {
 			panic(fmt.Sprintf("no AST for method %v", method))
 		}
 		rtyp := mast.Recv.List[0].Type
-		newRtype := ast.Expr(ast.NewIdent(instIdent.Name))
+		newRtype := ast.Expr(&ast.Ident{
+			NamePos: rtyp.Pos(),
+			Name:    instIdent.Name,
+		})
 		if p, ok := rtyp.(*ast.StarExpr); ok {
 			rtyp = p.X
+			newRtype = &ast.Ident{
+				NamePos: rtyp.Pos(),
+				Name:    instIdent.Name,
+			}
 			newRtype = &ast.StarExpr{
-				X: newRtype,
+				Star: p.Star,
+				X:    newRtype,
 			}
 		}
 		tparams := rtyp.(*ast.CallExpr).Args
@@ -618,6 +649,12 @@ func (t *translator) instantiateField(ta *typeArgs, f *ast.Field) *ast.Field {
 
 // instantiateExpr instantiates an expression.
 func (t *translator) instantiateExpr(ta *typeArgs, e ast.Expr) ast.Expr {
+	if ta.shape != nil && e != ta.skip {
+		if op, ok := ta.shape.ops[e]; ok {
+			return t.instantiateShapeOp(ta, op, e)
+		}
+	}
+
 	var r ast.Expr
 	switch e := e.(type) {
 	case nil:
@@ -626,7 +663,7 @@ func (t *translator) instantiateExpr(ta *typeArgs, e ast.Expr) ast.Expr {
 		obj := t.importer.info.ObjectOf(e)
 		if obj != nil {
 			if typ, ok := ta.ast(obj); ok {
-				return typ
+				return t.exprAt(typ, e.Pos())
 			}
 		}
 		return e
@@ -691,7 +728,26 @@ func (t *translator) instantiateExpr(ta *typeArgs, e ast.Expr) ast.Expr {
 			}
 		}
 
-		if x == e.X && !instantiate {
+		// A method with type parameters is instantiated using
+		// the type arguments of the receiver type, see methods.go,
+		// so make sure that the instantiated receiver has a type.
+		if _, method := t.genericMethod(e); method != nil && (x == e.X || t.lookupType(x) == nil) {
+			if xt := t.lookupType(e.X); xt != nil {
+				if it := t.instantiateType(ta, xt); it != xt {
+					x = &ast.ParenExpr{
+						Lparen: x.Pos(),
+						X:      x,
+						Rparen: x.End(),
+					}
+					t.setType(x, it)
+				}
+			}
+		}
+
+		// A reference to a C name may be rewritten when the
+		// instantiation is translated, see cgo.go, so don't
+		// share it with the generic code.
+		if x == e.X && !instantiate && !t.isCPackage(e.X) {
 			return e
 		}
 		r = &ast.SelectorExpr{
@@ -956,3 +1012,103 @@ func (t *translator) instantiateExprList(ta *typeArgs, el []ast.Expr) ([]ast.Exp
 	}
 	return nel, true
 }
+
+// exprAt returns a copy of the expression e with all positions set to pos.
+// This is used when substituting a type argument for a type parameter,
+// so that the substituted expression appears at the position of the
+// type parameter in the generic code. That keeps the printer from
+// breaking lines oddly, and keeps the //line directives that we emit
+// pointing at the generic code. Expressions that can't be easily copied
+// are returned unchanged.
+func (t *translator) exprAt(e ast.Expr, pos token.Pos) ast.Expr {
+	if !pos.IsValid() {
+		return e
+	}
+	var r ast.Expr
+	switch e := e.(type) {
+	case *ast.Ident:
+		id := &ast.Ident{
+			NamePos: pos,
+			Name:    e.Name,
+		}
+		if obj, ok := t.importer.info.Uses[e]; ok {
+			t.importer.info.Uses[id] = obj
+		}
+		r = id
+	case *ast.BasicLit:
+		r = &ast.BasicLit{
+			ValuePos: pos,
+			Kind:     e.Kind,
+			Value:    e.Value,
+		}
+	case *ast.SelectorExpr:
+		r = &ast.SelectorExpr{
+			X:   t.exprAt(e.X, pos),
+			Sel: t.exprAt(e.Sel, pos).(*ast.Ident),
+		}
+	case *ast.ParenExpr:
+		r = &ast.ParenExpr{
+			Lparen: pos,
+			X:      t.exprAt(e.X, pos),
+			Rparen: pos,
+		}
+	case *ast.StarExpr:
+		r = &ast.StarExpr{
+			Star: pos,
+			X:    t.exprAt(e.X, pos),
+		}
+	case *ast.ArrayType:
+		var ln ast.Expr
+		if e.Len != nil {
+			ln = t.exprAt(e.Len, pos)
+		}
+		r = &ast.ArrayType{
+			Lbrack: pos,
+			Len:    ln,
+			Elt:    t.exprAt(e.Elt, pos),
+		}
+	case *ast.MapType:
+		r = &ast.MapType{
+			Map:   pos,
+			Key:   t.exprAt(e.Key, pos),
+			Value: t.exprAt(e.Value, pos),
+		}
+	case *ast.ChanType:
+		arrow := token.NoPos
+		if e.Arrow.IsValid() {
+			arrow = pos
+		}
+		r = &ast.ChanType{
+			Begin: pos,
+			Arrow: arrow,
+			Dir:   e.Dir,
+			Value: t.exprAt(e.Value, pos),
+		}
+	case *ast.Ellipsis:
+		r = &ast.Ellipsis{
+			Ellipsis: pos,
+			Elt:      t.exprAt(e.Elt, pos),
+		}
+	case *ast.CallExpr:
+		args := make([]ast.Expr, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = t.exprAt(arg, pos)
+		}
+		call := &ast.CallExpr{
+			Fun:    t.exprAt(e.Fun, pos),
+			Lparen: pos,
+			Args:   args,
+			Rparen: pos,
+		}
+		if inferred, ok := t.importer.info.Inferred[e]; ok {
+			t.importer.info.Inferred[call] = inferred
+		}
+		r = call
+	default:
+		return e
+	}
+	if typ := t.lookupType(e); typ != nil {
+		t.setType(r, typ)
+	}
+	return r
+}
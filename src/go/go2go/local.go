@@ -0,0 +1,386 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// Instantiations are written out at package scope, so they can't
+// refer to function-local types. To support using local types as
+// type arguments, and to support local parameterized types, we move
+// ("hoist") such local types to package scope, giving them mangled
+// names. This is done for all the files in a package before any
+// translation, so that the hoisted types are visible when
+// instantiating generic code defined in any file.
+
+// A localType is a type declared within a function.
+type localType struct {
+	spec  *ast.TypeSpec
+	decl  *ast.GenDecl // declaration holding spec
+	stmts *[]ast.Stmt  // statement list holding decl
+	obj   *types.TypeName
+}
+
+// hoister is used to move local types of one function to package scope.
+type hoister struct {
+	importer *Importer
+	tpkg     *types.Package
+	fset     *token.FileSet
+	funcName string
+	generic  bool // whether the function is generic
+
+	locals  map[*types.TypeName]*localType
+	hoisted map[*types.TypeName]bool
+	decls   []ast.Decl // hoisted declarations
+}
+
+// hoistLocalTypes moves local types to package scope when they are
+// used as type arguments, or when they are parameterized.
+// The hoisted declarations are added to the file holding the function.
+func hoistLocalTypes(importer *Importer, fset *token.FileSet, tpkg *types.Package, files []*ast.File) error {
+	for _, file := range files {
+		var decls []ast.Decl
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			h := &hoister{
+				importer: importer,
+				tpkg:     tpkg,
+				fset:     fset,
+				funcName: fd.Name.Name,
+				generic:  isParameterizedFuncDecl(fd, importer.info),
+				locals:   make(map[*types.TypeName]*localType),
+				hoisted:  make(map[*types.TypeName]bool),
+			}
+			if err := h.hoist(fd.Body); err != nil {
+				return err
+			}
+			decls = append(decls, h.decls...)
+		}
+		file.Decls = append(file.Decls, decls...)
+	}
+	return nil
+}
+
+// hoist hoists the local types in body that need it.
+func (h *hoister) hoist(body *ast.BlockStmt) error {
+	h.collectLocals(body)
+	if len(h.locals) == 0 {
+		return nil
+	}
+
+	// Parameterized local types are always hoisted.
+	// Non-parameterized local types are hoisted if they are used
+	// as type arguments. We don't do this in generic functions,
+	// as a local type in a generic function may depend on the
+	// type parameters, and is instantiated along with the function.
+	var want []*types.TypeName
+	for obj, lt := range h.locals {
+		if lt.spec.TParams != nil {
+			want = append(want, obj)
+		}
+	}
+	if !h.generic {
+		want = append(want, h.typeArgLocals(body)...)
+	}
+
+	for _, obj := range want {
+		if err := h.hoistType(obj); err != nil {
+			return err
+		}
+	}
+	if len(h.hoisted) == 0 {
+		return nil
+	}
+
+	// Rename all references to the hoisted types.
+	ast.Inspect(body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if tn, ok := h.importer.info.ObjectOf(id).(*types.TypeName); ok && h.hoisted[tn] {
+				id.Name = h.importer.hoistedName(tn)
+			}
+		}
+		return true
+	})
+
+	// Remove the hoisted specs from their local declarations.
+	for _, lt := range h.locals {
+		if !h.hoisted[lt.obj] {
+			continue
+		}
+		specs := lt.decl.Specs[:0]
+		for _, s := range lt.decl.Specs {
+			if s != ast.Spec(lt.spec) {
+				specs = append(specs, s)
+			}
+		}
+		lt.decl.Specs = specs
+		if len(specs) > 0 {
+			continue
+		}
+		stmts := (*lt.stmts)[:0]
+		for _, s := range *lt.stmts {
+			if ds, ok := s.(*ast.DeclStmt); !ok || ds.Decl != ast.Decl(lt.decl) {
+				stmts = append(stmts, s)
+			}
+		}
+		*lt.stmts = stmts
+	}
+
+	return nil
+}
+
+// collectLocals records all the local type declarations in body.
+func (h *hoister) collectLocals(body *ast.BlockStmt) {
+	collect := func(stmts *[]ast.Stmt) {
+		for _, s := range *stmts {
+			ds, ok := s.(*ast.DeclStmt)
+			if !ok {
+				continue
+			}
+			gd := ds.Decl.(*ast.GenDecl)
+			if gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				obj, ok := h.importer.info.Defs[ts.Name].(*types.TypeName)
+				if !ok {
+					continue
+				}
+				h.locals[obj] = &localType{
+					spec:  ts,
+					decl:  gd,
+					stmts: stmts,
+					obj:   obj,
+				}
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			collect(&n.List)
+		case *ast.CaseClause:
+			collect(&n.Body)
+		case *ast.CommClause:
+			collect(&n.Body)
+		}
+		return true
+	})
+}
+
+// typeArgLocals returns the local types used as type arguments in body.
+func (h *hoister) typeArgLocals(body *ast.BlockStmt) []*types.TypeName {
+	info := h.importer.info
+	seen := make(map[*types.TypeName]bool)
+	var r []*types.TypeName
+	var add func(types.Type)
+	add = func(typ types.Type) {
+		walkType(typ, func(typ types.Type) {
+			named, ok := typ.(*types.Named)
+			if !ok {
+				return
+			}
+			if _, ok := h.locals[named.Obj()]; ok && !seen[named.Obj()] {
+				seen[named.Obj()] = true
+				r = append(r, named.Obj())
+			}
+		})
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if inferred, ok := info.Inferred[call]; ok {
+			for _, targ := range inferred.Targs {
+				add(targ)
+			}
+			return true
+		}
+		generic := false
+		switch ftyp := info.TypeOf(call.Fun).(type) {
+		case *types.Signature:
+			generic = len(ftyp.TParams()) > 0
+		case *types.Named:
+			generic = len(ftyp.TParams()) > 0 && len(ftyp.TArgs()) == 0
+		}
+		if generic {
+			for _, arg := range call.Args {
+				if typ := info.TypeOf(arg); typ != nil {
+					add(typ)
+				}
+			}
+		}
+		return true
+	})
+	return r
+}
+
+// hoistType hoists the local type obj, and any local types it refers to.
+func (h *hoister) hoistType(obj *types.TypeName) error {
+	if h.hoisted[obj] {
+		return nil
+	}
+	lt := h.locals[obj]
+	h.hoisted[obj] = true
+
+	// Check that the type only refers to package scope names,
+	// its own type parameters, or other local types that we can hoist.
+	var deps []*types.TypeName
+	var err error
+	ast.Inspect(lt.spec, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || err != nil {
+			return err == nil
+		}
+		ref := h.importer.info.Uses[id]
+		if ref == nil || ref.Parent() == nil || ref.Pkg() != h.tpkg || ref.Parent() == h.tpkg.Scope() || ref.Parent() == types.Universe {
+			return true
+		}
+		if _, ok := ref.(*types.PkgName); ok {
+			return true
+		}
+		if ref.Pos() >= lt.spec.Pos() && ref.Pos() < lt.spec.End() {
+			// Declared within the spec; a type parameter.
+			return true
+		}
+		if tn, ok := ref.(*types.TypeName); ok {
+			if _, ok := h.locals[tn]; ok {
+				deps = append(deps, tn)
+				return true
+			}
+		}
+		err = fmt.Errorf("%s: go2go tool does not support local type %s that refers to local name %s", h.fset.Position(lt.spec.Pos()), obj.Name(), ref.Name())
+		return false
+	})
+	if err != nil {
+		return err
+	}
+
+	h.importer.addHoisted(obj, h.funcName, lt.spec.TParams != nil)
+	if lt.spec.TParams != nil {
+		h.importer.idToTypeSpec[obj] = lt.spec
+	}
+	h.decls = append(h.decls, &ast.GenDecl{
+		TokPos: lt.decl.TokPos,
+		Tok:    token.TYPE,
+		Specs:  []ast.Spec{lt.spec},
+	})
+
+	for _, dep := range deps {
+		if err := h.hoistType(dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addHoisted records that obj, a local type declared in the function
+// funcName, is being hoisted to package scope.
+func (imp *Importer) addHoisted(obj *types.TypeName, funcName string, parameterized bool) {
+	pkg := obj.Pkg()
+	names := imp.hoistedNames[pkg]
+	if names == nil {
+		names = make(map[string]*types.TypeName)
+		imp.hoistedNames[pkg] = names
+	}
+	name := localTypeName(funcName, obj.Name(), 0)
+	for i := 1; names[name] != nil || pkg.Scope().Lookup(name) != nil; i++ {
+		name = localTypeName(funcName, obj.Name(), i)
+	}
+	names[name] = obj
+
+	h := &hoistedType{name: name}
+	if !parameterized {
+		tn := types.NewTypeName(obj.Pos(), pkg, name, nil)
+		h.typ = types.NewNamed(tn, obj.Type().Underlying(), nil)
+	}
+	imp.hoisted[obj] = h
+}
+
+// A hoistedType is a local type that has been moved to package scope.
+type hoistedType struct {
+	name string       // package scope name
+	typ  *types.Named // package scope type; nil if parameterized
+}
+
+// hoistedName returns the package scope name of a hoisted local type,
+// or the name of the type if it is not hoisted.
+func (imp *Importer) hoistedName(obj *types.TypeName) string {
+	if h, ok := imp.hoisted[obj]; ok {
+		return h.name
+	}
+	return obj.Name()
+}
+
+// lookupHoisted looks up a hoisted local type by package scope name.
+func (imp *Importer) lookupHoisted(pkg *types.Package, name string) *types.TypeName {
+	return imp.hoistedNames[pkg][name]
+}
+
+// hoistedGeneric returns the hoisted parameterized local type
+// whose type parameters are tparams, or nil if there is none.
+func (imp *Importer) hoistedGeneric(pkg *types.Package, tparams []*types.TypeName) *types.Named {
+	if len(tparams) == 0 {
+		return nil
+	}
+	for _, obj := range imp.hoistedNames[pkg] {
+		if named, ok := obj.Type().(*types.Named); ok {
+			if tp := named.TParams(); len(tp) > 0 && tp[0] == tparams[0] {
+				return named
+			}
+		}
+	}
+	return nil
+}
+
+// walkType calls f for typ and for each type that typ refers to,
+// not including the underlying types of named types.
+func walkType(typ types.Type, f func(types.Type)) {
+	f(typ)
+	switch typ := typ.(type) {
+	case *types.Array:
+		walkType(typ.Elem(), f)
+	case *types.Slice:
+		walkType(typ.Elem(), f)
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			walkType(typ.Field(i).Type(), f)
+		}
+	case *types.Pointer:
+		walkType(typ.Elem(), f)
+	case *types.Tuple:
+		for i := 0; i < typ.Len(); i++ {
+			walkType(typ.At(i).Type(), f)
+		}
+	case *types.Signature:
+		walkType(typ.Params(), f)
+		walkType(typ.Results(), f)
+	case *types.Interface:
+		for i := 0; i < typ.NumExplicitMethods(); i++ {
+			walkType(typ.ExplicitMethod(i).Type(), f)
+		}
+		for i := 0; i < typ.NumEmbeddeds(); i++ {
+			walkType(typ.EmbeddedType(i), f)
+		}
+	case *types.Map:
+		walkType(typ.Key(), f)
+		walkType(typ.Elem(), f)
+	case *types.Chan:
+		walkType(typ.Elem(), f)
+	case *types.Named:
+		for _, targ := range typ.TArgs() {
+			walkType(targ, f)
+		}
+	}
+}
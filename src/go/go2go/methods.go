@@ -0,0 +1,288 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// Methods with type parameters, which are only accepted when the
+// importer is told to accept them, can't be translated to methods,
+// because Go 1 methods can't have type parameters. Instead, each
+// instantiation of such a method becomes a function whose first
+// parameter is the receiver, and a call of the method becomes a call
+// of that function:
+//
+//	func (s *Set(E)) Map(type F)(f func(E) F) *Set(F)
+//	s.Map(string)(f)
+//
+// becomes
+//
+//	func instantiate୦୦Set୦Map୦int୦string(s *instantiate୦୦Set୦int, f func(int) string) *instantiate୦୦Set୦string
+//	instantiate୦୦Set୦Map୦int୦string(s, f)
+//
+// where the type arguments of the receiver type come first. The
+// receiver is passed by address or indirected as needed to match the
+// receiver of the method. Method values, method expressions, and
+// calls of promoted methods are not supported.
+
+// AcceptMethodTypeParams tells the importer to accept methods with
+// type parameters, see types.Config.AcceptMethodTypeParams.
+func (imp *Importer) AcceptMethodTypeParams() {
+	imp.methodTParams = true
+}
+
+// genericMethod returns the method with type parameters that fun
+// selects, or nil if fun does not select one.
+func (t *translator) genericMethod(fun ast.Expr) (*ast.SelectorExpr, *types.Func) {
+	sel, ok := unparen(fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil, nil
+	}
+	method, ok := t.importer.info.Uses[sel.Sel].(*types.Func)
+	if !ok {
+		return nil, nil
+	}
+	sig := method.Type().(*types.Signature)
+	if sig.Recv() == nil || len(sig.TParams()) == 0 {
+		return nil, nil
+	}
+	return sel, method
+}
+
+// isMethodInstantiation reports whether call instantiates a method
+// with type parameters, either explicitly, in which case call.Fun is
+// the instantiation, or by inference.
+func (t *translator) isMethodInstantiation(call *ast.CallExpr) bool {
+	if _, method := t.genericMethod(call.Fun); method != nil {
+		return true
+	}
+	if inst, ok := unparen(call.Fun).(*ast.CallExpr); ok {
+		if _, inferred := t.importer.info.Inferred[inst]; !inferred {
+			_, method := t.genericMethod(inst.Fun)
+			return method != nil
+		}
+	}
+	return false
+}
+
+// translateMethodInstantiation translates a call of a method with type
+// parameters to a call of the instantiated function.
+func (t *translator) translateMethodInstantiation(pe *ast.Expr) {
+	call := (*pe).(*ast.CallExpr)
+	inst := call
+	if _, ok := t.importer.info.Inferred[call]; !ok {
+		c, ok := unparen(call.Fun).(*ast.CallExpr)
+		if !ok {
+			t.err = fmt.Errorf("%s: go2go tool does not support using an instantiated method as a value", t.fset.Position(call.Pos()))
+			return
+		}
+		inst = c
+	}
+	sel, method := t.genericMethod(inst.Fun)
+	msig := method.Type().(*types.Signature)
+	pos := t.fset.Position(sel.Pos())
+
+	if sig, ok := t.lookupType(sel).(*types.Signature); ok && sig.Params().Len() != msig.Params().Len() {
+		t.err = fmt.Errorf("%s: go2go tool does not support method expressions of methods with type parameters", pos)
+		return
+	}
+
+	mrecv := msig.Recv().Type()
+	mptr := false
+	if p, ok := mrecv.(*types.Pointer); ok {
+		mrecv = p.Elem()
+		mptr = true
+	}
+	mnamed, ok := mrecv.(*types.Named)
+	if !ok || types.IsInterface(mnamed) {
+		t.err = fmt.Errorf("%s: go2go tool does not support calling interface methods with type parameters", pos)
+		return
+	}
+
+	rtyp := t.lookupType(sel.X)
+	ptr := false
+	if p, ok := rtyp.(*types.Pointer); ok {
+		rtyp = p.Elem()
+		ptr = true
+	}
+	named, ok := rtyp.(*types.Named)
+	if !ok || named.Obj().Pkg() != mnamed.Obj().Pkg() || named.Obj().Name() != mnamed.Obj().Name() {
+		t.err = fmt.Errorf("%s: go2go tool does not support calling promoted methods with type parameters", pos)
+		return
+	}
+
+	// When the receiver type has type arguments, the type checker
+	// records a copy of the method with those type arguments
+	// substituted; we want the declared method.
+	if tn, ok := method.Pkg().Scope().Lookup(mnamed.Obj().Name()).(*types.TypeName); ok {
+		if gnamed, ok := tn.Type().(*types.Named); ok {
+			for i := 0; i < gnamed.NumMethods(); i++ {
+				if m := gnamed.Method(i); m.Name() == method.Name() {
+					method = m
+				}
+			}
+		}
+	}
+
+	decl, ok := t.importer.lookupFunc(method)
+	if !ok {
+		t.err = fmt.Errorf("%s: could not find method body for %s", pos, method.FullName())
+		return
+	}
+
+	argList, typeList, _ := t.instantiationTypes(inst)
+	if t.err != nil {
+		return
+	}
+	recvTypes, recvArgs := t.typeListToASTList(named.TArgs())
+	typeList = append(append([]types.Type(nil), recvTypes...), typeList...)
+	argList = append(append([]ast.Expr(nil), recvArgs...), argList...)
+
+	qid := qualifiedIdent{
+		ident: ast.NewIdent(mnamed.Obj().Name() + string(nameSep) + method.Name()),
+	}
+	if method.Pkg() != t.tpkg {
+		qid.pkg = method.Pkg()
+	}
+
+	var instIdent *ast.Ident
+	if st := t.sharedTranslator(qid.pkg, typeList); st != nil && st != t {
+		typeList, argList := st.typeListToASTList(typeList)
+		id := st.methodInstantiation(qualifiedIdent{ident: qid.ident}, decl, argList, typeList, len(recvTypes))
+		instIdent = t.sharedIdent(st, id, call.Pos())
+	} else {
+		instIdent = t.identAt(t.methodInstantiation(qid, decl, argList, typeList, len(recvTypes)), call.Pos())
+	}
+	if t.err != nil {
+		return
+	}
+
+	recv := unparen(sel.X)
+	switch {
+	case mptr && !ptr:
+		recv = &ast.UnaryExpr{
+			OpPos: recv.Pos(),
+			Op:    token.AND,
+			X:     recv,
+		}
+		t.setType(recv, types.NewPointer(rtyp))
+	case !mptr && ptr:
+		recv = &ast.StarExpr{
+			Star: recv.Pos(),
+			X:    recv,
+		}
+		t.setType(recv, rtyp)
+	}
+
+	newCall := &ast.CallExpr{
+		Fun:      instIdent,
+		Lparen:   call.Lparen,
+		Args:     append([]ast.Expr{recv}, call.Args...),
+		Ellipsis: call.Ellipsis,
+		Rparen:   call.Rparen,
+	}
+	if typ := t.lookupType(call); typ != nil {
+		t.setType(newCall, typ)
+	}
+	*pe = newCall
+}
+
+// methodInstantiation returns the identifier of the instantiation of
+// the method decl, named by qid, with the type arguments typeList,
+// of which the first nrecv are those of the receiver type, creating
+// the instantiation if necessary.
+func (t *translator) methodInstantiation(qid qualifiedIdent, decl *ast.FuncDecl, argList []ast.Expr, typeList []types.Type, nrecv int) *ast.Ident {
+	key := qid.String()
+	for _, inst := range t.funcInstantiations(key) {
+		if t.sameTypes(typeList, inst.types) {
+			return inst.decl
+		}
+	}
+
+	instIdent, err := t.instantiateMethod(qid, decl, argList, typeList, nrecv)
+	if err != nil {
+		t.err = err
+		return nil
+	}
+
+	t.addFuncInstantiation(key, &funcInstantiation{
+		types: typeList,
+		decl:  instIdent,
+	})
+	return instIdent
+}
+
+// instantiateMethod creates a new instantiation of a method with type
+// parameters, as a function whose first parameter is the receiver.
+func (t *translator) instantiateMethod(qid qualifiedIdent, decl *ast.FuncDecl, astTypes []ast.Expr, typeTypes []types.Type, nrecv int) (*ast.Ident, error) {
+	name, err := t.instantiatedName(qid, typeTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	ta := typeArgsFromFields(t, astTypes[nrecv:], typeTypes[nrecv:], decl.Type.TParams.List)
+	ta.types = typeTypes
+
+	recv := decl.Recv.List[0]
+	rtyp := recv.Type
+	if p, ok := rtyp.(*ast.StarExpr); ok {
+		rtyp = p.X
+	}
+	if rcall, ok := rtyp.(*ast.CallExpr); ok {
+		rta := typeArgsFromExprs(t, astTypes[:nrecv], typeTypes[:nrecv], rcall.Args)
+		for obj, e := range rta.toAST {
+			ta.toAST[obj] = e
+		}
+		for param, typ := range rta.toTyp {
+			ta.toTyp[param] = typ
+		}
+	}
+
+	ftyp := t.instantiateExpr(ta, decl.Type).(*ast.FuncType)
+
+	recvField := &ast.Field{
+		Doc:     recv.Doc,
+		Names:   recv.Names,
+		Type:    t.instantiateExpr(ta, recv.Type),
+		Comment: recv.Comment,
+	}
+	params := append([]*ast.Field{recvField}, ftyp.Params.List...)
+
+	// Parameters must be either all named or all unnamed.
+	if len(params) > 1 {
+		if len(recv.Names) == 0 && len(params[1].Names) > 0 {
+			recvField.Names = []*ast.Ident{ast.NewIdent("_")}
+		} else if len(recv.Names) > 0 && len(params[1].Names) == 0 {
+			for i, f := range params[1:] {
+				nf := *f
+				nf.Names = []*ast.Ident{ast.NewIdent("_")}
+				params[i+1] = &nf
+			}
+		}
+	}
+
+	instIdent := ast.NewIdent(name)
+	newDecl := &ast.FuncDecl{
+		Doc:  decl.Doc,
+		Name: instIdent,
+		Type: &ast.FuncType{
+			Func: ftyp.Func,
+			Params: &ast.FieldList{
+				Opening: decl.Recv.Opening,
+				List:    params,
+				Closing: ftyp.Params.Closing,
+			},
+			Results: ftyp.Results,
+		},
+		Body: t.instantiateBlockStmt(ta, decl.Body),
+	}
+	t.newDecls = append(t.newDecls, newDecl)
+
+	return instIdent, nil
+}
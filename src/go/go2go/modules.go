@@ -0,0 +1,258 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// A module describes a module in the build list of the main module.
+type module struct {
+	path    string // module path
+	dir     string // module root directory; empty if not downloaded
+	version string // module version; empty for the main module
+	main    bool   // whether this is the main module
+}
+
+// A modulePackage describes a package found in module mode.
+type modulePackage struct {
+	dir string  // package directory
+	mod *module // module holding the package
+}
+
+// UseModules tells the importer to find imported packages in
+// module mode, using the main module rooted at modRoot.
+// Packages that contain .go2 files and are part of the main module
+// are translated in place. Packages that contain .go2 files and are
+// part of some other module, including modules in the module cache,
+// are translated in a copy of that module under the temporary
+// directory; ModuleReplacements reports those copies.
+func (imp *Importer) UseModules(modRoot string) {
+	imp.modRoot = modRoot
+	imp.replacements = make(map[string]string)
+}
+
+// ModuleReplacements returns a map from module path to the directory
+// holding a translated copy of that module. The caller is expected
+// to use these as replacements when running the go tool.
+// This returns nil if the importer is not in module mode.
+func (imp *Importer) ModuleReplacements() map[string]string {
+	if imp.replacements == nil {
+		return nil
+	}
+	r := make(map[string]string, len(imp.replacements))
+	for path, dir := range imp.replacements {
+		r[path] = dir
+	}
+	return r
+}
+
+// ModulePackageDir returns the directory in which the package
+// importPath should be translated. This is only valid in module mode.
+func (imp *Importer) ModulePackageDir(importPath string) (string, error) {
+	mpkg, err := imp.findModulePackage(importPath)
+	if err != nil {
+		return "", err
+	}
+	return imp.moduleTranslationDir(mpkg)
+}
+
+// findModulePackage finds an imported package in module mode.
+// We can't simply ask "go list" for the package, because the go tool
+// doesn't consider a directory holding only .go2 files to be a package.
+// Instead we look for the module in the build list that provides
+// the package, much as the go tool does: the one with the longest
+// path that is a prefix of importPath and has a matching directory.
+func (imp *Importer) findModulePackage(importPath string) (*modulePackage, error) {
+	mods, err := imp.modules()
+	if err != nil {
+		return nil, err
+	}
+
+	var best *module
+	var bestDir string
+	for _, mod := range mods {
+		if importPath != mod.path && !strings.HasPrefix(importPath, mod.path+"/") {
+			continue
+		}
+		if best != nil && len(mod.path) <= len(best.path) {
+			continue
+		}
+		if mod.dir == "" {
+			if err := imp.downloadModule(mod); err != nil {
+				return nil, err
+			}
+		}
+		dir := filepath.Join(mod.dir, filepath.FromSlash(strings.TrimPrefix(importPath, mod.path)))
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			continue
+		}
+		best, bestDir = mod, dir
+	}
+	if best == nil {
+		return nil, fmt.Errorf("cannot find package %q in main module or its dependencies", importPath)
+	}
+	return &modulePackage{dir: bestDir, mod: best}, nil
+}
+
+// modules returns the modules in the build list of the main module,
+// loading them on first use.
+func (imp *Importer) modules() ([]*module, error) {
+	if imp.buildList == nil {
+		mods, err := imp.loadBuildList()
+		if err != nil {
+			return nil, err
+		}
+		imp.buildList = mods
+	}
+	return imp.buildList, nil
+}
+
+// loadBuildList returns the modules in the build list of the main module.
+func (imp *Importer) loadBuildList() ([]*module, error) {
+	out, err := imp.runGo("list", "-m", "-f", "{{.Path}}\t{{.Dir}}\t{{.Version}}\t{{.Main}}", "all")
+	if err != nil {
+		return nil, err
+	}
+	var mods []*module
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected output from go list -m: %q", line)
+		}
+		mods = append(mods, &module{
+			path:    fields[0],
+			dir:     fields[1],
+			version: fields[2],
+			main:    fields[3] == "true",
+		})
+	}
+	return mods, nil
+}
+
+// downloadModule downloads a module into the module cache,
+// and records the directory that holds it.
+func (imp *Importer) downloadModule(mod *module) error {
+	if _, err := imp.runGo("mod", "download", mod.path+"@"+mod.version); err != nil {
+		return err
+	}
+	out, err := imp.runGo("list", "-m", "-f", "{{.Dir}}", mod.path)
+	if err != nil {
+		return err
+	}
+	mod.dir = strings.TrimSpace(string(out))
+	if mod.dir == "" {
+		return fmt.Errorf("no directory for module %s@%s after download", mod.path, mod.version)
+	}
+	return nil
+}
+
+// runGo runs the go tool in the main module, and returns its output.
+func (imp *Importer) runGo(args ...string) ([]byte, error) {
+	gotool := filepath.Join(runtime.GOROOT(), "bin", "go")
+	cmd := exec.Command(gotool, args...)
+	cmd.Dir = imp.modRoot
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go %s failed: %v\n%s", strings.Join(args, " "), err, stderr.Bytes())
+	}
+	return out, nil
+}
+
+// moduleTranslationDir returns the directory in which to translate
+// the package described by mp. Packages in the main module are
+// translated in place, or, with an output root, in a copy of the
+// main module under the root. Packages in other modules are
+// translated in a writable copy of the module, which is recorded
+// as a replacement.
+func (imp *Importer) moduleTranslationDir(mp *modulePackage) (string, error) {
+	if mp.mod.main && !imp.outputRoot {
+		return mp.dir, nil
+	}
+	rel, err := filepath.Rel(mp.mod.dir, mp.dir)
+	if err != nil {
+		return "", err
+	}
+	if mp.mod.main {
+		cdir, err := imp.mainModuleCopy()
+		if err != nil {
+			return "", err
+		}
+		dir := filepath.Join(cdir, rel)
+		imp.SetSourceDir(dir, mp.dir)
+		return dir, nil
+	}
+	if cdir, ok := imp.replacements[mp.mod.path]; ok {
+		dir := filepath.Join(cdir, rel)
+		imp.SetSourceDir(dir, mp.dir)
+		return dir, nil
+	}
+
+	name := mp.mod.path
+	if mp.mod.version != "" {
+		name += "@" + mp.mod.version
+	}
+	cdir := filepath.Join(imp.tmpdir, "mod", filepath.FromSlash(name))
+	if err := copyModule(mp.mod.dir, cdir, mp.mod.path); err != nil {
+		return "", err
+	}
+	imp.replacements[mp.mod.path] = cdir
+	dir := filepath.Join(cdir, rel)
+	imp.SetSourceDir(dir, mp.dir)
+	return dir, nil
+}
+
+// copyModule copies the module rooted at from into the directory to.
+// Files in the module cache are read-only, so the copies are made
+// writable. If the module has no go.mod file, one is created,
+// as the go tool requires one for a directory replacement.
+func copyModule(from, to, modPath string) error {
+	err := filepath.Walk(from, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		if info.IsDir() {
+			if rel != "." && (strings.HasPrefix(info.Name(), ".") || strings.HasPrefix(info.Name(), "_")) {
+				return filepath.SkipDir
+			}
+			if path == to {
+				// The copy is inside the module,
+				// as an output root may be.
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		return err
+	}
+	gomod := filepath.Join(to, "go.mod")
+	if _, err := os.Stat(gomod); os.IsNotExist(err) {
+		return ioutil.WriteFile(gomod, []byte(fmt.Sprintf("module %s\n", modPath)), 0644)
+	}
+	return nil
+}
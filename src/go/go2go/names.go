@@ -39,9 +39,20 @@ var nameCodes = map[rune]int{
 }
 
 // instantiatedName returns the name of a newly instantiated function.
+// Shared instantiations of the generic code of the current package
+// have exported names, so that other packages can refer to them.
 func (t *translator) instantiatedName(qid qualifiedIdent, types []types.Type) (string, error) {
+	if t.shared && qid.pkg == nil {
+		return t.mangledName("Instantiate", qid, types), nil
+	}
+	return t.mangledName("instantiate", qid, types), nil
+}
+
+// mangledName returns a name for qid instantiated with types,
+// starting with prefix.
+func (t *translator) mangledName(prefix string, qid qualifiedIdent, types []types.Type) string {
 	var sb strings.Builder
-	fmt.Fprintf(&sb, "instantiate%c", nameSep)
+	fmt.Fprintf(&sb, "%s%c", prefix, nameSep)
 	if qid.pkg != nil {
 		fmt.Fprintf(&sb, qid.pkg.Name())
 	}
@@ -65,7 +76,7 @@ func (t *translator) instantiatedName(qid qualifiedIdent, types []types.Type) (s
 			}
 		}
 	}
-	return sb.String(), nil
+	return sb.String()
 }
 
 // importableName returns a name that we define in each package, so that
@@ -73,3 +84,38 @@ func (t *translator) instantiatedName(qid qualifiedIdent, types []types.Type) (s
 func (t *translator) importableName() string {
 	return "Importable" + string(nameSep)
 }
+
+// importName returns the name to use to refer to the imported
+// package pkg. This is normally the package name, but if that name
+// is used by a package scope object of the current package, or by
+// another package imported by the current file, we import pkg under
+// a mangled name instead.
+func (t *translator) importName(pkg *types.Package) string {
+	name := pkg.Name()
+	if t.tpkg.Scope().Lookup(name) != nil {
+		return name + string(nameSep)
+	}
+	if t.fileScope != nil {
+		if pn, ok := t.fileScope.Lookup(name).(*types.PkgName); ok && pn.Imported().Path() != pkg.Path() {
+			return name + string(nameSep)
+		}
+	}
+	return name
+}
+
+// exportedName returns the name of the exported trampoline for the
+// unexported package scope name, as described in exported.go.
+func exportedName(name string) string {
+	return "Exported" + string(nameSep) + name
+}
+
+// localTypeName returns the package scope name to use for the local
+// type name declared in the function funcName. If n is not zero,
+// it is added to make the name unique.
+func localTypeName(funcName, name string, n int) string {
+	s := fmt.Sprintf("local%c%s%c%s", nameSep, funcName, nameSep, name)
+	if n > 0 {
+		s += fmt.Sprintf("%c%d", nameSep, n)
+	}
+	return s
+}
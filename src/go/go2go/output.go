@@ -0,0 +1,183 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Normally Rewrite writes the translated .go files next to the .go2
+// files, and removes stale generated files there. With SetOutputRoot,
+// source directories are never modified: each package is translated
+// in a copy of its source directory under the output root.
+//
+// The output root is laid out like the importer's temporary directory,
+// which it replaces. In GOPATH mode a package found in GO2PATH or
+// GOPATH at DIR/src/path is translated in root/src/path, whether it is
+// passed to Rewrite or imported by another package. Any other directory
+// d, for which the go tool uses the import path "_/d", is translated
+// in root/src/_/d. In module mode the main module is copied to
+// root/mod/modpath, and its packages are translated there, while other
+// modules are copied to root/mod/modpath@version as usual.
+
+// SetOutputRoot tells the importer to write all translated packages
+// under root rather than next to their .go2 files. The root replaces
+// the temporary directory passed to NewImporter, and is created if it
+// does not exist. OutputDir reports where the translation of a
+// directory is written.
+func (imp *Importer) SetOutputRoot(root string) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(root, 0777); err != nil {
+		return err
+	}
+	imp.tmpdir = root
+	imp.outputRoot = true
+	return nil
+}
+
+// OutputDir returns the directory that holds the translation of the
+// package in dir, creating it if necessary. Without an output root,
+// see SetOutputRoot, this is dir itself.
+func (imp *Importer) OutputDir(dir string) (string, error) {
+	if !imp.outputRoot {
+		return dir, nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if inDir(dir, imp.tmpdir) {
+		// Already a translated copy.
+		return dir, nil
+	}
+	var odir string
+	if imp.modRoot != "" && inDir(dir, imp.modRoot) {
+		cdir, err := imp.mainModuleCopy()
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(imp.modRoot, dir)
+		if err != nil {
+			return "", err
+		}
+		odir = filepath.Join(cdir, rel)
+	} else {
+		odir = filepath.Join(imp.tmpdir, "src", filepath.FromSlash(gopathImportPath(dir)))
+	}
+	if err := os.MkdirAll(odir, 0777); err != nil {
+		return "", err
+	}
+	return odir, nil
+}
+
+// outputCopy copies the source files of the package in dir to its
+// output directory, and returns that directory. If names is not nil,
+// only those files are copied. Without an output root this does
+// nothing and returns dir.
+func (imp *Importer) outputCopy(dir string, names []string) (string, error) {
+	odir, err := imp.OutputDir(dir)
+	if err != nil || odir == dir {
+		return odir, err
+	}
+	if names == nil {
+		if err := copySourceFiles(dir, odir); err != nil {
+			return "", err
+		}
+	} else if err := copyFiles(dir, odir, names); err != nil {
+		return "", err
+	}
+	imp.SetSourceDir(odir, dir)
+	return odir, nil
+}
+
+// mainModuleCopy returns the directory holding the copy of the main
+// module under the output root, copying the module on first use.
+func (imp *Importer) mainModuleCopy() (string, error) {
+	if imp.mainCopy != "" {
+		return imp.mainCopy, nil
+	}
+	mods, err := imp.modules()
+	if err != nil {
+		return "", err
+	}
+	for _, mod := range mods {
+		if !mod.main {
+			continue
+		}
+		cdir := filepath.Join(imp.tmpdir, "mod", filepath.FromSlash(mod.path))
+		if err := copyModule(mod.dir, cdir, mod.path); err != nil {
+			return "", err
+		}
+		imp.mainCopy = cdir
+		return cdir, nil
+	}
+	return "", fmt.Errorf("no main module in build list of %s", imp.modRoot)
+}
+
+// copySourceFiles copies the source files of the package in from,
+// see sourceFiles, into the directory to. Other files in to, left
+// over from an earlier translation, are removed.
+func copySourceFiles(from, to string) error {
+	if err := os.MkdirAll(to, 0755); err != nil {
+		return err
+	}
+	names, err := sourceFiles(from)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	fis, err := ioutil.ReadDir(to)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if fi.Mode().IsRegular() && !keep[fi.Name()] {
+			if err := os.Remove(filepath.Join(to, fi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return copyFiles(from, to, names)
+}
+
+// gopathImportPath returns the import path that the go tool uses in
+// GOPATH mode for the package in the absolute directory dir.
+func gopathImportPath(dir string) string {
+	var roots []string
+	if go2path := os.Getenv("GO2PATH"); go2path != "" {
+		roots = append(roots, filepath.SplitList(go2path)...)
+	}
+	roots = append(roots, filepath.SplitList(build.Default.GOPATH)...)
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		src := filepath.Join(root, "src")
+		if dir != src && inDir(dir, src) {
+			rel, err := filepath.Rel(src, dir)
+			if err == nil {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return "_" + filepath.ToSlash(strings.TrimPrefix(dir, filepath.VolumeName(dir)))
+}
+
+// inDir reports whether dir is the directory root or is inside it.
+func inDir(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -11,6 +11,7 @@ import (
 	"go/printer"
 	"go/token"
 	"go/types"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -23,6 +24,39 @@ var config = printer.Config{
 	Tabwidth: 8,
 }
 
+// printFile prints file with config. The printer only prints the doc
+// comments of declarations that are among the comments of the file,
+// so declarations with a doc comment that we made up, which are not,
+// are printed on their own after the rest of the file.
+func printFile(w io.Writer, fset *token.FileSet, file *ast.File) error {
+	decls := file.Decls
+	defer func() { file.Decls = decls }()
+	var rest, docDecls []ast.Decl
+	for _, decl := range decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Doc == noinlineDoc {
+			docDecls = append(docDecls, decl)
+		} else {
+			rest = append(rest, decl)
+		}
+	}
+	file.Decls = rest
+	if err := config.Fprint(w, fset, file); err != nil {
+		return err
+	}
+	for _, decl := range docDecls {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+		if err := config.Fprint(w, fset, decl); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // isParameterizedFuncDecl reports whether fd is a parameterized function.
 func isParameterizedFuncDecl(fd *ast.FuncDecl, info *types.Info) bool {
 	if fd.Type.TParams != nil {
@@ -138,10 +172,17 @@ type translator struct {
 	fset         *token.FileSet
 	importer     *Importer
 	tpkg         *types.Package
+	file         *token.File  // file being translated
+	fileScope    *types.Scope // scope of the file being translated
+	importsC     bool         // whether the file imports "C"
 	types        map[ast.Expr]types.Type
 	newDecls     []ast.Decl
 	typePackages map[*types.Package]bool
 
+	// shared is set if this translator adds the shared
+	// instantiations of tpkg; see shared.go.
+	shared bool
+
 	// typeDepth tracks recursive type instantiations.
 	typeDepth int
 
@@ -152,8 +193,9 @@ type translator struct {
 
 // instantiations tracks all function and type instantiations for a package.
 type instantiations struct {
-	funcInstantiations map[string][]*funcInstantiation
-	typeInstantiations map[types.Type][]*typeInstantiation
+	funcInstantiations  map[string][]*funcInstantiation
+	typeInstantiations  map[types.Type][]*typeInstantiation
+	shapeInstantiations map[string][]*shapeInstantiation
 }
 
 // A funcInstantiation is a single instantiation of a function.
@@ -208,8 +250,9 @@ func (t *translator) pkgInstantiations() *instantiations {
 	insts := t.importer.instantiations[t.tpkg]
 	if insts == nil {
 		insts = &instantiations{
-			funcInstantiations: make(map[string][]*funcInstantiation),
-			typeInstantiations: make(map[types.Type][]*typeInstantiation),
+			funcInstantiations:  make(map[string][]*funcInstantiation),
+			typeInstantiations:  make(map[types.Type][]*typeInstantiation),
+			shapeInstantiations: make(map[string][]*shapeInstantiation),
 		}
 		t.importer.instantiations[t.tpkg] = insts
 	}
@@ -242,7 +285,7 @@ func rewriteFile(dir string, fset *token.FileSet, importer *Importer, importPath
 	}()
 	fmt.Fprintln(w, rewritePrefix)
 
-	return config.Fprint(w, fset, file)
+	return printFile(w, fset, file)
 }
 
 // rewriteAST rewrites the AST for a file.
@@ -251,11 +294,20 @@ func rewriteAST(fset *token.FileSet, importer *Importer, importPath string, tpkg
 		fset:         fset,
 		importer:     importer,
 		tpkg:         tpkg,
+		file:         fset.File(file.Package),
+		fileScope:    importer.info.Scopes[file],
+		importsC:     importsC(file),
 		types:        make(map[ast.Expr]types.Type),
 		typePackages: make(map[*types.Package]bool),
 	}
 	t.translate(file)
 
+	// Add trampolines for the C names used by generic code
+	// in this file, see cgo.go.
+	if t.importsC {
+		file.Decls = append(file.Decls, t.exportedDecls(t.cgoRefs(file), file.Package)...)
+	}
+
 	// Add all the transitive imports. This is more than we need,
 	// but we're not trying to be elegant here.
 	imps := make(map[string]bool)
@@ -264,13 +316,14 @@ func rewriteAST(fset *token.FileSet, importer *Importer, importPath string, tpkg
 		imps[p] = true
 	}
 	for pkg := range t.typePackages {
-		if pkg != t.tpkg {
+		if pkg != t.tpkg && pkg.Path() != "C" {
 			imps[pkg.Path()] = true
 		}
 	}
 
 	decls := make([]ast.Decl, 0, len(file.Decls))
 	var specs []ast.Spec
+	var cdecls []ast.Decl
 	for _, decl := range file.Decls {
 		gen, ok := decl.(*ast.GenDecl)
 		if !ok || gen.Tok != token.IMPORT {
@@ -279,6 +332,16 @@ func rewriteAST(fset *token.FileSet, importer *Importer, importPath string, tpkg
 		}
 		for _, spec := range gen.Specs {
 			imp := spec.(*ast.ImportSpec)
+			if imp.Path.Value == `"C"` {
+				// cgo wants import "C" right after the
+				// preamble, which we kept in file.Comments.
+				cdecls = append(cdecls, &ast.GenDecl{
+					TokPos: imp.Pos(),
+					Tok:    token.IMPORT,
+					Specs:  []ast.Spec{imp},
+				})
+				continue
+			}
 			if imp.Name != nil {
 				specs = append(specs, imp)
 			}
@@ -310,28 +373,34 @@ func rewriteAST(fset *token.FileSet, importer *Importer, importPath string, tpkg
 	}
 	sort.Strings(paths)
 
-	for _, p := range paths {
-		specs = append(specs, ast.Spec(&ast.ImportSpec{
-			Path: &ast.BasicLit{
-				Kind:  token.STRING,
-				Value: strconv.Quote(p),
-			},
-		}))
+	fileDir := filepath.Dir(fset.Position(file.Name.Pos()).Filename)
+	pspecs, err := t.importSpecs(paths, fileDir, file.Package)
+	if err != nil {
+		return err
 	}
+	specs = append(specs, pspecs...)
 	if len(specs) > 0 {
 		first := &ast.GenDecl{
-			Tok:   token.IMPORT,
-			Specs: specs,
+			TokPos: file.Package,
+			Tok:    token.IMPORT,
+			Specs:  specs,
 		}
 		file.Decls = append([]ast.Decl{first}, file.Decls...)
 	}
+	file.Decls = append(cdecls, file.Decls...)
+
+	// The declarations that we add from here on are not in the
+	// source code. Give them the position of the package clause,
+	// so that the //line directives that we write don't attribute
+	// them to whatever declaration happens to precede them.
 
 	// Add a name that other packages can reference to avoid an error
 	// about an unused package.
 	if addImportableName {
 		file.Decls = append(file.Decls,
 			&ast.GenDecl{
-				Tok: token.TYPE,
+				TokPos: file.Package,
+				Tok:    token.TYPE,
 				Specs: []ast.Spec{
 					&ast.TypeSpec{
 						Name: ast.NewIdent(t.importableName()),
@@ -339,10 +408,49 @@ func rewriteAST(fset *token.FileSet, importer *Importer, importPath string, tpkg
 					},
 				},
 			})
+
+		// Add trampolines for the unexported names used by
+		// generic code, so that other packages can
+		// instantiate it.
+		file.Decls = append(file.Decls, t.exportedDecls(t.unexportedRefs(), file.Package)...)
 	}
 
-	// Add a reference for each imported package to avoid an error
-	// about an unused package.
+	if err := t.addImportReferences(file, fileDir); err != nil {
+		return err
+	}
+
+	return t.err
+}
+
+// importSpecs returns the import specs for the packages paths,
+// imported by a file in dir, at position pos.
+func (t *translator) importSpecs(paths []string, dir string, pos token.Pos) ([]ast.Spec, error) {
+	var specs []ast.Spec
+	for _, p := range paths {
+		spec := &ast.ImportSpec{
+			Path: &ast.BasicLit{
+				ValuePos: pos,
+				Kind:     token.STRING,
+				Value:    strconv.Quote(p),
+			},
+		}
+		// If the package name is used by a package scope
+		// object, import the package under a different name.
+		pkg, err := t.importedPackage(p, dir)
+		if err != nil {
+			return nil, err
+		}
+		if name := t.importName(pkg); name != pkg.Name() {
+			spec.Name = &ast.Ident{NamePos: pos, Name: name}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// addImportReferences adds a reference for each package imported by
+// file, a file in dir, to avoid an error about an unused package.
+func (t *translator) addImportReferences(file *ast.File, dir string) error {
 	for _, decl := range file.Decls {
 		gen, ok := decl.(*ast.GenDecl)
 		if !ok || gen.Tok != token.IMPORT {
@@ -350,7 +458,7 @@ func rewriteAST(fset *token.FileSet, importer *Importer, importPath string, tpkg
 		}
 		for _, spec := range gen.Specs {
 			imp := spec.(*ast.ImportSpec)
-			if imp.Name != nil && imp.Name.Name == "_" {
+			if imp.Name != nil && imp.Name.Name == "_" || imp.Path.Value == `"C"` {
 				continue
 			}
 			path := strings.TrimPrefix(strings.TrimSuffix(imp.Path.Value, `"`), `"`)
@@ -358,13 +466,12 @@ func rewriteAST(fset *token.FileSet, importer *Importer, importPath string, tpkg
 
 			var tok token.Token
 			var importableName string
-			if pkg, ok := importer.lookupPackage(path); ok {
+			if pkg, ok := t.importer.lookupPackage(path); ok {
 				tok = token.TYPE
 				importableName = t.importableName()
 				pname = pkg.Name()
 			} else {
-				fileDir := filepath.Dir(fset.Position(file.Name.Pos()).Filename)
-				pkg, err := importer.ImportFrom(path, fileDir, 0)
+				pkg, err := t.importedPackage(path, dir)
 				if err != nil {
 					return err
 				}
@@ -430,13 +537,23 @@ func rewriteAST(fset *token.FileSet, importer *Importer, importPath string, tpkg
 			}
 			file.Decls = append(file.Decls,
 				&ast.GenDecl{
-					Tok:   tok,
-					Specs: []ast.Spec{spec},
+					TokPos: file.Package,
+					Tok:    tok,
+					Specs:  []ast.Spec{spec},
 				})
 		}
 	}
 
-	return t.err
+	return nil
+}
+
+// importedPackage returns the package imported by path
+// in a file in the directory dir.
+func (t *translator) importedPackage(path, dir string) (*types.Package, error) {
+	if pkg, ok := t.importer.lookupPackage(path); ok {
+		return pkg, nil
+	}
+	return t.importer.ImportFrom(path, dir, 0)
 }
 
 // translate translates the AST for a file from Go with contracts to Go 1.
@@ -676,7 +793,13 @@ func (t *translator) translateExpr(pe *ast.Expr) {
 		t.translateExpr(&e.X)
 		t.translateExpr(&e.Type)
 	case *ast.CallExpr:
-		if ftyp, ok := t.lookupType(e.Fun).(*types.Signature); ok && len(ftyp.TParams()) > 0 {
+		if t.isMethodInstantiation(e) {
+			t.translateMethodInstantiation(pe)
+			if t.err != nil {
+				return
+			}
+			e = (*pe).(*ast.CallExpr)
+		} else if ftyp, ok := t.lookupType(e.Fun).(*types.Signature); ok && len(ftyp.TParams()) > 0 {
 			t.translateFunctionInstantiation(pe)
 		} else if ntyp, ok := t.lookupType(e.Fun).(*types.Named); ok && len(ntyp.TParams()) > 0 && len(ntyp.TArgs()) == 0 {
 			t.translateTypeInstantiation(pe)
@@ -725,6 +848,23 @@ func (t *translator) translateIdent(pe *ast.Expr) {
 	if obj == nil {
 		return
 	}
+	if pn, ok := obj.(*types.PkgName); ok {
+		if t.fset.File(pn.Pos()) == t.file {
+			// Imported by this file; leave it alone.
+			return
+		}
+		// A reference to an imported package in code
+		// instantiated from some other file. Make sure that
+		// we import the package, and use the name under which
+		// we import it into this file.
+		t.typePackages[pn.Imported()] = true
+		if name := t.importName(pn.Imported()); name != e.Name {
+			nid := &ast.Ident{NamePos: e.Pos(), Name: name}
+			t.importer.info.Uses[nid] = pn
+			*pe = nid
+		}
+		return
+	}
 	if named, ok := obj.Type().(*types.Named); ok && len(named.TParams()) > 0 {
 		// A generic function that will be instantiated locally.
 		return
@@ -741,9 +881,16 @@ func (t *translator) translateIdent(pe *ast.Expr) {
 		return
 	}
 
+	if needsExport(ipkg, obj) {
+		// An unexported name used by generic code in another
+		// package. Refer to it through its trampoline.
+		*pe = t.exportedRef(obj, e.Pos())
+		return
+	}
+
 	// Add package qualifier.
 	*pe = &ast.SelectorExpr{
-		X:   ast.NewIdent(ipkg.Name()),
+		X:   ast.NewIdent(t.importName(ipkg)),
 		Sel: e,
 	}
 }
@@ -753,6 +900,16 @@ func (t *translator) translateIdent(pe *ast.Expr) {
 func (t *translator) translateSelectorExpr(pe *ast.Expr) {
 	e := (*pe).(*ast.SelectorExpr)
 
+	if t.isCPackage(e.X) {
+		// A C name can only be used directly in a file that
+		// imports "C" in the package that has the preamble.
+		// Elsewhere, refer to it through its trampoline.
+		if obj := t.importer.info.Uses[e.Sel]; obj != nil && (obj.Pkg() != t.tpkg || !t.importsC) {
+			*pe = t.exportedRef(obj, e.Pos())
+		}
+		return
+	}
+
 	t.translateExpr(&e.X)
 
 	obj := t.importer.info.ObjectOf(e.Sel)
@@ -883,37 +1040,67 @@ func (t *translator) translateFunctionInstantiation(pe *ast.Expr) {
 	}
 
 	var instIdent *ast.Ident
+	if st := t.sharedTranslator(qid.pkg, typeList); st != nil && st != t {
+		typeList, argList := st.typeListToASTList(typeList)
+		id := st.functionInstantiation(qualifiedIdent{ident: qid.ident}, argList, typeList)
+		instIdent = t.sharedIdent(st, id, call.Pos())
+	} else {
+		instIdent = t.identAt(t.functionInstantiation(qid, argList, typeList), call.Pos())
+	}
+	if t.err != nil {
+		return
+	}
+
+	if typeArgs {
+		*pe = instIdent
+	} else {
+		newCall := *call
+		newCall.Fun = instIdent
+		*pe = &newCall
+	}
+}
+
+// functionInstantiation returns the identifier of the instantiation
+// of the function qid with the type arguments typeList, creating
+// the instantiation if necessary.
+func (t *translator) functionInstantiation(qid qualifiedIdent, argList []ast.Expr, typeList []types.Type) *ast.Ident {
 	key := qid.String()
 	insts := t.funcInstantiations(key)
 	for _, inst := range insts {
 		if t.sameTypes(typeList, inst.types) {
-			instIdent = inst.decl
-			break
+			return inst.decl
 		}
 	}
 
-	if instIdent == nil {
-		var err error
-		instIdent, err = t.instantiateFunction(qid, argList, typeList)
-		if err != nil {
-			t.err = err
-			return
-		}
+	instIdent, err := t.instantiateFunction(qid, argList, typeList)
+	if err != nil {
+		t.err = err
+		return nil
+	}
 
-		n := &funcInstantiation{
-			types: typeList,
-			decl:  instIdent,
-		}
-		t.addFuncInstantiation(key, n)
+	n := &funcInstantiation{
+		types: typeList,
+		decl:  instIdent,
 	}
+	t.addFuncInstantiation(key, n)
+	return instIdent
+}
 
-	if typeArgs {
-		*pe = instIdent
-	} else {
-		newCall := *call
-		newCall.Fun = instIdent
-		*pe = &newCall
+// identAt returns a copy of the instantiated identifier id at
+// position pos, so that references to instantiations keep the
+// position of the code that refers to them.
+func (t *translator) identAt(id *ast.Ident, pos token.Pos) *ast.Ident {
+	if id == nil || !pos.IsValid() {
+		return id
+	}
+	nid := &ast.Ident{
+		NamePos: pos,
+		Name:    id.Name,
+	}
+	if typ := t.lookupType(id); typ != nil {
+		t.setType(nid, typ)
 	}
+	return nid
 }
 
 // translateTypeInstantiation translates an instantiated type to Go 1.
@@ -929,6 +1116,14 @@ func (t *translator) translateTypeInstantiation(pe *ast.Expr) {
 		panic("no type arguments for type")
 	}
 
+	if st := t.sharedTranslator(typ.Obj().Pkg(), typeList); st != nil && st != t {
+		_, id := st.lookupInstantiatedType(t.updateTArgs(typ, typeList))
+		if id := t.sharedIdent(st, id, call.Pos()); id != nil {
+			*pe = id
+		}
+		return
+	}
+
 	var seen *typeInstantiation
 	key := t.typeWithoutArgs(typ)
 	for _, inst := range t.typeInstantiations(key) {
@@ -942,7 +1137,7 @@ func (t *translator) translateTypeInstantiation(pe *ast.Expr) {
 				seen = inst
 				break
 			}
-			*pe = inst.decl
+			*pe = t.identAt(inst.decl, call.Pos())
 			return
 		}
 	}
@@ -981,7 +1176,7 @@ func (t *translator) translateTypeInstantiation(pe *ast.Expr) {
 		seen.typ = instType
 	}
 
-	*pe = instIdent
+	*pe = t.identAt(instIdent, call.Pos())
 }
 
 // instantiatedIdent returns the qualified identifer that is being
@@ -1036,10 +1231,14 @@ func (t *translator) instantiationTypes(call *ast.CallExpr) (argList []ast.Expr,
 		typeList, argList = t.typeListToASTList(inferred.Targs)
 	}
 
-	// Instantiating with a locally defined type won't work.
+	// Instantiating with a locally defined type won't work,
+	// unless the type has been hoisted to package scope.
 	// Check that here.
 	for i, typ := range typeList {
 		if named, ok := typ.(*types.Named); ok && named.Obj().Pkg() != nil {
+			if _, ok := t.importer.hoisted[named.Obj()]; ok {
+				continue
+			}
 			if scope := named.Obj().Parent(); scope != nil && scope != named.Obj().Pkg().Scope() {
 				var pos token.Pos
 				if haveInferred {
@@ -1073,6 +1272,11 @@ func (t *translator) lookupInstantiatedType(typ *types.Named) (types.Type, *ast.
 	}
 
 	targs := typ.TArgs()
+	if st := t.sharedTranslator(typ.Obj().Pkg(), targs); st != nil && st != t {
+		instType, id := st.lookupInstantiatedType(typ)
+		return instType, t.sharedIdent(st, id, token.NoPos)
+	}
+
 	key := t.typeWithoutArgs(typ)
 	var seen *typeInstantiation
 	for _, inst := range t.typeInstantiations(key) {
@@ -1098,7 +1302,7 @@ func (t *translator) lookupInstantiatedType(typ *types.Named) (types.Type, *ast.
 
 	typeList, argList := t.typeListToASTList(targs)
 
-	qid := qualifiedIdent{ident: ast.NewIdent(typ.Obj().Name())}
+	qid := qualifiedIdent{ident: ast.NewIdent(t.importer.hoistedName(key.Obj()))}
 	if typPkg := typ.Obj().Pkg(); typPkg != t.tpkg {
 		qid.pkg = typPkg
 	}
@@ -1164,6 +1368,9 @@ func (t *translator) typeWithoutArgs(typ *types.Named) *types.Named {
 	if tpkg == nil {
 		panic(fmt.Sprintf("can't find package for %s", name))
 	}
+	if g := t.importer.hoistedGeneric(tpkg, typ.TParams()); g != nil {
+		return g
+	}
 	nobj := tpkg.Scope().Lookup(name)
 	if nobj == nil {
 		panic(fmt.Sprintf("can't find %q in scope of package %q", name, tpkg.Name()))
@@ -0,0 +1,1156 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// Normally each instantiation of a generic function is a complete
+// copy of the function, stamped out for its type arguments. A package
+// that calls slices.Index with a dozen pointer types gets a dozen
+// copies of the same code. With UseShapes, type arguments are instead
+// grouped by shape, and all the instantiations of a function that
+// have the same shape share one copy, the shape instantiation, which
+// is passed a dictionary of the operations that depend on the actual
+// type arguments.
+//
+// The only shape so far is that of pointer types. A type parameter
+// whose type argument is an unnamed pointer type, and whose constraint
+// has no type list, is replaced by unsafe.Pointer in the shape
+// instantiation:
+//
+//	func shape୦୦Index୦unsafe୮aPointer(dict୦ *shapeDict୦୦Index୦unsafe୮aPointer, s []unsafe.Pointer, v unsafe.Pointer) int
+//
+// Each instantiation is then a small function with the usual name and
+// signature, that converts its arguments and calls the shape
+// instantiation:
+//
+//	func instantiate୦୦Index୦୮1bytes୮aBuffer(p୦0 []*bytes.Buffer, p୦1 *bytes.Buffer) int {
+//		return shape୦୦Index୦unsafe୮aPointer(&dict୦୦Index୦୮1bytes୮aBuffer, *(*[]unsafe.Pointer)(unsafe.Pointer(&p୦0)), unsafe.Pointer(p୦1))
+//	}
+//
+// The shape instantiation is marked go:noinline. Otherwise the compiler
+// inlines it into each of those small functions, which are then too
+// large to inline into their callers, and the program ends up with as
+// many copies of the code as if it had been stamped out, plus calls.
+//
+// Values whose types are built from the type parameter, such as []T
+// or func(T) bool, have the same representation for every pointer
+// type, so they are converted through unsafe.Pointer. That includes
+// a map[K]T, whose elements the runtime only copies and scans, as it
+// does all pointers. It does not include a map whose key type mentions
+// the type parameter: the runtime hashes and compares keys as the type
+// descriptor of the map type says, and relying on that to agree for
+// map[*A]V and map[unsafe.Pointer]V would tie the translation to the
+// runtime's layout of map types. A function that uses such a map is
+// stamped out.
+//
+// Two operations depend on the type argument itself: converting a
+// value of the type parameter to an interface type, which must yield
+// the original dynamic type, and calling a method of the constraint.
+// The shape instantiation does these through its dictionary, a struct
+// of functions filled in for each instantiation. The field T୦ converts
+// a value of the type parameter T to interface{}, and the field T୦M
+// calls its method M.
+//
+// A generic function is still stamped out for its type arguments
+// when it does anything else that depends on them: using the type
+// parameter in a conversion, type assertion or type switch, converting
+// a value of a type such as []T to an interface type, using a method
+// value, declaring a local type, instantiating a generic type with the
+// type parameter, or passing the type parameter to a generic function
+// that needs a dictionary itself. A function that passes its type
+// parameter to itself is stamped out too, as is any generic type and
+// its methods.
+
+// UseShapes tells the importer to group pointer type arguments of
+// generic functions by shape, so that instantiations that differ only
+// in those type arguments share one copy of the function.
+func (imp *Importer) UseShapes() {
+	imp.shapes = make(map[shapeKey]*shapeFunc)
+}
+
+// noinlineDoc is the doc comment of a shape instantiation.
+// See printFile for how it is printed.
+var noinlineDoc = &ast.CommentGroup{
+	List: []*ast.Comment{{Text: "//go:noinline"}},
+}
+
+// shapeDictParam is the name of the dictionary parameter
+// of a shape instantiation.
+const shapeDictParam = "dict" + string(nameSep)
+
+// A shapeKey identifies a generic function along with the
+// type parameters that are replaced by their shape.
+type shapeKey struct {
+	fn     types.Object
+	shaped string // the shaped type parameters, one '0' or '1' each
+}
+
+// A shapeFunc describes how a generic function can be shared by
+// type arguments of the same shape.
+type shapeFunc struct {
+	ok         bool                  // whether the function can be shared
+	inProgress bool                  // whether the function is being checked
+	params     shapeParams           // shaped type parameters
+	ops        map[ast.Expr]*shapeOp // operations done through the dictionary
+	dict       []*shapeEntry         // dictionary entries, in order
+}
+
+// A shapeEntry is one function in the dictionary of a shape instantiation.
+type shapeEntry struct {
+	param  *types.TypeParam // type parameter the entry is for
+	method *types.Func      // method to call; nil to convert to interface{}
+	sig    *types.Signature // signature of method, in the generic function
+}
+
+// A shapeOp is an expression in the generic function that is
+// replaced by a call of a dictionary entry in the shape instantiation.
+type shapeOp struct {
+	entry  *shapeEntry
+	target types.Type // for a conversion, the interface type converted to
+}
+
+// field returns the name of the dictionary field holding e.
+func (e *shapeEntry) field() string {
+	name := e.param.Obj().Name() + string(nameSep)
+	if e.method != nil {
+		name += e.method.Name()
+	}
+	return name
+}
+
+// shapeParams is a set of shaped type parameters.
+type shapeParams map[*types.TypeParam]bool
+
+// involves reports whether typ mentions a type parameter in ps.
+func (ps shapeParams) involves(typ types.Type) bool {
+	switch typ := typ.(type) {
+	case *types.TypeParam:
+		return ps[typ]
+	case *types.Array:
+		return ps.involves(typ.Elem())
+	case *types.Slice:
+		return ps.involves(typ.Elem())
+	case *types.Pointer:
+		return ps.involves(typ.Elem())
+	case *types.Chan:
+		return ps.involves(typ.Elem())
+	case *types.Map:
+		return ps.involves(typ.Key()) || ps.involves(typ.Elem())
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			if ps.involves(typ.Field(i).Type()) {
+				return true
+			}
+		}
+	case *types.Tuple:
+		for i := 0; i < typ.Len(); i++ {
+			if ps.involves(typ.At(i).Type()) {
+				return true
+			}
+		}
+	case *types.Signature:
+		return ps.involves(typ.Params()) || ps.involves(typ.Results())
+	case *types.Interface:
+		for i := 0; i < typ.NumExplicitMethods(); i++ {
+			if ps.involves(typ.ExplicitMethod(i).Type()) {
+				return true
+			}
+		}
+		for i := 0; i < typ.NumEmbeddeds(); i++ {
+			if ps.involves(typ.EmbeddedType(i)) {
+				return true
+			}
+		}
+		for _, t := range typ.TypeList() {
+			if ps.involves(t) {
+				return true
+			}
+		}
+	case *types.Named:
+		for _, targ := range typ.TArgs() {
+			if ps.involves(targ) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shapeable reports whether values of type typ have the same
+// representation for all the type arguments of the same shape.
+// Interface types and instantiated types that mention a shaped
+// type parameter don't, as their methods depend on it, and neither
+// do map types whose key type mentions one, see above.
+func (ps shapeParams) shapeable(typ types.Type) bool {
+	switch typ := typ.(type) {
+	case *types.Array:
+		return ps.shapeable(typ.Elem())
+	case *types.Slice:
+		return ps.shapeable(typ.Elem())
+	case *types.Pointer:
+		return ps.shapeable(typ.Elem())
+	case *types.Chan:
+		return ps.shapeable(typ.Elem())
+	case *types.Map:
+		return !ps.involves(typ.Key()) && ps.shapeable(typ.Elem())
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			if !ps.shapeable(typ.Field(i).Type()) {
+				return false
+			}
+		}
+	case *types.Tuple:
+		for i := 0; i < typ.Len(); i++ {
+			if !ps.shapeable(typ.At(i).Type()) {
+				return false
+			}
+		}
+	case *types.Signature:
+		return ps.shapeable(typ.Params()) && ps.shapeable(typ.Results())
+	case *types.Interface, *types.Named:
+		return !ps.involves(typ)
+	}
+	return true
+}
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+// underOf returns the underlying type of the type of e,
+// or nil if it is not known.
+func (c *shapeChecker) underOf(e ast.Expr) types.Type {
+	if typ := c.typeOf(e); typ != nil {
+		return typ.Underlying()
+	}
+	return nil
+}
+
+// isInterface reports whether typ is an interface type.
+// Unlike types.IsInterface, it does not report type parameters.
+func isInterface(typ types.Type) bool {
+	if _, ok := typ.(*types.TypeParam); ok {
+		return false
+	}
+	_, ok := typ.Underlying().(*types.Interface)
+	return ok
+}
+
+// shapeFunc reports how the generic function fn, declared by decl,
+// can be shared by type arguments of the same shape for its type
+// parameters tparams for which shaped is set.
+func (imp *Importer) shapeFunc(fn types.Object, decl *ast.FuncDecl, tparams []*types.TypeParam, shaped []bool) *shapeFunc {
+	mask := make([]byte, len(shaped))
+	params := make(shapeParams)
+	for i, s := range shaped {
+		mask[i] = '0'
+		if s {
+			mask[i] = '1'
+			params[tparams[i]] = true
+		}
+	}
+	key := shapeKey{fn: fn, shaped: string(mask)}
+	if sf, ok := imp.shapes[key]; ok {
+		// A function that is being checked is only shared
+		// once we know that it can be; until then, this
+		// makes callers in a recursive cycle stamp it out.
+		return sf
+	}
+
+	sf := &shapeFunc{
+		inProgress: true,
+		params:     params,
+		ops:        make(map[ast.Expr]*shapeOp),
+	}
+	imp.shapes[key] = sf
+
+	c := &shapeChecker{
+		imp:     imp,
+		sf:      sf,
+		entries: make(map[shapeEntryKey]*shapeEntry),
+		called:  make(map[*ast.SelectorExpr]bool),
+	}
+	v := &shapeVisitor{c: c, sig: fn.Type().(*types.Signature)}
+	ast.Walk(v, decl.Type.Params)
+	if decl.Type.Results != nil {
+		ast.Walk(v, decl.Type.Results)
+	}
+	ast.Walk(v, decl.Body)
+
+	sf.inProgress = false
+	sf.ok = !c.failed
+	return sf
+}
+
+// A shapeChecker checks whether a generic function can be shared
+// by type arguments of the same shape.
+type shapeChecker struct {
+	imp     *Importer
+	sf      *shapeFunc
+	entries map[shapeEntryKey]*shapeEntry
+	called  map[*ast.SelectorExpr]bool // method selectors that are called
+	failed  bool                       // whether the function can't be shared
+}
+
+// A shapeEntryKey identifies a dictionary entry.
+type shapeEntryKey struct {
+	param  *types.TypeParam
+	method string
+}
+
+// typeOf returns the type of e, or nil if it is not known.
+func (c *shapeChecker) typeOf(e ast.Expr) types.Type {
+	return c.imp.info.TypeOf(e)
+}
+
+// entry returns the dictionary entry for method of param, or for
+// converting param to interface{} if method is nil.
+func (c *shapeChecker) entry(param *types.TypeParam, method *types.Func, sig *types.Signature) *shapeEntry {
+	key := shapeEntryKey{param: param}
+	if method != nil {
+		key.method = method.Name()
+	}
+	if e, ok := c.entries[key]; ok {
+		return e
+	}
+	e := &shapeEntry{param: param, method: method, sig: sig}
+	c.entries[key] = e
+	c.sf.dict = append(c.sf.dict, e)
+	return e
+}
+
+// assign checks that the value e is assigned to, or converted to,
+// the type target. Converting a value of a shaped type parameter to
+// an interface type is done through the dictionary.
+func (c *shapeChecker) assign(e ast.Expr, target types.Type) {
+	src := c.typeOf(e)
+	if src == nil || target == nil || !isInterface(target) || !c.sf.params.involves(src) {
+		return
+	}
+	if tp, ok := src.(*types.TypeParam); ok && !c.sf.params.involves(target) {
+		c.sf.ops[e] = &shapeOp{
+			entry:  c.entry(tp, nil, nil),
+			target: target,
+		}
+		return
+	}
+	c.failed = true
+}
+
+// assignType checks that a value of type src is assigned to the type
+// target, in a context where the value can't be converted.
+func (c *shapeChecker) assignType(src, target types.Type) {
+	if src != nil && target != nil && isInterface(target) && c.sf.params.involves(src) {
+		c.failed = true
+	}
+}
+
+// assignList checks the assignment of the values rhs
+// to variables of the types targets.
+func (c *shapeChecker) assignList(targets []types.Type, rhs []ast.Expr) {
+	if len(rhs) == 1 && len(targets) > 1 {
+		if tuple, ok := c.typeOf(rhs[0]).(*types.Tuple); ok {
+			for i := 0; i < tuple.Len() && i < len(targets); i++ {
+				c.assignType(tuple.At(i).Type(), targets[i])
+			}
+		} else {
+			// A comma-ok expression.
+			c.assignType(c.typeOf(rhs[0]), targets[0])
+		}
+		return
+	}
+	for i, e := range rhs {
+		if i < len(targets) {
+			c.assign(e, targets[i])
+		}
+	}
+}
+
+// lhsType returns the type of the assigned expression e,
+// or nil for the blank identifier.
+func (c *shapeChecker) lhsType(e ast.Expr) types.Type {
+	if e == nil {
+		return nil
+	}
+	if id, ok := e.(*ast.Ident); ok {
+		if obj := c.imp.info.ObjectOf(id); obj != nil {
+			return obj.Type()
+		}
+		return nil
+	}
+	return c.typeOf(e)
+}
+
+// compare checks the comparison of x and y. Comparing a value with
+// an interface value converts it to the interface type.
+func (c *shapeChecker) compare(x, y ast.Expr) {
+	tx, ty := c.typeOf(x), c.typeOf(y)
+	if tx == nil || ty == nil {
+		return
+	}
+	if isInterface(ty) {
+		c.assign(x, ty)
+	} else if isInterface(tx) {
+		c.assign(y, tx)
+	}
+}
+
+// call checks the call, or conversion, e.
+func (c *shapeChecker) call(e *ast.CallExpr) {
+	ftv := c.imp.info.Types[e.Fun]
+	switch {
+	case ftv.IsType():
+		if len(e.Args) != 1 {
+			return
+		}
+		src, target := c.typeOf(e.Args[0]), ftv.Type
+		if isInterface(target) {
+			c.assign(e.Args[0], target)
+		} else if (c.sf.params.involves(src) || c.sf.params.involves(target)) && !types.Identical(src, target) {
+			c.failed = true
+		}
+		return
+	case ftv.IsBuiltin():
+		c.builtin(e)
+		return
+	}
+
+	sig, ok := ftv.Type.Underlying().(*types.Signature)
+	if !ok {
+		return
+	}
+	if len(sig.TParams()) > 0 {
+		if inferred, ok := c.imp.info.Inferred[e]; ok {
+			c.genericCall(e.Fun, sig, inferred.Targs)
+			sig = inferred.Sig
+		} else {
+			// An explicit instantiation.
+			targs := make([]types.Type, len(e.Args))
+			for i, arg := range e.Args {
+				targs[i] = c.typeOf(arg)
+			}
+			c.genericCall(e.Fun, sig, targs)
+			return
+		}
+	}
+
+	if sel, ok := unparen(e.Fun).(*ast.SelectorExpr); ok {
+		if s := c.imp.info.Selections[sel]; s != nil && s.Kind() == types.MethodVal {
+			if tp, ok := s.Recv().(*types.TypeParam); ok && c.sf.params[tp] {
+				c.sf.ops[e] = &shapeOp{
+					entry: c.entry(tp, s.Obj().(*types.Func), sig),
+				}
+				c.called[sel] = true
+			}
+		}
+	}
+
+	params := sig.Params()
+	if len(e.Args) == 1 && params.Len() > 1 {
+		if tuple, ok := c.typeOf(e.Args[0]).(*types.Tuple); ok {
+			for i := 0; i < tuple.Len(); i++ {
+				c.assignType(tuple.At(i).Type(), paramType(sig, i, false))
+			}
+			return
+		}
+	}
+	for i, arg := range e.Args {
+		c.assign(arg, paramType(sig, i, e.Ellipsis.IsValid()))
+	}
+}
+
+// paramType returns the type of the parameter of sig that receives
+// argument i of a call, which passes a slice to a variadic parameter
+// if ellipsis is set.
+func paramType(sig *types.Signature, i int, ellipsis bool) types.Type {
+	params := sig.Params()
+	n := params.Len()
+	if sig.Variadic() && i >= n-1 {
+		last := params.At(n - 1).Type()
+		if ellipsis {
+			return last
+		}
+		if s, ok := last.(*types.Slice); ok {
+			return s.Elem()
+		}
+		return nil
+	}
+	if i < n {
+		return params.At(i).Type()
+	}
+	return nil
+}
+
+// builtin checks the call e of a builtin function.
+func (c *shapeChecker) builtin(e *ast.CallExpr) {
+	var id *ast.Ident
+	switch fun := unparen(e.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	}
+	b, ok := c.imp.info.ObjectOf(id).(*types.Builtin)
+	if !ok || len(e.Args) == 0 {
+		return
+	}
+	switch b.Name() {
+	case "append":
+		if s, ok := c.underOf(e.Args[0]).(*types.Slice); ok && !e.Ellipsis.IsValid() {
+			for _, arg := range e.Args[1:] {
+				c.assign(arg, s.Elem())
+			}
+		}
+	case "delete":
+		if m, ok := c.underOf(e.Args[0]).(*types.Map); ok && len(e.Args) > 1 {
+			c.assign(e.Args[1], m.Key())
+		}
+	case "panic":
+		c.assign(e.Args[0], types.NewInterfaceType(nil, nil).Complete())
+	}
+}
+
+// genericCall checks the instantiation of the generic function fun,
+// with signature sig, with the type arguments targs. If a type
+// argument mentions a shaped type parameter, the instantiation in the
+// shape instantiation uses unsafe.Pointer for it, and is stamped out,
+// so fun must not depend on its type arguments.
+func (c *shapeChecker) genericCall(fun ast.Expr, sig *types.Signature, targs []types.Type) {
+	tnames := sig.TParams()
+	if len(targs) != len(tnames) {
+		c.failed = true
+		return
+	}
+	shaped := make([]bool, len(targs))
+	tparams := make([]*types.TypeParam, len(targs))
+	any := false
+	for i, targ := range targs {
+		tparams[i] = tnames[i].Type().(*types.TypeParam)
+		if c.sf.params.involves(targ) {
+			if len(tparams[i].Bound().TypeList()) > 0 {
+				c.failed = true
+				return
+			}
+			shaped[i] = true
+			any = true
+		}
+	}
+	if !any {
+		return
+	}
+
+	var id *ast.Ident
+	switch fun := unparen(fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	}
+	obj := c.imp.info.ObjectOf(id)
+	if obj == nil {
+		c.failed = true
+		return
+	}
+	if sig, ok := obj.Type().(*types.Signature); !ok || sig.Recv() != nil {
+		// A method with type parameters is always stamped out.
+		c.failed = true
+		return
+	}
+	decl, ok := c.imp.lookupFunc(obj)
+	if !ok {
+		c.failed = true
+		return
+	}
+	if sf := c.imp.shapeFunc(obj, decl, tparams, shaped); !sf.ok || len(sf.dict) > 0 {
+		c.failed = true
+	}
+}
+
+// compositeLit checks the elements of the composite literal e.
+func (c *shapeChecker) compositeLit(e *ast.CompositeLit) {
+	typ := c.typeOf(e)
+	if typ == nil {
+		return
+	}
+	if p, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = p.Elem()
+	}
+	value := func(elt ast.Expr) ast.Expr {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			return kv.Value
+		}
+		return elt
+	}
+	switch u := typ.Underlying().(type) {
+	case *types.Struct:
+		for i, elt := range e.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				if id, ok := kv.Key.(*ast.Ident); ok {
+					for j := 0; j < u.NumFields(); j++ {
+						if u.Field(j).Name() == id.Name {
+							c.assign(kv.Value, u.Field(j).Type())
+						}
+					}
+				}
+			} else if i < u.NumFields() {
+				c.assign(elt, u.Field(i).Type())
+			}
+		}
+	case *types.Array:
+		for _, elt := range e.Elts {
+			c.assign(value(elt), u.Elem())
+		}
+	case *types.Slice:
+		for _, elt := range e.Elts {
+			c.assign(value(elt), u.Elem())
+		}
+	case *types.Map:
+		for _, elt := range e.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				c.assign(kv.Key, u.Key())
+				c.assign(kv.Value, u.Elem())
+			}
+		}
+	}
+}
+
+// A shapeVisitor walks the body of a generic function for a shapeChecker.
+type shapeVisitor struct {
+	c   *shapeChecker
+	sig *types.Signature // signature of the enclosing function
+}
+
+func (v *shapeVisitor) Visit(n ast.Node) ast.Visitor {
+	c := v.c
+	if c.failed || n == nil {
+		return nil
+	}
+	params := c.sf.params
+	if e, ok := n.(ast.Expr); ok {
+		if typ := c.typeOf(e); typ != nil && !params.shapeable(typ) {
+			c.failed = true
+			return nil
+		}
+	}
+
+	switch n := n.(type) {
+	case *ast.FuncLit:
+		if sig, ok := c.typeOf(n).(*types.Signature); ok {
+			return &shapeVisitor{c: c, sig: sig}
+		}
+	case *ast.DeclStmt:
+		if gen, ok := n.Decl.(*ast.GenDecl); ok && gen.Tok == token.TYPE {
+			c.failed = true
+		}
+	case *ast.ValueSpec:
+		if n.Type != nil {
+			typ := c.typeOf(n.Type)
+			targets := make([]types.Type, len(n.Names))
+			for i := range targets {
+				targets[i] = typ
+			}
+			c.assignList(targets, n.Values)
+		}
+	case *ast.AssignStmt:
+		if n.Tok == token.ASSIGN || n.Tok == token.DEFINE {
+			targets := make([]types.Type, len(n.Lhs))
+			for i, lhs := range n.Lhs {
+				targets[i] = c.lhsType(lhs)
+			}
+			c.assignList(targets, n.Rhs)
+		}
+	case *ast.RangeStmt:
+		if n.Tok == token.ASSIGN {
+			var key, value types.Type
+			switch u := c.underOf(n.X).(type) {
+			case *types.Slice:
+				value = u.Elem()
+			case *types.Array:
+				value = u.Elem()
+			case *types.Pointer:
+				if a, ok := u.Elem().Underlying().(*types.Array); ok {
+					value = a.Elem()
+				}
+			case *types.Map:
+				key, value = u.Key(), u.Elem()
+			case *types.Chan:
+				key = u.Elem()
+			}
+			c.assignType(key, c.lhsType(n.Key))
+			c.assignType(value, c.lhsType(n.Value))
+		}
+	case *ast.ReturnStmt:
+		if results := v.sig.Results(); len(n.Results) > 0 {
+			targets := make([]types.Type, results.Len())
+			for i := range targets {
+				targets[i] = results.At(i).Type()
+			}
+			c.assignList(targets, n.Results)
+		}
+	case *ast.SendStmt:
+		if ch, ok := c.underOf(n.Chan).(*types.Chan); ok {
+			c.assign(n.Value, ch.Elem())
+		}
+	case *ast.SwitchStmt:
+		if n.Tag != nil {
+			for _, s := range n.Body.List {
+				for _, e := range s.(*ast.CaseClause).List {
+					c.compare(n.Tag, e)
+				}
+			}
+		}
+	case *ast.TypeSwitchStmt:
+		for _, s := range n.Body.List {
+			for _, e := range s.(*ast.CaseClause).List {
+				if params.involves(c.typeOf(e)) {
+					c.failed = true
+				}
+			}
+		}
+	case *ast.TypeAssertExpr:
+		if params.involves(c.typeOf(n.X)) || (n.Type != nil && params.involves(c.typeOf(n.Type))) {
+			c.failed = true
+		}
+	case *ast.CallExpr:
+		c.call(n)
+	case *ast.SelectorExpr:
+		if s := c.imp.info.Selections[n]; s != nil && s.Kind() != types.FieldVal && params.involves(s.Recv()) && !c.called[n] {
+			c.failed = true
+		}
+	case *ast.CompositeLit:
+		c.compositeLit(n)
+	case *ast.IndexExpr:
+		if m, ok := c.underOf(n.X).(*types.Map); ok {
+			c.assign(n.Index, m.Key())
+		}
+	case *ast.BinaryExpr:
+		if n.Op == token.EQL || n.Op == token.NEQ {
+			c.compare(n.X, n.Y)
+		}
+	}
+	if c.failed {
+		return nil
+	}
+	return v
+}
+
+// A shapeInstantiation is an instantiation of a generic function
+// that is shared by the instantiations of the same shape.
+type shapeInstantiation struct {
+	types []types.Type // type arguments, unsafe.Pointer for shaped ones
+	decl  *ast.Ident   // name of the shape instantiation
+	dict  *ast.Ident   // name of the dictionary type; nil if none
+}
+
+// instantiateShaped creates the instantiation of the generic function
+// qid, declared by decl, with the type arguments typeTypes, as a call
+// of its shape instantiation. It returns nil if the instantiation has
+// no shape, and must be stamped out.
+func (t *translator) instantiateShaped(qid qualifiedIdent, decl *ast.FuncDecl, name string, astTypes []ast.Expr, typeTypes []types.Type) *ast.Ident {
+	fn := t.findTypesObject(qid)
+	if fn == nil || decl.Recv != nil {
+		return nil
+	}
+	sig := fn.Type().(*types.Signature)
+	tnames := sig.TParams()
+	if len(tnames) != len(typeTypes) {
+		return nil
+	}
+	tparams := make([]*types.TypeParam, len(tnames))
+	shaped := make([]bool, len(tnames))
+	any := false
+	for i, tn := range tnames {
+		tparams[i] = tn.Type().(*types.TypeParam)
+		if _, ok := typeTypes[i].(*types.Pointer); ok && len(tparams[i].Bound().TypeList()) == 0 {
+			shaped[i] = true
+			any = true
+		}
+	}
+	if !any {
+		return nil
+	}
+	sf := t.importer.shapeFunc(fn, decl, tparams, shaped)
+	if !sf.ok {
+		return nil
+	}
+
+	shapeTypes := make([]types.Type, len(typeTypes))
+	shapeASTs := make([]ast.Expr, len(astTypes))
+	for i := range typeTypes {
+		if shaped[i] {
+			shapeTypes[i] = types.Typ[types.UnsafePointer]
+			shapeASTs[i] = t.typeToAST(shapeTypes[i])
+		} else {
+			shapeTypes[i] = typeTypes[i]
+			shapeASTs[i] = astTypes[i]
+		}
+	}
+	si := t.shapeInstantiation(qid, decl, sf, shapeASTs, shapeTypes)
+	sta := typeArgsFromFields(t, shapeASTs, shapeTypes, decl.Type.TParams.List)
+	cta := typeArgsFromFields(t, astTypes, typeTypes, decl.Type.TParams.List)
+
+	var args []ast.Expr
+	if si.dict != nil {
+		dictName := t.mangledName("dict", qid, typeTypes)
+		t.newDecls = append(t.newDecls, t.shapeDictVar(sf, si, dictName, sta, cta, decl.Type.Func))
+		args = append(args, &ast.UnaryExpr{
+			Op: token.AND,
+			X:  ast.NewIdent(dictName),
+		})
+	}
+
+	// The instantiation has the usual signature, with its
+	// parameters renamed, and converts them to their shape.
+	ftyp := t.instantiateExpr(cta, decl.Type).(*ast.FuncType)
+	var fields []*ast.Field
+	k := 0
+	for _, f := range ftyp.Params.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		nf := &ast.Field{Type: f.Type}
+		for i := 0; i < n; i++ {
+			id := ast.NewIdent(fmt.Sprintf("p%c%d", nameSep, k))
+			nf.Names = append(nf.Names, id)
+			args = append(args, t.shapeConvert(id, sig.Params().At(k).Type(), sf.params, sta, true))
+			k++
+		}
+		fields = append(fields, nf)
+	}
+	call := &ast.CallExpr{
+		Fun:  ast.NewIdent(si.decl.Name),
+		Args: args,
+	}
+	if sig.Variadic() {
+		call.Ellipsis = decl.Type.Func
+	}
+
+	instIdent := ast.NewIdent(name)
+	t.newDecls = append(t.newDecls, &ast.FuncDecl{
+		Doc:  decl.Doc,
+		Name: instIdent,
+		Type: &ast.FuncType{
+			Func: ftyp.Func,
+			Params: &ast.FieldList{
+				Opening: ftyp.Params.Opening,
+				List:    fields,
+				Closing: ftyp.Params.Closing,
+			},
+			Results: ftyp.Results,
+		},
+		Body: &ast.BlockStmt{
+			List: t.shapeForward(call, sig.Results(), sf.params, cta, false),
+		},
+	})
+	return instIdent
+}
+
+// shapeInstantiation returns the shape instantiation of the generic
+// function qid, declared by decl, with the type arguments shapeTypes,
+// creating it if necessary.
+func (t *translator) shapeInstantiation(qid qualifiedIdent, decl *ast.FuncDecl, sf *shapeFunc, shapeASTs []ast.Expr, shapeTypes []types.Type) *shapeInstantiation {
+	insts := t.pkgInstantiations()
+	key := qid.String()
+	for _, si := range insts.shapeInstantiations[key] {
+		if t.sameTypes(si.types, shapeTypes) {
+			return si
+		}
+	}
+
+	si := &shapeInstantiation{
+		types: shapeTypes,
+		decl:  ast.NewIdent(t.mangledName("shape", qid, shapeTypes)),
+	}
+	insts.shapeInstantiations[key] = append(insts.shapeInstantiations[key], si)
+
+	ta := typeArgsFromFields(t, shapeASTs, shapeTypes, decl.Type.TParams.List)
+	ta.shape = sf
+
+	ftyp := t.instantiateExpr(ta, decl.Type).(*ast.FuncType)
+	var fields []*ast.Field
+	if len(sf.dict) > 0 {
+		si.dict = ast.NewIdent(t.mangledName("shapeDict", qid, shapeTypes))
+		var dfields []*ast.Field
+		for _, e := range sf.dict {
+			dfields = append(dfields, &ast.Field{
+				Names: []*ast.Ident{ast.NewIdent(e.field())},
+				Type:  t.shapeEntryType(e, ta, decl.Pos()),
+			})
+		}
+		t.newDecls = append(t.newDecls, &ast.GenDecl{
+			TokPos: decl.Pos(),
+			Tok:    token.TYPE,
+			Specs: []ast.Spec{
+				&ast.TypeSpec{
+					Name: si.dict,
+					Type: &ast.StructType{
+						Fields: &ast.FieldList{List: dfields},
+					},
+				},
+			},
+		})
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(shapeDictParam)},
+			Type:  &ast.StarExpr{X: ast.NewIdent(si.dict.Name)},
+		})
+	}
+	for _, f := range ftyp.Params.List {
+		if len(f.Names) == 0 && len(fields) > 0 {
+			// The parameters must all be named,
+			// as the dictionary parameter is.
+			f = &ast.Field{
+				Names: []*ast.Ident{ast.NewIdent("_")},
+				Type:  f.Type,
+			}
+		}
+		fields = append(fields, f)
+	}
+
+	t.newDecls = append(t.newDecls, &ast.FuncDecl{
+		Doc:  noinlineDoc,
+		Name: si.decl,
+		Type: &ast.FuncType{
+			Func: ftyp.Func,
+			Params: &ast.FieldList{
+				Opening: ftyp.Params.Opening,
+				List:    fields,
+				Closing: ftyp.Params.Closing,
+			},
+			Results: ftyp.Results,
+		},
+		Body: t.instantiateBlockStmt(ta, decl.Body),
+	})
+	return si
+}
+
+// instantiateShapeOp instantiates the expression e of the generic
+// function as the operation op of the shape instantiation.
+func (t *translator) instantiateShapeOp(ta *typeArgs, op *shapeOp, e ast.Expr) ast.Expr {
+	fun := &ast.SelectorExpr{
+		X:   ast.NewIdent(shapeDictParam),
+		Sel: ast.NewIdent(op.entry.field()),
+	}
+	if op.entry.method == nil {
+		ta.skip = e
+		x := t.instantiateExpr(ta, e)
+		ta.skip = nil
+		var r ast.Expr = &ast.CallExpr{
+			Fun:    fun,
+			Lparen: e.Pos(),
+			Args:   []ast.Expr{x},
+			Rparen: e.End(),
+		}
+		target := t.instantiateType(ta, op.target)
+		if target.Underlying().(*types.Interface).NumMethods() > 0 {
+			r = &ast.TypeAssertExpr{
+				X:    r,
+				Type: t.typeToAST(target),
+			}
+			t.setType(r, target)
+		}
+		return r
+	}
+
+	call := e.(*ast.CallExpr)
+	sel := unparen(call.Fun).(*ast.SelectorExpr)
+	args, _ := t.instantiateExprList(ta, call.Args)
+	r := &ast.CallExpr{
+		Fun:      fun,
+		Lparen:   call.Lparen,
+		Args:     append([]ast.Expr{t.instantiateExpr(ta, sel.X)}, args...),
+		Ellipsis: call.Ellipsis,
+		Rparen:   call.Rparen,
+	}
+	if et := t.lookupType(e); et != nil {
+		t.setType(r, t.instantiateType(ta, et))
+	}
+	return r
+}
+
+// shapeEntryType returns the type of the dictionary entry e,
+// for the shape type arguments ta, at position pos.
+func (t *translator) shapeEntryType(e *shapeEntry, ta *typeArgs, pos token.Pos) *ast.FuncType {
+	params := []*ast.Field{{Type: t.typeToAST(types.Typ[types.UnsafePointer])}}
+	if e.method == nil {
+		return &ast.FuncType{
+			Params: &ast.FieldList{List: params},
+			Results: &ast.FieldList{
+				List: []*ast.Field{{
+					Type: &ast.InterfaceType{
+						Interface: pos,
+						Methods:   &ast.FieldList{Opening: pos, Closing: pos},
+					},
+				}},
+			},
+		}
+	}
+	sig := t.instantiateType(ta, e.sig).(*types.Signature)
+	for i := 0; i < sig.Params().Len(); i++ {
+		params = append(params, &ast.Field{Type: t.shapeParamType(sig, i)})
+	}
+	var results []*ast.Field
+	for i := 0; i < sig.Results().Len(); i++ {
+		results = append(results, &ast.Field{Type: t.typeToAST(sig.Results().At(i).Type())})
+	}
+	return &ast.FuncType{
+		Params:  &ast.FieldList{List: params},
+		Results: &ast.FieldList{List: results},
+	}
+}
+
+// shapeParamType returns the AST for the type of parameter i of sig,
+// using ... for the final parameter of a variadic signature.
+func (t *translator) shapeParamType(sig *types.Signature, i int) ast.Expr {
+	typ := sig.Params().At(i).Type()
+	t.addTypePackages(typ)
+	if sig.Variadic() && i == sig.Params().Len()-1 {
+		return &ast.Ellipsis{Elt: t.typeToAST(typ.(*types.Slice).Elem())}
+	}
+	return t.typeToAST(typ)
+}
+
+// shapeDictVar returns the declaration of the variable name holding
+// the dictionary of si for the type arguments cta, whose shape
+// type arguments are sta. The declaration is at pos.
+func (t *translator) shapeDictVar(sf *shapeFunc, si *shapeInstantiation, name string, sta, cta *typeArgs, pos token.Pos) ast.Decl {
+	var elts []ast.Expr
+	for _, e := range sf.dict {
+		recv := ast.NewIdent("p" + string(nameSep))
+		ftyp := t.shapeEntryType(e, sta, pos)
+		ftyp.Params.List[0].Names = []*ast.Ident{recv}
+		x := t.shapeConvert(recv, e.param, sf.params, cta, false)
+
+		var body []ast.Stmt
+		if e.method == nil {
+			body = []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{x}}}
+		} else {
+			concrete := t.instantiateType(cta, e.param)
+			sel := ast.NewIdent(e.method.Name())
+			if obj, _, _ := types.LookupFieldOrMethod(concrete, true, e.method.Pkg(), e.method.Name()); obj != nil {
+				t.importer.info.Uses[sel] = obj
+				t.setType(x, concrete)
+			}
+			call := &ast.CallExpr{
+				Fun: &ast.SelectorExpr{X: x, Sel: sel},
+			}
+			params := e.sig.Params()
+			for i := 0; i < params.Len(); i++ {
+				id := ast.NewIdent(fmt.Sprintf("p%c%d", nameSep, i))
+				ftyp.Params.List[i+1].Names = []*ast.Ident{id}
+				call.Args = append(call.Args, t.shapeConvert(id, params.At(i).Type(), sf.params, cta, false))
+			}
+			if e.sig.Variadic() {
+				call.Ellipsis = pos
+			}
+			body = t.shapeForward(call, e.sig.Results(), sf.params, sta, true)
+		}
+
+		elts = append(elts, &ast.KeyValueExpr{
+			Key: ast.NewIdent(e.field()),
+			Value: &ast.FuncLit{
+				Type: ftyp,
+				Body: &ast.BlockStmt{List: body},
+			},
+		})
+	}
+	return &ast.GenDecl{
+		TokPos: pos,
+		Tok:    token.VAR,
+		Specs: []ast.Spec{
+			&ast.ValueSpec{
+				Names: []*ast.Ident{ast.NewIdent(name)},
+				Values: []ast.Expr{
+					&ast.CompositeLit{
+						Type: ast.NewIdent(si.dict.Name),
+						Elts: elts,
+					},
+				},
+			},
+		},
+	}
+}
+
+// shapeForward returns the statements of a function that returns the
+// results of call, whose generic types are results, converted to the
+// instantiation ta, which is a shape instantiation if toShape is set.
+func (t *translator) shapeForward(call *ast.CallExpr, results *types.Tuple, params shapeParams, ta *typeArgs, toShape bool) []ast.Stmt {
+	if results.Len() == 0 {
+		return []ast.Stmt{&ast.ExprStmt{X: call}}
+	}
+	if !params.involves(results) {
+		return []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{call}}}
+	}
+	var lhs, rets []ast.Expr
+	for i := 0; i < results.Len(); i++ {
+		id := ast.NewIdent(fmt.Sprintf("r%c%d", nameSep, i))
+		lhs = append(lhs, id)
+		rets = append(rets, t.shapeConvert(id, results.At(i).Type(), params, ta, toShape))
+	}
+	return []ast.Stmt{
+		&ast.AssignStmt{
+			Lhs: lhs,
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{call},
+		},
+		&ast.ReturnStmt{Results: rets},
+	}
+}
+
+// shapeConvert converts the variable v, whose type is the generic
+// type typ instantiated one way, to typ instantiated by ta, which is a
+// shape instantiation if toShape is set. Values of types mentioning
+// a shaped type parameter have the same representation either way.
+func (t *translator) shapeConvert(v *ast.Ident, typ types.Type, params shapeParams, ta *typeArgs, toShape bool) ast.Expr {
+	if !params.involves(typ) {
+		return v
+	}
+	unsafePointer := t.typeToAST(types.Typ[types.UnsafePointer])
+	target := t.instantiateType(ta, typ)
+	t.addTypePackages(target)
+	if _, ok := typ.(*types.TypeParam); ok {
+		if toShape {
+			// unsafe.Pointer(v)
+			return &ast.CallExpr{
+				Fun:  unsafePointer,
+				Args: []ast.Expr{v},
+			}
+		}
+		// (*T)(v)
+		return &ast.CallExpr{
+			Fun:  &ast.ParenExpr{X: t.typeToAST(target)},
+			Args: []ast.Expr{v},
+		}
+	}
+	// *(*T)(unsafe.Pointer(&v))
+	return &ast.StarExpr{
+		X: &ast.CallExpr{
+			Fun: &ast.ParenExpr{
+				X: &ast.StarExpr{X: t.typeToAST(target)},
+			},
+			Args: []ast.Expr{
+				&ast.CallExpr{
+					Fun: unsafePointer,
+					Args: []ast.Expr{
+						&ast.UnaryExpr{Op: token.AND, X: v},
+					},
+				},
+			},
+		},
+	}
+}
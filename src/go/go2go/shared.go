@@ -0,0 +1,320 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package go2go
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"internal/goroot"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Normally each package gets its own copy of the instantiations that
+// it uses. Two packages that both use sets.Set(int) then get two
+// distinct types, and can't pass values of that type to each other.
+// With ShareInstantiations, an instantiation is instead emitted once
+// and every package refers to that copy.
+//
+// The shared copy can't go into a package of its own, as that package
+// would have to import the package defining the generic code, which
+// itself uses the instantiations. So it goes into the defining
+// package, under an exported name, in the generated file sharedFile:
+//
+//	// in package sets
+//	type Instantiate୦୦Set୦int struct { ... }
+//
+//	// in any package using sets.Set(int)
+//	var s sets.Instantiate୦୦Set୦int
+//
+// This only works if the defining package can refer to the type
+// arguments, so an instantiation is shared only if all the types
+// it mentions are predeclared, are defined by the standard library,
+// or are exported by a package that the defining package imports.
+// The defining package's own types may be used as well, except for
+// types defined in test files. Other instantiations, such as
+// sets.Set(T) for a type T defined by the importing package, are
+// still emitted in the package that uses them.
+//
+// Only packages imported by path have shared instantiations, since
+// only they can be referred to from elsewhere. As the file of shared
+// instantiations grows while translating the packages that import a
+// package, it is written out again after translating each package.
+
+// sharedFile is the name of the file holding the shared
+// instantiations of a package.
+const sharedFile = "go2go_instances.go"
+
+// sharedInstances holds the shared instantiations of a package.
+type sharedInstances struct {
+	t        *translator     // translator adding the instantiations
+	dir      string          // directory holding translated files
+	visible  map[string]bool // import paths visible to the package
+	decls    []ast.Decl      // translated declarations
+	dirty    bool            // whether decls must be written out
+	flushing bool            // whether flush is running
+}
+
+// ShareInstantiations tells the importer to emit each instantiation
+// of a generic function or type, where possible, in the package that
+// defines it, so that all packages that use the instantiation refer to
+// the same code and, for a type, to the same type.
+// This disables the cache, see UseCache, since the translation of a
+// package then depends on the packages that import it.
+func (imp *Importer) ShareInstantiations() {
+	imp.shared = make(map[*types.Package]*sharedInstances)
+}
+
+// sharedInstances returns the shared instantiations of pkg,
+// or nil if pkg can't have shared instantiations.
+func (imp *Importer) sharedInstances(pkg *types.Package) *sharedInstances {
+	if imp.shared == nil || pkg == nil {
+		return nil
+	}
+	if s, ok := imp.shared[pkg]; ok {
+		return s
+	}
+	var s *sharedInstances
+	path := pkg.Path()
+	if dir := imp.translated[path]; dir != "" && imp.packages[path] == pkg {
+		s = &sharedInstances{
+			t: &translator{
+				fset:         imp.fset,
+				importer:     imp,
+				tpkg:         pkg,
+				types:        make(map[ast.Expr]types.Type),
+				typePackages: make(map[*types.Package]bool),
+				shared:       true,
+			},
+			dir:     dir,
+			visible: make(map[string]bool),
+		}
+		for _, p := range imp.transitiveImports(path) {
+			s.visible[p] = true
+		}
+	}
+	imp.shared[pkg] = s
+	return s
+}
+
+// sharedTranslator returns the translator that adds the shared
+// instantiation of a generic defined in pkg with the type arguments
+// targs, or nil if that instantiation is not shared.
+func (t *translator) sharedTranslator(pkg *types.Package, targs []types.Type) *translator {
+	if pkg == nil {
+		pkg = t.tpkg
+	}
+	s := t.importer.sharedInstances(pkg)
+	if s == nil {
+		return nil
+	}
+	for _, targ := range targs {
+		if !s.canRefer(targ, make(map[types.Type]bool)) {
+			return nil
+		}
+	}
+	return s.t
+}
+
+// canRefer reports whether the package holding s can refer to typ.
+func (s *sharedInstances) canRefer(typ types.Type, seen map[types.Type]bool) bool {
+	if seen[typ] {
+		return true
+	}
+	seen[typ] = true
+	pkg := s.t.tpkg
+	switch typ := typ.(type) {
+	case *types.Basic:
+		return typ.Kind() != types.UnsafePointer || s.visible["unsafe"]
+	case *types.Array:
+		return s.canRefer(typ.Elem(), seen)
+	case *types.Slice:
+		return s.canRefer(typ.Elem(), seen)
+	case *types.Pointer:
+		return s.canRefer(typ.Elem(), seen)
+	case *types.Map:
+		return s.canRefer(typ.Key(), seen) && s.canRefer(typ.Elem(), seen)
+	case *types.Chan:
+		return s.canRefer(typ.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			f := typ.Field(i)
+			// The identity of an unexported field name
+			// depends on the package that declares it.
+			if !f.Exported() && f.Pkg() != pkg {
+				return false
+			}
+			if !s.canRefer(f.Type(), seen) {
+				return false
+			}
+		}
+		return true
+	case *types.Tuple:
+		for i := 0; i < typ.Len(); i++ {
+			if !s.canRefer(typ.At(i).Type(), seen) {
+				return false
+			}
+		}
+		return true
+	case *types.Signature:
+		return s.canRefer(typ.Params(), seen) && s.canRefer(typ.Results(), seen)
+	case *types.Interface:
+		for i := 0; i < typ.NumExplicitMethods(); i++ {
+			m := typ.ExplicitMethod(i)
+			if !m.Exported() && m.Pkg() != pkg {
+				return false
+			}
+			if !s.canRefer(m.Type(), seen) {
+				return false
+			}
+		}
+		for i := 0; i < typ.NumEmbeddeds(); i++ {
+			if !s.canRefer(typ.EmbeddedType(i), seen) {
+				return false
+			}
+		}
+		return true
+	case *types.Named:
+		obj := typ.Obj()
+		switch opkg := obj.Pkg(); {
+		case opkg == nil:
+			// A predeclared type such as error.
+		case opkg == pkg:
+			filename := s.t.fset.Position(obj.Pos()).Filename
+			if strings.HasSuffix(filename, "_test.go2") {
+				return false
+			}
+		case !obj.Exported():
+			return false
+		case !s.visible[opkg.Path()] && !goroot.IsStandardPackage(runtime.GOROOT(), "gc", opkg.Path()):
+			return false
+		}
+		for _, targ := range typ.TArgs() {
+			if !s.canRefer(targ, seen) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// sharedIdent returns an identifier at pos that refers to the
+// instantiation id added by the translator st.
+func (t *translator) sharedIdent(st *translator, id *ast.Ident, pos token.Pos) *ast.Ident {
+	if st.err != nil {
+		t.err = st.err
+		return nil
+	}
+	st.importer.shared[st.tpkg].flush()
+	if st.err != nil {
+		t.err = st.err
+		return nil
+	}
+	name := id.Name
+	if st.tpkg != t.tpkg {
+		t.typePackages[st.tpkg] = true
+		name = t.importName(st.tpkg) + "." + name
+	}
+	nid := &ast.Ident{NamePos: pos, Name: name}
+	if typ := st.lookupType(id); typ != nil {
+		t.setType(nid, typ)
+	}
+	return nid
+}
+
+// flush translates the instantiations added since the last call.
+func (s *sharedInstances) flush() {
+	if s.flushing || len(s.t.newDecls) == 0 {
+		return
+	}
+	s.flushing = true
+	defer func() { s.flushing = false }()
+
+	file := &ast.File{Decls: s.t.newDecls}
+	s.t.newDecls = nil
+	s.t.translate(file)
+	s.decls = append(s.decls, file.Decls...)
+	s.dirty = true
+}
+
+// writeShared writes out the shared instantiations
+// that have changed since they were last written.
+func (imp *Importer) writeShared() error {
+	pkgs := make([]*types.Package, 0, len(imp.shared))
+	for pkg, s := range imp.shared {
+		if s != nil && s.dirty {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Path() < pkgs[j].Path()
+	})
+	for _, pkg := range pkgs {
+		s := imp.shared[pkg]
+		if err := s.write(); err != nil {
+			return err
+		}
+		s.dirty = false
+	}
+	return nil
+}
+
+// write writes the file of shared instantiations.
+func (s *sharedInstances) write() (err error) {
+	t := s.t
+	file := &ast.File{
+		Name: ast.NewIdent(t.tpkg.Name()),
+	}
+
+	paths := make([]string, 0, len(t.typePackages))
+	for pkg := range t.typePackages {
+		if pkg != t.tpkg {
+			paths = append(paths, pkg.Path())
+		}
+	}
+	sort.Strings(paths)
+	specs, err := t.importSpecs(paths, s.dir, token.NoPos)
+	if err != nil {
+		return err
+	}
+	if len(specs) > 0 {
+		file.Decls = append(file.Decls, &ast.GenDecl{
+			Tok:   token.IMPORT,
+			Specs: specs,
+		})
+	}
+	file.Decls = append(file.Decls, s.decls...)
+	if err := t.addImportReferences(file, s.dir); err != nil {
+		return err
+	}
+
+	o, err := os.Create(filepath.Join(s.dir, sharedFile))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := o.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	w := bufio.NewWriter(o)
+	defer func() {
+		if flushErr := w.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	fmt.Fprintln(w, rewritePrefix)
+
+	return printFile(w, t.fset, file)
+}
@@ -194,6 +194,9 @@ func (t *translator) doInstantiateType(ta *typeArgs, typ types.Type) types.Type
 		}
 		return types.NewChan(typ.Dir(), elem)
 	case *types.Named:
+		if h, ok := t.importer.hoisted[typ.Obj()]; ok && h.typ != nil {
+			return h.typ
+		}
 		targs := typ.TArgs()
 		targsChanged := false
 		if len(targs) > 0 {
@@ -223,13 +226,19 @@ func (t *translator) doInstantiateType(ta *typeArgs, typ types.Type) types.Type
 
 // setTargs returns a new named type with updated type arguments.
 func (t *translator) updateTArgs(typ *types.Named, targs []types.Type) *types.Named {
+	return t.renamedType(typ, typ.Obj().Name(), targs)
+}
+
+// renamedType returns a new named type with a new name
+// and updated type arguments.
+func (t *translator) renamedType(typ *types.Named, name string, targs []types.Type) *types.Named {
 	nm := typ.NumMethods()
 	methods := make([]*types.Func, 0, nm)
 	for i := 0; i < nm; i++ {
 		methods = append(methods, typ.Method(i))
 	}
 	obj := typ.Obj()
-	obj = types.NewTypeName(obj.Pos(), obj.Pkg(), obj.Name(), nil)
+	obj = types.NewTypeName(obj.Pos(), obj.Pkg(), name, nil)
 	nt := types.NewNamed(obj, typ.Underlying(), methods)
 	nt.SetTArgs(targs)
 	return nt
@@ -266,6 +275,9 @@ func (t *translator) instantiateTypeTuple(ta *typeArgs, tuple *types.Tuple) *typ
 func (t *translator) addTypePackages(typ types.Type) {
 	switch typ := typ.(type) {
 	case *types.Basic:
+		if typ.Kind() == types.UnsafePointer {
+			t.typePackages[types.Unsafe] = true
+		}
 	case *types.Array:
 		t.addTypePackages(typ.Elem())
 	case *types.Slice:
@@ -312,7 +324,9 @@ func (t *translator) addTypePackages(typ types.Type) {
 }
 
 // withoutTags returns a type with no struct tags. If typ has no
-// struct tags anyhow, this just returns typ.
+// struct tags anyhow, this just returns typ. Local types that have
+// been hoisted to package scope are replaced by the hoisted types,
+// so that the type string does not depend on local names.
 func (t *translator) withoutTags(typ types.Type) types.Type {
 	switch typ := typ.(type) {
 	case *types.Basic:
@@ -412,6 +426,9 @@ func (t *translator) withoutTags(typ types.Type) types.Type {
 		}
 		return types.NewChan(typ.Dir(), elem)
 	case *types.Named:
+		if h, ok := t.importer.hoisted[typ.Obj()]; ok && h.typ != nil {
+			return h.typ
+		}
 		targs := typ.TArgs()
 		targsChanged := false
 		if len(targs) > 0 {
@@ -425,6 +442,11 @@ func (t *translator) withoutTags(typ types.Type) types.Type {
 			}
 			targs = newTargs
 		}
+		if len(targs) > 0 {
+			if g := t.importer.hoistedGeneric(typ.Obj().Pkg(), typ.TParams()); g != nil {
+				return t.renamedType(typ, t.importer.hoistedName(g.Obj()), targs)
+			}
+		}
 		if targsChanged {
 			return t.updateTArgs(typ, targs)
 		}
@@ -468,7 +490,15 @@ func (t *translator) typeToAST(typ types.Type) ast.Expr {
 	var r ast.Expr
 	switch typ := typ.(type) {
 	case *types.Basic:
-		r = ast.NewIdent(typ.Name())
+		if typ.Kind() == types.UnsafePointer {
+			t.typePackages[types.Unsafe] = true
+			r = &ast.SelectorExpr{
+				X:   ast.NewIdent(t.importName(types.Unsafe)),
+				Sel: ast.NewIdent("Pointer"),
+			}
+		} else {
+			r = ast.NewIdent(typ.Name())
+		}
 	case *types.Array:
 		r = &ast.ArrayType{
 			Len: &ast.BasicLit{
@@ -528,7 +558,7 @@ func (t *translator) typeToAST(typ types.Type) ast.Expr {
 		}
 		r = &ast.FuncType{
 			Params:  t.tupleToFieldList(typ.Params()),
-			Results: t.tupleToFieldList(typ.Params()),
+			Results: t.tupleToFieldList(typ.Results()),
 		}
 	case *types.Interface:
 		var methods []*ast.Field
@@ -582,13 +612,26 @@ func (t *translator) typeToAST(typ types.Type) ast.Expr {
 			_, id := t.lookupInstantiatedType(typ)
 			r = id
 		} else {
-			var sb strings.Builder
 			tn := typ.Obj()
+			if tn.Pkg() == t.tpkg && cgoName(tn) != "" {
+				// A C type, see cgo.go.
+				if t.importsC {
+					r = cgoRef(tn, token.NoPos)
+				} else {
+					r = ast.NewIdent(exportedName(tn.Name()))
+				}
+				break
+			}
+			var sb strings.Builder
 			if tn.Pkg() != nil && tn.Pkg() != t.tpkg {
-				sb.WriteString(tn.Pkg().Name())
+				sb.WriteString(t.importName(tn.Pkg()))
 				sb.WriteByte('.')
 			}
-			sb.WriteString(tn.Name())
+			if tn.Pkg() != nil && tn.Pkg() != t.tpkg && needsExport(tn.Pkg(), tn) {
+				sb.WriteString(exportedName(tn.Name()))
+			} else {
+				sb.WriteString(t.importer.hoistedName(tn))
+			}
 			r = ast.NewIdent(sb.String())
 		}
 	case *types.TypeParam:
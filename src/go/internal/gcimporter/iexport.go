@@ -0,0 +1,852 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Indexed package export, for packages type checked by go/types.
+// See cmd/compile/internal/gc/iexport.go for the export data format.
+//
+// Version 2 of the format, which is only written by this package,
+// extends version 1 so that parameterized functions and types can be
+// described:
+//
+//     Object = ...
+//            | GenericFunc
+//            | GenericType
+//
+//     GenericFunc struct {
+//         Tag     byte // 'G'
+//         Pos     Pos
+//         TParams TParamList
+//         Signature
+//     }
+//
+//     GenericType struct {
+//         Tag        byte // 'U'
+//         Pos        Pos
+//         TParams    TParamList
+//         Underlying typeOff
+//
+//         Methods []struct{  // omitted if Underlying is an interface type
+//             Pos       Pos
+//             Name      stringOff
+//             RParams   TParamList
+//             Recv      Param
+//             Signature Signature
+//         }
+//     }
+//
+//     TParamList []struct{
+//         Pos        Pos
+//         Name       stringOff
+//         Ptr        bool
+//     }
+//     Constraints [len(TParamList)]typeOff
+//
+// The type parameters of a type are referred to by the type name,
+// and the receiver type parameters of its methods by Type.Method.
+// Interface types are followed by their type list, and there are
+// two new kinds of types:
+//
+//     type TypeParamType struct {
+//         Tag   itag // typeParamType
+//         Pkg   stringOff
+//         Owner stringOff
+//         Index uint64
+//     }
+//
+//     type InstanceType struct {
+//         Tag   itag // instanceType
+//         Pos   Pos
+//         Base  typeOff
+//         TArgs []typeOff
+//     }
+//
+// The predeclared type comparable has index 30.
+
+package gcimporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+)
+
+const iexportVersion = 2
+
+// IExportData writes indexed export data for pkg to out, in a
+// format that can also describe parameterized functions and types.
+// Such a package can then be imported from the export data, without
+// type checking its source again.
+// If no file set is provided, position info will be missing.
+func IExportData(out io.Writer, fset *token.FileSet, pkg *types.Package) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			if ierr, ok := e.(internalError); ok {
+				err = ierr
+				return
+			}
+			// Not an internal error; panic again.
+			panic(e)
+		}
+	}()
+
+	p := iexporter{
+		fset:         fset,
+		localpkg:     pkg,
+		allPkgs:      map[*types.Package]bool{},
+		stringIndex:  map[string]uint64{},
+		declIndex:    map[types.Object]uint64{},
+		typIndex:     map[types.Type]uint64{},
+		tparamOwners: map[*types.TypeParam]tparamRef{},
+	}
+
+	for i, pt := range predeclared {
+		p.typIndex[pt] = uint64(i)
+	}
+	if len(p.typIndex) > predeclReserved {
+		panic(internalErrorf("too many predeclared types: %d > %d", len(p.typIndex), predeclReserved))
+	}
+
+	// Initialize work queue with exported declarations.
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		p.pushDecl(scope.Lookup(name))
+	}
+
+	// Loop until no more work.
+	for len(p.declTodo) > 0 {
+		obj := p.declTodo[0]
+		p.declTodo = p.declTodo[1:]
+		p.doDecl(obj)
+	}
+
+	// Append indices to data0 section.
+	dataLen := uint64(p.data0.Len())
+	w := p.newWriter()
+	w.writeIndex(p.declIndex)
+	w.flush()
+
+	// Assemble header.
+	var hdr intWriter
+	hdr.uint64(iexportVersion)
+	hdr.uint64(uint64(p.strings.Len()))
+	hdr.uint64(dataLen)
+
+	// Flush output.
+	for _, buf := range []*intWriter{&hdr, &p.strings, &p.data0} {
+		if _, err := io.Copy(out, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// An internalError is a problem with the package being exported,
+// such as a type that can't be described by the export data.
+type internalError string
+
+func (e internalError) Error() string { return "gcimporter: " + string(e) }
+
+func internalErrorf(format string, args ...interface{}) error {
+	return internalError(fmt.Sprintf(format, args...))
+}
+
+// A tparamRef identifies a type parameter by the declaration
+// that declares it and its index in the declared list.
+type tparamRef struct {
+	pkg   *types.Package
+	owner string
+	index int
+}
+
+type iexporter struct {
+	fset     *token.FileSet
+	localpkg *types.Package
+
+	// allPkgs tracks all packages that have been referenced by
+	// the export data, so we can ensure to include them in the
+	// main index.
+	allPkgs map[*types.Package]bool
+
+	declTodo []types.Object
+
+	strings     intWriter
+	stringIndex map[string]uint64
+
+	data0     intWriter
+	declIndex map[types.Object]uint64
+	typIndex  map[types.Type]uint64
+
+	tparamOwners map[*types.TypeParam]tparamRef
+}
+
+// stringOff returns the offset of s within the string section.
+// If not already present, it's added to the end.
+func (p *iexporter) stringOff(s string) uint64 {
+	off, ok := p.stringIndex[s]
+	if !ok {
+		off = uint64(p.strings.Len())
+		p.stringIndex[s] = off
+
+		p.strings.uint64(uint64(len(s)))
+		p.strings.WriteString(s)
+	}
+	return off
+}
+
+// pushDecl adds obj to the declaration work queue, if not already present.
+func (p *iexporter) pushDecl(obj types.Object) {
+	// Package unsafe is known to the importer and predeclared.
+	if obj.Pkg() == types.Unsafe {
+		panic(internalErrorf("unexpected reference to package unsafe: %v", obj))
+	}
+
+	if _, ok := p.declIndex[obj]; ok {
+		return
+	}
+
+	p.declIndex[obj] = ^uint64(0) // mark obj present in work queue
+	p.declTodo = append(p.declTodo, obj)
+}
+
+// exportWriter handles writing out individual data section chunks.
+type exportWriter struct {
+	p *iexporter
+
+	data       intWriter
+	currPkg    *types.Package
+	prevFile   string
+	prevLine   int64
+	prevColumn int64
+}
+
+func (p *iexporter) newWriter() *exportWriter {
+	return &exportWriter{p: p}
+}
+
+// exportPath returns the path of pkg as written to the export data.
+// The local package has the empty path.
+func (w *exportWriter) exportPath(pkg *types.Package) string {
+	if pkg == w.p.localpkg {
+		return ""
+	}
+	return pkg.Path()
+}
+
+func (w *exportWriter) writeIndex(index map[types.Object]uint64) {
+	// Build a map from packages to objects from that package.
+	pkgObjs := map[*types.Package][]types.Object{}
+
+	// For the main index, make sure to include every package that
+	// we reference, even if we're not exporting (or reexporting)
+	// any symbols from it.
+	pkgObjs[w.p.localpkg] = nil
+	for pkg := range w.p.allPkgs {
+		pkgObjs[pkg] = nil
+	}
+
+	for obj := range index {
+		pkgObjs[obj.Pkg()] = append(pkgObjs[obj.Pkg()], obj)
+	}
+
+	var pkgs []*types.Package
+	for pkg, objs := range pkgObjs {
+		pkgs = append(pkgs, pkg)
+
+		sort.Slice(objs, func(i, j int) bool {
+			return objs[i].Name() < objs[j].Name()
+		})
+	}
+
+	// The local package, with the empty path, sorts first.
+	sort.Slice(pkgs, func(i, j int) bool {
+		return w.exportPath(pkgs[i]) < w.exportPath(pkgs[j])
+	})
+
+	w.uint64(uint64(len(pkgs)))
+	for _, pkg := range pkgs {
+		w.string(w.exportPath(pkg))
+		w.string(pkg.Name())
+		w.uint64(uint64(0)) // package height is not needed for go/types
+
+		objs := pkgObjs[pkg]
+		w.uint64(uint64(len(objs)))
+		for _, obj := range objs {
+			w.string(obj.Name())
+			w.uint64(index[obj])
+		}
+	}
+}
+
+func (p *iexporter) doDecl(obj types.Object) {
+	w := p.newWriter()
+	w.currPkg = obj.Pkg()
+
+	switch obj := obj.(type) {
+	case *types.Var:
+		w.tag('V')
+		w.pos(obj.Pos())
+		w.typ(obj.Type(), obj.Pkg())
+
+	case *types.Func:
+		sig, _ := obj.Type().(*types.Signature)
+		if sig.Recv() != nil {
+			panic(internalErrorf("unexpected method: %v", sig))
+		}
+		if tparams := sig.TParams(); len(tparams) > 0 {
+			w.tag('G')
+			w.pos(obj.Pos())
+			w.tparamList(obj.Name(), tparams, obj.Pkg())
+		} else {
+			w.tag('F')
+			w.pos(obj.Pos())
+		}
+		w.signature(sig)
+
+	case *types.Const:
+		w.tag('C')
+		w.pos(obj.Pos())
+		w.value(obj.Type(), obj.Val())
+
+	case *types.TypeName:
+		if obj.IsAlias() {
+			w.tag('A')
+			w.pos(obj.Pos())
+			w.typ(obj.Type(), obj.Pkg())
+			break
+		}
+
+		// Defined type.
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			panic(internalErrorf("%s is not a defined type: %v", obj.Name(), obj.Type()))
+		}
+		tparams := named.TParams()
+		if len(tparams) > 0 {
+			w.tag('U')
+			w.pos(obj.Pos())
+			w.tparamList(obj.Name(), tparams, obj.Pkg())
+		} else {
+			w.tag('T')
+			w.pos(obj.Pos())
+		}
+
+		underlying := obj.Type().Underlying()
+		w.typ(underlying, obj.Pkg())
+
+		if types.IsInterface(underlying) {
+			break
+		}
+
+		n := named.NumMethods()
+		w.uint64(uint64(n))
+		for i := 0; i < n; i++ {
+			m := named.Method(i)
+			w.pos(m.Pos())
+			w.string(m.Name())
+			sig, _ := m.Type().(*types.Signature)
+			if len(tparams) > 0 {
+				w.tparamList(obj.Name()+"."+m.Name(), sig.RParams(), obj.Pkg())
+			}
+			w.param(sig.Recv())
+			w.signature(sig)
+		}
+
+	default:
+		panic(internalErrorf("unexpected object: %v", obj))
+	}
+
+	p.declIndex[obj] = w.flush()
+}
+
+func (w *exportWriter) tag(tag byte) {
+	w.data.WriteByte(tag)
+}
+
+func (w *exportWriter) pos(pos token.Pos) {
+	if w.p.fset == nil {
+		w.int64(0)
+		return
+	}
+
+	p := w.p.fset.Position(pos)
+	file := p.Filename
+	line := int64(p.Line)
+	column := int64(p.Column)
+
+	// Encode position relative to the last position: column
+	// delta, then line delta, then file name. We reserve the
+	// bottom bit of the column and line deltas to encode whether
+	// the remaining fields are present.
+	//
+	// Note: Because data objects may be read out of order (or not
+	// at all), we can only apply delta encoding within a single
+	// object. This is handled implicitly by tracking prevFile,
+	// prevLine, and prevColumn as fields of exportWriter.
+
+	deltaColumn := (column - w.prevColumn) << 1
+	deltaLine := (line - w.prevLine) << 1
+
+	if file != w.prevFile {
+		deltaLine |= 1
+	}
+	if deltaLine != 0 {
+		deltaColumn |= 1
+	}
+
+	w.int64(deltaColumn)
+	if deltaColumn&1 != 0 {
+		w.int64(deltaLine)
+		if deltaLine&1 != 0 {
+			w.string(file)
+		}
+	}
+
+	w.prevFile = file
+	w.prevLine = line
+	w.prevColumn = column
+}
+
+func (w *exportWriter) pkg(pkg *types.Package) {
+	// Ensure any referenced packages are declared in the main index.
+	w.p.allPkgs[pkg] = true
+
+	w.string(w.exportPath(pkg))
+}
+
+func (w *exportWriter) qualifiedIdent(obj types.Object) {
+	// Ensure any referenced declarations are written out too.
+	w.p.pushDecl(obj)
+
+	w.string(obj.Name())
+	w.pkg(obj.Pkg())
+}
+
+// tparamList writes the type parameters declared by owner.
+// They are recorded before their constraints are written,
+// as the constraints may refer to them.
+func (w *exportWriter) tparamList(owner string, list []*types.TypeName, pkg *types.Package) {
+	w.uint64(uint64(len(list)))
+	for i, tname := range list {
+		tpar := tname.Type().(*types.TypeParam)
+		w.p.tparamOwners[tpar] = tparamRef{pkg, owner, i}
+		w.pos(tname.Pos())
+		w.string(tname.Name())
+		w.bool(tpar.Ptr())
+	}
+	for _, tname := range list {
+		w.typ(tname.Type().(*types.TypeParam).Constraint(), pkg)
+	}
+}
+
+func (w *exportWriter) typ(t types.Type, pkg *types.Package) {
+	w.data.uint64(w.p.typOff(t, pkg))
+}
+
+func (p *iexporter) typOff(t types.Type, pkg *types.Package) uint64 {
+	off, ok := p.typIndex[t]
+	if !ok {
+		w := p.newWriter()
+		w.doTyp(t, pkg)
+		off = predeclReserved + w.flush()
+		p.typIndex[t] = off
+	}
+	return off
+}
+
+func (w *exportWriter) startType(k itag) {
+	w.data.uint64(uint64(k))
+}
+
+func (w *exportWriter) doTyp(t types.Type, pkg *types.Package) {
+	switch t := t.(type) {
+	case *types.Named:
+		if targs := t.TArgs(); len(targs) > 0 {
+			w.startType(instanceType)
+			w.pos(t.Obj().Pos())
+			w.typ(w.baseType(t), pkg)
+			w.uint64(uint64(len(targs)))
+			for _, targ := range targs {
+				w.typ(targ, pkg)
+			}
+			break
+		}
+		obj := t.Obj()
+		if obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+			panic(internalErrorf("local type %v cannot be exported", t))
+		}
+		w.startType(definedType)
+		w.qualifiedIdent(obj)
+
+	case *types.TypeParam:
+		ref, ok := w.p.tparamOwners[t]
+		if !ok {
+			panic(internalErrorf("type parameter %v used outside its declaration", t))
+		}
+		w.startType(typeParamType)
+		w.pkg(ref.pkg)
+		w.string(ref.owner)
+		w.uint64(uint64(ref.index))
+
+	case *types.Pointer:
+		w.startType(pointerType)
+		w.typ(t.Elem(), pkg)
+
+	case *types.Slice:
+		w.startType(sliceType)
+		w.typ(t.Elem(), pkg)
+
+	case *types.Array:
+		w.startType(arrayType)
+		w.uint64(uint64(t.Len()))
+		w.typ(t.Elem(), pkg)
+
+	case *types.Chan:
+		w.startType(chanType)
+		// 1 RecvOnly; 2 SendOnly; 3 SendRecv
+		var dir uint64
+		switch t.Dir() {
+		case types.RecvOnly:
+			dir = 1
+		case types.SendOnly:
+			dir = 2
+		case types.SendRecv:
+			dir = 3
+		}
+		w.uint64(dir)
+		w.typ(t.Elem(), pkg)
+
+	case *types.Map:
+		w.startType(mapType)
+		w.typ(t.Key(), pkg)
+		w.typ(t.Elem(), pkg)
+
+	case *types.Signature:
+		w.startType(signatureType)
+		w.setPkg(pkg)
+		w.signature(t)
+
+	case *types.Struct:
+		w.startType(structType)
+		n := t.NumFields()
+		if n > 0 {
+			pkg = t.Field(0).Pkg()
+		}
+		w.setPkg(pkg)
+		w.uint64(uint64(n))
+		for i := 0; i < n; i++ {
+			f := t.Field(i)
+			w.pos(f.Pos())
+			w.string(f.Name())
+			w.typ(f.Type(), pkg)
+			w.bool(f.Anonymous())
+			w.string(t.Tag(i))
+		}
+
+	case *types.Interface:
+		w.startType(interfaceType)
+		if t.NumExplicitMethods() > 0 {
+			pkg = t.ExplicitMethod(0).Pkg()
+		}
+		w.setPkg(pkg)
+
+		n := t.NumEmbeddeds()
+		w.uint64(uint64(n))
+		for i := 0; i < n; i++ {
+			ft := t.EmbeddedType(i)
+			// The importer can't instantiate types while it reads
+			// the declarations, so an instantiated constraint is
+			// written as the interface that it stands for.
+			if named, _ := ft.(*types.Named); named != nil && len(named.TArgs()) > 0 {
+				ft = named.Underlying()
+			}
+			w.pos(token.NoPos)
+			w.typ(ft, pkg)
+		}
+
+		n = t.NumExplicitMethods()
+		w.uint64(uint64(n))
+		for i := 0; i < n; i++ {
+			m := t.ExplicitMethod(i)
+			w.pos(m.Pos())
+			w.string(m.Name())
+			sig, _ := m.Type().(*types.Signature)
+			w.signature(sig)
+		}
+
+		tlist := t.TypeList()
+		w.uint64(uint64(len(tlist)))
+		for _, typ := range tlist {
+			w.typ(typ, pkg)
+		}
+
+	default:
+		// Instantiated types that were never expanded, such as in
+		// the constraints of type parameters, expand to a *types.Named.
+		if named := t.Named(); named != nil {
+			w.doTyp(named, pkg)
+			break
+		}
+		panic(internalErrorf("unexpected type: %v, %v", t, reflect.TypeOf(t)))
+	}
+}
+
+// baseType returns the parameterized type instantiated by t.
+func (w *exportWriter) baseType(t *types.Named) *types.Named {
+	obj := t.Obj()
+	if obj.Pkg() != nil {
+		if tname, _ := obj.Pkg().Scope().Lookup(obj.Name()).(*types.TypeName); tname != nil {
+			if base, _ := tname.Type().(*types.Named); base != nil && len(base.TParams()) > 0 {
+				return base
+			}
+		}
+	}
+	panic(internalErrorf("cannot find parameterized type instantiated by %v", t))
+}
+
+func (w *exportWriter) setPkg(pkg *types.Package) {
+	w.pkg(pkg)
+	w.currPkg = pkg
+}
+
+func (w *exportWriter) signature(sig *types.Signature) {
+	w.paramList(sig.Params())
+	w.paramList(sig.Results())
+	if sig.Params().Len() > 0 {
+		w.bool(sig.Variadic())
+	}
+}
+
+func (w *exportWriter) paramList(tup *types.Tuple) {
+	n := tup.Len()
+	w.uint64(uint64(n))
+	for i := 0; i < n; i++ {
+		w.param(tup.At(i))
+	}
+}
+
+func (w *exportWriter) param(obj types.Object) {
+	w.pos(obj.Pos())
+	w.string(obj.Name())
+	w.typ(obj.Type(), w.currPkg)
+}
+
+func (w *exportWriter) value(typ types.Type, v constant.Value) {
+	w.typ(typ, nil)
+
+	switch b := typ.Underlying().(*types.Basic); b.Info() & types.IsConstType {
+	case types.IsBoolean:
+		w.bool(constant.BoolVal(v))
+	case types.IsInteger:
+		w.mpint(constantToInt(v), b)
+	case types.IsFloat:
+		w.mpfloat(constantToFloat(v), b)
+	case types.IsComplex:
+		w.mpfloat(constantToFloat(constant.Real(v)), b)
+		w.mpfloat(constantToFloat(constant.Imag(v)), b)
+	case types.IsString:
+		w.string(constant.StringVal(v))
+	default:
+		panic(internalErrorf("unexpected type %v (%v)", typ, typ.Underlying()))
+	}
+}
+
+// constantToInt returns the integer value of x.
+func constantToInt(x constant.Value) *big.Int {
+	x = constant.ToInt(x)
+	if v, ok := constant.Int64Val(x); ok {
+		return big.NewInt(v)
+	}
+	return valueToInt(x)
+}
+
+// constantToFloat returns the value of x, which must be
+// representable as a float, as a big.Float.
+func constantToFloat(x constant.Value) *big.Float {
+	x = constant.ToFloat(x)
+	// Use the same floating-point precision (512) as cmd/compile
+	// (see Mpprec in cmd/compile/internal/gc/mpfloat.go).
+	const mpprec = 512
+	var f big.Float
+	f.SetPrec(mpprec)
+	if v, exact := constant.Float64Val(x); exact {
+		// float64
+		f.SetFloat64(v)
+	} else if num, denom := constant.Num(x), constant.Denom(x); num.Kind() == constant.Int {
+		// TODO(gri): add big.Rat accessor to constant.Value.
+		n := valueToInt(num)
+		d := valueToInt(denom)
+		f.SetRat(new(big.Rat).SetFrac(n, d))
+	} else {
+		// Value too large to represent as a fraction => inaccessible.
+		// TODO(gri): add big.Float accessor to constant.Value.
+		_, ok := f.SetString(x.ExactString())
+		if !ok {
+			panic(internalErrorf("invalid float constant %s", x))
+		}
+	}
+	return &f
+}
+
+// valueToInt returns the value of the integer constant x as a big.Int.
+func valueToInt(x constant.Value) *big.Int {
+	// Convert little-endian to big-endian.
+	bytes := constant.Bytes(x)
+	for i := 0; i < len(bytes)/2; i++ {
+		bytes[i], bytes[len(bytes)-1-i] = bytes[len(bytes)-1-i], bytes[i]
+	}
+	v := new(big.Int).SetBytes(bytes)
+	if constant.Sign(x) < 0 {
+		v.Neg(v)
+	}
+	return v
+}
+
+// mpint exports a multi-precision integer.
+//
+// For unsigned types, small values are written out as a single
+// byte. Larger values are written out as a length-prefixed big-endian
+// byte string, where the length prefix is encoded as its complement.
+// For example, bytes 0, 1, and 2 directly represent the integer
+// values 0, 1, and 2; while bytes 255, 254, and 253 indicate a 1-,
+// 2-, and 3-byte big-endian string follow.
+//
+// Encoding for signed types use the same general approach as for
+// unsigned types, except small values use zig-zag encoding and the
+// bottom bit of length prefix byte for large values is reserved as a
+// sign bit.
+//
+// The exact boundary between small and large encodings varies
+// according to the maximum number of bytes needed to encode a value
+// of type typ. As a special case, 8-bit types are always encoded as a
+// single byte.
+func (w *exportWriter) mpint(x *big.Int, typ *types.Basic) {
+	signed, maxBytes := intSize(typ)
+
+	negative := x.Sign() < 0
+	if !signed && negative {
+		panic(internalErrorf("negative unsigned integer; type %v, value %v", typ, x))
+	}
+
+	b := x.Bytes()
+	if len(b) > 0 && b[0] == 0 {
+		panic(internalErrorf("leading zeros"))
+	}
+	if uint(len(b)) > maxBytes {
+		panic(internalErrorf("bad mpint length: %d > %d (type %v, value %v)", len(b), maxBytes, typ, x))
+	}
+
+	maxSmall := 256 - maxBytes
+	if signed {
+		maxSmall = 256 - 2*maxBytes
+	}
+	if maxBytes == 1 {
+		maxSmall = 256
+	}
+
+	// Check if x can use small value encoding.
+	if len(b) <= 1 {
+		var ux uint
+		if len(b) == 1 {
+			ux = uint(b[0])
+		}
+		if signed {
+			ux <<= 1
+			if negative {
+				ux--
+			}
+		}
+		if ux < maxSmall {
+			w.data.WriteByte(byte(ux))
+			return
+		}
+	}
+
+	n := 256 - uint(len(b))
+	if signed {
+		n = 256 - 2*uint(len(b))
+		if negative {
+			n |= 1
+		}
+	}
+	if n < maxSmall || n >= 256 {
+		panic(internalErrorf("encoding mistake: %d, %v, %v => %d", len(b), signed, negative, n))
+	}
+
+	w.data.WriteByte(byte(n))
+	w.data.Write(b)
+}
+
+// mpfloat exports a multi-precision floating point number.
+//
+// The number's value is decomposed into mantissa × 2**exponent, where
+// mantissa is an integer. The value is written out as mantissa (as a
+// multi-precision integer) and then the exponent, except exponent is
+// omitted if mantissa is zero.
+func (w *exportWriter) mpfloat(f *big.Float, typ *types.Basic) {
+	if f.IsInf() {
+		panic(internalErrorf("infinite constant"))
+	}
+
+	// Break into f = mant × 2**exp, with 0.5 <= mant < 1.
+	var mant big.Float
+	exp := int64(f.MantExp(&mant))
+
+	// Scale so that mant is an integer.
+	prec := mant.MinPrec()
+	mant.SetMantExp(&mant, int(prec))
+	exp -= int64(prec)
+
+	manti, acc := mant.Int(nil)
+	if acc != big.Exact {
+		panic(internalErrorf("mantissa scaling failed for %f (%s)", f, acc))
+	}
+	w.mpint(manti, typ)
+	if manti.Sign() != 0 {
+		w.int64(exp)
+	}
+}
+
+func (w *exportWriter) bool(b bool) bool {
+	var x uint64
+	if b {
+		x = 1
+	}
+	w.uint64(x)
+	return b
+}
+
+func (w *exportWriter) int64(x int64)   { w.data.int64(x) }
+func (w *exportWriter) uint64(x uint64) { w.data.uint64(x) }
+func (w *exportWriter) string(s string) { w.uint64(w.p.stringOff(s)) }
+
+// flush writes out the chunk to the data section
+// and returns its offset.
+func (w *exportWriter) flush() uint64 {
+	off := uint64(w.p.data0.Len())
+	io.Copy(&w.p.data0, &w.data)
+	return off
+}
+
+type intWriter struct {
+	bytes.Buffer
+}
+
+func (w *intWriter) int64(x int64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], x)
+	w.Write(buf[:n])
+}
+
+func (w *intWriter) uint64(x uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], x)
+	w.Write(buf[:n])
+}
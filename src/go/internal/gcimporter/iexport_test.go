@@ -0,0 +1,189 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcimporter
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+const genericSrc = `package p
+
+import "fmt"
+
+type Number interface {
+	type int, int64, float64
+}
+
+type Ordered interface {
+	comparable
+	type int, string
+}
+
+type Stringer interface {
+	String() string
+}
+
+type List(type T) struct {
+	next *List(T)
+	val  T
+}
+
+func (l *List(T)) Push(v T) *List(T) { return &List(T){l, v} }
+
+func (l *List(E)) Len() int {
+	if l == nil {
+		return 0
+	}
+	return 1 + l.next.Len()
+}
+
+type Pair(type K Ordered, V interface{}) struct {
+	Key K
+	Val V
+}
+
+type Setter(type B) interface {
+	Set(string)
+	type *B
+}
+
+func Sum(type T Number)(x []T) T {
+	var s T
+	for _, v := range x {
+		s += v
+	}
+	return s
+}
+
+func FromStrings(type T interface{}, PT Setter(T))(s []string) []T { return nil }
+
+func Print(type T Stringer)(x T) { fmt.Println(x.String()) }
+
+var Ints *List(int)
+
+var Pairs []Pair(string, List(float64))
+
+const Big = 1 << 100
+
+func Plain(x int) (y string) { return }
+`
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// objectString is like types.ObjectString, but leaves out the
+// subscripts of type parameters, which depend on the order in which
+// they were created, and the marker of not yet expanded instances.
+func objectString(obj types.Object) string {
+	return strings.Map(func(r rune) rune {
+		if '₀' <= r && r <= '₉' || r == '#' {
+			return -1
+		}
+		return r
+	}, types.ObjectString(obj, nil))
+}
+
+func TestIExportGeneric(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go2", genericSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+		return Import(fset, make(map[string]*types.Package), path, "", nil)
+	})}
+	pkg, err := conf.Check("example.com/p", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := IExportData(&buf, fset, pkg); err != nil {
+		t.Fatal(err)
+	}
+
+	imports := make(map[string]*types.Package)
+	_, pkg2, err := iImportData(token.NewFileSet(), imports, buf.Bytes(), pkg.Path())
+	if err != nil {
+		t.Fatalf("reading export data: %v", err)
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj1 := scope.Lookup(name)
+		obj2 := pkg2.Scope().Lookup(name)
+		if obj2 == nil {
+			t.Errorf("%s: missing after import", name)
+			continue
+		}
+		if got, want := objectString(obj2), objectString(obj1); got != want {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+		if named1, ok := obj1.Type().(*types.Named); ok {
+			named2 := obj2.Type().(*types.Named)
+			if got, want := len(named2.TParams()), len(named1.TParams()); got != want {
+				t.Errorf("%s: got %d type parameters, want %d", name, got, want)
+			}
+			if got, want := named2.NumMethods(), named1.NumMethods(); got != want {
+				t.Errorf("%s: got %d methods, want %d", name, got, want)
+				continue
+			}
+			for i := 0; i < named1.NumMethods(); i++ {
+				m1, m2 := named1.Method(i), named2.Method(i)
+				if got, want := objectString(m2), objectString(m1); got != want {
+					t.Errorf("%s: got method %s, want %s", name, got, want)
+				}
+				sig1, sig2 := m1.Type().(*types.Signature), m2.Type().(*types.Signature)
+				if got, want := len(sig2.RParams()), len(sig1.RParams()); got != want {
+					t.Errorf("%s.%s: got %d receiver type parameters, want %d", name, m1.Name(), got, want)
+				}
+			}
+		}
+	}
+
+	// The imported declarations can be used.
+	lookup := func(name string) types.Type {
+		return pkg2.Scope().Lookup(name).Type()
+	}
+	number := lookup("Number").Underlying().(*types.Interface)
+	if got := len(number.TypeList()); got != 3 {
+		t.Errorf("Number has %d types in its type list, want 3", got)
+	}
+	if !types.Satisfies(types.Typ[types.Int64], number) {
+		t.Errorf("int64 does not satisfy Number")
+	}
+	if types.Satisfies(types.Typ[types.String], number) {
+		t.Errorf("string satisfies Number")
+	}
+
+	sum := pkg2.Scope().Lookup("Sum").(*types.Func)
+	tpar := sum.Type().(*types.Signature).TParams()[0].Type().(*types.TypeParam)
+	if tpar.Constraint() != lookup("Number") {
+		t.Errorf("constraint of Sum's type parameter is %v, want Number", tpar.Constraint())
+	}
+	if _, err := types.Instantiate(sum.Type(), []types.Type{types.Typ[types.String]}); err == nil {
+		t.Errorf("Sum(string) was accepted")
+	}
+
+	inst, err := types.Instantiate(lookup("List"), []types.Type{types.Typ[types.String]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := inst.Underlying().String(), "struct{next *example.com/p.List(string); val string}"; got != want {
+		t.Errorf("List(string) has underlying type %s, want %s", got, want)
+	}
+
+	ints := lookup("Ints").(*types.Pointer).Elem().(*types.Named)
+	if got, want := ints.Underlying().String(), "struct{next *example.com/p.List(int); val int}"; got != want {
+		t.Errorf("Ints has type %s, want %s", got, want)
+	}
+}
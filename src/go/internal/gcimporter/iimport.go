@@ -3,7 +3,8 @@
 // license that can be found in the LICENSE file.
 
 // Indexed package import.
-// See cmd/compile/internal/gc/iexport.go for the export data format.
+// See cmd/compile/internal/gc/iexport.go for the export data format,
+// and iexport.go for the extensions describing parameterized code.
 
 package gcimporter
 
@@ -16,6 +17,8 @@ import (
 	"go/types"
 	"io"
 	"sort"
+	"strings"
+	_ "unsafe" // for go:linkname
 )
 
 type intReader struct {
@@ -54,6 +57,8 @@ const (
 	signatureType
 	structType
 	interfaceType
+	typeParamType // version 2
+	instanceType  // version 2
 )
 
 // iImportData imports a package from the serialized package data
@@ -61,7 +66,7 @@ const (
 // If the export data version is not recognized or the format is otherwise
 // compromised, an error is returned.
 func iImportData(fset *token.FileSet, imports map[string]*types.Package, data []byte, path string) (_ int, pkg *types.Package, err error) {
-	const currentVersion = 1
+	const currentVersion = 2
 	version := int64(-1)
 	defer func() {
 		if e := recover(); e != nil {
@@ -77,7 +82,7 @@ func iImportData(fset *token.FileSet, imports map[string]*types.Package, data []
 
 	version = int64(r.uint64())
 	switch version {
-	case currentVersion, 0:
+	case currentVersion, 1, 0:
 	default:
 		errorf("unknown iexport format version %d", version)
 	}
@@ -102,6 +107,8 @@ func iImportData(fset *token.FileSet, imports map[string]*types.Package, data []
 		pkgIndex: make(map[*types.Package]map[string]uint64),
 		typCache: make(map[uint64]types.Type),
 
+		tparamIndex: make(map[tparamOwner][]*types.TypeName),
+
 		fake: fakeFileSet{
 			fset:  fset,
 			files: make(map[string]*token.File),
@@ -144,6 +151,9 @@ func iImportData(fset *token.FileSet, imports map[string]*types.Package, data []
 
 	localpkg := pkgList[0]
 
+	// The checker instantiates the parameterized types used by the package.
+	p.check = types.NewChecker(nil, fset, localpkg, nil)
+
 	names := make([]string, 0, len(p.pkgIndex[localpkg]))
 	for name := range p.pkgIndex[localpkg] {
 		names = append(names, name)
@@ -157,6 +167,11 @@ func iImportData(fset *token.FileSet, imports map[string]*types.Package, data []
 		typ.Complete()
 	}
 
+	// All declarations are read, so the instances can be expanded.
+	if p.version >= 2 {
+		expandInstances(pkgList)
+	}
+
 	// record all referenced packages as imports
 	list := append(([]*types.Package)(nil), pkgList[1:]...)
 	sort.Sort(byPath(list))
@@ -183,6 +198,16 @@ type iimporter struct {
 
 	fake          fakeFileSet
 	interfaceList []*types.Interface
+
+	check       *types.Checker
+	tparamIndex map[tparamOwner][]*types.TypeName
+}
+
+// A tparamOwner identifies the declaration of a list of type parameters:
+// a function or type name, or the name of a method as Type.Method.
+type tparamOwner struct {
+	pkg  *types.Package
+	name string
 }
 
 func (p *iimporter) doDecl(pkg *types.Package, name string) {
@@ -274,13 +299,36 @@ func (r *importReader) obj(name string) {
 
 		r.declare(types.NewFunc(pos, r.currPkg, name, sig))
 
-	case 'T':
+	case 'G':
+		tparams := r.tparamList(name)
+		sig := r.signature(nil)
+		sig.SetTParams(tparams)
+
+		r.declare(types.NewFunc(pos, r.currPkg, name, sig))
+
+	case 'I':
+		// The copy of an instantiated type, which the compiler
+		// declares with -G, is read as the defined type named after
+		// the instantiation, as in Set[int]. Skip the generic type
+		// and the type arguments.
+		r.string()
+		for n := r.uint64(); n > 0; n-- {
+			r.uint64()
+		}
+		fallthrough
+
+	case 'T', 'U':
 		// Types can be recursive. We need to setup a stub
 		// declaration before recursing.
 		obj := types.NewTypeName(pos, r.currPkg, name, nil)
 		named := types.NewNamed(obj, nil, nil)
 		r.declare(obj)
 
+		generic := tag == 'U'
+		if generic {
+			named.SetTParams(r.tparamList(name))
+		}
+
 		underlying := r.p.typAt(r.uint64(), named).Underlying()
 		named.SetUnderlying(underlying)
 
@@ -288,8 +336,13 @@ func (r *importReader) obj(name string) {
 			for n := r.uint64(); n > 0; n-- {
 				mpos := r.pos()
 				mname := r.ident()
+				var rparams []*types.TypeName
+				if generic {
+					rparams = r.tparamList(name + "." + mname)
+				}
 				recv := r.param()
 				msig := r.signature(recv)
+				msig.SetRParams(rparams)
 
 				named.AddMethod(types.NewFunc(mpos, r.currPkg, mname, msig))
 			}
@@ -309,6 +362,25 @@ func (r *importReader) declare(obj types.Object) {
 	obj.Pkg().Scope().Insert(obj)
 }
 
+// tparamList reads the type parameters declared by owner.
+// The type parameters are recorded before their constraints
+// are read, as the constraints may refer to them.
+func (r *importReader) tparamList(owner string) []*types.TypeName {
+	tparams := make([]*types.TypeName, r.uint64())
+	for i := range tparams {
+		pos := r.pos()
+		name := r.ident()
+		ptr := r.bool()
+		tparams[i] = types.NewTypeName(pos, r.currPkg, name, nil)
+		r.p.check.NewTypeParam(ptr, tparams[i], i, types.NewInterfaceType(nil, nil))
+	}
+	r.p.tparamIndex[tparamOwner{r.currPkg, owner}] = tparams
+	for _, tname := range tparams {
+		tname.Type().(*types.TypeParam).SetConstraint(r.typ())
+	}
+	return tparams
+}
+
 func (r *importReader) value() (typ types.Type, val constant.Value) {
 	typ = r.typ()
 
@@ -557,9 +629,50 @@ func (r *importReader) doType(base *types.Named) types.Type {
 			methods[i] = types.NewFunc(mpos, r.currPkg, mname, msig)
 		}
 
-		typ := types.NewInterfaceType(methods, embeddeds)
+		var tlist []types.Type
+		if r.p.version >= 2 {
+			tlist = make([]types.Type, r.uint64())
+			for i := range tlist {
+				tlist[i] = r.typ()
+			}
+		}
+
+		typ := types.NewConstraintType(methods, embeddeds, tlist)
 		r.p.interfaceList = append(r.p.interfaceList, typ)
 		return typ
+
+	case typeParamType:
+		pkg := r.pkg()
+		owner := r.string()
+		index := r.uint64()
+
+		// Make sure the declaration of the type parameter has been read.
+		key := tparamOwner{pkg, owner}
+		if _, ok := r.p.tparamIndex[key]; !ok {
+			name := owner
+			if i := strings.Index(owner, "."); i >= 0 {
+				name = owner[:i]
+			}
+			r.p.doDecl(pkg, name)
+		}
+
+		tparams, ok := r.p.tparamIndex[key]
+		if !ok || index >= uint64(len(tparams)) {
+			errorf("missing type parameter %d of %s.%s", index, pkg.Path(), owner)
+		}
+		return tparams[index].Type()
+
+	case instanceType:
+		pos := r.pos()
+		base, ok := r.typ().(*types.Named)
+		if !ok {
+			errorf("instance of non-defined type in %q", r.p.ipath)
+		}
+		targs := make([]types.Type, r.uint64())
+		for i := range targs {
+			targs[i] = r.typ()
+		}
+		return newInstance(r.p.check, pos, base, targs)
 	}
 }
 
@@ -616,3 +729,12 @@ func (r *importReader) byte() byte {
 	}
 	return x
 }
+
+// newInstance returns the lazily instantiated type base(targs...).
+//go:linkname newInstance go/types.gcimporter_newInstance
+func newInstance(check *types.Checker, pos token.Pos, base *types.Named, targs []types.Type) types.Type
+
+// expandInstances replaces the types returned by newInstance
+// by the instantiated types, in the package level objects of pkgs.
+//go:linkname expandInstances go/types.gcimporter_expandInstances
+func expandInstances(pkgs []*types.Package)
@@ -119,6 +119,9 @@ var predeclared = []types.Type{
 
 	// used internally by gc; never used by this package or in .a files
 	anyType{},
+
+	// predeclared constraint; only in export data written by IExportData
+	types.Universe.Lookup("comparable").Type(),
 }
 
 type anyType struct{}
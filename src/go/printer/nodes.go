@@ -366,7 +366,13 @@ func (p *printer) parameters(isTypeParam bool, fields *ast.FieldList) {
 			}
 			// parameter type
 			if par.Type != nil {
-				p.expr(stripParensAlways(par.Type))
+				typ := stripParensAlways(par.Type)
+				if len(par.Names) == 0 && isInstantiatedIdent(typ) {
+					// Keep the parentheses: (T(A)) is a parameter of
+					// type T(A), but T(A) is a parameter T of type (A).
+					typ = &ast.ParenExpr{X: typ}
+				}
+				p.expr(typ)
 			}
 			prevLine = parLineEnd
 		}
@@ -421,6 +427,21 @@ func identListSize(list []*ast.Ident, maxSize int) (size int) {
 }
 
 func (p *printer) isOneLineFieldList(list []*ast.Field) bool {
+	if isTypeList(list) {
+		// allow a single type list
+		const maxSize = 30 // same as below
+		size := len("type")
+		for _, f := range list {
+			if f.Comment != nil {
+				return false
+			}
+			size += 2 + p.nodeSize(f.Type, maxSize) // blank or ", " before type
+			if size > maxSize {
+				return false
+			}
+		}
+		return true
+	}
 	if len(list) != 1 {
 		return false // allow only one field
 	}
@@ -438,6 +459,36 @@ func (p *printer) isOneLineFieldList(list []*ast.Field) bool {
 	return namesSize+typeSize <= maxSize
 }
 
+// isInstantiatedIdent reports whether x has the form T(A), where T
+// is an identifier.
+func isInstantiatedIdent(x ast.Expr) bool {
+	call, ok := x.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	_, ok = call.Fun.(*ast.Ident)
+	return ok
+}
+
+// isTypeList reports whether list consists of the types of a
+// single interface type list. The parser represents each type of
+// the list as a separate field, all sharing the same "type" name.
+func isTypeList(list []*ast.Field) bool {
+	if len(list) == 0 {
+		return false
+	}
+	name := list[0].Names
+	if len(name) != 1 || name[0].Name != "type" {
+		return false
+	}
+	for _, f := range list[1:] {
+		if len(f.Names) != 1 || f.Names[0] != name[0] {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *printer) setLineComment(text string) {
 	p.setComment(&ast.CommentGroup{List: []*ast.Comment{{Slash: token.NoPos, Text: text}}})
 }
@@ -478,9 +529,14 @@ func (p *printer) fieldList(fields *ast.FieldList, isStruct, isIncomplete bool)
 					name := f.Names[0] // "type" or method name
 					p.expr(name)
 					if name.Name == "type" {
-						// type list type
-						p.print(blank)
-						p.expr(f.Type)
+						// type list
+						for i, f := range list {
+							if i > 0 {
+								p.print(token.COMMA)
+							}
+							p.print(blank)
+							p.expr(f.Type)
+						}
 					} else {
 						// method
 						p.signature(f.Type.(*ast.FuncType)) // don't print "func"
@@ -579,7 +635,9 @@ func (p *printer) fieldList(fields *ast.FieldList, isStruct, isIncomplete bool)
 						p.print(token.COMMA, blank)
 					} else {
 						// type starts a new list of types
-						p.print(name, blank)
+						// (print "type" as a keyword, not as an identifier,
+						// so that a following comment doesn't imply a line break)
+						p.print(name.Pos(), token.TYPE, blank)
 					}
 					p.expr(f.Type)
 					prev = name
@@ -1869,13 +1927,22 @@ func (p *printer) funcDecl(d *ast.FuncDecl) {
 	// different line (all whitespace preceding the FUNC is emitted only when the
 	// FUNC is emitted).
 	startCol := p.out.Column - len("func ")
+	startLine := p.out.Line
 	if d.Recv != nil {
 		p.parameters(false, d.Recv) // method: print receiver
 		p.print(blank)
 	}
 	p.expr(d.Name)
 	p.signature(d.Type)
-	p.funcBody(p.distanceFrom(d.Pos(), startCol), vtab, d.Body)
+	headerSize := p.distanceFrom(d.Pos(), startCol)
+	if p.out.Line != startLine {
+		// The header was broken across lines, for instance because it
+		// contains a constraint that doesn't fit on one line. Don't put
+		// the body on the same line, as we wouldn't do so when formatting
+		// the result again.
+		headerSize = infinity
+	}
+	p.funcBody(headerSize, vtab, d.Body)
 }
 
 func (p *printer) decl(decl ast.Decl) {
@@ -39,16 +39,30 @@ import (
 // package (such as "unused variable"); "hard" errors may lead to unpredictable
 // behavior if ignored.
 type Error struct {
-	Fset *token.FileSet // file set for interpretation of Pos
-	Pos  token.Pos      // error position
-	Msg  string         // default error message, user-friendly
-	Full string         // full error message, for debugging (may contain internal details)
-	Soft bool           // if set, error is "soft"
+	Fset    *token.FileSet // file set for interpretation of Pos
+	Pos     token.Pos      // error position
+	Msg     string         // default error message, user-friendly
+	Full    string         // full error message, for debugging (may contain internal details)
+	Soft    bool           // if set, error is "soft"
+	Code    ErrorCode      // kind of error, see ErrorCode
+	Related []RelatedInfo  // positions related to the error, if any
+}
+
+// A RelatedInfo describes a position related to an Error, such as
+// the declaration of a constraint that a type argument does not
+// satisfy. Its position is interpreted with the Fset of the Error.
+type RelatedInfo struct {
+	Pos token.Pos // related position
+	Msg string    // description of the position
 }
 
 // Error returns an error string formatted as follows:
 // filename:line:column: message
+// If the error has no position, Error returns the message.
 func (err Error) Error() string {
+	if !err.Pos.IsValid() {
+		return err.Msg
+	}
 	return fmt.Sprintf("%s: %s", err.Fset.Position(err.Pos), err.Msg)
 }
 
@@ -59,6 +73,16 @@ func (err Error) FullError() string {
 	return fmt.Sprintf("%s: %s", err.Fset.Position(err.Pos), err.Full)
 }
 
+// An ArgumentError is returned by Instantiate when a type argument
+// does not satisfy the constraint of its type parameter.
+type ArgumentError struct {
+	Index int   // index of the type argument
+	Err   error // why the type argument does not satisfy the constraint; an Error
+}
+
+func (err *ArgumentError) Error() string { return err.Err.Error() }
+func (err *ArgumentError) Unwrap() error { return err.Err }
+
 // An Importer resolves import paths to Packages.
 //
 // CAUTION: This interface does not support the import of locally
@@ -158,6 +182,35 @@ func srcimporter_setUsesCgo(conf *Config) {
 	conf.go115UsesCgo = true
 }
 
+// go2go_setUsesCgo is like srcimporter_setUsesCgo, for go/go2go,
+// which runs cmd/cgo on .go2 files that import "C".
+func go2go_setUsesCgo(conf *Config) {
+	conf.go115UsesCgo = true
+}
+
+// gcimporter_newInstance returns the instantiation of the parameterized
+// type base with the type arguments targs, for go/internal/gcimporter.
+// The type is instantiated on first use, as the underlying type of base
+// may not be set yet while reading export data. All the instances of an
+// import must use the same checker, so that recursive instantiations
+// terminate. The type arguments must satisfy their constraints.
+func gcimporter_newInstance(check *Checker, pos token.Pos, base *Named, targs []Type) Type {
+	return &instance{check: check, pos: pos, base: base, targs: targs}
+}
+
+// gcimporter_expandInstances replaces the instances made by
+// gcimporter_newInstance by the instantiated types, in the
+// types of the package level objects of pkgs.
+func gcimporter_expandInstances(pkgs []*Package) {
+	var s sanitizer = make(map[Type]Type)
+	for _, pkg := range pkgs {
+		for _, name := range pkg.scope.Names() {
+			obj := pkg.scope.Lookup(name)
+			obj.setType(s.typ(obj.Type()))
+		}
+	}
+}
+
 // Info holds result type information for a type-checked package.
 // Only the information for which a map is provided is collected.
 // If the package has type errors, the collected information may
@@ -411,6 +464,73 @@ func Implements(V Type, T *Interface) bool {
 	return f == nil
 }
 
+// Instantiate instantiates the parameterized type or function typ,
+// a *Named type or a *Signature with type parameters, with the type
+// arguments targs, and returns the instantiated *Named type or
+// *Signature. Type bounds that refer to the type parameters are
+// instantiated with targs as well.
+//
+// If a type argument does not satisfy the constraint of its type
+// parameter, Instantiate returns an *ArgumentError describing the
+// first such argument. It also returns an error if typ is not
+// parameterized, or if the number of type arguments is wrong.
+//
+// Instantiating the same type twice with identical type arguments
+// results in distinct *Named types, which are identical per Identical.
+func Instantiate(typ Type, targs []Type) (Type, error) {
+	var tparams []*TypeName
+	switch t := typ.(type) {
+	case *Named:
+		if len(t.targs) > 0 {
+			return nil, fmt.Errorf("%s is already instantiated", typ)
+		}
+		tparams = t.tparams
+	case *Signature:
+		tparams = t.tparams
+	}
+	if len(tparams) == 0 {
+		return nil, fmt.Errorf("%s is not parameterized", typ)
+	}
+	if len(targs) != len(tparams) {
+		return nil, fmt.Errorf("got %d arguments but %d type parameters", len(targs), len(tparams))
+	}
+
+	// The checker is only used for its type and interface caches,
+	// and to format error messages, which qualify all package names.
+	check := NewChecker(nil, token.NewFileSet(), nil, nil)
+	targs = append([]Type(nil), targs...) // makeSubstMap expands targs in place
+	smap := makeSubstMap(tparams, targs)
+	for i, tname := range tparams {
+		if err := check.satisfiesBound(token.NoPos, tname.typ.(*TypeParam), targs[i], smap); err != nil {
+			return nil, &ArgumentError{Index: i, Err: *err}
+		}
+	}
+
+	res := check.subst(token.NoPos, typ, smap)
+	if sig, _ := res.(*Signature); sig != nil {
+		// As in Checker.instantiate, the instantiated
+		// signature is no longer generic.
+		if sig == typ {
+			copy := *sig
+			sig = &copy
+		}
+		sig.tparams = nil
+		res = sig
+	}
+	return res, nil
+}
+
+// Satisfies reports whether type T satisfies the constraint interface C.
+// T must implement the methods of C, and, if C has a type list, the
+// underlying type of T must be one of the types in the list. If T is
+// a type parameter, each type in the type list of its own constraint
+// must be in the type list of C.
+func Satisfies(T Type, C *Interface) bool {
+	C.Complete()
+	check := NewChecker(nil, token.NewFileSet(), nil, nil)
+	return check.satisfies(T, C, C, false) == nil
+}
+
 // Identical reports whether x and y are identical types.
 // Receivers of Signature types are ignored.
 func Identical(x, y Type) bool {
@@ -1470,6 +1470,40 @@ func TestIdentical_issue15173(t *testing.T) {
 	}
 }
 
+func TestIdenticalInstances(t *testing.T) {
+	// Instantiations of the same generic type with identical type
+	// arguments are identical, even if made while checking
+	// different packages.
+	fset := token.NewFileSet()
+	imports := make(testImporter)
+	makePkg := func(path, src string) *Package {
+		f, err := parser.ParseFile(fset, path, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf := Config{Importer: imports}
+		pkg, err := conf.Check(path, fset, []*ast.File{f}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imports[path] = pkg
+		return pkg
+	}
+
+	a := makePkg("a", "package a; type T(type P) struct{ f P }; var V T(int)")
+	b := makePkg("b", `package b; import "a"; var V a.T(int); var W a.T(string)`)
+
+	av := a.Scope().Lookup("V").Type()
+	bv := b.Scope().Lookup("V").Type()
+	bw := b.Scope().Lookup("W").Type()
+	if !Identical(av, bv) {
+		t.Errorf("Identical(%v, %v) = false, want true", av, bv)
+	}
+	if Identical(av, bw) {
+		t.Errorf("Identical(%v, %v) = true, want false", av, bw)
+	}
+}
+
 func TestIssue15305(t *testing.T) {
 	const src = "package p; func f() int16; var _ = f(undef)"
 	fset := token.NewFileSet()
@@ -1689,3 +1723,188 @@ func f(x T) T { return foo.F(x) }
 		}
 	}
 }
+
+func TestInstantiate(t *testing.T) {
+	const src = `package p
+
+type Stringer interface{ String() string }
+
+type Number interface{ type int, float64 }
+
+type List(type T Stringer) []T
+
+func Sum(type T Number)(x []T) T { var s T; for _, v := range x { s += v }; return s }
+
+type myInt int
+func (myInt) String() string { return "" }
+
+type myFloat float64
+`
+	pkg, err := pkgFor("p", src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := func(name string) Type {
+		return pkg.Scope().Lookup(name).Type()
+	}
+	list, sum := lookup("List"), lookup("Sum")
+	myInt, myFloat := lookup("myInt"), lookup("myFloat")
+
+	// Instantiating a type.
+	inst, err := Instantiate(list, []Type{myInt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := TypeString(inst.Underlying(), nil), "[]p.myInt"; got != want {
+		t.Errorf("underlying type of List(myInt) is %s, want %s", got, want)
+	}
+	inst2, err := Instantiate(list, []Type{myInt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Identical(inst, inst2) {
+		t.Errorf("two instantiations of List(myInt) are not identical")
+	}
+
+	// Instantiating a function.
+	inst, err = Instantiate(sum, []Type{myFloat})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := TypeString(inst, nil), "func(x []p.myFloat) p.myFloat"; got != want {
+		t.Errorf("Sum(myFloat) has type %s, want %s", got, want)
+	}
+	if sig := sum.(*Signature); len(sig.TParams()) != 1 {
+		t.Errorf("instantiating Sum changed its type parameters")
+	}
+
+	// Type arguments that don't satisfy their constraints.
+	for _, test := range []struct {
+		typ  Type
+		targ Type
+		err  string
+	}{
+		{list, Typ[Int], "int does not satisfy p.Stringer (missing method String)"},
+		{sum, Typ[String], "string does not satisfy p.Number (string not found in int, float64)"},
+	} {
+		_, err := Instantiate(test.typ, []Type{test.targ})
+		aerr, ok := err.(*ArgumentError)
+		if !ok {
+			t.Errorf("Instantiate(%s, %s) returned %v, want an *ArgumentError", test.typ, test.targ, err)
+			continue
+		}
+		if aerr.Index != 0 || aerr.Error() != test.err {
+			t.Errorf("Instantiate(%s, %s) returned error %d %q, want 0 %q", test.typ, test.targ, aerr.Index, aerr, test.err)
+		}
+	}
+
+	// Invalid instantiations.
+	if _, err := Instantiate(myInt, []Type{Typ[Int]}); err == nil {
+		t.Errorf("Instantiate of non-parameterized type succeeded")
+	}
+	if _, err := Instantiate(list, []Type{myInt, myInt}); err == nil {
+		t.Errorf("Instantiate with too many type arguments succeeded")
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	const src = `package p
+
+type Stringer interface{ String() string }
+
+type Integer interface{ type int, int64 }
+
+type StringInteger interface{ Integer; Stringer }
+
+type myInt int
+func (myInt) String() string { return "" }
+
+func F(type T Integer)()
+`
+	pkg, err := pkgFor("p", src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := func(name string) Type {
+		return pkg.Scope().Lookup(name).Type()
+	}
+	stringer := lookup("Stringer").Underlying().(*Interface)
+	integer := lookup("Integer").Underlying().(*Interface)
+	stringInteger := lookup("StringInteger").Underlying().(*Interface)
+	myInt := lookup("myInt")
+	tparam := lookup("F").(*Signature).TParams()[0].Type()
+
+	for _, test := range []struct {
+		T    Type
+		C    *Interface
+		want bool
+	}{
+		{myInt, stringer, true},
+		{Typ[Int], stringer, false},
+		{Typ[Int], integer, true},
+		{myInt, integer, true},
+		{Typ[Uint], integer, false},
+		{myInt, stringInteger, true},
+		{Typ[Int], stringInteger, false},
+		{tparam, integer, true},
+		{tparam, stringer, false},
+	} {
+		if got := Satisfies(test.T, test.C); got != test.want {
+			t.Errorf("Satisfies(%s, %s) = %v, want %v", test.T, test.C, got, test.want)
+		}
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	for _, test := range []struct {
+		src     string
+		code    ErrorCode
+		related string // description of the first related position, if any
+	}{
+		{`var x = nil`, UntypedNilUse, ""},
+		{`var x int; var x int`, DuplicateDecl, "other declaration of x"},
+		{`func f() { x := 1 }`, UnusedVar, ""},
+		{`var s string; var _ = 1 << s`, InvalidShiftCount, ""},
+		{`var _ int8 = 1000`, NumericOverflow, ""},
+		{`var _ = undeclared`, UndeclaredName, ""},
+		{`type List(type T) []T; var _ List`, MissingTypeArgs, ""},
+		{`type List(type T) []T; var _ List(int, int)`, WrongTypeArgCount, ""},
+		{`func Zero(type T)() T { var z T; return z }; var _ = Zero()`, CannotInferTypeArgs, "type parameter T declared here"},
+		{`type Stringer interface{ String() string }; func F(type T Stringer)(T) {}; var _ = F(int)`, UnsatisfiedConstraint, "constraint Stringer declared here"},
+		{`type Number interface{ type int, float64 }; func F(type T Number)(T) {}; var _ = F(string)`, TypeListMismatch, "constraint Number declared here"},
+		{`func F(type T interface{ type int })(T) {}; var _ = F(string)`, TypeListMismatch, "type parameter T declared here"},
+		{`type Number interface{ type int, float64 }; var _ Number`, MisplacedConstraintIface, ""},
+	} {
+		src := "package p; " + test.src
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "p.go2", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var first *Error
+		conf := Config{Error: func(err error) {
+			if first == nil {
+				e := err.(Error)
+				first = &e
+			}
+		}}
+		conf.Check(f.Name.Name, fset, []*ast.File{f}, nil)
+		if first == nil {
+			t.Errorf("%s: no error reported", test.src)
+			continue
+		}
+		if first.Code != test.code {
+			t.Errorf("%s: got code %d (%s), want %d", test.src, first.Code, first.Msg, test.code)
+		}
+		var related string
+		if len(first.Related) > 0 {
+			related = first.Related[0].Msg
+			if !first.Related[0].Pos.IsValid() {
+				t.Errorf("%s: related position is not valid", test.src)
+			}
+		}
+		if related != test.related {
+			t.Errorf("%s: got related information %q, want %q", test.src, related, test.related)
+		}
+	}
+}
@@ -38,14 +38,14 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 		// conversion
 		switch n := len(e.Args); n {
 		case 0:
-			check.errorf(e.Rparen, "missing argument in conversion to %s", T)
+			check.errorf(e.Rparen, WrongArgCount, "missing argument in conversion to %s", T)
 		case 1:
 			check.expr(x, e.Args[0])
 			if x.mode != invalid {
 				if t := T.Interface(); t != nil {
 					check.completeInterface(token.NoPos, t)
 					if t.IsConstraint() {
-						check.errorf(e.Pos(), "cannot use interface %s in conversion (contains type list or is comparable)", T)
+						check.errorf(e.Pos(), MisplacedConstraintIface, "cannot use interface %s in conversion (contains type list or is comparable)", T)
 						break
 					}
 				}
@@ -53,7 +53,7 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 			}
 		default:
 			check.use(e.Args...)
-			check.errorf(e.Args[n-1].Pos(), "too many arguments in conversion to %s", T)
+			check.errorf(e.Args[n-1].Pos(), WrongArgCount, "too many arguments in conversion to %s", T)
 		}
 		x.expr = e
 		return conversion
@@ -76,7 +76,7 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 
 		sig := x.typ.Signature()
 		if sig == nil {
-			check.invalidOp(x.pos(), "cannot call non-function %s", x)
+			check.invalidOp(x.pos(), InvalidCall, "cannot call non-function %s", x)
 			x.mode = invalid
 			x.expr = e
 			return statement
@@ -92,7 +92,7 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 			// we must have the correct number of type parameters
 			// TODO(gri) do this in the instantiate call?
 			if n != len(sig.tparams) {
-				check.errorf(args[n-1].pos(), "got %d type arguments but want %d", n, len(sig.tparams))
+				check.errorf(args[n-1].pos(), WrongTypeArgCount, "got %d type arguments but want %d", n, len(sig.tparams))
 				x.mode = invalid
 				x.expr = e
 				return expression
@@ -193,7 +193,7 @@ func (check *Checker) exprOrTypeList(elist []ast.Expr) (xlist []*operand, ok boo
 			}
 		}
 		if 0 < ntypes && ntypes < len(xlist) {
-			check.errorf(xlist[0].pos(), "mix of value and type expressions")
+			check.errorf(xlist[0].pos(), NotAnExpr, "mix of value and type expressions")
 			ok = false
 		}
 	}
@@ -248,7 +248,7 @@ func (check *Checker) arguments(call *ast.CallExpr, sig *Signature, args []*oper
 	for _, a := range args {
 		switch a.mode {
 		case typexpr:
-			check.errorf(a.pos(), "%s used as value", a)
+			check.errorf(a.pos(), NotAnExpr, "%s used as value", a)
 			return
 		case invalid:
 			return
@@ -276,7 +276,7 @@ func (check *Checker) arguments(call *ast.CallExpr, sig *Signature, args []*oper
 			// variadic_func(a, b, c...)
 			if len(call.Args) == 1 && nargs > 1 {
 				// f()... is not permitted if f() is multi-valued
-				check.errorf(call.Ellipsis, "cannot use ... with %d-valued %s", nargs, call.Args[0])
+				check.errorf(call.Ellipsis, InvalidDotDotDot, "cannot use ... with %d-valued %s", nargs, call.Args[0])
 				return
 			}
 		} else {
@@ -303,7 +303,7 @@ func (check *Checker) arguments(call *ast.CallExpr, sig *Signature, args []*oper
 	} else {
 		if ddd {
 			// standard_func(a, b, c...)
-			check.errorf(call.Ellipsis, "cannot use ... in call to non-variadic %s", call.Fun)
+			check.errorf(call.Ellipsis, NonVariadicDotDotDot, "cannot use ... in call to non-variadic %s", call.Fun)
 			return
 		}
 		// standard_func(a, b, c)
@@ -312,10 +312,10 @@ func (check *Checker) arguments(call *ast.CallExpr, sig *Signature, args []*oper
 	// check argument count
 	switch {
 	case nargs < npars:
-		check.errorf(call.Rparen, "not enough arguments in call to %s", call.Fun)
+		check.errorf(call.Rparen, WrongArgCount, "not enough arguments in call to %s", call.Fun)
 		return
 	case nargs > npars:
-		check.errorf(args[npars].pos(), "too many arguments in call to %s", call.Fun) // report at first extra argument
+		check.errorf(args[npars].pos(), WrongArgCount, "too many arguments in call to %s", call.Fun) // report at first extra argument
 		return
 	}
 
@@ -405,7 +405,7 @@ func (check *Checker) selector(x *operand, e *ast.SelectorExpr) {
 					}
 				}
 				if exp == nil {
-					check.errorf(e.Sel.Pos(), "%s not declared by package C", sel)
+					check.errorf(e.Sel.Pos(), UndeclaredImportedName, "%s not declared by package C", sel)
 					goto Error
 				}
 				check.objDecl(exp, nil)
@@ -413,12 +413,12 @@ func (check *Checker) selector(x *operand, e *ast.SelectorExpr) {
 				exp = pkg.scope.Lookup(sel)
 				if exp == nil {
 					if !pkg.fake {
-						check.errorf(e.Sel.Pos(), "%s not declared by package %s", sel, pkg.name)
+						check.errorf(e.Sel.Pos(), UndeclaredImportedName, "%s not declared by package %s", sel, pkg.name)
 					}
 					goto Error
 				}
 				if !exp.Exported() {
-					check.errorf(e.Sel.Pos(), "%s not exported by package %s", sel, pkg.name)
+					check.errorf(e.Sel.Pos(), UnexportedName, "%s not exported by package %s", sel, pkg.name)
 					// ok to continue
 				}
 			}
@@ -471,9 +471,9 @@ func (check *Checker) selector(x *operand, e *ast.SelectorExpr) {
 		switch {
 		case index != nil:
 			// TODO(gri) should provide actual type where the conflict happens
-			check.errorf(e.Sel.Pos(), "ambiguous selector %s.%s", x.expr, sel)
+			check.errorf(e.Sel.Pos(), AmbiguousSelector, "ambiguous selector %s.%s", x.expr, sel)
 		case indirect:
-			check.errorf(e.Sel.Pos(), "cannot call pointer method %s on %s", sel, x.typ)
+			check.errorf(e.Sel.Pos(), InvalidMethodExpr, "cannot call pointer method %s on %s", sel, x.typ)
 		default:
 			var why string
 			if tpar := x.typ.TypeParam(); tpar != nil {
@@ -501,7 +501,7 @@ func (check *Checker) selector(x *operand, e *ast.SelectorExpr) {
 				}
 			}
 
-			check.errorf(e.Sel.Pos(), "%s.%s undefined (%s)", x.expr, sel, why)
+			check.errorf(e.Sel.Pos(), MissingFieldOrMethod, "%s.%s undefined (%s)", x.expr, sel, why)
 
 		}
 		goto Error
@@ -557,7 +557,7 @@ func (check *Checker) selector(x *operand, e *ast.SelectorExpr) {
 		m, _ := obj.(*Func)
 		if m == nil {
 			// TODO(gri) should check if capitalization of sel matters and provide better error message in that case
-			check.errorf(e.Sel.Pos(), "%s.%s undefined (type %s has no method %s)", x.expr, sel, x.typ, sel)
+			check.errorf(e.Sel.Pos(), MissingFieldOrMethod, "%s.%s undefined (type %s has no method %s)", x.expr, sel, x.typ, sel)
 			goto Error
 		}
 
@@ -11,12 +11,12 @@ import (
 	"go/token"
 )
 
-func (check *Checker) reportAltDecl(obj Object) {
+func (check *Checker) reportAltDecl(obj Object, code ErrorCode) {
 	if pos := obj.Pos(); pos.IsValid() {
 		// We use "other" rather than "previous" here because
 		// the first declaration seen may not be textually
 		// earlier in the source.
-		check.errorf(pos, "\tother declaration of %s", obj.Name()) // secondary error, \t indented
+		check.errorf(pos, code, "\tother declaration of %s", obj.Name()) // secondary error, \t indented
 	}
 }
 
@@ -27,8 +27,7 @@ func (check *Checker) declare(scope *Scope, id *ast.Ident, obj Object, pos token
 	// binding."
 	if obj.Name() != "_" {
 		if alt := scope.Insert(obj); alt != nil {
-			check.errorf(obj.Pos(), "%s redeclared in this block", obj.Name())
-			check.reportAltDecl(alt)
+			check.declErrorf(alt, obj.Pos(), DuplicateDecl, false, "%s redeclared in this block", obj.Name())
 			return
 		}
 		obj.setScopePos(pos)
@@ -364,16 +363,20 @@ func (check *Checker) cycleError(cycle []Object) {
 	//           cycle? That would be more consistent with other error messages.
 	i := firstInSrc(cycle)
 	obj := cycle[i]
-	check.errorf(obj.Pos(), "illegal cycle in declaration of %s", obj.Name())
+	code := InvalidDeclCycle
+	if _, ok := obj.(*TypeName); ok {
+		code = InvalidTypeCycle
+	}
+	check.errorf(obj.Pos(), code, "illegal cycle in declaration of %s", obj.Name())
 	for range cycle {
-		check.errorf(obj.Pos(), "\t%s refers to", obj.Name()) // secondary error, \t indented
+		check.errorf(obj.Pos(), code, "\t%s refers to", obj.Name()) // secondary error, \t indented
 		i++
 		if i >= len(cycle) {
 			i = 0
 		}
 		obj = cycle[i]
 	}
-	check.errorf(obj.Pos(), "\t%s", obj.Name())
+	check.errorf(obj.Pos(), code, "\t%s", obj.Name())
 }
 
 // firstInSrc reports the index of the object with the "smallest"
@@ -405,7 +408,7 @@ func (check *Checker) constDecl(obj *Const, typ, init ast.Expr) {
 			// don't report an error if the type is an invalid C (defined) type
 			// (issue #22090)
 			if t.Under() != Typ[Invalid] {
-				check.errorf(typ.Pos(), "invalid constant type %s", t)
+				check.errorf(typ.Pos(), InvalidConstType, "invalid constant type %s", t)
 			}
 			obj.typ = Typ[Invalid]
 			return
@@ -559,7 +562,7 @@ func (check *Checker) typeDecl(obj *TypeName, tdecl *ast.TypeSpec, def *Named) {
 		// type alias declaration
 
 		if tdecl.TParams != nil {
-			check.errorf(tdecl.TParams.Pos(), "type alias cannot be parameterized")
+			check.errorf(tdecl.TParams.Pos(), ParameterizedAlias, "type alias cannot be parameterized")
 			// continue but ignore type parameters
 		}
 
@@ -636,7 +639,7 @@ func (check *Checker) collectTypeParams(list *ast.FieldList) (tparams []*TypeNam
 			if isGeneric(bound) {
 				base := bound.(*Named) // only a *Named type can be generic
 				if len(base.tparams) != 1 {
-					check.errorf(f.Type.Pos(), "cannot use generic type %s without instantiation (more than one type parameter)", bound)
+					check.errorf(f.Type.Pos(), MissingTypeArgs, "cannot use generic type %s without instantiation (more than one type parameter)", bound)
 					goto next
 				}
 				// We have exactly one type parameter.
@@ -673,7 +676,7 @@ func (check *Checker) collectTypeParams(list *ast.FieldList) (tparams []*TypeNam
 				setBoundAt(index+i, bound)
 			}
 		} else if bound != Typ[Invalid] {
-			check.errorf(f.Type.Pos(), "%s is not an interface", bound)
+			check.errorf(f.Type.Pos(), InvalidConstraint, "%s is not an interface", bound)
 		}
 
 	next:
@@ -748,13 +751,12 @@ func (check *Checker) collectMethods(obj *TypeName) {
 		if alt := mset.insert(m); alt != nil {
 			switch alt.(type) {
 			case *Var:
-				check.errorf(m.pos, "field and method with the same name %s", m.name)
+				check.declErrorf(alt, m.pos, DuplicateFieldAndMethod, false, "field and method with the same name %s", m.name)
 			case *Func:
-				check.errorf(m.pos, "method %s already declared for %s", m.name, obj)
+				check.declErrorf(alt, m.pos, DuplicateMethod, false, "method %s already declared for %s", m.name, obj)
 			default:
 				unreachable()
 			}
-			check.reportAltDecl(alt)
 			continue
 		}
 
@@ -72,54 +72,76 @@ func (check *Checker) dump(format string, args ...interface{}) {
 	fmt.Println(check.sprintf(format, args...))
 }
 
-func (check *Checker) err(pos token.Pos, msg string, soft bool) {
+// newError returns an error at pos with the given code and formatted
+// message, for reporting with report.
+func (check *Checker) newError(pos token.Pos, code ErrorCode, soft bool, format string, args ...interface{}) *Error {
+	msg := check.sprintf(format, args...)
+	return &Error{Fset: check.fset, Pos: pos, Msg: stripAnnotations(msg), Full: msg, Soft: soft, Code: code}
+}
+
+func (check *Checker) report(err *Error) {
 	// Cheap trick: Don't report errors with messages containing
 	// "invalid operand" or "invalid type" as those tend to be
 	// follow-on errors which don't add useful information. Only
 	// exclude them if these strings are not at the beginning,
 	// and only if we have at least one error already reported.
-	if check.firstErr != nil && (strings.Index(msg, "invalid operand") > 0 || strings.Index(msg, "invalid type") > 0) {
+	if check.firstErr != nil && (strings.Index(err.Full, "invalid operand") > 0 || strings.Index(err.Full, "invalid type") > 0) {
 		return
 	}
 
-	err := Error{check.fset, pos, stripAnnotations(msg), msg, soft}
 	if check.firstErr == nil {
-		check.firstErr = err
+		check.firstErr = *err
 	}
 
 	if check.conf.Trace {
-		check.trace(pos, "ERROR: %s", msg)
+		check.trace(err.Pos, "ERROR: %s", err.Full)
 	}
 
 	f := check.conf.Error
 	if f == nil {
 		panic(bailout{}) // report only first error
 	}
-	f(err)
+	f(*err)
+}
+
+func (check *Checker) err(pos token.Pos, code ErrorCode, msg string, soft bool) {
+	check.report(&Error{Fset: check.fset, Pos: pos, Msg: stripAnnotations(msg), Full: msg, Soft: soft, Code: code})
 }
 
-func (check *Checker) error(pos token.Pos, msg string) {
-	check.err(pos, msg, false)
+func (check *Checker) error(pos token.Pos, code ErrorCode, msg string) {
+	check.err(pos, code, msg, false)
 }
 
-func (check *Checker) errorf(pos token.Pos, format string, args ...interface{}) {
-	check.err(pos, check.sprintf(format, args...), false)
+func (check *Checker) errorf(pos token.Pos, code ErrorCode, format string, args ...interface{}) {
+	check.err(pos, code, check.sprintf(format, args...), false)
 }
 
-func (check *Checker) softErrorf(pos token.Pos, format string, args ...interface{}) {
-	check.err(pos, check.sprintf(format, args...), true)
+func (check *Checker) softErrorf(pos token.Pos, code ErrorCode, format string, args ...interface{}) {
+	check.err(pos, code, check.sprintf(format, args...), true)
+}
+
+// declErrorf reports an error for the declaration at pos, which
+// conflicts with the declaration of alt. The declaration of alt is
+// related to the error, and reported as a secondary error as well.
+func (check *Checker) declErrorf(alt Object, pos token.Pos, code ErrorCode, soft bool, format string, args ...interface{}) {
+	err := check.newError(pos, code, soft, format, args...)
+	if apos := alt.Pos(); apos.IsValid() {
+		err.Related = []RelatedInfo{{apos, "other declaration of " + alt.Name()}}
+	}
+	check.report(err)
+	check.reportAltDecl(alt, code)
 }
 
 func (check *Checker) invalidAST(pos token.Pos, format string, args ...interface{}) {
-	check.errorf(pos, "invalid AST: "+format, args...)
+	check.errorf(pos, InvalidSyntaxTree, "invalid AST: "+format, args...)
 }
 
-func (check *Checker) invalidArg(pos token.Pos, format string, args ...interface{}) {
-	check.errorf(pos, "invalid argument: "+format, args...)
+func (check *Checker) invalidArg(pos token.Pos, code ErrorCode, format string, args ...interface{}) {
+	check.errorf(pos, code, "invalid argument: "+format, args...)
 }
 
-func (check *Checker) invalidOp(pos token.Pos, format string, args ...interface{}) {
-	check.errorf(pos, "invalid operation: "+format, args...)
+func (check *Checker) invalidOp(pos token.Pos, code ErrorCode, format string, args ...interface{}) {
+	check.errorf(pos, code, "invalid operation: "+format, args...)
 }
 
 // stripAnnotations removes internal (type) annotations from s.
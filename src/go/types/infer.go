@@ -36,16 +36,16 @@ func (check *Checker) infer(pos token.Pos, tparams []*TypeName, params *Tuple, a
 				}
 			}
 			if allFailed {
-				check.errorf(arg.pos(), "%s %s of %s does not match %s (cannot infer %s)", kind, targ, arg.expr, tpar, typeNamesString(tparams))
+				check.errorf(arg.pos(), CannotInferTypeArgs, "%s %s of %s does not match %s (cannot infer %s)", kind, targ, arg.expr, tpar, typeNamesString(tparams))
 				return
 			}
 		}
 		smap := makeSubstMap(tparams, targs)
 		inferred := check.subst(arg.pos(), tpar, smap)
 		if inferred != tpar {
-			check.errorf(arg.pos(), "%s %s of %s does not match inferred type %s for %s", kind, targ, arg.expr, inferred, tpar)
+			check.errorf(arg.pos(), CannotInferTypeArgs, "%s %s of %s does not match inferred type %s for %s", kind, targ, arg.expr, inferred, tpar)
 		} else {
-			check.errorf(arg.pos(), "%s %s of %s does not match %s", kind, targ, arg.expr, tpar)
+			check.errorf(arg.pos(), CannotInferTypeArgs, "%s %s of %s does not match %s", kind, targ, arg.expr, tpar)
 		}
 	}
 
@@ -120,7 +120,9 @@ func (check *Checker) infer(pos token.Pos, tparams []*TypeName, params *Tuple, a
 	if failed >= 0 {
 		tpar := tparams[failed]
 		ppos := check.fset.Position(tpar.pos).String()
-		check.errorf(pos, "cannot infer %s (%s)", tpar.name, ppos)
+		err := check.newError(pos, CannotInferTypeArgs, false, "cannot infer %s (%s)", tpar.name, ppos)
+		err.Related = []RelatedInfo{{tpar.pos, "type parameter " + tpar.name + " declared here"}}
+		check.report(err)
 		return nil
 	}
 
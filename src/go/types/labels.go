@@ -21,21 +21,26 @@ func (check *Checker) labels(body *ast.BlockStmt) {
 	// never defined, or they are inside blocks and not reachable
 	// for the respective gotos.
 	for _, jmp := range fwdJumps {
-		var msg string
+		var (
+			msg  string
+			code ErrorCode
+		)
 		name := jmp.Label.Name
 		if alt := all.Lookup(name); alt != nil {
 			msg = "goto %s jumps into block"
+			code = JumpIntoBlock
 			alt.(*Label).used = true // avoid another error
 		} else {
 			msg = "label %s not declared"
+			code = UndeclaredLabel
 		}
-		check.errorf(jmp.Label.Pos(), msg, name)
+		check.errorf(jmp.Label.Pos(), code, msg, name)
 	}
 
 	// spec: "It is illegal to define a label that is never used."
 	for _, obj := range all.elems {
 		if lbl := obj.(*Label); !lbl.used {
-			check.softErrorf(lbl.pos, "label %s declared but not used", lbl.name)
+			check.softErrorf(lbl.pos, UnusedLabel, "label %s declared but not used", lbl.name)
 		}
 	}
 }
@@ -133,8 +138,7 @@ func (check *Checker) blockBranches(all *Scope, parent *block, lstmt *ast.Labele
 			if name := s.Label.Name; name != "_" {
 				lbl := NewLabel(s.Label.Pos(), check.pkg, name)
 				if alt := all.Insert(lbl); alt != nil {
-					check.softErrorf(lbl.pos, "label %s already declared", name)
-					check.reportAltDecl(alt)
+					check.declErrorf(alt, lbl.pos, DuplicateLabel, true, "label %s already declared", name)
 					// ok to continue
 				} else {
 					b.insert(s)
@@ -150,6 +154,7 @@ func (check *Checker) blockBranches(all *Scope, parent *block, lstmt *ast.Labele
 						if jumpsOverVarDecl(jmp) {
 							check.softErrorf(
 								jmp.Label.Pos(),
+								JumpOverDecl,
 								"goto %s jumps over variable declaration at line %d",
 								name,
 								check.fset.Position(varDeclPos).Line,
@@ -187,7 +192,7 @@ func (check *Checker) blockBranches(all *Scope, parent *block, lstmt *ast.Labele
 					}
 				}
 				if !valid {
-					check.errorf(s.Label.Pos(), "invalid break label %s", name)
+					check.errorf(s.Label.Pos(), MisplacedLabel, "invalid break label %s", name)
 					return
 				}
 
@@ -202,7 +207,7 @@ func (check *Checker) blockBranches(all *Scope, parent *block, lstmt *ast.Labele
 					}
 				}
 				if !valid {
-					check.errorf(s.Label.Pos(), "invalid continue label %s", name)
+					check.errorf(s.Label.Pos(), MisplacedLabel, "invalid continue label %s", name)
 					return
 				}
 
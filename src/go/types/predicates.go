@@ -351,7 +351,22 @@ func (check *Checker) identical0(x, y Type, cmpTags bool, p *ifacePair) bool {
 			// TODO(gri) Why is x == y not sufficient? And if it is,
 			//           we can just return false here because x == y
 			//           is caught in the very beginning of this function.
-			return x.obj == y.obj
+			if x.obj == y.obj {
+				return true
+			}
+			// Two instantiated types are identical if they are
+			// instantiations of the same generic type with identical
+			// type arguments. Instantiations made while checking
+			// different packages don't share a type name.
+			if len(x.targs) == 0 || len(x.targs) != len(y.targs) || len(x.tparams) == 0 || len(x.tparams) != len(y.tparams) || x.tparams[0] != y.tparams[0] {
+				return false
+			}
+			for i, xa := range x.targs {
+				if !check.identical0(xa, y.targs[i], cmpTags, p) {
+					return false
+				}
+			}
+			return true
 		}
 
 	case *TypeParam:
@@ -59,22 +59,22 @@ func (check *Checker) arityMatch(s, init *ast.ValueSpec) {
 	case init == nil && r == 0:
 		// var decl w/o init expr
 		if s.Type == nil {
-			check.errorf(s.Pos(), "missing type or init expr")
+			check.errorf(s.Pos(), WrongAssignCount, "missing type or init expr")
 		}
 	case l < r:
 		if l < len(s.Values) {
 			// init exprs from s
 			n := s.Values[l]
-			check.errorf(n.Pos(), "extra init expr %s", n)
+			check.errorf(n.Pos(), WrongAssignCount, "extra init expr %s", n)
 			// TODO(gri) avoid declared but not used error here
 		} else {
 			// init exprs "inherited"
-			check.errorf(s.Pos(), "extra init expr at %s", check.fset.Position(init.Pos()))
+			check.errorf(s.Pos(), WrongAssignCount, "extra init expr at %s", check.fset.Position(init.Pos()))
 			// TODO(gri) avoid declared but not used error here
 		}
 	case l > r && (init != nil || r != 1):
 		n := s.Names[r]
-		check.errorf(n.Pos(), "missing init expr for %s", n)
+		check.errorf(n.Pos(), WrongAssignCount, "missing init expr for %s", n)
 	}
 }
 
@@ -103,14 +103,14 @@ func (check *Checker) declarePkgObj(ident *ast.Ident, obj Object, d *declInfo) {
 	// spec: "A package-scope or file-scope identifier with name init
 	// may only be declared to be a function with this (func()) signature."
 	if ident.Name == "init" {
-		check.errorf(ident.Pos(), "cannot declare init - must be func")
+		check.errorf(ident.Pos(), InvalidInitDecl, "cannot declare init - must be func")
 		return
 	}
 
 	// spec: "The main package must have package name main and declare
 	// a function main that takes no arguments and returns no value."
 	if ident.Name == "main" && check.pkg.name == "main" {
-		check.errorf(ident.Pos(), "cannot declare main - must be func")
+		check.errorf(ident.Pos(), InvalidMainDecl, "cannot declare main - must be func")
 		return
 	}
 
@@ -168,7 +168,7 @@ func (check *Checker) importPackage(pos token.Pos, path, dir string) *Package {
 			imp = nil // create fake package below
 		}
 		if err != nil {
-			check.errorf(pos, "could not import %s (%s)", path, err)
+			check.errorf(pos, BrokenImport, "could not import %s (%s)", path, err)
 			if imp == nil {
 				// create a new fake package
 				// come up with a sensible package name (heuristic)
@@ -255,7 +255,7 @@ func (check *Checker) collectObjects() {
 						// import package
 						path, err := validatedImportPath(s.Path.Value)
 						if err != nil {
-							check.errorf(s.Path.Pos(), "invalid import path (%s)", err)
+							check.errorf(s.Path.Pos(), BadImportPath, "invalid import path (%s)", err)
 							continue
 						}
 
@@ -278,11 +278,11 @@ func (check *Checker) collectObjects() {
 							name = s.Name.Name
 							if path == "C" {
 								// match cmd/compile (not prescribed by spec)
-								check.errorf(s.Name.Pos(), `cannot rename import "C"`)
+								check.errorf(s.Name.Pos(), ImportCRenamed, `cannot rename import "C"`)
 								continue
 							}
 							if name == "init" {
-								check.errorf(s.Name.Pos(), "cannot declare init - must be func")
+								check.errorf(s.Name.Pos(), InvalidInitDecl, "cannot declare init - must be func")
 								continue
 							}
 						}
@@ -313,8 +313,7 @@ func (check *Checker) collectObjects() {
 									// the object may be imported into more than one file scope
 									// concurrently. See issue #32154.)
 									if alt := fileScope.Insert(obj); alt != nil {
-										check.errorf(s.Name.Pos(), "%s redeclared in this block", obj.Name())
-										check.reportAltDecl(alt)
+										check.declErrorf(alt, s.Name.Pos(), DuplicateDecl, false, "%s redeclared in this block", obj.Name())
 									}
 								}
 							}
@@ -406,15 +405,15 @@ func (check *Checker) collectObjects() {
 				if !d.IsMethod() {
 					// regular function
 					if d.Recv != nil {
-						check.errorf(d.Recv.Pos(), "method is missing receiver")
+						check.errorf(d.Recv.Pos(), BadRecv, "method is missing receiver")
 						// treat as function
 					}
 					if name == "init" {
 						if d.Type.TParams != nil {
-							check.softErrorf(d.Type.TParams.Pos(), "func init must have no type parameters")
+							check.softErrorf(d.Type.TParams.Pos(), InvalidInitSig, "func init must have no type parameters")
 						}
 						if t := d.Type; t.Params.NumFields() != 0 || t.Results != nil {
-							check.softErrorf(d.Pos(), "func init must have no arguments and no return values")
+							check.softErrorf(d.Pos(), InvalidInitSig, "func init must have no arguments and no return values")
 						}
 						// don't declare init functions in the package scope - they are invisible
 						obj.parent = pkg.scope
@@ -422,7 +421,7 @@ func (check *Checker) collectObjects() {
 						// init functions must have a body
 						if d.Body == nil {
 							// TODO(gri) make this error message consistent with the others above
-							check.softErrorf(obj.pos, "missing function body")
+							check.softErrorf(obj.pos, MissingInitBody, "missing function body")
 						}
 					} else {
 						check.declare(pkg.scope, d.Name, obj, token.NoPos)
@@ -461,12 +460,10 @@ func (check *Checker) collectObjects() {
 		for _, obj := range scope.elems {
 			if alt := pkg.scope.Lookup(obj.Name()); alt != nil {
 				if pkg, ok := obj.(*PkgName); ok {
-					check.errorf(alt.Pos(), "%s already declared through import of %s", alt.Name(), pkg.Imported())
-					check.reportAltDecl(pkg)
+					check.declErrorf(pkg, alt.Pos(), DuplicateDecl, false, "%s already declared through import of %s", alt.Name(), pkg.Imported())
 				} else {
-					check.errorf(alt.Pos(), "%s already declared through dot-import of %s", alt.Name(), obj.Pkg())
 					// TODO(gri) dot-imported objects don't have a position; reportAltDecl won't print anything
-					check.reportAltDecl(obj)
+					check.declErrorf(obj, alt.Pos(), DuplicateDecl, false, "%s already declared through dot-import of %s", alt.Name(), obj.Pkg())
 				}
 			}
 		}
@@ -525,7 +522,7 @@ L: // unpack receiver type
 				case nil:
 					check.invalidAST(ptyp.Pos(), "parameterized receiver contains nil parameters")
 				default:
-					check.errorf(arg.Pos(), "receiver type parameter %s must be an identifier", arg)
+					check.errorf(arg.Pos(), InvalidRecv, "receiver type parameter %s must be an identifier", arg)
 				}
 				if par == nil {
 					par = &ast.Ident{NamePos: arg.Pos(), Name: "_"}
@@ -683,9 +680,9 @@ func (check *Checker) unusedImports() {
 					path := obj.imported.path
 					base := pkgName(path)
 					if obj.name == base {
-						check.softErrorf(obj.pos, "%q imported but not used", path)
+						check.softErrorf(obj.pos, UnusedImport, "%q imported but not used", path)
 					} else {
-						check.softErrorf(obj.pos, "%q imported but not used as %s", path, obj.name)
+						check.softErrorf(obj.pos, UnusedImport, "%q imported but not used as %s", path, obj.name)
 					}
 				}
 			}
@@ -695,7 +692,7 @@ func (check *Checker) unusedImports() {
 	// check use of dot-imported packages
 	for _, unusedDotImports := range check.unusedDotImports {
 		for pkg, pos := range unusedDotImports {
-			check.softErrorf(pos, "%q imported but not used", pkg.path)
+			check.softErrorf(pos, UnusedImport, "%q imported but not used", pkg.path)
 		}
 	}
 }
@@ -70,6 +70,8 @@ func (s sanitizer) typ(typ Type) Type {
 		s.tuple(t)
 
 	case *Signature:
+		s.tparams(t.rparams)
+		s.tparams(t.tparams)
 		s.var_(t.recv)
 		s.tuple(t.params)
 		s.tuple(t.results)
@@ -92,6 +94,7 @@ func (s sanitizer) typ(typ Type) Type {
 		t.elem = s.typ(t.elem)
 
 	case *Named:
+		s.tparams(t.tparams)
 		t.orig = s.typ(t.orig)
 		t.underlying = s.typ(t.underlying)
 		s.typeList(t.targs)
@@ -111,6 +114,12 @@ func (s sanitizer) typ(typ Type) Type {
 	return typ
 }
 
+func (s sanitizer) tparams(list []*TypeName) {
+	for _, tname := range list {
+		s.typ(tname.typ)
+	}
+}
+
 func (s sanitizer) var_(v *Var) {
 	if v != nil {
 		v.typ = s.typ(v.typ)
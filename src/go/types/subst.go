@@ -106,7 +106,7 @@ func (check *Checker) instantiate(pos token.Pos, typ Type, targs []Type, poslist
 	// the number of supplied types must match the number of type parameters
 	if len(targs) != len(tparams) {
 		// TODO(gri) provide better error message
-		check.errorf(pos, "got %d arguments but %d type parameters", len(targs), len(tparams))
+		check.errorf(pos, WrongTypeArgCount, "got %d arguments but %d type parameters", len(targs), len(tparams))
 		return Typ[Invalid]
 	}
 
@@ -118,96 +118,113 @@ func (check *Checker) instantiate(pos token.Pos, typ Type, targs []Type, poslist
 
 	// check bounds
 	for i, tname := range tparams {
-		tpar := tname.typ.(*TypeParam)
-		iface := tpar.Bound()
-		if iface.Empty() {
-			continue // no type bound
-		}
-
-		targ := targs[i]
-
 		// best position for error reporting
 		pos := pos
 		if i < len(poslist) {
 			pos = poslist[i]
 		}
 
-		// The type parameter bound is parameterized with the same type parameters
-		// as the instantiated type; before we can use it for bounds checking we
-		// need to instantiate it with the type arguments with which we instantiate
-		// the parameterized type.
-		iface = check.subst(pos, iface, smap).(*Interface)
-
-		// targ must implement iface (methods)
-		// - check only if we have methods
-		check.completeInterface(token.NoPos, iface)
-		if len(iface.allMethods) > 0 {
-			// If the type argument is a type parameter itself, its pointer designation
-			// must match the pointer designation of the callee's type parameter.
-			// If the type argument is a pointer to a type parameter, the type argument's
-			// method set is empty.
-			// TODO(gri) is this what we want? (spec question)
-			if tparg := targ.TypeParam(); tparg != nil {
-				if tparg.ptr != tpar.ptr {
-					check.errorf(pos, "pointer designation mismatch")
-					break
-				}
-			} else if base, isPtr := deref(targ); isPtr && base.TypeParam() != nil {
-				check.errorf(pos, "%s has no methods", targ)
-				break
-			}
-			// If a type parameter is marked as a pointer type, the type bound applies
-			// to a pointer of the type argument.
-			actual := targ
-			if tpar.ptr {
-				actual = NewPointer(targ)
-			}
-			if m, _ := check.missingMethod(actual, iface, true); m != nil {
-				// TODO(gri) needs to print updated name to avoid major confusion in error message!
-				//           (print warning for now)
-				// check.softErrorf(pos, "%s does not satisfy %s (warning: name not updated) = %s (missing method %s)", targ, tpar.bound, iface, m)
-				if m.name == "==" {
-					// We don't want to report "missing method ==".
-					check.softErrorf(pos, "%s does not satisfy comparable", targ)
-				} else {
-					check.softErrorf(pos, "%s does not satisfy %s (missing method %s)", targ, tpar.bound, m.name)
-				}
-				break
-			}
+		if err := check.satisfiesBound(pos, tname.typ.(*TypeParam), targs[i], smap); err != nil {
+			err.Soft = true
+			check.report(err)
+			break
 		}
+	}
 
-		// targ's underlying type must also be one of the interface types listed, if any
-		if iface.allTypes == nil {
-			continue // nothing to do
+	return check.subst(pos, typ, smap)
+}
+
+// satisfiesBound returns an error at pos if the type argument targ does
+// not satisfy the type bound of the type parameter tpar. smap maps all
+// the type parameters of the instantiated type or function to their type
+// arguments. The declaration of the bound is related to the error.
+func (check *Checker) satisfiesBound(pos token.Pos, tpar *TypeParam, targ Type, smap *substMap) *Error {
+	iface := tpar.Bound()
+	if iface.Empty() {
+		return nil // no type bound
+	}
+
+	// The type parameter bound is parameterized with the same type parameters
+	// as the instantiated type; before we can use it for bounds checking we
+	// need to instantiate it with the type arguments with which we instantiate
+	// the parameterized type.
+	iface = check.subst(pos, iface, smap).(*Interface)
+	check.completeInterface(token.NoPos, iface)
+	err := check.satisfies(targ, iface, tpar.bound, tpar.ptr)
+	if err != nil {
+		err.Pos = pos
+		if named, _ := tpar.bound.(*Named); named != nil && named.obj.pos.IsValid() {
+			err.Related = []RelatedInfo{{named.obj.pos, "constraint " + named.obj.name + " declared here"}}
+		} else if tpar.obj.pos.IsValid() {
+			err.Related = []RelatedInfo{{tpar.obj.pos, "type parameter " + tpar.obj.name + " declared here"}}
 		}
-		// iface.allTypes != nil
+	}
+	return err
+}
 
-		// If targ is itself a type parameter, each of its possible types, but at least one, must be in the
-		// list of iface types (i.e., the targ type list must be a non-empty subset of the iface types).
-		if targ := targ.TypeParam(); targ != nil {
-			targBound := targ.Bound()
-			if targBound.allTypes == nil {
-				check.softErrorf(pos, "%s does not satisfy %s (%s has no type constraints)", targ, tpar.bound, targ)
-				break
+// satisfies returns an error without position if targ does not satisfy
+// the complete interface iface. The bound is the interface as written,
+// for error messages. If ptr is set, iface applies to a pointer to targ.
+func (check *Checker) satisfies(targ Type, iface *Interface, bound Type, ptr bool) *Error {
+	// targ must implement iface (methods)
+	// - check only if we have methods
+	if len(iface.allMethods) > 0 {
+		// If the type argument is a type parameter itself, its pointer designation
+		// must match the pointer designation of the callee's type parameter.
+		// If the type argument is a pointer to a type parameter, the type argument's
+		// method set is empty.
+		// TODO(gri) is this what we want? (spec question)
+		if tparg := targ.TypeParam(); tparg != nil {
+			if tparg.ptr != ptr {
+				return check.newError(token.NoPos, UnsatisfiedConstraint, false, "pointer designation mismatch")
 			}
-			for _, t := range unpack(targBound.allTypes) {
-				if !iface.includes(t.Under()) {
-					// TODO(gri) match this error message with the one below (or vice versa)
-					check.softErrorf(pos, "%s does not satisfy %s (%s type constraint %s not found in %s)", targ, tpar.bound, targ, t, iface.allTypes)
-					break
-				}
+		} else if base, isPtr := deref(targ); isPtr && base.TypeParam() != nil {
+			return check.newError(token.NoPos, UnsatisfiedConstraint, false, "%s has no methods", targ)
+		}
+		// If a type parameter is marked as a pointer type, the type bound applies
+		// to a pointer of the type argument.
+		actual := targ
+		if ptr {
+			actual = NewPointer(targ)
+		}
+		if m, _ := check.missingMethod(actual, iface, true); m != nil {
+			// TODO(gri) needs to print updated name to avoid major confusion in error message!
+			//           (print warning for now)
+			if m.name == "==" {
+				// We don't want to report "missing method ==".
+				return check.newError(token.NoPos, UnsatisfiedConstraint, false, "%s does not satisfy comparable", targ)
 			}
-			break
+			return check.newError(token.NoPos, UnsatisfiedConstraint, false, "%s does not satisfy %s (missing method %s)", targ, bound, m.name)
 		}
+	}
 
-		// Otherwise, targ's underlying type must also be one of the interface types listed, if any.
-		if !iface.includes(targ.Under()) {
-			check.softErrorf(pos, "%s does not satisfy %s (%s not found in %s)", targ, tpar.bound, targ.Under(), iface.allTypes)
-			break
+	// targ's underlying type must also be one of the interface types listed, if any
+	if iface.allTypes == nil {
+		return nil // nothing to do
+	}
+	// iface.allTypes != nil
+
+	// If targ is itself a type parameter, each of its possible types, but at least one, must be in the
+	// list of iface types (i.e., the targ type list must be a non-empty subset of the iface types).
+	if targ := targ.TypeParam(); targ != nil {
+		targBound := targ.Bound()
+		if targBound.allTypes == nil {
+			return check.newError(token.NoPos, TypeListMismatch, false, "%s does not satisfy %s (%s has no type constraints)", targ, bound, targ)
 		}
+		for _, t := range unpack(targBound.allTypes) {
+			if !iface.includes(t.Under()) {
+				// TODO(gri) match this error message with the one below (or vice versa)
+				return check.newError(token.NoPos, TypeListMismatch, false, "%s does not satisfy %s (%s type constraint %s not found in %s)", targ, bound, targ, t, iface.allTypes)
+			}
+		}
+		return nil
 	}
 
-	return check.subst(pos, typ, smap)
+	// Otherwise, targ's underlying type must also be one of the interface types listed, if any.
+	if !iface.includes(targ.Under()) {
+		return check.newError(token.NoPos, TypeListMismatch, false, "%s does not satisfy %s (%s not found in %s)", targ, bound, targ.Under(), iface.allTypes)
+	}
+	return nil
 }
 
 // subst returns the type typ with its type parameters tpars replaced by
@@ -418,7 +435,6 @@ func (subst *subster) typ(typ Type) Type {
 }
 
 // TODO(gri) Eventually, this should be more sophisticated.
-//           It won't work correctly for locally declared types.
 func instantiatedHash(typ *Named, targs []Type) string {
 	var buf bytes.Buffer
 	writeTypeName(&buf, typ.obj, nil)
@@ -426,6 +442,13 @@ func instantiatedHash(typ *Named, targs []Type) string {
 	writeTypeList(&buf, targs, nil, nil)
 	buf.WriteByte(')')
 
+	// Function-local types with the same name are printed
+	// the same way. Distinguish them by their positions.
+	writeLocalTypes(&buf, typ)
+	for _, targ := range targs {
+		writeLocalTypes(&buf, targ)
+	}
+
 	// With respect to the represented type, whether a
 	// type is fully expanded or stored as instance
 	// does not matter - they are the same types.
@@ -442,6 +465,57 @@ func instantiatedHash(typ *Named, targs []Type) string {
 	return string(res[:i])
 }
 
+// writeLocalTypes writes the positions of any function-local
+// named types referred to by typ, not looking at the underlying
+// types of named types.
+func writeLocalTypes(buf *bytes.Buffer, typ Type) {
+	switch t := typ.(type) {
+	case *Array:
+		writeLocalTypes(buf, t.elem)
+	case *Slice:
+		writeLocalTypes(buf, t.elem)
+	case *Struct:
+		for _, f := range t.fields {
+			writeLocalTypes(buf, f.typ)
+		}
+	case *Pointer:
+		writeLocalTypes(buf, t.base)
+	case *Tuple:
+		if t != nil {
+			for _, v := range t.vars {
+				writeLocalTypes(buf, v.typ)
+			}
+		}
+	case *Signature:
+		writeLocalTypes(buf, t.params)
+		writeLocalTypes(buf, t.results)
+	case *Interface:
+		for _, m := range t.methods {
+			writeLocalTypes(buf, m.typ)
+		}
+		for _, e := range t.embeddeds {
+			writeLocalTypes(buf, e)
+		}
+	case *Map:
+		writeLocalTypes(buf, t.key)
+		writeLocalTypes(buf, t.elem)
+	case *Chan:
+		writeLocalTypes(buf, t.elem)
+	case *Named:
+		if obj := t.obj; obj != nil && obj.pkg != nil && obj.parent != nil && obj.parent != obj.pkg.scope {
+			fmt.Fprintf(buf, "@%d", obj.pos)
+		}
+		for _, targ := range t.targs {
+			writeLocalTypes(buf, targ)
+		}
+	case *instance:
+		writeLocalTypes(buf, t.base)
+		for _, targ := range t.targs {
+			writeLocalTypes(buf, targ)
+		}
+	}
+}
+
 func typeListString(list []Type) string {
 	var buf bytes.Buffer
 	writeTypeList(&buf, list, nil, nil)
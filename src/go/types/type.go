@@ -322,6 +322,14 @@ func (s *Signature) TParams() []*TypeName { return s.tparams }
 // SetTParams sets the type parameters of signature s.
 func (s *Signature) SetTParams(tparams []*TypeName) { s.tparams = tparams }
 
+// RParams returns the receiver type parameters of signature s, or nil.
+// A method of a parameterized type has receiver type parameters
+// that stand for the type parameters of the receiver type.
+func (s *Signature) RParams() []*TypeName { return s.rparams }
+
+// SetRParams sets the receiver type parameters of signature s.
+func (s *Signature) SetRParams(rparams []*TypeName) { s.rparams = rparams }
+
 // Params returns the parameters of signature s, or nil.
 func (s *Signature) Params() *Tuple { return s.params }
 
@@ -479,6 +487,26 @@ func NewInterfaceType(methods []*Func, embeddeds []Type) *Interface {
 	return typ
 }
 
+// NewConstraintType returns a new (incomplete) interface for the given methods,
+// embedded types, and type list, as used for type parameter constraints.
+// It is like NewInterfaceType, except that the interface also restricts the
+// types that satisfy it to those whose underlying type is in the type list.
+func NewConstraintType(methods []*Func, embeddeds []Type, types []Type) *Interface {
+	if len(types) == 0 {
+		return NewInterfaceType(methods, embeddeds)
+	}
+	typ := NewInterfaceType(methods, embeddeds)
+	if typ == &emptyInterface {
+		typ = new(Interface)
+	}
+	typ.types = NewSum(types)
+	return typ
+}
+
+// TypeList returns the types in the type list of interface t,
+// not including those of embedded interfaces, or nil if there are none.
+func (t *Interface) TypeList() []Type { return unpack(t.types) }
+
 // NumExplicitMethods returns the number of explicitly declared methods of interface t.
 func (t *Interface) NumExplicitMethods() int { return len(t.methods) }
 
@@ -543,6 +571,14 @@ func (t *Interface) HasTypeList() bool {
 	}, nil)
 }
 
+// AllTypes returns the types in the type list of interface t,
+// including those of embedded interfaces, or nil if t has no type list.
+// The interface must have been completed.
+func (t *Interface) AllTypes() []Type {
+	t.assertCompleteness()
+	return unpack(t.allTypes)
+}
+
 // IsComparable reports whether interface t is or embeds the predeclared interface "comparable".
 func (t *Interface) IsComparable() bool {
 	if t.allMethods != nil {
@@ -803,6 +839,9 @@ func (t *Named) TArgs() []Type { return t.targs }
 // SetTArgs sets the type arguments of Named.
 func (t *Named) SetTArgs(args []Type) { t.targs = args }
 
+// SetTParams sets the type parameters of Named, making it a parameterized type.
+func (t *Named) SetTParams(tparams []*TypeName) { t.tparams = tparams }
+
 // NumMethods returns the number of explicit methods whose receiver is named type t.
 func (t *Named) NumMethods() int { return len(t.methods) }
 
@@ -849,6 +888,26 @@ func (check *Checker) NewTypeParam(ptr bool, obj *TypeName, index int, bound Typ
 	return typ
 }
 
+// Obj returns the type name for the type parameter t.
+func (t *TypeParam) Obj() *TypeName { return t.obj }
+
+// Index returns the index of the type parameter t
+// in the list of type parameters that declares it.
+func (t *TypeParam) Index() int { return t.index }
+
+// Ptr reports whether the type parameter t has
+// a pointer designation, as in (type *T C).
+func (t *TypeParam) Ptr() bool { return t.ptr }
+
+// Constraint returns the type bound of t as written, a *Named or
+// *Interface type; see Bound for the underlying interface.
+func (t *TypeParam) Constraint() Type { return t.bound }
+
+// SetConstraint sets the type bound of t, a *Named or *Interface type.
+// This permits constructing type parameters whose bounds refer to them.
+func (t *TypeParam) SetConstraint(bound Type) { t.bound = bound }
+
+// Bound returns the underlying interface of the type bound of t.
 func (t *TypeParam) Bound() *Interface {
 	iface := t.bound.Interface()
 	// use the type bound position if we have one
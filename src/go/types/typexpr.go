@@ -30,9 +30,9 @@ func (check *Checker) ident(x *operand, e *ast.Ident, def *Named, wantType bool)
 	scope, obj := check.scope.LookupParent(e.Name, check.pos)
 	if obj == nil {
 		if e.Name == "_" {
-			check.errorf(e.Pos(), "cannot use _ as value or type")
+			check.errorf(e.Pos(), InvalidBlank, "cannot use _ as value or type")
 		} else {
-			check.errorf(e.Pos(), "undeclared name: %s", e.Name)
+			check.errorf(e.Pos(), UndeclaredName, "undeclared name: %s", e.Name)
 		}
 		return
 	}
@@ -63,7 +63,7 @@ func (check *Checker) ident(x *operand, e *ast.Ident, def *Named, wantType bool)
 
 	switch obj := obj.(type) {
 	case *PkgName:
-		check.errorf(e.Pos(), "use of package %s not in selector", obj.name)
+		check.errorf(e.Pos(), InvalidPkgUse, "use of package %s not in selector", obj.name)
 		return
 
 	case *Const:
@@ -73,7 +73,7 @@ func (check *Checker) ident(x *operand, e *ast.Ident, def *Named, wantType bool)
 		}
 		if obj == universeIota {
 			if check.iota == nil {
-				check.errorf(e.Pos(), "cannot use iota outside constant declaration")
+				check.errorf(e.Pos(), InvalidIota, "cannot use iota outside constant declaration")
 				return
 			}
 			x.val = check.iota
@@ -135,11 +135,11 @@ func (check *Checker) varType(e ast.Expr) Type {
 		check.atEnd(func() {
 			check.completeInterface(e.Pos(), t) // TODO(gri) is this the correct position?
 			if t.allTypes != nil {
-				check.softErrorf(e.Pos(), "interface type for variable cannot contain type constraints (%s)", t.allTypes)
+				check.softErrorf(e.Pos(), MisplacedConstraintIface, "interface type for variable cannot contain type constraints (%s)", t.allTypes)
 				return
 			}
 			if t.IsComparable() {
-				check.softErrorf(e.Pos(), "interface type for variable cannot be (or embed) comparable")
+				check.softErrorf(e.Pos(), MisplacedConstraintIface, "interface type for variable cannot be (or embed) comparable")
 			}
 		})
 	}
@@ -164,7 +164,7 @@ func (check *Checker) definedType(e ast.Expr, def *Named) Type {
 	typ := check.typInternal(e, def)
 	assert(isTyped(typ))
 	if isGeneric(typ) {
-		check.errorf(e.Pos(), "cannot use generic type %s without instantiation", typ)
+		check.errorf(e.Pos(), MissingTypeArgs, "cannot use generic type %s without instantiation", typ)
 		typ = Typ[Invalid]
 	}
 	check.recordTypeAndValue(e, typexpr, typ, nil)
@@ -177,7 +177,7 @@ func (check *Checker) genericType(e ast.Expr, reportErr bool) Type {
 	assert(isTyped(typ))
 	if typ != Typ[Invalid] && !isGeneric(typ) {
 		if reportErr {
-			check.errorf(e.Pos(), "%s is not a generic type", typ)
+			check.errorf(e.Pos(), NotAGenericType, "%s is not a generic type", typ)
 		}
 		typ = Typ[Invalid]
 	}
@@ -314,7 +314,7 @@ func (check *Checker) funcType(sig *Signature, recvPar *ast.FieldList, ftyp *ast
 		// (A separate check is needed when type-checking interface method signatures because
 		// they don't have a receiver specification.)
 		if recvPar != nil && !check.conf.AcceptMethodTypeParams {
-			check.errorf(ftyp.TParams.Pos(), "methods cannot have type parameters")
+			check.errorf(ftyp.TParams.Pos(), InvalidMethodTypeParams, "methods cannot have type parameters")
 		}
 	}
 
@@ -326,8 +326,7 @@ func (check *Checker) funcType(sig *Signature, recvPar *ast.FieldList, ftyp *ast
 	params, variadic := check.collectParams(scope, ftyp.Params, nil, true)
 	results, _ := check.collectParams(scope, ftyp.Results, nil, false)
 	scope.Squash(func(obj, alt Object) {
-		check.errorf(obj.Pos(), "%s redeclared in this block", obj.Name())
-		check.reportAltDecl(alt)
+		check.declErrorf(alt, obj.Pos(), DuplicateDecl, false, "%s redeclared in this block", obj.Name())
 	})
 
 	if recvPar != nil {
@@ -341,7 +340,7 @@ func (check *Checker) funcType(sig *Signature, recvPar *ast.FieldList, ftyp *ast
 			recv = NewParam(0, nil, "", Typ[Invalid]) // ignore recv below
 		default:
 			// more than one receiver
-			check.error(recvList[len(recvList)-1].Pos(), "method must have exactly one receiver")
+			check.error(recvList[len(recvList)-1].Pos(), BadRecv, "method must have exactly one receiver")
 			fallthrough // continue with first receiver
 		case 1:
 			recv = recvList[0]
@@ -377,7 +376,7 @@ func (check *Checker) funcType(sig *Signature, recvPar *ast.FieldList, ftyp *ast
 				err = "basic or unnamed type"
 			}
 			if err != "" {
-				check.errorf(recv.pos, "invalid receiver %s (%s)", recv.typ, err)
+				check.errorf(recv.pos, InvalidRecv, "invalid receiver %s (%s)", recv.typ, err)
 				// ok to continue
 			}
 		}
@@ -436,9 +435,9 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) (T Type) {
 		case invalid:
 			// ignore - error reported before
 		case novalue:
-			check.errorf(x.pos(), "%s used as type", &x)
+			check.errorf(x.pos(), NotAType, "%s used as type", &x)
 		default:
-			check.errorf(x.pos(), "%s is not a type", &x)
+			check.errorf(x.pos(), NotAType, "%s is not a type", &x)
 		}
 
 	case *ast.SelectorExpr:
@@ -453,9 +452,9 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) (T Type) {
 		case invalid:
 			// ignore - error reported before
 		case novalue:
-			check.errorf(x.pos(), "%s used as type", &x)
+			check.errorf(x.pos(), NotAType, "%s used as type", &x)
 		default:
-			check.errorf(x.pos(), "%s is not a type", &x)
+			check.errorf(x.pos(), NotAType, "%s is not a type", &x)
 		}
 
 	case *ast.CallExpr:
@@ -562,7 +561,7 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) (T Type) {
 		// it is safe to continue in any case (was issue 6667).
 		check.atEnd(func() {
 			if !Comparable(typ.key) {
-				check.errorf(e.Key.Pos(), "invalid map key type %s", typ.key)
+				check.errorf(e.Key.Pos(), IncomparableMapKey, "invalid map key type %s", typ.key)
 			}
 		})
 
@@ -590,7 +589,7 @@ func (check *Checker) typInternal(e ast.Expr, def *Named) (T Type) {
 		return typ
 
 	default:
-		check.errorf(e.Pos(), "%s is not a type", e)
+		check.errorf(e.Pos(), NotAType, "%s is not a type", e)
 	}
 
 	typ := Typ[Invalid]
@@ -609,7 +608,7 @@ func (check *Checker) typOrNil(e ast.Expr) Type {
 	case invalid:
 		// ignore - error reported before
 	case novalue:
-		check.errorf(x.pos(), "%s used as type", &x)
+		check.errorf(x.pos(), NotAType, "%s used as type", &x)
 	case typexpr:
 		return x.typ
 	case value:
@@ -618,7 +617,7 @@ func (check *Checker) typOrNil(e ast.Expr) Type {
 		}
 		fallthrough
 	default:
-		check.errorf(x.pos(), "%s is not a type", &x)
+		check.errorf(x.pos(), NotAType, "%s is not a type", &x)
 	}
 	return Typ[Invalid]
 }
@@ -631,7 +630,7 @@ func (check *Checker) arrayLength(e ast.Expr) int64 {
 	check.expr(&x, e)
 	if x.mode != constant_ {
 		if x.mode != invalid {
-			check.errorf(x.pos(), "array length %s must be constant", &x)
+			check.errorf(x.pos(), InvalidArrayLen, "array length %s must be constant", &x)
 		}
 		return -1
 	}
@@ -641,12 +640,12 @@ func (check *Checker) arrayLength(e ast.Expr) int64 {
 				if n, ok := constant.Int64Val(val); ok && n >= 0 {
 					return n
 				}
-				check.errorf(x.pos(), "invalid array length %s", &x)
+				check.errorf(x.pos(), InvalidArrayLen, "invalid array length %s", &x)
 				return -1
 			}
 		}
 	}
-	check.errorf(x.pos(), "array length %s must be integer", &x)
+	check.errorf(x.pos(), InvalidArrayLen, "array length %s must be integer", &x)
 	return -1
 }
 
@@ -684,7 +683,7 @@ func (check *Checker) collectParams(scope *Scope, list *ast.FieldList, type0 ast
 			if variadicOk && i == len(list.List)-1 && len(field.Names) <= 1 {
 				variadic = true
 			} else {
-				check.softErrorf(t.Pos(), "can only use ... with final parameter in list")
+				check.softErrorf(t.Pos(), MisplacedDotDotDot, "can only use ... with final parameter in list")
 				// ignore ... and continue
 			}
 		}
@@ -731,8 +730,7 @@ func (check *Checker) collectParams(scope *Scope, list *ast.FieldList, type0 ast
 
 func (check *Checker) declareInSet(oset *objset, pos token.Pos, obj Object) bool {
 	if alt := oset.insert(obj); alt != nil {
-		check.errorf(pos, "%s redeclared", obj.Name())
-		check.reportAltDecl(alt)
+		check.declErrorf(alt, pos, DuplicateDecl, false, "%s redeclared", obj.Name())
 		return false
 	}
 	return true
@@ -749,7 +747,7 @@ func (check *Checker) interfaceType(ityp *Interface, iface *ast.InterfaceType, d
 			// and we don't care if a constructed AST has more.)
 			name := f.Names[0]
 			if name.Name == "_" {
-				check.errorf(name.Pos(), "invalid method name _")
+				check.errorf(name.Pos(), BlankIfaceMethod, "invalid method name _")
 				continue // ignore
 			}
 
@@ -759,7 +757,7 @@ func (check *Checker) interfaceType(ityp *Interface, iface *ast.InterfaceType, d
 				// the author intended to include all types.
 				types = append(types, f.Type)
 				if tlist != nil && tlist != name {
-					check.errorf(name.Pos(), "cannot have multiple type lists in an interface")
+					check.errorf(name.Pos(), InvalidTypeList, "cannot have multiple type lists in an interface")
 				}
 				tlist = name
 				continue
@@ -778,7 +776,7 @@ func (check *Checker) interfaceType(ityp *Interface, iface *ast.InterfaceType, d
 			// (This extra check is needed here because interface method signatures don't have
 			// a receiver specification.)
 			if sig.tparams != nil && !check.conf.AcceptMethodTypeParams {
-				check.errorf(f.Type.(*ast.FuncType).TParams.Pos(), "methods cannot have type parameters")
+				check.errorf(f.Type.(*ast.FuncType).TParams.Pos(), InvalidMethodTypeParams, "methods cannot have type parameters")
 			}
 
 			// use named receiver type if available (for better error messages)
@@ -873,14 +871,14 @@ func (check *Checker) completeInterface(pos token.Pos, ityp *Interface) {
 			methods = append(methods, m)
 			mpos[m] = pos
 		case explicit:
-			check.errorf(pos, "duplicate method %s", m.name)
-			check.errorf(mpos[other.(*Func)], "\tother declaration of %s", m.name) // secondary error, \t indented
+			check.errorf(pos, DuplicateDecl, "duplicate method %s", m.name)
+			check.errorf(mpos[other.(*Func)], DuplicateDecl, "\tother declaration of %s", m.name) // secondary error, \t indented
 		default:
 			// check method signatures after all types are computed (issue #33656)
 			check.atEnd(func() {
 				if !check.identical(m.typ, other.Type()) {
-					check.errorf(pos, "duplicate method %s", m.name)
-					check.errorf(mpos[other.(*Func)], "\tother declaration of %s", m.name) // secondary error, \t indented
+					check.errorf(pos, DuplicateDecl, "duplicate method %s", m.name)
+					check.errorf(mpos[other.(*Func)], DuplicateDecl, "\tother declaration of %s", m.name) // secondary error, \t indented
 				}
 			})
 		}
@@ -906,7 +904,7 @@ func (check *Checker) completeInterface(pos token.Pos, ityp *Interface) {
 				} else {
 					format = "%s is not an interface"
 				}
-				check.errorf(pos, format, typ)
+				check.errorf(pos, InvalidIfaceEmbed, format, typ)
 			}
 			continue
 		}
@@ -1053,7 +1051,7 @@ func (check *Checker) structType(styp *Struct, e *ast.StructType) {
 			pos := f.Type.Pos()
 			name := embeddedFieldIdent(f.Type)
 			if name == nil {
-				check.errorf(pos, "invalid embedded field type %s", f.Type)
+				check.errorf(pos, InvalidSyntaxTree, "invalid embedded field type %s", f.Type)
 				name = ast.NewIdent("_")
 				name.NamePos = pos
 				addInvalid(name, pos)
@@ -1076,13 +1074,13 @@ func (check *Checker) structType(styp *Struct, e *ast.StructType) {
 					}
 					// unsafe.Pointer is treated like a regular pointer
 					if t.kind == UnsafePointer {
-						check.errorf(embeddedPos, "embedded field type cannot be unsafe.Pointer")
+						check.errorf(embeddedPos, InvalidPtrEmbed, "embedded field type cannot be unsafe.Pointer")
 					}
 				case *Pointer:
-					check.errorf(embeddedPos, "embedded field type cannot be a pointer")
+					check.errorf(embeddedPos, InvalidPtrEmbed, "embedded field type cannot be a pointer")
 				case *Interface:
 					if isPtr {
-						check.errorf(embeddedPos, "embedded field type cannot be a pointer to an interface")
+						check.errorf(embeddedPos, InvalidPtrEmbed, "embedded field type cannot be a pointer to an interface")
 					}
 				}
 			})
@@ -1127,7 +1125,7 @@ func (check *Checker) collectTypeConstraints(pos token.Pos, types []ast.Expr) []
 		const restricted = false
 		var why string
 		if restricted && !check.typeConstraint(typ, &why) {
-			check.errorf(texpr.Pos(), "invalid type constraint %s (%s)", typ, why)
+			check.errorf(texpr.Pos(), InvalidConstraint, "invalid type constraint %s (%s)", typ, why)
 			continue
 		}
 		list = append(list, typ)
@@ -1144,7 +1142,7 @@ func (check *Checker) collectTypeConstraints(pos token.Pos, types []ast.Expr) []
 				check.completeInterface(types[i].Pos(), t)
 			}
 			if contains(uniques, t) {
-				check.softErrorf(types[i].Pos(), "duplicate type %s in type list", t)
+				check.softErrorf(types[i].Pos(), InvalidTypeList, "duplicate type %s in type list", t)
 			}
 			uniques = append(uniques, t)
 		}
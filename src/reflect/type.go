@@ -870,7 +870,16 @@ func (t *rtype) Name() string {
 	}
 	s := t.String()
 	i := len(s) - 1
-	for i >= 0 && s[i] != '.' {
+	// The names of instantiated types, as in Set[pkg.T],
+	// have qualified type arguments in brackets.
+	brackets := 0
+	for i >= 0 && (s[i] != '.' || brackets != 0) {
+		switch s[i] {
+		case ']':
+			brackets++
+		case '[':
+			brackets--
+		}
 		i--
 	}
 	return s[i+1:]
@@ -238,8 +238,7 @@ func main() {
 				if c != 4 {
 					ppanic("c != 4")
 				}
-				for i := 0; i < 10; i++ { // prevent inlining
-				}
+				recover() // prevent inlining
 			}()
 		}()
 		if c != 4 {
@@ -127,8 +127,7 @@ func s1(x int) int {
 	return foo() // ERROR "inlining call to s1.func1"
 }
 
-// can't currently inline functions with a break statement
-func switchBreak(x, y int) int {
+func switchBreak(x, y int) int { // ERROR "can inline switchBreak"
 	var n int
 	switch x {
 	case 0:
@@ -198,3 +197,58 @@ func gg(x int) { // ERROR "can inline gg"
 func hh(x int) { // ERROR "can inline hh"
 	ff(x - 1) // ERROR "inlining call to ff"  // ERROR "inlining call to gg"
 }
+
+func for1(fn func() bool) { // ERROR "can inline for1" "fn does not escape"
+	for {
+		if fn() {
+			break
+		} else {
+			continue
+		}
+	}
+}
+
+func for2(fn func() bool) { // ERROR "can inline for2" "fn does not escape"
+Loop:
+	for {
+		if fn() {
+			break Loop
+		} else {
+			continue Loop
+		}
+	}
+}
+
+func for3(s []int, x int) int { // ERROR "can inline for3" "s does not escape"
+	for i, v := range s {
+		if v == x {
+			return i
+		}
+	}
+	return -1
+}
+
+func for4(s [][]int, x int) bool { // ERROR "can inline for4" "s does not escape"
+Outer:
+	for _, t := range s {
+		for _, v := range t {
+			if v == x {
+				return true
+			}
+			if v > x {
+				continue Outer
+			}
+		}
+	}
+	return false
+}
+
+func callFor(fn func() bool, s []int, t [][]int) int { // ERROR "fn does not escape" "s does not escape" "t does not escape"
+	for1(fn) // ERROR "inlining call to for1"
+	for2(fn) // ERROR "inlining call to for2"
+	for2(fn) // ERROR "inlining call to for2"
+	if for4(t, 1) { // ERROR "inlining call to for4"
+		return 0
+	}
+	return for3(s, 1) // ERROR "inlining call to for3"
+}
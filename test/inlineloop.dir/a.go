@@ -0,0 +1,52 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+func Index(s []int, x int) int {
+	for i, v := range s {
+		if v == x {
+			return i
+		}
+	}
+	return -1
+}
+
+func Max(s []int) int {
+	m := s[0]
+	for _, v := range s[1:] {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+func Sum(n int) int {
+	t := 0
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			continue
+		}
+		t += i
+	}
+	return t
+}
+
+func Find(s [][]int, x int) (int, int) {
+	i, j := -1, -1
+Outer:
+	for k, t := range s {
+		for l, v := range t {
+			if v > x {
+				continue Outer
+			}
+			if v == x {
+				i, j = k, l
+				break Outer
+			}
+		}
+	}
+	return i, j
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"./a"
+)
+
+func main() {
+	s := []int{3, 1, 4, 1, 5}
+	if got := a.Index(s, 4) + a.Index(s, 9); got != 1 {
+		panic(fmt.Sprintf("Index: got %d, want 1", got))
+	}
+	if got := a.Max(s); got != 5 {
+		panic(fmt.Sprintf("Max: got %d, want 5", got))
+	}
+	if got := a.Sum(10) + a.Sum(4); got != 29 {
+		panic(fmt.Sprintf("Sum: got %d, want 29", got))
+	}
+
+	t := [][]int{{1, 9, 2}, {2, 3}, {4, 2}}
+	// Inline Find twice into the same function, so that its
+	// labels must be kept apart.
+	i, j := a.Find(t, 2)
+	k, l := a.Find(t, 4)
+	if i != 1 || j != 0 || k != 2 || l != 0 {
+		panic(fmt.Sprintf("Find: got (%d, %d), (%d, %d), want (1, 0), (2, 0)", i, j, k, l))
+	}
+}
@@ -1,6 +1,6 @@
 package x
 
-func indexByte(xs []byte, b byte) int { // ERROR "xs does not escape"
+func indexByte(xs []byte, b byte) int { // ERROR "can inline indexByte" "xs does not escape"
 	for i, x := range xs {
 		if x == b {
 			return i